@@ -0,0 +1,70 @@
+package pjson
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/charlievieth/pjson/termcolor"
+)
+
+// A subtree is an object or array being formatted by IndentAnnotated.
+type subtree struct {
+	start  int // offset of its opening bracket in src
+	commas int // commas between its members or elements
+}
+
+var annotationColor = termcolor.NewColor(termcolor.Faint)
+
+// An annotation describes an object or array closed by IndentAnnotated.
+type annotation struct {
+	object bool // an object, else an array
+	n      int  // members or elements, 0 if there is no annotation
+	size   int  // size in src
+}
+
+// writeAnnotation writes the comment of a, if any, to dst and returns the
+// zero annotation.
+func (conf *IndentConfig) writeAnnotation(dst *bytes.Buffer, a annotation) annotation {
+	if a.n == 0 {
+		return a
+	}
+	var buf [64]byte
+	b := append(buf[:0], " // "...)
+	b = strconv.AppendInt(b, int64(a.n), 10)
+	switch {
+	case a.object && a.n == 1:
+		b = append(b, " key, "...)
+	case a.object:
+		b = append(b, " keys, "...)
+	case a.n == 1:
+		b = append(b, " item, "...)
+	default:
+		b = append(b, " items, "...)
+	}
+	b = appendSize(b, a.size)
+	if conf.isPlain() {
+		dst.Write(b)
+	} else {
+		dst.WriteString(annotationColor.Format())
+		dst.Write(b)
+		dst.WriteString(annotationColor.Reset())
+	}
+	return annotation{}
+}
+
+// appendSize appends the size of n bytes in human readable form, such as
+// "512 B" or "18.3 KB", to dst. Sizes use units of 1024 bytes.
+func appendSize(dst []byte, n int) []byte {
+	if n < 1024 {
+		dst = strconv.AppendInt(dst, int64(n), 10)
+		return append(dst, " B"...)
+	}
+	size := float64(n) / 1024
+	unit := 0
+	for size >= 1024 && unit < 3 {
+		size /= 1024
+		unit++
+	}
+	dst = strconv.AppendFloat(dst, size, 'f', 1, 64)
+	return append(dst, [...]string{" KB", " MB", " GB", " TB"}[unit]...)
+}
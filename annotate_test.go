@@ -0,0 +1,63 @@
+package pjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/charlievieth/pjson/termcolor"
+)
+
+func TestIndentAnnotated(t *testing.T) {
+	in := `{"a":[1,2,{"x":null}],"b":{},"c":[true]}`
+	want := strings.Join([]string{
+		`{`,
+		`  "a": [`,
+		`    1,`,
+		`    2,`,
+		`    {`,
+		`      "x": null`,
+		`    } // 1 key, 10 B`,
+		`  ], // 3 items, 16 B`,
+		`  "b": {},`,
+		`  "c": [`,
+		`    true`,
+		`  ] // 1 item, 6 B`,
+		`} // 3 keys, 40 B`,
+	}, "\n")
+	for _, conf := range []*IndentConfig{&noColorIndentConfig, &DefaultIndentConfig} {
+		var dst bytes.Buffer
+		if err := conf.IndentAnnotated(&dst, []byte(in), "", "  "); err != nil {
+			t.Fatal(err)
+		}
+		if got := string(termcolor.Strip(nil, dst.Bytes())); got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	}
+
+	var dst bytes.Buffer
+	if err := noColorIndentConfig.IndentAnnotated(&dst, []byte(`[1,`), "", "  "); err == nil {
+		t.Error("expected an error")
+	}
+	if dst.Len() != 0 {
+		t.Errorf("wrote output on error: %q", dst.String())
+	}
+}
+
+func TestAppendSize(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{18739, "18.3 KB"},
+		{5 * 1024 * 1024, "5.0 MB"},
+	}
+	for _, test := range tests {
+		if got := string(appendSize(nil, test.n)); got != test.want {
+			t.Errorf("appendSize(%d) = %q; want: %q", test.n, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package pjson
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Canonicalize appends to dst the JSON Canonicalization Scheme (RFC 8785)
+// form of the JSON value src: object members sorted by the UTF-16 code
+// units of their keys, numbers formatted like ECMAScript, strings with
+// only the required escapes and no whitespace. It is an error if src has
+// duplicate keys or a number that is not representable as an IEEE 754
+// double, as required by I-JSON (RFC 7493).
+func Canonicalize(dst *bytes.Buffer, src []byte) error {
+	var buf bytes.Buffer
+	if err := compact(&buf, src, false); err != nil {
+		return err
+	}
+	b, err := appendCanonical(nil, buf.Bytes())
+	if err != nil {
+		return err
+	}
+	dst.Write(b)
+	return nil
+}
+
+// appendCanonical appends the canonical form of the compact JSON value src
+// to dst.
+func appendCanonical(dst, src []byte) ([]byte, error) {
+	var err error
+	switch kindOf(src[0]) {
+	case KindObject:
+		members := objectMembers(nil, src)
+		keys := make([][]uint16, len(members))
+		for i := range members {
+			keys[i] = utf16.Encode([]rune(members[i].key))
+		}
+		sort.Sort(&utf16Members{members, keys})
+		dst = append(dst, '{')
+		for i, m := range members {
+			if i > 0 {
+				if m.key == members[i-1].key {
+					return dst, fmt.Errorf("pjson: duplicate key %q", m.key)
+				}
+				dst = append(dst, ',')
+			}
+			dst = appendCanonicalString(dst, m.key)
+			dst = append(dst, ':')
+			if dst, err = appendCanonical(dst, m.value); err != nil {
+				return dst, err
+			}
+		}
+		return append(dst, '}'), nil
+	case KindArray:
+		dst = append(dst, '[')
+		for i, e := range arrayElems(nil, src) {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			if dst, err = appendCanonical(dst, e); err != nil {
+				return dst, err
+			}
+		}
+		return append(dst, ']'), nil
+	case KindString:
+		s, _ := unquote(src)
+		return appendCanonicalString(dst, s), nil
+	case KindNumber:
+		f, err := strconv.ParseFloat(string(src), 64)
+		if err != nil || math.IsInf(f, 0) {
+			return dst, fmt.Errorf("pjson: number %s is out of the range of an IEEE 754 double", src)
+		}
+		return appendES6Number(dst, f), nil
+	}
+	return append(dst, src...), nil
+}
+
+// utf16Members sorts object members by their keys encoded as UTF-16.
+type utf16Members struct {
+	members []member
+	keys    [][]uint16
+}
+
+func (m *utf16Members) Len() int { return len(m.members) }
+
+func (m *utf16Members) Less(i, j int) bool {
+	a, b := m.keys[i], m.keys[j]
+	for k := 0; k < len(a) && k < len(b); k++ {
+		if a[k] != b[k] {
+			return a[k] < b[k]
+		}
+	}
+	return len(a) < len(b)
+}
+
+func (m *utf16Members) Swap(i, j int) {
+	m.members[i], m.members[j] = m.members[j], m.members[i]
+	m.keys[i], m.keys[j] = m.keys[j], m.keys[i]
+}
+
+// appendCanonicalString appends s as a JSON string to dst escaping only
+// quotes, backslashes and control characters, which use the short escapes
+// if they have one.
+func appendCanonicalString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, n := utf8.DecodeRuneInString(s[i:])
+			dst = utf8.AppendRune(dst, r)
+			i += n
+			continue
+		}
+		switch c {
+		case '"', '\\':
+			dst = append(dst, '\\', c)
+		case '\b':
+			dst = append(dst, '\\', 'b')
+		case '\f':
+			dst = append(dst, '\\', 'f')
+		case '\n':
+			dst = append(dst, '\\', 'n')
+		case '\r':
+			dst = append(dst, '\\', 'r')
+		case '\t':
+			dst = append(dst, '\\', 't')
+		default:
+			if c < 0x20 {
+				dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+			} else {
+				dst = append(dst, c)
+			}
+		}
+		i++
+	}
+	return append(dst, '"')
+}
+
+// appendES6Number appends f formatted like the ECMAScript
+// Number.prototype.toString method to dst.
+func appendES6Number(dst []byte, f float64) []byte {
+	if f == 0 {
+		return append(dst, '0') // and -0
+	}
+	if f < 0 {
+		dst = append(dst, '-')
+		f = -f
+	}
+	// The shortest digits that round trip and the decimal exponent n such
+	// that f = 0.digits × 10^n.
+	b := strconv.AppendFloat(nil, f, 'e', -1, 64)
+	e := bytes.IndexByte(b, 'e')
+	exp, _ := strconv.Atoi(string(b[e+1:]))
+	digits := b[:1:1]
+	if e > 1 {
+		digits = append(digits, b[2:e]...) // skip the '.'
+	}
+	n := exp + 1
+	k := len(digits)
+	switch {
+	case k <= n && n <= 21:
+		dst = append(dst, digits...)
+		for i := k; i < n; i++ {
+			dst = append(dst, '0')
+		}
+	case 0 < n && n <= 21:
+		dst = append(dst, digits[:n]...)
+		dst = append(dst, '.')
+		dst = append(dst, digits[n:]...)
+	case -6 < n && n <= 0:
+		dst = append(dst, '0', '.')
+		for i := n; i < 0; i++ {
+			dst = append(dst, '0')
+		}
+		dst = append(dst, digits...)
+	default:
+		dst = append(dst, digits[0])
+		if k > 1 {
+			dst = append(dst, '.')
+			dst = append(dst, digits[1:]...)
+		}
+		dst = append(dst, 'e')
+		if n-1 > 0 {
+			dst = append(dst, '+')
+		}
+		dst = strconv.AppendInt(dst, int64(n-1), 10)
+	}
+	return dst
+}
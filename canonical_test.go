@@ -0,0 +1,91 @@
+package pjson
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		// RFC 8785 section 3.2.2.
+		{
+			`{
+  "numbers": [333333333.33333329, 1E30, 4.50,
+              2e-3, 0.000000000000000000000000001],
+  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+  "literals": [null, true, false]
+}`,
+			`{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`,
+		},
+		// RFC 8785 section 3.2.3: sorted by UTF-16 code units.
+		{
+			`{"\u20ac": "Euro Sign", "\r": "Carriage Return", "\ufb33": "Hebrew Letter Dalet With Dagesh",
+			  "1": "One", "\ud83d\ude00": "Emoji: Grinning Face", "\u0080": "Control", "\u00f6": "Latin Small Letter O With Diaeresis"}`,
+			"{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\"," +
+				"\"\u20ac\":\"Euro Sign\",\"\U0001f600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+		{`"<&>\u2028"`, "\"<&>\u2028\""},
+		{`[-0, 0.0, 1e21, 1e20, 123e-9, 1e-6, 1e-7, -1.5e-10, 100]`, `[0,0,1e+21,100000000000000000000,1.23e-7,0.000001,1e-7,-1.5e-10,100]`},
+		{`{"b":{"d":1,"c":2},"a":[]}`, `{"a":[],"b":{"c":2,"d":1}}`},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := Canonicalize(&buf, []byte(test.in)); err != nil {
+			t.Errorf("%s: %v", test.in, err)
+			continue
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("%s:\ngot:  %s\nwant: %s", test.in, got, test.want)
+		}
+	}
+}
+
+func TestCanonicalizeError(t *testing.T) {
+	for _, in := range []string{`{"a":1,"a":2}`, `1e400`, `[-1e309]`, `{"a"`} {
+		var buf bytes.Buffer
+		if err := Canonicalize(&buf, []byte(in)); err == nil {
+			t.Errorf("%s: expected an error", in)
+		}
+	}
+}
+
+func TestAppendES6Number(t *testing.T) {
+	// RFC 8785 appendix B.
+	tests := []struct {
+		bits uint64
+		want string
+	}{
+		{0x0000000000000000, "0"},
+		{0x8000000000000000, "0"},
+		{0x0000000000000001, "5e-324"},
+		{0x8000000000000001, "-5e-324"},
+		{0x7fefffffffffffff, "1.7976931348623157e+308"},
+		{0xffefffffffffffff, "-1.7976931348623157e+308"},
+		{0x4340000000000000, "9007199254740992"},
+		{0xc340000000000000, "-9007199254740992"},
+		{0x4430000000000000, "295147905179352830000"},
+		{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+		{0x44b52d02c7e14af6, "1e+23"},
+		{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+		{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+		{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+		{0x444b1ae4d6e2ef50, "1e+21"},
+		{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+		{0x3eb0c6f7a0b5ed8d, "0.000001"},
+		{0x41b3de4355555553, "333333333.3333332"},
+		{0x41b3de4355555554, "333333333.33333325"},
+		{0x41b3de4355555555, "333333333.3333333"},
+		{0x41b3de4355555556, "333333333.3333334"},
+		{0x41b3de4355555557, "333333333.33333343"},
+		{0xbecbf647612f3696, "-0.0000033333333333333333"},
+		{0x43143ff3c1cb0959, "1424953923781206.2"},
+	}
+	for _, test := range tests {
+		if got := string(appendES6Number(nil, math.Float64frombits(test.bits))); got != test.want {
+			t.Errorf("%#016x: got: %s want: %s", test.bits, got, test.want)
+		}
+	}
+}
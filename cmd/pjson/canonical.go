@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/charlievieth/pjson"
+	"github.com/spf13/cobra"
+)
+
+// runCanonicalize writes the canonical form of each of the named files, or
+// STDIN if there are none, to w. The forms are separated by newlines.
+// Input that cannot be read or is not valid exits with status 2.
+func runCanonicalize(w io.Writer, names []string) error {
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	out := bufio.NewWriter(w)
+	var buf bytes.Buffer
+	for i, name := range names {
+		data, err := readInput(name)
+		if err == nil {
+			buf.Reset()
+			err = pjson.Canonicalize(&buf, data)
+		}
+		if err != nil {
+			return &statusError{
+				status: exitInputError,
+				err:    fmt.Errorf("%s: %w", displayName(name), err),
+			}
+		}
+		if i > 0 {
+			out.WriteByte('\n')
+		}
+		if _, err := out.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+func newCanonicalizeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "canonicalize [file]...",
+		Short: "Print the JSON Canonicalization Scheme form of JSON documents",
+		Long: "Print the JSON Canonicalization Scheme (RFC 8785) form of each file,\n" +
+			"or STDIN: object members sorted by key, numbers formatted like\n" +
+			"ECMAScript and no whitespace. The output of a single file is not\n" +
+			"followed by a newline so it can be hashed or signed as it is; the\n" +
+			"output of multiple files is separated by newlines. Documents with\n" +
+			"duplicate keys or numbers that are out of the range of a double are\n" +
+			"rejected.",
+		Aliases: []string{"jcs"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCanonicalize(os.Stdout, args)
+		},
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charlievieth/pjson"
+	"github.com/spf13/cobra"
+)
+
+// maxCaptureSize limits the size of the input saved by --capture-on-error.
+const maxCaptureSize = 1024 * 1024
+
+// captureOptions are the formatting options a capture is replayed with.
+type captureOptions struct {
+	Indent     string `json:"indent"`
+	Compact    bool   `json:"compact"`
+	SortKeys   string `json:"sort_keys,omitempty"`
+	EscapeHTML bool   `json:"escape_html,omitempty"`
+}
+
+// newStream returns a Stream that formats values with the options o.
+func (o *captureOptions) newStream(r io.Reader) (*pjson.Stream, error) {
+	var conf pjson.IndentConfig
+	stream := pjson.NewStream(r, &conf)
+	stream.SetIndent("", o.Indent)
+	stream.SetCompact(o.Compact)
+	stream.SetEscapeHTML(o.EscapeHTML)
+	if o.SortKeys != "" {
+		order, err := pjson.ParseKeyOrder(o.SortKeys)
+		if err != nil {
+			return nil, err
+		}
+		stream.SetSortKeys(order)
+		stream.SetStreamArrays(true)
+	}
+	return stream, nil
+}
+
+// A capture describes a value that pjson failed to format. The value is
+// saved next to it in a file with the extension ".input".
+type capture struct {
+	Version   string         `json:"version"`
+	Input     string         `json:"input"`
+	Index     int64          `json:"index"`
+	Offset    int64          `json:"offset"`
+	Error     string         `json:"error"`
+	State     []string       `json:"scanner_state"`
+	Size      int            `json:"size"`
+	Truncated bool           `json:"truncated"`
+	Redacted  bool           `json:"redacted"`
+	Options   captureOptions `json:"options"`
+}
+
+// redactValue appends src to dst with the letters and digits of strings
+// replaced by 'x' and '0' and other non-ASCII characters replaced by
+// 'x' once per byte. The structure of src and the offset of every byte is
+// preserved, so syntax errors are reproduced. Escape sequences are kept.
+func redactValue(dst, src []byte) []byte {
+	inString := false
+	escaped := false
+	for _, c := range src {
+		switch {
+		case !inString:
+			inString = c == '"'
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			inString = false
+		case '0' <= c && c <= '9':
+			c = '0'
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', c >= utf8.RuneSelf:
+			c = 'x'
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
+
+// writeCapture saves the value that caused the error err, reading the
+// input named name, to dir if err is a *pjson.StreamError. It returns the
+// name of the capture's metadata file.
+func writeCapture(dir, name string, err error, opts *captureOptions, redact bool) (string, error) {
+	var se *pjson.StreamError
+	if !errors.As(err, &se) {
+		return "", nil
+	}
+	value := se.Value
+	c := capture{
+		Version:  version,
+		Input:    displayName(name),
+		Index:    se.Index,
+		Offset:   se.Offset,
+		Error:    se.Err.Error(),
+		Size:     len(value),
+		Redacted: redact,
+		Options:  *opts,
+	}
+	if len(value) > maxCaptureSize {
+		value = value[:maxCaptureSize]
+		c.Truncated = true
+	}
+	if redact {
+		value = redactValue(nil, value)
+	}
+	for _, p := range se.State {
+		c.State = append(c.State, p.String())
+	}
+	data, err := pjson.MarshalIndent(&c, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(dir, "capture-*.input")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(value); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	meta := strings.TrimSuffix(f.Name(), ".input") + ".json"
+	return meta, os.WriteFile(meta, append(data, '\n'), 0644)
+}
+
+// replayCapture formats the input of the capture described by the
+// metadata file meta. It returns the capture, or nil if it could not be
+// read, and the error formatting its input, if any.
+func replayCapture(meta string) (*capture, error) {
+	data, err := os.ReadFile(meta)
+	if err != nil {
+		return nil, err
+	}
+	var c capture
+	if err := pjson.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%s: %w", meta, err)
+	}
+	input, err := os.ReadFile(strings.TrimSuffix(meta, filepath.Ext(meta)) + ".input")
+	if err != nil {
+		return nil, err
+	}
+	stream, err := c.Options.newStream(bytes.NewReader(input))
+	if err != nil {
+		return nil, err
+	}
+	_, err = stream.WriteTo(io.Discard)
+	return &c, err
+}
+
+func newReplayCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "replay capture.json...",
+		Short: "Re-run the values saved by --capture-on-error",
+		Long: "Format the value saved by --capture-on-error with the options it\n" +
+			"was captured with and report if the error still occurs. The exit\n" +
+			"status is 1 if any error is reproduced and 2 if a capture could not\n" +
+			"be read.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reproduced := false
+			for _, meta := range args {
+				c, err := replayCapture(meta)
+				if c == nil {
+					return &statusError{status: exitInputError, err: err}
+				}
+				if err != nil {
+					reproduced = true
+					fmt.Printf("%s: reproduced: %v\n", meta, err)
+				} else {
+					fmt.Printf("%s: no error (captured: %s)\n", meta, c.Error)
+				}
+			}
+			if reproduced {
+				return exitStatusError(1)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charlievieth/pjson"
+	"github.com/charlievieth/pjson/termcolor"
+)
+
+// jqColorFields are the token classes colored by the fields of JQ_COLORS,
+// in order. pjson colors arrays and objects with the same color, so the
+// later objects field wins.
+var jqColorFields = []string{"null", "false", "true", "number", "string", "array", "object", "key"}
+
+// setColorField sets the color of the token class named name in conf.
+func setColorField(conf *pjson.IndentConfig, name, params string) error {
+	c, err := termcolor.ParseColor(params)
+	if err != nil {
+		return err
+	}
+	switch name {
+	case "null":
+		conf.Null = c
+	case "false":
+		conf.False = c
+	case "true":
+		conf.True = c
+	case "number":
+		conf.Numeric = c
+	case "string":
+		conf.String = c
+	case "array", "object", "punct":
+		conf.Punctuation = c
+	case "key":
+		conf.Keyword = c
+	case "emptykey":
+		conf.EmptyKey = c
+	default:
+		return fmt.Errorf("unknown color field: %q", name)
+	}
+	return nil
+}
+
+// parseColorEnv sets the colors of conf from the value of JQ_COLORS, a
+// colon separated list of SGR parameters for the token classes in
+// jqColorFields (e.g. "0;90:0;37:0;37:0;37:0;32:1;37:1;37:34;1"). Fields
+// may be omitted from the end of the list. If named is true the list may
+// end with "field=SGR" entries, which set any class by name (the
+// PJSON_COLORS superset). Named fields are those of jqColorFields and
+// "punct" and "emptykey".
+func parseColorEnv(conf *pjson.IndentConfig, value string, named bool) error {
+	sawNamed := false
+	for i, field := range strings.Split(value, ":") {
+		name, params, ok := strings.Cut(field, "=")
+		switch {
+		case ok && named:
+			sawNamed = true
+		case ok || sawNamed:
+			return fmt.Errorf("invalid color field: %q", field)
+		case i >= len(jqColorFields):
+			return fmt.Errorf("too many color fields: %q", value)
+		default:
+			name, params = jqColorFields[i], field
+		}
+		if err := setColorField(conf, name, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultColors returns the colors pjson uses when output is colored.
+func defaultColors() pjson.IndentConfig {
+	return envColors(pjson.DefaultIndentConfig)
+}
+
+// envColors returns conf modified by JQ_COLORS and then PJSON_COLORS.
+// Invalid variables are reported to STDERR and ignored, like jq does.
+func envColors(conf pjson.IndentConfig) pjson.IndentConfig {
+	for _, env := range []string{"JQ_COLORS", "PJSON_COLORS"} {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		next := conf
+		if err := parseColorEnv(&next, value, env == "PJSON_COLORS"); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: ignoring %s: %v\n", env, err)
+			continue
+		}
+		conf = next
+	}
+	return conf
+}
+
+// colorFlags are the --color-* flags that set the color of a token class,
+// the name of the class passed to setColorField and what it colors.
+var colorFlags = []struct {
+	flag, field, desc string
+}{
+	{"color-key", "key", "object keys"},
+	{"color-string", "string", "strings"},
+	{"color-number", "number", "numbers"},
+	{"color-true", "true", "true"},
+	{"color-false", "false", "false"},
+	{"color-null", "null", "null"},
+	{"color-punct", "punct", "brackets, braces, colons and commas"},
+	{"color-empty-key", "emptykey", "empty and white space object keys"},
+}
+
+// setColorFlags sets the colors of conf from the values of the colorFlags,
+// in order. Empty values are ignored.
+func setColorFlags(conf *pjson.IndentConfig, values []*string) error {
+	for i, f := range colorFlags {
+		if *values[i] == "" {
+			continue
+		}
+		if err := setColorField(conf, f.field, *values[i]); err != nil {
+			return fmt.Errorf("invalid --%s: %w", f.flag, err)
+		}
+	}
+	return nil
+}
+
+// themeColors returns the colors of the theme name, for a terminal with
+// the color capability level, modified by the environment.
+func themeColors(name string, level termcolor.Level) (pjson.IndentConfig, error) {
+	conf, ok := pjson.Theme(name, level)
+	if !ok {
+		return pjson.IndentConfig{}, fmt.Errorf("unknown theme: %q (see --theme list)", name)
+	}
+	return envColors(*conf), nil
+}
+
+// writeThemes writes the names of the built-in themes, each in its own
+// colors if colored is true, to w.
+func writeThemes(w io.Writer, level termcolor.Level, colored bool) error {
+	out := bufio.NewWriter(w)
+	for _, name := range pjson.ThemeNames() {
+		if !colored {
+			fmt.Fprintln(out, name)
+			continue
+		}
+		conf, _ := pjson.Theme(name, level)
+		var buf bytes.Buffer
+		sample := `{"key": "string", "number": 1.5, "true": true, "null": null}`
+		if err := conf.Compact(&buf, []byte(sample)); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%-16s %s\n", name, buf.Bytes())
+	}
+	return out.Flush()
+}
+
+// writeColorExplanation writes the decision d, for the output named
+// output, and every check it was based on to w.
+func writeColorExplanation(w io.Writer, output string, d *termcolor.Decision) error {
+	out := bufio.NewWriter(w)
+	state := "disabled"
+	if d.Enabled {
+		state = "enabled"
+	}
+	fmt.Fprintf(out, "color %s for %s: %s\n", state, output, d.Reason)
+	for _, c := range d.Checks {
+		if c.Note != "" {
+			fmt.Fprintf(out, "  %-16s %-12s # %s\n", c.Name, c.Value, c.Note)
+		} else {
+			fmt.Fprintf(out, "  %-16s %s\n", c.Name, c.Value)
+		}
+	}
+	return out.Flush()
+}
+
+// runPassthrough writes the named files (or STDIN if there are none),
+// which may already be colored, to w indented with their color preserved.
+func runPassthrough(w io.Writer, names []string, indent string) error {
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	var buf bytes.Buffer
+	for _, name := range names {
+		buf.Reset()
+		data, err := readInput(name)
+		if err == nil {
+			err = pjson.IndentPassthrough(&buf, data, "", indent)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", displayName(name), err)
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		if _, err := buf.WriteTo(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
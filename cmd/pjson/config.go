@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/charlievieth/pjson"
+	"github.com/spf13/cobra"
+)
+
+// configGroups are flags that select the same setting. If any flag of a
+// group is set on the command line the config file values of the group
+// are ignored.
+var configGroups = [][]string{
+	{"indent", "tab", "indent-string"},
+	{"color", "monochrome"},
+}
+
+// configDir returns the directory of the config file: $XDG_CONFIG_HOME/pjson
+// or ~/.config/pjson.
+func configDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "pjson"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "pjson"), nil
+}
+
+// readConfig reads the options of the first of config.json and
+// config.toml that exists in dir. It returns the name of the file read,
+// which is empty if there is none.
+func readConfig(dir string) (string, map[string]string, error) {
+	for _, ext := range []string{".json", ".toml"} {
+		name := filepath.Join(dir, "config"+ext)
+		data, err := os.ReadFile(name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return name, nil, err
+		}
+		var opts map[string]string
+		if ext == ".json" {
+			opts, err = parseJSONConfig(data)
+		} else {
+			opts, err = parseTOMLConfig(data)
+		}
+		if err != nil {
+			return name, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return name, opts, nil
+	}
+	return "", nil, nil
+}
+
+// parseJSONConfig parses a config file that is a JSON object of flag
+// names and their values, such as {"indent": 2, "theme": "dracula"}.
+func parseJSONConfig(data []byte) (map[string]string, error) {
+	var m map[string]interface{}
+	if err := pjson.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	opts := make(map[string]string, len(m))
+	for k, v := range m {
+		switch v := v.(type) {
+		case string:
+			opts[k] = v
+		case bool:
+			opts[k] = strconv.FormatBool(v)
+		case float64:
+			opts[k] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			return nil, fmt.Errorf("invalid value of %q: %v", k, v)
+		}
+	}
+	return opts, nil
+}
+
+// parseTOMLConfig parses a config file of TOML "key = value" pairs. Only
+// the subset of TOML needed to set flags is supported: comments, strings,
+// numbers and booleans. Tables and arrays are not.
+func parseTOMLConfig(data []byte) (map[string]string, error) {
+	opts := make(map[string]string)
+	lines := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; lines.Scan(); n++ {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value: %q", n, line)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		var err error
+		switch {
+		case strings.HasPrefix(value, `"`):
+			value, err = strconv.QuotedPrefix(value)
+			if err == nil {
+				value, err = strconv.Unquote(value)
+			}
+		case strings.HasPrefix(value, "'"):
+			if i := strings.IndexByte(value[1:], '\''); i >= 0 {
+				value = value[1 : i+1]
+			} else {
+				err = errors.New("unterminated string")
+			}
+		default:
+			if i := strings.IndexByte(value, '#'); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+			if _, e := strconv.ParseFloat(value, 64); e != nil && value != "true" && value != "false" {
+				err = fmt.Errorf("unsupported value: %q", value)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", n, key, err)
+		}
+		opts[key] = value
+	}
+	return opts, lines.Err()
+}
+
+// applyConfig sets the flags of cmd that were not set on the command line
+// to the values of the config file, if any. The "color" option also
+// accepts "always", "never" and "auto".
+func applyConfig(cmd *cobra.Command) error {
+	dir, err := configDir()
+	if err != nil {
+		return nil // no home directory: no config
+	}
+	name, opts, err := readConfig(dir)
+	if err != nil || opts == nil {
+		return err
+	}
+	flags := cmd.Flags()
+	for _, group := range configGroups {
+		for _, f := range group {
+			if flags.Changed(f) {
+				for _, f := range group {
+					delete(opts, f)
+				}
+				break
+			}
+		}
+	}
+	switch opts["color"] {
+	case "always":
+		opts["color"] = "true"
+	case "never":
+		delete(opts, "color")
+		opts["monochrome"] = "true"
+	case "auto":
+		delete(opts, "color")
+	}
+	for key, value := range opts {
+		if key == "no-config" || flags.Lookup(key) == nil {
+			return fmt.Errorf("%s: unknown option: %q", name, key)
+		}
+		if flags.Changed(key) {
+			continue
+		}
+		if err := flags.Set(key, value); err != nil {
+			return fmt.Errorf("%s: %s: %w", name, key, err)
+		}
+	}
+	return nil
+}
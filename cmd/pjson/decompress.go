@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// decompress returns a reader of the decompressed contents of r if it is
+// compressed with gzip, zstd or bzip2, which is detected by its magic
+// bytes, not by the name of the file, which may be wrong. Otherwise the
+// contents of r are returned unchanged.
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		// A single goroutine decodes synchronously, so the decoder
+		// does not need to be closed.
+		return zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		return bzip2.NewReader(br), nil
+	}
+	return br, nil
+}
+
+// A decompressReader reads the decompressed contents of a file and closes
+// the file.
+type decompressReader struct {
+	io.Reader
+	io.Closer
+}
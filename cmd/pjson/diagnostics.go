@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charlievieth/pjson"
+	"github.com/spf13/cobra"
+)
+
+// maxDiagnosticErrors is the maximum number of syntax errors reported
+// per file.
+const maxDiagnosticErrors = 100
+
+// readInput reads all of the named file or STDIN if name is empty,
+// decompressing it if it is compressed.
+func readInput(name string) ([]byte, error) {
+	f, err := openInput(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// reporter returns the lint reporter for format.
+func reporter(format string) (func(io.Writer, []pjson.Finding) error, error) {
+	switch format {
+	case "json":
+		return pjson.ReportJSON, nil
+	case "text":
+		return pjson.ReportText, nil
+	}
+	return nil, fmt.Errorf("invalid diagnostics format: %q (supported formats: json, text)", format)
+}
+
+// runDiagnostics writes the diagnostics for each of the named files (or
+// STDIN if there are none) to w in the given format (json or text) and
+// returns the number of diagnostics.
+func runDiagnostics(w io.Writer, format string, names []string) (int, error) {
+	report, err := reporter(format)
+	if err != nil {
+		return 0, err
+	}
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	var diags []pjson.Diagnostic
+	for _, name := range names {
+		data, err := readInput(name)
+		if err != nil {
+			return 0, err
+		}
+		for _, d := range pjson.Diagnose(data, maxDiagnosticErrors) {
+			d.File = displayName(name)
+			diags = append(diags, d)
+		}
+	}
+	return len(diags), report(w, diags)
+}
+
+// A lineReader counts the lines read from r so that ValidateStream errors
+// can be located by line and column, as the errors of a Stream are. It
+// keeps the bytes of the last read, which hold the error.
+type lineReader struct {
+	r         io.Reader
+	off       int64  // offset of last
+	lines     int64  // newlines before last
+	lineStart int64  // offset of the line containing the start of last
+	last      []byte // bytes of the last read
+}
+
+func (l *lineReader) Read(p []byte) (int, error) {
+	off, lines, lineStart := l.off+int64(len(l.last)), l.lines, l.lineStart
+	if i := bytes.LastIndexByte(l.last, '\n'); i >= 0 {
+		lines += int64(bytes.Count(l.last, []byte{'\n'}))
+		lineStart = l.off + int64(i) + 1
+	}
+	n, err := l.r.Read(p)
+	if n > 0 {
+		// Keep the last bytes read at EOF, where the error may be.
+		l.off, l.lines, l.lineStart = off, lines, lineStart
+		l.last = p[:n]
+	}
+	return n, err
+}
+
+// locate returns the line and column, starting at 1, of the syntax error
+// err and the line containing it, as much of it as was read, with the
+// index of the error in it. The line is 0 if err is not in the last read.
+func (l *lineReader) locate(err *pjson.SyntaxError) (line, col int64, source []byte, index int) {
+	pos := err.Offset
+	if pos > 0 {
+		pos-- // the offset is just past the offending byte
+	}
+	i := int(pos - l.off)
+	if i < 0 || i > len(l.last) {
+		return 0, 0, nil, 0
+	}
+	line = l.lines + int64(bytes.Count(l.last[:i], []byte{'\n'})) + 1
+	start := bytes.LastIndexByte(l.last[:i], '\n') + 1
+	lineStart := l.off + int64(start)
+	if start == 0 {
+		lineStart = l.lineStart
+	}
+	end := len(l.last)
+	if j := bytes.IndexByte(l.last[i:], '\n'); j >= 0 {
+		end = i + j
+	}
+	return line, pos - lineStart + 1, l.last[start:end], i - start
+}
+
+// runValidate checks that each of the named files (or STDIN if there are
+// none) is valid JSON, without formatting it, and writes a line for each
+// file that is not, located by line and column like the errors of other
+// inputs, and for each file that is if status is true, to w. It reports
+// whether all of the files were valid.
+func runValidate(w io.Writer, names []string, status bool) (bool, error) {
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	valid := true
+	for _, name := range names {
+		f, err := openInput(name)
+		lr := &lineReader{r: f}
+		if err == nil {
+			err = pjson.ValidateStream(lr)
+			f.Close()
+		}
+		if err != nil {
+			valid = false
+			var msg string
+			se, ok := err.(*pjson.SyntaxError)
+			line, col, source, index := int64(0), int64(0), []byte(nil), 0
+			if ok {
+				line, col, source, index = lr.locate(se)
+			}
+			switch {
+			case line > 0:
+				msg = fmt.Sprintf("%s:%d:%d: %v\n", displayName(name), line, col, err)
+			case ok:
+				msg = fmt.Sprintf("%s: offset %d: %v\n", displayName(name), se.Offset, err)
+			default:
+				msg = fmt.Sprintf("%s: %v\n", displayName(name), err)
+			}
+			if _, err := io.WriteString(w, msg); err != nil {
+				return false, err
+			}
+			writeSource(w, source, index)
+		} else if status {
+			if _, err := fmt.Fprintf(w, "%s: ok\n", displayName(name)); err != nil {
+				return false, err
+			}
+		}
+	}
+	return valid, nil
+}
+
+// runCheckSorted writes the object keys of each of the named files (or
+// STDIN if there are none) that are not sorted by order to w and reports
+// whether all of the files were valid and sorted.
+func runCheckSorted(w io.Writer, order pjson.KeyOrder, names []string) (bool, error) {
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	var findings []pjson.Finding
+	for _, name := range names {
+		f, err := openInput(name)
+		if err != nil {
+			return false, err
+		}
+		for _, d := range pjson.Lint(f, pjson.SortedKeysRule(order)) {
+			d.File = displayName(name)
+			findings = append(findings, d)
+		}
+		f.Close()
+	}
+	if err := pjson.ReportText(w, findings); err != nil {
+		return false, err
+	}
+	return len(findings) == 0, nil
+}
+
+// lintRules maps the names of the rules that may be selected by the lint
+// command to their constructors.
+var lintRules = map[string]func() pjson.Rule{
+	"duplicate-keys":  pjson.DuplicateKeysRule,
+	"large-integers":  pjson.LargeIntegersRule,
+	"numeric-strings": pjson.NumericStringsRule,
+	"empty-keys":      pjson.EmptyKeysRule,
+	"invalid-utf8":    pjson.InvalidUTF8Rule,
+	"mixed-types":     pjson.MixedTypesRule,
+	"sorted-keys":     func() pjson.Rule { return pjson.SortedKeysRule(nil) },
+	"max-depth":       func() pjson.Rule { return pjson.MaxDepthRule(pjson.LintMaxDepth) },
+}
+
+func parseRules(names string) ([]pjson.Rule, error) {
+	var rules []pjson.Rule
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		fn, ok := lintRules[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown lint rule: %q", name)
+		}
+		rules = append(rules, fn())
+	}
+	return rules, nil
+}
+
+func newLintCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint [flags] [file]...",
+		Short: "Report syntax errors and questionable constructs",
+		Long: "Report the syntax errors of each file, or STDIN, and warnings about\n" +
+			"questionable constructs, such as duplicate keys. The exit status is\n" +
+			"2 if an input could not be read or has a syntax error and, with\n" +
+			"--strict, 1 if any warnings are reported.",
+	}
+	format := cmd.Flags().String("format", "text", "Output format: text or json.")
+	ruleNames := cmd.Flags().String("rules", "",
+		"Comma separated list of rules to run (default all but sorted-keys).\n"+
+			"Rules: duplicate-keys, large-integers, numeric-strings, empty-keys,\n"+
+			"invalid-utf8, mixed-types, max-depth, sorted-keys.")
+	strict := cmd.Flags().Bool("strict", false,
+		"Exit with status 1 if any warnings are reported. Syntax errors\n"+
+			"always exit with status 2.")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		report, err := reporter(*format)
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			args = []string{""}
+		}
+		var findings []pjson.Finding
+		for _, name := range args {
+			f, err := openInput(name)
+			if err != nil {
+				return &statusError{status: exitInputError, err: err}
+			}
+			// Rules are stateful so create them for each file.
+			rules, err := parseRules(*ruleNames)
+			if err != nil {
+				f.Close()
+				return err
+			}
+			for _, d := range pjson.Lint(f, rules...) {
+				d.File = displayName(name)
+				findings = append(findings, d)
+			}
+			f.Close()
+		}
+		if err := report(os.Stdout, findings); err != nil {
+			return err
+		}
+		warnings := false
+		for _, f := range findings {
+			if f.Severity == pjson.SeverityError {
+				return exitStatusError(exitInputError)
+			}
+			warnings = true
+		}
+		if *strict && warnings {
+			return exitStatusError(1)
+		}
+		return nil
+	}
+	return cmd
+}
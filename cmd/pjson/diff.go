@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/charlievieth/pjson"
+	"github.com/charlievieth/pjson/termcolor"
+	"github.com/spf13/cobra"
+)
+
+// appendChangeLine appends a line for the value of a change to dst: the
+// sign ('-' for the old value, '+' for the new one), path and value. If
+// colored is true the sign is colored and control characters in strings
+// are escaped (see pjson.Sanitize).
+func appendChangeLine(dst []byte, conf *pjson.IndentConfig, sign byte, path pjson.Path, value []byte, colored bool) []byte {
+	var clr *termcolor.Color
+	if colored {
+		clr = termcolor.Green
+		if sign == '-' {
+			clr = termcolor.Red
+		}
+	}
+	dst = clr.Append(dst)
+	dst = append(dst, sign)
+	dst = append(dst, clr.Reset()...)
+	dst = append(dst, ' ')
+	dst = conf.AppendPath(dst, path, false)
+	dst = append(dst, ": "...)
+	vc := conf.ValueColor(value)
+	dst = vc.Append(dst)
+	if colored {
+		dst = pjson.Sanitize(dst, value)
+	} else {
+		dst = append(dst, value...)
+	}
+	dst = append(dst, vc.Reset()...)
+	return append(dst, '\n')
+}
+
+// writeChanges writes changes to w. Each removed value is written on a
+// line beginning with '-' and each added value on a line beginning with
+// '+'; replaced values are written as both.
+func writeChanges(w io.Writer, conf *pjson.IndentConfig, changes []pjson.Change, colored bool) error {
+	out := bufio.NewWriter(w)
+	var buf []byte
+	for _, c := range changes {
+		buf = buf[:0]
+		if c.Op != pjson.DiffAdd {
+			buf = appendChangeLine(buf, conf, '-', c.Path, c.Old, colored)
+		}
+		if c.Op != pjson.DiffRemove {
+			buf = appendChangeLine(buf, conf, '+', c.Path, c.New, colored)
+		}
+		if _, err := out.Write(buf); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+// readJSON reads the named file, or STDIN if name is empty, and returns
+// an error if it is not valid JSON.
+func readJSON(name string) ([]byte, error) {
+	data, err := readInput(name)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := pjson.Compact(&buf, data); err != nil {
+		return nil, fmt.Errorf("%s: %w", displayName(name), err)
+	}
+	return data, nil
+}
+
+// writePatch writes the JSON Patch that applies changes to w, indented.
+func writePatch(w io.Writer, conf *pjson.IndentConfig, changes []pjson.Change) error {
+	var buf bytes.Buffer
+	if err := conf.Indent(&buf, pjson.AppendPatch(nil, changes), "", "    "); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// runDiff writes the differences between the named files to w in the
+// given output format (text or jsonpatch) and reports whether they are
+// equal.
+func runDiff(w io.Writer, conf *pjson.IndentConfig, opts *pjson.DiffOptions, output, name1, name2 string, colored bool) (bool, error) {
+	a, err := readJSON(name1)
+	if err != nil {
+		return false, err
+	}
+	b, err := readJSON(name2)
+	if err != nil {
+		return false, err
+	}
+	changes, err := pjson.Diff(a, b, opts)
+	if err != nil {
+		return false, err
+	}
+	if output == "jsonpatch" {
+		return len(changes) == 0, writePatch(w, conf, changes)
+	}
+	return len(changes) == 0, writeChanges(w, conf, changes, colored)
+}
+
+func newDiffCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff [flags] old.json new.json",
+		Short: "Print the structural differences between two JSON documents",
+		Long: "Print the structural differences between two JSON documents, one\n" +
+			"value per line with its path. Removed values are prefixed with '-'\n" +
+			"and added values with '+'. As with diff(1) the exit status is 0 if\n" +
+			"the documents are equal, 1 if they differ and 2 if there was an\n" +
+			"error, such as a file that is not valid JSON.",
+		Args: func(cmd *cobra.Command, args []string) error {
+			return diffError(cobra.ExactArgs(2)(cmd, args))
+		},
+	}
+	cmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return diffError(err)
+	})
+	forceColor := cmd.Flags().BoolP("color", "C", false,
+		"Colorize the output even if not writing to a terminal.")
+	monochrome := cmd.Flags().BoolP("monochrome", "M", false,
+		"Do not colorize the output, even if writing to a terminal.")
+	preserveOrder := cmd.Flags().Bool("preserve-order", false,
+		"Treat the order of object members and the encoding of values as\n"+
+			"significant (byte-level structural diff) instead of comparing the\n"+
+			"decoded documents.")
+	arrayKey := cmd.Flags().String("array-key", "",
+		"Align the objects of arrays by the value of the given field, such as\n"+
+			"\"id\", instead of by their longest common subsequence.")
+	output := cmd.Flags().String("output", "text",
+		"Output format: text or jsonpatch (an RFC 6902 JSON Patch that\n"+
+			"transforms old.json into new.json).")
+	quiet := cmd.Flags().BoolP("quiet", "q", false,
+		"Print nothing, only report whether the documents differ with the\n"+
+			"exit status.")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if *output != "text" && *output != "jsonpatch" {
+			return diffError(fmt.Errorf("invalid output format: %q", *output))
+		}
+		w := io.Writer(os.Stdout)
+		if *quiet {
+			w = io.Discard
+		}
+		var conf pjson.IndentConfig
+		colored := termcolor.Decide(os.Stdout, *forceColor, *monochrome).Enabled
+		if colored {
+			conf = defaultColors()
+		}
+		opts := pjson.DiffOptions{
+			PreserveOrder: *preserveOrder,
+			ArrayKey:      *arrayKey,
+		}
+		equal, err := runDiff(w, &conf, &opts, *output, args[0], args[1], colored)
+		if err != nil {
+			return diffError(err)
+		}
+		if !equal {
+			return exitStatusError(1)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// diffError returns err, if not nil, with the exit status 2 of diff
+// errors, since 1 means that the documents differ.
+func diffError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &statusError{status: exitInputError, err: err}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+)
+
+func newDocsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "docs [flags] man|markdown",
+		Short: "Generate the man pages or Markdown reference of pjson",
+		Long: "Generate a man page or Markdown reference for pjson and each of its\n" +
+			"commands from their flags and help. The documentation of the pjson\n" +
+			"command is written to STDOUT unless --dir is given, in which case a\n" +
+			"file is written to the directory for each command. The date of man\n" +
+			"pages is read from SOURCE_DATE_EPOCH, if set, for reproducible builds.",
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{"man", "markdown"},
+	}
+	dir := cmd.Flags().String("dir", "", "Write the documentation of every command to files in the directory.")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		root := cmd.Root()
+		root.DisableAutoGenTag = true
+		header := &doc.GenManHeader{
+			Title:   "PJSON",
+			Section: "1",
+			Source:  "pjson " + version,
+			Manual:  "pjson manual",
+		}
+		if *dir != "" {
+			if err := os.MkdirAll(*dir, 0755); err != nil {
+				return err
+			}
+		}
+		switch {
+		case args[0] == "man" && *dir != "":
+			return doc.GenManTree(root, header, *dir)
+		case args[0] == "man":
+			return doc.GenMan(root, header, os.Stdout)
+		case *dir != "":
+			return doc.GenMarkdownTree(root, *dir)
+		default:
+			return doc.GenMarkdown(root, os.Stdout)
+		}
+	}
+	return cmd
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/charlievieth/pjson"
+	"github.com/spf13/cobra"
+)
+
+// exitInputError is the exit status when an input could not be read or
+// is not valid JSON. Other errors, such as invalid flags, exit with 1.
+const exitInputError = 2
+
+// inputError is returned, after the errors were reported, when some of the
+// inputs could not be read or formatted.
+type inputError struct {
+	failed, total int
+	// reported is set if a summary was already written to STDERR, or
+	// there is only one input and its error was reported.
+	reported bool
+	// notWritten is set if the output file was not written.
+	notWritten bool
+}
+
+func (e *inputError) Error() string {
+	s := fmt.Sprintf("%d of %d inputs failed", e.failed, e.total)
+	if e.notWritten {
+		s += ": output not written"
+	}
+	return s
+}
+
+// An exitStatusError sets the exit status of pjson, for --exit-status,
+// without printing an error.
+type exitStatusError int
+
+func (e exitStatusError) Error() string {
+	return "exit status " + strconv.Itoa(int(e))
+}
+
+// A statusError is an error, which is printed, that exits with status
+// instead of 1, for commands that exit with 1 to report a result, such as
+// diff when the documents differ.
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e *statusError) Error() string { return e.err.Error() }
+func (e *statusError) Unwrap() error { return e.err }
+
+// exitCode returns the exit status for the error err returned by a
+// command.
+func exitCode(err error) int {
+	var ie *inputError
+	var es exitStatusError
+	var se *statusError
+	switch {
+	case errors.As(err, &ie):
+		return exitInputError
+	case errors.As(err, &es):
+		return int(es)
+	case errors.As(err, &se):
+		return se.status
+	}
+	return 1
+}
+
+// silenceExit returns err, the error returned by the command cmd, with the
+// usage, and the error if it was already reported, not printed if err is
+// an error that sets the exit status, which are not usage errors.
+func silenceExit(cmd *cobra.Command, err error) error {
+	var ie *inputError
+	var es exitStatusError
+	var se *statusError
+	switch {
+	case errors.As(err, &ie):
+		cmd.SilenceErrors = ie.reported && !ie.notWritten
+		cmd.SilenceUsage = true
+	case errors.As(err, &es):
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+	case errors.As(err, &se):
+		cmd.SilenceUsage = true
+	}
+	return err
+}
+
+// silenceSubcommands wraps the RunE function of the subcommands of cmd
+// with silenceExit.
+func silenceSubcommands(cmd *cobra.Command) {
+	for _, c := range cmd.Commands() {
+		run := c.RunE
+		if run == nil {
+			continue
+		}
+		c.RunE = func(cmd *cobra.Command, args []string) error {
+			return silenceExit(cmd, run(cmd, args))
+		}
+	}
+}
+
+// An exitStatus records the last value written for --exit-status. A nil
+// *exitStatus records nothing.
+type exitStatus struct {
+	written bool // a value was written
+	falsy   bool // the last value written was null or false
+}
+
+// update records the last value written by stream, if any, since it was
+// reset.
+func (e *exitStatus) update(stream *pjson.Stream) {
+	if e == nil || stream.ValueIndex() < 0 {
+		return
+	}
+	e.written = true
+	e.falsy = stream.LastValueFalsy()
+}
+
+// err returns the exitStatusError, as with jq -e: 1 if the last value was
+// null or false and 4 if no value was written, or nil.
+func (e *exitStatus) err() error {
+	switch {
+	case e == nil:
+		return nil
+	case !e.written:
+		return exitStatusError(4)
+	case e.falsy:
+		return exitStatusError(1)
+	}
+	return nil
+}
+
+// maxSnippetWidth is the maximum number of bytes of the offending line
+// shown by reportError.
+const maxSnippetWidth = 80
+
+// reportError writes the error err, reading the input named name, to w.
+// Errors in values are located by line and column and followed by the
+// line they occurred on with a caret under the offending byte:
+//
+//	file.json:3:9: invalid character '}' looking for beginning of object key string
+//	  "b": 2,}
+//	         ^
+func reportError(w io.Writer, name string, err error) {
+	name = displayName(name)
+	var se *pjson.StreamError
+	if !errors.As(err, &se) {
+		fmt.Fprintf(w, "error: %s: %v\n", name, err)
+		return
+	}
+	if se.Line == 0 {
+		fmt.Fprintf(w, "%s: offset %d: %v\n", name, se.Offset, se.Err)
+		return
+	}
+	fmt.Fprintf(w, "%s:%d:%d: %v\n", name, se.Line, se.Column, se.Err)
+	line, index := se.Source()
+	writeSource(w, line, index)
+}
+
+// writeSource writes the line of input line with a caret under the byte
+// at index to w, as reportError does. Nothing is written if line is
+// empty and index is 0.
+func writeSource(w io.Writer, line []byte, index int) {
+	if len(line) == 0 && index == 0 {
+		return
+	}
+	line, index = snippet(line, index)
+	var buf bytes.Buffer
+	buf.WriteString("  ")
+	buf.Write(line)
+	buf.WriteString("\n  ")
+	// Keep tabs so the caret lines up with the byte above it.
+	for _, c := range line[:index] {
+		switch {
+		case c == '\t':
+			buf.WriteByte('\t')
+		case !utf8.RuneStart(c):
+		default:
+			buf.WriteByte(' ')
+		}
+	}
+	buf.WriteString("^\n")
+	w.Write(buf.Bytes())
+}
+
+// snippet returns at most maxSnippetWidth bytes of line around index, with
+// "..." marking the bytes omitted, and the index of the same byte in it.
+// Control characters other than tab are replaced with '.'.
+func snippet(line []byte, index int) ([]byte, int) {
+	start, end := 0, len(line)
+	if end > maxSnippetWidth {
+		start = index - maxSnippetWidth/2
+		if start < 0 {
+			start = 0
+		}
+		end = start + maxSnippetWidth
+		if end > len(line) {
+			end = len(line)
+			start = end - maxSnippetWidth
+		}
+		// Don't split UTF-8 sequences.
+		for start > 0 && !utf8.RuneStart(line[start]) {
+			start--
+		}
+		for end < len(line) && !utf8.RuneStart(line[end]) {
+			end++
+		}
+	}
+	var b []byte
+	if start > 0 {
+		b = append(b, "..."...)
+	}
+	index += len(b) - start
+	for _, c := range line[start:end] {
+		if c < ' ' && c != '\t' || c == 0x7f {
+			c = '.'
+		}
+		b = append(b, c)
+	}
+	if end < len(line) {
+		b = append(b, "..."...)
+	}
+	return b, index
+}
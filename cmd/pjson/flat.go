@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/charlievieth/pjson"
+)
+
+// runUnflatten reconstructs JSON from the `path = value` lines of each of
+// the named files (or STDIN if there are none) and formats it with stream.
+// Inputs that cannot be read or reconstructed are reported to STDERR and
+// skipped.
+func runUnflatten(w io.Writer, stream *pjson.Stream, names []string) error {
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	out := bufio.NewWriter(w)
+	failed := 0
+	for _, name := range names {
+		data, err := readInput(name)
+		if err == nil {
+			data, err = pjson.Unflatten(data)
+		}
+		if err == nil {
+			stream.Reset(bytes.NewReader(data))
+			_, err = stream.WriteTo(out)
+		}
+		if err != nil {
+			reportError(os.Stderr, name, err)
+			failed++
+		}
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return &inputError{failed: failed, total: len(names), reported: true}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/charlievieth/pjson"
+	"github.com/charlievieth/pjson/termcolor"
+)
+
+// readGitBlob reads a file passed to an external diff driver by git. The
+// missing side of an added or deleted file is "/dev/null", which is read
+// as null.
+func readGitBlob(name string) ([]byte, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return []byte("null"), nil
+	}
+	return data, nil
+}
+
+// runGitDiff writes the structural diff of a file to w. It is called by
+// git as an external diff driver (GIT_EXTERNAL_DIFF) with the arguments:
+//
+//	path old-file old-hex old-mode new-file new-hex new-mode
+//
+// Git treats a non-zero exit status as a failure so documents that are
+// not valid JSON are reported in the output instead of as an error.
+func runGitDiff(w io.Writer, args []string, forceColor bool) error {
+	if len(args) != 7 && len(args) != 9 { // 9 for renames
+		return fmt.Errorf("--git-diff: expected 7 or 9 arguments from git got: %d", len(args))
+	}
+	path, oldFile, newFile := args[0], args[1], args[4]
+
+	// Git sets GIT_PAGER_IN_USE if its pager, which displays color,
+	// is reading our output.
+	colored := termcolor.Decide(os.Stdout,
+		forceColor || os.Getenv("GIT_PAGER_IN_USE") != "", false).Enabled
+	var conf pjson.IndentConfig
+	if colored {
+		conf = defaultColors()
+	}
+	header := termcolor.NewColor(termcolor.Bold)
+	if !colored {
+		header = nil
+	}
+	if _, err := header.Fprintf(w, "pjson diff a/%s b/%s\n", path, path); err != nil {
+		return err
+	}
+
+	a, err := readGitBlob(oldFile)
+	if err != nil {
+		return err
+	}
+	b, err := readGitBlob(newFile)
+	if err != nil {
+		return err
+	}
+	changes, err := pjson.Diff(a, b, nil)
+	if err != nil {
+		_, err = fmt.Fprintf(w, "not valid JSON: %v\n", err)
+		return err
+	}
+	return writeChanges(w, &conf, changes, colored)
+}
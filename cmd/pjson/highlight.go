@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+
+	"github.com/charlievieth/pjson"
+	"github.com/charlievieth/pjson/termcolor"
+)
+
+// highlightWriter highlights the matches of re in the lines written to w
+// with clr (see pjson.Highlight). Lines are buffered until they end, so
+// flush must be called after the last write.
+type highlightWriter struct {
+	w    io.Writer
+	re   *regexp.Regexp
+	clr  *termcolor.Color
+	line []byte // incomplete line
+	buf  []byte
+}
+
+func (h *highlightWriter) Write(p []byte) (int, error) {
+	i := bytes.LastIndexByte(p, '\n')
+	if i == -1 {
+		h.line = append(h.line, p...)
+		return len(p), nil
+	}
+	lines := p[:i+1]
+	if len(h.line) > 0 {
+		h.line = append(h.line, lines...)
+		lines = h.line
+	}
+	h.buf = pjson.Highlight(h.buf[:0], lines, h.re, h.clr)
+	h.line = append(h.line[:0], p[i+1:]...)
+	if _, err := h.w.Write(h.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// flush writes the incomplete line, if any.
+func (h *highlightWriter) flush() error {
+	if len(h.line) == 0 {
+		return nil
+	}
+	h.buf = pjson.Highlight(h.buf[:0], h.line, h.re, h.clr)
+	h.line = h.line[:0]
+	_, err := h.w.Write(h.buf)
+	return err
+}
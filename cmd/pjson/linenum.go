@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+
+	"github.com/charlievieth/pjson/termcolor"
+)
+
+// lineNumberWidth is the minimum width of the line numbers of the gutter,
+// as with cat -n.
+const lineNumberWidth = 6
+
+// lineNumberWriter prefixes each line written to w with its number in a
+// gutter, dimmed if colored is true.
+type lineNumberWriter struct {
+	w       io.Writer
+	colored bool
+	line    int  // number of the current line
+	midLine bool // the gutter of the current line was written
+	buf     []byte
+}
+
+func newLineNumberWriter(w io.Writer, colored bool) *lineNumberWriter {
+	return &lineNumberWriter{w: w, colored: colored}
+}
+
+// reset numbers the next line written 1. The current line, if any, is
+// ended.
+func (l *lineNumberWriter) reset() error {
+	l.line = 0
+	if l.midLine {
+		l.midLine = false
+		_, err := l.w.Write([]byte{'\n'})
+		return err
+	}
+	return nil
+}
+
+var lineNumberColor = termcolor.NewColor(termcolor.Faint)
+
+func (l *lineNumberWriter) appendGutter(dst []byte) []byte {
+	l.line++
+	var clr *termcolor.Color
+	if l.colored {
+		clr = lineNumberColor
+	}
+	dst = clr.Append(dst)
+	num := strconv.Itoa(l.line)
+	for i := len(num); i < lineNumberWidth; i++ {
+		dst = append(dst, ' ')
+	}
+	dst = append(dst, num...)
+	dst = append(dst, clr.Reset()...)
+	return append(dst, ' ', ' ')
+}
+
+func (l *lineNumberWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	buf := l.buf[:0]
+	for len(p) > 0 {
+		if !l.midLine {
+			buf = l.appendGutter(buf)
+			l.midLine = true
+		}
+		i := bytes.IndexByte(p, '\n')
+		if i == -1 {
+			buf = append(buf, p...)
+			break
+		}
+		buf = append(buf, p[:i+1]...)
+		p = p[i+1:]
+		l.midLine = false
+	}
+	l.buf = buf
+	if _, err := l.w.Write(buf); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// A prefixWriter begins each line written to w with prefix.
+type prefixWriter struct {
+	w       io.Writer
+	prefix  []byte
+	midLine bool // the prefix of the current line was written
+	buf     []byte
+}
+
+func (l *prefixWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	buf := l.buf[:0]
+	for len(p) > 0 {
+		if !l.midLine {
+			buf = append(buf, l.prefix...)
+			l.midLine = true
+		}
+		i := bytes.IndexByte(p, '\n')
+		if i == -1 {
+			buf = append(buf, p...)
+			break
+		}
+		buf = append(buf, p[:i+1]...)
+		p = p[i+1:]
+		l.midLine = false
+	}
+	l.buf = buf
+	if _, err := l.w.Write(buf); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,35 +16,112 @@ import (
 )
 
 type statReader struct {
-	f *os.File
+	r io.Reader
 	n int64
 }
 
 func (r *statReader) Read(p []byte) (int, error) {
-	n, err := r.f.Read(p)
+	n, err := r.r.Read(p)
 	r.n += int64(n)
 	return n, err
 }
 
-func streamFile(name string, stream *pjson.Stream, wr *bufio.Writer) (read, written int64, err error) {
-	f, err := os.Open(name)
-	if err != nil {
-		return 0, 0, err
+func streamFile(name string, stream *pjson.Stream, wr io.Writer, prog *progress) (read, written int64, err error) {
+	var f io.ReadCloser
+	var size int64
+	switch {
+	case name == "":
+		f, size = io.NopCloser(os.Stdin), -1
+	case isURL(name):
+		f, size, err = openURL(name)
+		if err != nil {
+			return 0, 0, err
+		}
+	default:
+		file, err := os.Open(name)
+		if err != nil {
+			return 0, 0, err
+		}
+		fi, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return 0, 0, err
+		}
+		f, size = file, fi.Size()
 	}
 	defer f.Close()
-	fi, err := f.Stat()
+
+	prog.start(name, size)
+	sr := statReader{r: prog.reader(f)}
+	r, err := decompress(&sr)
 	if err != nil {
 		return 0, 0, err
 	}
-
-	stream.Reset(f)
+	stream.Reset(r)
 	written, err = stream.WriteTo(wr)
 	if err != nil {
-		return 0, written, err
+		return sr.n, written, err
+	}
+	return sr.n, written, nil
+}
+
+// filterArg returns the filter that is the first of args, if any, and the
+// remaining args. The first argument is a filter if it begins with '.' and
+// is not the name of a file, or of a directory if recursive is true.
+func filterArg(args []string, recursive bool) (*pjson.Query, []string, error) {
+	if len(args) == 0 || !strings.HasPrefix(args[0], ".") ||
+		strings.HasPrefix(args[0], "./") || strings.HasPrefix(args[0], "../") {
+		return nil, args, nil
+	}
+	if fi, err := os.Stat(args[0]); err == nil && (!fi.IsDir() || recursive) {
+		return nil, args, nil
+	}
+	q, err := pjson.ParseQuery(args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	return q, args[1:], nil
+}
+
+// indentFlag returns the indentation selected by the --indent, --tab and
+// --indent-string flags. At most one of them may be set.
+func indentFlag(changed func(name string) bool, count int, tab bool, str string) (string, error) {
+	var set []string
+	for _, name := range []string{"indent", "tab", "indent-string"} {
+		if changed(name) {
+			set = append(set, "--"+name)
+		}
+	}
+	if len(set) > 1 {
+		return "", fmt.Errorf("%s cannot be used together", strings.Join(set, " and "))
+	}
+	switch {
+	case tab:
+		return "\t", nil
+	case changed("indent-string"):
+		s, ok := unescapeFlag(str)
+		if !ok {
+			return "", fmt.Errorf("invalid indent string: %q", str)
+		}
+		return s, nil
+	case count < 0:
+		return "", fmt.Errorf("invalid indent: %d", count)
 	}
-	return fi.Size(), written, nil
+	return strings.Repeat(" ", count), nil
+}
+
+// unescapeFlag interprets the escape sequences, such as \t, of the flag
+// value s as in a Go string literal.
+func unescapeFlag(s string) (string, bool) {
+	u, err := strconv.Unquote(`"` + strings.ReplaceAll(s, `"`, `\"`) + `"`)
+	return u, err == nil
 }
 
+// version is the version of pjson, it is set when building a release with:
+//
+//	-ldflags "-X main.version=v1.2.3"
+var version = "devel"
+
 const statsFormat = `
   # stats
   time:  %s
@@ -51,38 +130,477 @@ const statsFormat = `
 `
 
 func main() {
+	setVersion()
+	if err := newRootCmd().Execute(); err != nil {
+		os.Exit(exitCode(err))
+	}
+}
+
+// newRootCmd returns the pjson command, with its subcommands.
+func newRootCmd() *cobra.Command {
+	var versionText strings.Builder
+	writeVersion(&versionText)
+
 	root := cobra.Command{
-		Use: "pjson [flags] [file]...",
+		Use:   "pjson [flags] [filter] [file]...",
+		Short: "Pretty print and colorize JSON",
+		Long: "Pretty print and colorize the JSON values read from each file or\n" +
+			"HTTP(S) URL, or STDIN if there are none or the file is \"-\". Output\n" +
+			"is colored when writing to a terminal, unless NO_COLOR is set or TERM\n" +
+			"is \"dumb\". Setting CLICOLOR_FORCE (to anything but 0) colors output\n" +
+			"written to pipes and files. The -C and -M flags override the\n" +
+			"environment (see --explain-color).\n\n" +
+			"If the first argument is a filter, in the subset of jq made of\n" +
+			"identity, field access, indexing, slices, iteration and pipes (for\n" +
+			"example '.items[] | .name'), each value it produces is printed. Use\n" +
+			"./name to read a file whose name is also a filter.\n\n" +
+			"Colors are read from JQ_COLORS, in the format used by jq, and\n" +
+			"PJSON_COLORS, which also accepts named fields after the jq fields:\n" +
+			"null, false, true, number, string, array, object, key, punct and\n" +
+			"emptykey. For example: PJSON_COLORS=\"0;90:key=1;34:emptykey=7\"\n\n" +
+			"Default flag values are read from ~/.config/pjson/config.json or\n" +
+			"config.toml (in $XDG_CONFIG_HOME/pjson if set), which map the long\n" +
+			"names of flags to values, for example: {\"indent\": 2, \"theme\": \"jq\"}.\n" +
+			"The \"color\" option also accepts \"always\", \"never\" or \"auto\".\n" +
+			"Flags given on the command line override the config file.\n\n" +
+			"The exit status is 2 if any input could not be read or is not valid\n" +
+			"JSON, and 1 for other errors.",
+		// Arguments that are not subcommands are files.
+		Args:    cobra.ArbitraryArgs,
+		Version: version,
 	}
+	root.SetVersionTemplate(versionText.String())
 	flags := root.Flags()
+	flags.BoolP("version", "v", false, "Print the version of pjson and how it was built.")
 	indentCount := flags.Int("indent", 4, "Use the given number of spaces for indentation.")
+	indentTab := flags.Bool("tab", false, "Use a tab for indentation.")
+	indentString := flags.String("indent-string", "",
+		"Use the given string for indentation. Escape sequences such as \\t\n"+
+			"are interpreted as in a Go string literal.")
+	prefix := flags.String("prefix", "",
+		"Begin each line of the output with the given string, such as four\n"+
+			"spaces or \"// \", to embed it in another document. Escape sequences\n"+
+			"are interpreted as for --indent-string.")
 	compact := flags.BoolP("compact", "c", false, "Compact JSON output")
-	printStats := flags.Bool("stats", false, "Print stats to STDERR.")
+	printStats := flags.Bool("stats", false,
+		"Print stats to STDERR. If STDERR is a terminal, a progress bar with\n"+
+			"the throughput is shown while reading files.")
 	forceColor := flags.BoolP("color", "C", false,
 		"By default, pjson outputs colored JSON if writing to a terminal.\n"+
 			"You can force it to produce color even if writing to a pipe or a\n"+
 			"file using -C, and disable color with -M.")
+	monochrome := flags.BoolP("monochrome", "M", false,
+		"Do not colorize the output, even if writing to a terminal.")
+	diagnostics := flags.String("diagnostics", "",
+		"Print syntax errors and lint warnings in the given format (json or\n"+
+			"text) instead of formatting the input.")
+	paths := flags.Bool("paths", false, "Print the path of every leaf value, one per line.")
+	pathFormat := flags.String("path-format", "dotted",
+		"Format of the paths printed by --paths: dotted or pointer (JSON Pointer).")
+	pathValues := flags.Bool("path-values", false, "Print leaf values after the paths printed by --paths.")
+	pointer := flags.String("pointer", "",
+		"Print only the value at the JSON Pointer (RFC 6901), such as\n"+
+			"/data/items/0. The rest of the input is not kept in memory.")
+	jsonPath := flags.String("jsonpath", "",
+		"Print each value selected by the JSONPath (RFC 9535) query, such as\n"+
+			"'$.items[?@.price < 10].name', as its own document.")
+	keys := flags.Bool("keys", false,
+		"Print the distinct paths of the object keys of all inputs, with array\n"+
+			"indices replaced by [], such as .items[].name, one per line.")
+	kv := flags.Bool("kv", false,
+		"Print a path=value line for every scalar, or empty object or array,\n"+
+			"such as .items[0].name='Jane Doe'. Strings are unquoted and paths and\n"+
+			"values are quoted for a POSIX shell if needed.")
+	summary := flags.Bool("summary", false,
+		"Print a tree of the paths of all inputs, with array indices replaced\n"+
+			"by [], and the types, number and total size of the values at each,\n"+
+			"such as \".items: array[10432] of object (1.1 MB)\", instead of the\n"+
+			"values.")
+	from := flags.String("from", "json",
+		"Input format: json or flat (the \"path = value\" lines printed by\n"+
+			"--paths --path-values, with either --path-format, or by gron).")
 
-	root.RunE = func(cmd *cobra.Command, args []string) error {
+	colorReport := flags.Bool("color-overhead", false,
+		"Report the number of bytes color escape sequences add to the output\n"+
+			"instead of formatting the input.")
+	maxColorOverhead := flags.Float64("max-color-overhead", 0,
+		"Disable color for inputs where it would increase the size of the\n"+
+			"output by more than the given percent (0 means no limit).")
+	unwrapArray := flags.Bool("unwrap-array", false,
+		"Write each element of the top-level array on its own line (NDJSON).")
+	wrapArray := flags.Bool("wrap-array", false,
+		"Write the values of each input as the elements of a single array\n"+
+			"(the inverse of --unwrap-array).")
+	exitStatusFlag := flags.BoolP("exit-status", "e", false,
+		"Like jq -e, exit with status 1 if the last value written is null or\n"+
+			"false, or 4 if no value is written. Use with a filter or --pointer\n"+
+			"in shell conditionals.")
+	lintFlag := flags.Bool("lint", false,
+		"List syntax errors and lint warnings, such as duplicate keys,\n"+
+			"integers beyond 2^53, deep nesting and invalid UTF-8, with the path\n"+
+			"of each value, instead of formatting the input (same as\n"+
+			"--diagnostics text).")
+	lintStrict := flags.Bool("strict", false,
+		"Exit with status 1 if --lint or --diagnostics report any errors or\n"+
+			"warnings.")
+	noEmptyKeys := flags.Bool("no-empty-key-highlight", false,
+		"Do not highlight object keys that are empty or only white space.")
+	sortKeys := flags.String("sort-keys", "",
+		"Sort the keys of objects in the given order: lexical (if no order is\n"+
+			"given), natural (\"item2\" before \"item10\"), numeric (keys that are\n"+
+			"numbers by value, before all other keys), ci (case-insensitive) or\n"+
+			"collate:LANG (the collation rules of a language, e.g. collate:de).")
+	flags.Lookup("sort-keys").NoOptDefVal = "lexical"
+	checkSorted := flags.Bool("check-sorted", false,
+		"Check that the keys of every object are sorted, in the order given\n"+
+			"by --sort-keys, instead of formatting the input. Unsorted and\n"+
+			"duplicate keys are listed and the exit status is 1.")
+	gitTextconv := flags.Bool("git-textconv", false,
+		"Write stable output for git to diff: uncolored and indented with\n"+
+			"sorted keys. Use with: git config diff.json.textconv \"pjson --git-textconv\"")
+	gitDiff := flags.Bool("git-diff", false,
+		"Act as a git external diff driver and print the structural diff of\n"+
+			"the old and new version of a file. Use with:\n"+
+			"GIT_EXTERNAL_DIFF=\"pjson --git-diff\" git diff")
+	escapeHTML := flags.Bool("escape-html", false,
+		"Escape <, >, & and the line and paragraph separators in strings so\n"+
+			"the output is safe to embed in HTML <script> tags. Combined with\n"+
+			"--compact and --sort-keys this makes pjson a canonical minifier.")
+	ascii := flags.Bool("ascii", false,
+		"Escape every non-ASCII character in strings as \\uXXXX, using\n"+
+			"surrogate pairs above U+FFFF, so the output is plain ASCII.")
+	unescape := flags.Bool("unescape", false,
+		"Decode \\uXXXX escapes in strings, including surrogate pairs, to the\n"+
+			"characters they represent. Escapes of quotes, backslashes and\n"+
+			"control characters are kept so the output is valid JSON.")
+	redact := flags.StringSlice("redact", nil,
+		"Replace the values of object members with the given keys, such as\n"+
+			"password,token,authorization, with --redaction. Keys are matched\n"+
+			"without regard to case and objects and arrays are replaced whole.")
+	redaction := flags.String("redaction", pjson.DefaultRedaction,
+		"Replacement string for the values redacted by --redact.")
+	expandStrings := flags.Bool("expand-strings", false,
+		"Replace string values that contain a JSON object or array, such as\n"+
+			"\"{\\\"a\\\": 1}\", with the object or array so it is formatted\n"+
+			"like the rest of the value.")
+	maxString := flags.Int("max-string", 0,
+		"Truncate string values longer than N characters and follow them\n"+
+			"with an ellipsis and their size, such as \"AAAA… (84.0 KB)\".\n"+
+			"0 prints whole strings.")
+	sample := flags.Int("sample", 0,
+		"Print only the first N elements of each array, followed by a string\n"+
+			"counting the rest, such as \"… 1,234,567 more elements\". The\n"+
+			"elements of top-level arrays are not kept in memory. 0 prints every\n"+
+			"element.")
+	highlight := flags.String("highlight", "",
+		"Highlight the substrings of keys and values that match the given\n"+
+			"regular expression when the output is colored. The contents of\n"+
+			"strings are matched without their quotes.")
+	highlightColor := flags.String("highlight-color", "7",
+		"SGR parameters of the --highlight color, such as \"7\" (inverse\n"+
+			"video) or \"1;43\" (bold on yellow).")
+	theme := flags.String("theme", "default",
+		"Color scheme: default, jq, monokai, solarized-dark, solarized-light\n"+
+			"or dracula. Use \"--theme list\" to list and preview them.")
+	colorValues := make([]*string, len(colorFlags))
+	for i, f := range colorFlags {
+		colorValues[i] = flags.String(f.flag, "",
+			"Color of "+f.desc+" as SGR parameters, such as \"1;34\".\n"+
+				"Overrides --theme, JQ_COLORS and PJSON_COLORS.")
+	}
+	validate := flags.Bool("validate", false,
+		"Only check that each input is valid JSON: print nothing and exit 0\n"+
+			"if all are, else print the location of the error in each invalid\n"+
+			"input and exit 2, as for other invalid inputs.")
+	schemaFile := flags.String("schema", "",
+		"Validate each input value against the JSON Schema in the given file\n"+
+			"instead of formatting it: print a line with the JSON Pointer of each\n"+
+			"value that does not conform and exit 1 if there are any.")
+	recursive := flags.BoolP("recursive", "R", false,
+		"Read the files in directory arguments and their subdirectories whose\n"+
+			"names match --glob, and report the status of each file to STDERR.")
+	glob := flags.String("glob", "*.json",
+		"Pattern of the file names read from directories with --recursive.")
+	unbuffered := flags.BoolP("unbuffered", "u", false,
+		"Write each value as soon as it is formatted instead of buffering the\n"+
+			"output, for pipelines such as: tail -f log | pjson -u | grep ...")
+	readBuffer := flags.String("read-buffer", "4K",
+		"Size of the buffer used to read the input, such as 64K or 1M.")
+	writeBuffer := flags.String("write-buffer", "96K",
+		"Size of the buffer of the output, such as 4K or 1M. 0 is the same\n"+
+			"as --unbuffered.")
+	maxValueSize := flags.String("max-value-size", "0",
+		"Fail instead of reading a value larger than the given size, such as\n"+
+			"512M, into memory. The limit applies to each element of top-level\n"+
+			"arrays when they are streamed (with --sort-keys). 0 sets no limit.")
+	filesFromStdin := flags.Bool("files-from-stdin", false,
+		"Read the names of the input files from STDIN, one per line, instead\n"+
+			"of from the arguments. Use to format more files than fit on a\n"+
+			"command line.")
+	nullFiles := flags.BoolP("null", "0", false,
+		"Read NUL-separated file names from STDIN, such as the output of\n"+
+			"find -print0 (implies --files-from-stdin).")
+	logs := flags.Bool("logs", false,
+		"Format the first JSON object of each line of the input and pass the\n"+
+			"text around it, such as a timestamp, through unchanged. Lines\n"+
+			"without an object are written as they are.")
+	ndjson := flags.Bool("ndjson", false,
+		"Format each line of the input as an independent JSON document.\n"+
+			"Invalid lines are reported, with their line number, and skipped.")
+	rawOutput := flags.BoolP("raw-output", "r", false,
+		"Write top-level strings without quotes or escapes, like jq -r.")
+	annotate := flags.Bool("annotate", false,
+		"Follow each closing bracket with a comment of the number of members\n"+
+			"or elements and the input size of the object or array, such as\n"+
+			"\"} // 42 keys, 18.3 KB\". The output is not valid JSON.")
+	depth := flags.Int("depth", 0,
+		"Print only the first N nesting levels of each value and replace the\n"+
+			"contents of deeper objects and arrays with \"...\", as in {...}\n"+
+			"and [...]. The output is not valid JSON. 0 prints every level.")
+	atomic := flags.Bool("atomic", false,
+		"Write nothing for a file, or STDIN, unless all of its values are\n"+
+			"valid. By default the values before an invalid value are written.")
+	recordHeaders := flags.Bool("record-headers", false,
+		"Write a \"--- record N (SIZE bytes) ---\" header, where N counts from 1,\n"+
+			"before each value to help locate values in long streams.")
+	passthrough := flags.Bool("passthrough-color", false,
+		"Accept input that is already colored, such as the output of another\n"+
+			"JSON colorizer, and re-indent it preserving its color instead of\n"+
+			"coloring it again.")
+	captureDir := flags.String("capture-on-error", "",
+		"Save each value that cannot be formatted, with the error and scanner\n"+
+			"state, to the given directory to attach to bug reports. Captures\n"+
+			"are re-run with: pjson replay DIR/capture-*.json")
+	captureRedact := flags.Bool("capture-redact", false,
+		"Replace the letters and digits of strings saved by --capture-on-error\n"+
+			"with 'x' and '0'.")
+	terminalProgress := flags.String("terminal-progress", "",
+		"Report the progress of reading each input to the terminal: \"osc\"\n"+
+			"for the OSC 9;4 progress indicator of Windows Terminal and ConEmu,\n"+
+			"or \"title\" to show the percent read in the terminal title.")
+	output := flags.StringP("output", "o", "",
+		"Write the output to the given file instead of STDOUT. The file is\n"+
+			"only replaced once all of the input was formatted. Output is not\n"+
+			"colored unless -C is given.")
+	flags.DurationVar(&fetchOptions.Timeout, "timeout", time.Minute,
+		"Time limit for fetching each URL input (0 means no limit).")
+	flags.StringArrayVarP(&fetchOptions.Headers, "header", "H", nil,
+		"Add the header \"Name: value\" to the requests for URL inputs. May be\n"+
+			"repeated.")
+	lineNumbers := flags.Bool("line-numbers", false,
+		"Prefix each line of the output with its number, in a dimmed gutter.\n"+
+			"Lines are numbered from 1 for each input file. The output is not\n"+
+			"valid JSON.")
+	noConfig := flags.Bool("no-config", false, "Do not read the config file.")
+	explainColor := flags.Bool("explain-color", false,
+		"Explain why output to STDOUT is or is not colored (terminal\n"+
+			"detection, NO_COLOR, CLICOLOR_FORCE, TERM, COLORTERM and the\n"+
+			"Windows virtual terminal status) instead of formatting the input.")
+
+	var filter *pjson.Query // the filter argument, if any
+
+	// run formats args to stdout, which is STDOUT or the temporary file
+	// of --output.
+	run := func(stdout *os.File, outputName string, args []string) error {
+		policy := termcolor.Policy{Force: *forceColor, Disable: *monochrome || *gitTextconv}
+		if stdout != os.Stdout {
+			// The --output file is only colored with -C.
+			policy.Getenv = func(key string) string {
+				if key == "CLICOLOR_FORCE" {
+					return ""
+				}
+				return os.Getenv(key)
+			}
+		}
+		if *explainColor {
+			d := policy.Decide(stdout)
+			return writeColorExplanation(stdout, outputName, &d)
+		}
+		if *from != "json" && *from != "flat" {
+			return fmt.Errorf("invalid input format: %q", *from)
+		}
+		if *lintFlag && *diagnostics == "" {
+			*diagnostics = "text"
+		}
+		if *diagnostics != "" {
+			n, err := runDiagnostics(stdout, *diagnostics, args)
+			if err == nil && n > 0 && *lintStrict {
+				return exitStatusError(1)
+			}
+			return err
+		}
+		var order pjson.KeyOrder
+		if *sortKeys != "" {
+			var err error
+			if order, err = pjson.ParseKeyOrder(*sortKeys); err != nil {
+				return err
+			}
+		}
+		if *validate {
+			valid, err := runValidate(stdout, args, *recursive)
+			if err == nil && !valid {
+				return exitStatusError(exitInputError)
+			}
+			return err
+		}
+		if *schemaFile != "" {
+			valid, err := runSchema(stdout, *schemaFile, args, *recursive)
+			if err == nil && !valid {
+				return exitStatusError(1)
+			}
+			return err
+		}
+		if *checkSorted {
+			sorted, err := runCheckSorted(stdout, order, args)
+			if err == nil && !sorted {
+				return exitStatusError(1)
+			}
+			return err
+		}
+		if *unwrapArray {
+			return runUnwrapArray(stdout, args)
+		}
+		if *gitDiff {
+			return runGitDiff(stdout, args, *forceColor)
+		}
+		if *gitTextconv && order == nil {
+			order = pjson.LexicalOrder
+		}
+
+		decision := policy.Decide(stdout)
+		colored := decision.Enabled
+		level := decision.Level
+		if !colored {
+			level = termcolor.Detect()
+		}
+		if *theme == "list" {
+			return writeThemes(stdout, level, colored)
+		}
+		themed, err := themeColors(*theme, level)
+		if err != nil {
+			return err
+		}
+		if err := setColorFlags(&themed, colorValues); err != nil {
+			return err
+		}
 		var conf pjson.IndentConfig
-		if *forceColor || termcolor.IsTerminal(int(os.Stdout.Fd())) {
-			conf = pjson.DefaultIndentConfig
+		if colored {
+			conf = themed
 		}
-		var indent string
-		if *indentCount == 8 {
-			indent = "\t"
-		} else {
-			indent = strings.Repeat(" ", *indentCount)
+		if *noEmptyKeys {
+			conf.EmptyKey = nil
+		}
+		if *paths {
+			if *pathFormat != "dotted" && *pathFormat != "pointer" {
+				return fmt.Errorf("invalid path format: %q", *pathFormat)
+			}
+			return runPaths(stdout, &conf, args, *pathFormat == "pointer", *pathValues, colored)
+		}
+		if *keys {
+			return runKeys(stdout, args)
+		}
+		if *summary {
+			return runSummary(stdout, args)
+		}
+		if *kv {
+			return runKV(stdout, args)
 		}
 
-		// WARN WARN WARN
-		if *compact {
-			return errors.New("compact not supported")
+		indent, err := indentFlag(flags.Changed, *indentCount, *indentTab, *indentString)
+		if err != nil {
+			return err
+		}
+
+		if *passthrough {
+			if *compact {
+				return errors.New("--passthrough-color cannot be used with --compact")
+			}
+			return runPassthrough(stdout, args, indent)
 		}
 
 		start := time.Now()
 		stream := pjson.NewStream(nil, &conf)
 		stream.SetIndent("", indent)
+		stream.SetCompact(*compact)
+		// Never let colorized or terminal output carry terminal control
+		// sequences.
+		stream.SetSanitize(colored || termcolor.IsTerminal(int(stdout.Fd())))
+		stream.SetWrapArray(*wrapArray)
+		stream.SetSortKeys(order)
+		// Objects are buffered to sort their keys but the elements of
+		// large top-level arrays are sorted, and sampled, one at a time.
+		stream.SetStreamArrays(order != nil || *sample > 0)
+		stream.SetEscapeHTML(*escapeHTML)
+		if *ascii && *unescape {
+			return errors.New("--ascii cannot be used with --unescape")
+		}
+		stream.SetASCII(*ascii)
+		stream.SetUnescape(*unescape)
+		stream.SetExpandStrings(*expandStrings)
+		stream.SetRedact(*redact, *redaction)
+		if *maxString < 0 {
+			return fmt.Errorf("invalid --max-string: %d", *maxString)
+		}
+		stream.SetMaxString(*maxString)
+		if *sample < 0 {
+			return fmt.Errorf("invalid --sample: %d", *sample)
+		}
+		stream.SetSample(*sample)
+		readSize, err := parseSize(*readBuffer)
+		if err != nil {
+			return fmt.Errorf("invalid --read-buffer: %w", err)
+		}
+		stream.SetReadBuffer(readSize)
+		maxValue, err := parseSize(*maxValueSize)
+		if err != nil {
+			return fmt.Errorf("invalid --max-value-size: %w", err)
+		}
+		stream.SetMaxValueSize(maxValue)
+		stream.SetAtomic(*atomic)
+		stream.SetRawStrings(*rawOutput)
+		switch {
+		case filter != nil && *jsonPath != "":
+			return errors.New("a filter argument cannot be used with --jsonpath")
+		case filter != nil:
+			stream.SetQuery(filter)
+		case *jsonPath != "":
+			p, err := pjson.ParseJSONPath(*jsonPath)
+			if err != nil {
+				return err
+			}
+			stream.SetQuery(p)
+		}
+		if *depth < 0 {
+			return fmt.Errorf("invalid --depth: %d", *depth)
+		}
+		stream.SetMaxDepth(*depth)
+		if *annotate {
+			if *compact {
+				return errors.New("--annotate cannot be used with --compact")
+			}
+			stream.SetAnnotate(true)
+		}
+		if *recordHeaders {
+			stream.SetValueHeader(appendRecordHeader)
+		}
+
+		if *from == "flat" {
+			err := runUnflatten(stdout, stream, args)
+			var ie *inputError
+			if errors.As(err, &ie) {
+				ie.notWritten = stdout != os.Stdout
+			}
+			return err
+		}
+		if *colorReport {
+			if !colored {
+				conf = themed
+			}
+			return runColorReport(stdout, &conf, stream, args)
+		}
+		if colored && *maxColorOverhead > 0 {
+			return runColorBudget(stdout, &conf, stream, args, *maxColorOverhead)
+		}
 
 		statsFn := func(nr, nw int64) {
 			if *printStats {
@@ -97,36 +615,231 @@ func main() {
 			}
 		}
 
+		captureOpts := captureOptions{
+			Indent:     indent,
+			Compact:    *compact,
+			SortKeys:   *sortKeys,
+			EscapeHTML: *escapeHTML,
+		}
+		captureError := func(name string, err error) {
+			if *captureDir == "" {
+				return
+			}
+			meta, cerr := writeCapture(*captureDir, name, err, &captureOpts, *captureRedact)
+			if cerr != nil {
+				fmt.Fprintf(os.Stderr, "error: capturing %s: %v\n", displayName(name), cerr)
+			} else if meta != "" {
+				fmt.Fprintf(os.Stderr, "captured %s: %s\n", displayName(name), meta)
+			}
+		}
+
+		progressMode := *terminalProgress
+		if progressMode == "" && *printStats {
+			progressMode = "bar"
+		}
+		prog, err := newProgress(progressMode)
+		if err != nil {
+			return err
+		}
+		defer prog.finish()
+
+		var w io.Writer = stdout
+		if *prefix != "" {
+			// Unlike the prefix of Indent, which only follows newlines
+			// in a value, this begins every line, including compact values.
+			s, ok := unescapeFlag(*prefix)
+			if !ok {
+				return fmt.Errorf("invalid prefix: %q", *prefix)
+			}
+			w = &prefixWriter{w: w, prefix: []byte(s)}
+		}
+		var lines *lineNumberWriter
+		if *lineNumbers {
+			lines = newLineNumberWriter(w, colored)
+			w = lines
+		}
+		if *highlight != "" && colored {
+			re, err := regexp.Compile(*highlight)
+			if err != nil {
+				return fmt.Errorf("invalid --highlight: %w", err)
+			}
+			clr, err := termcolor.ParseColor(*highlightColor)
+			if err != nil {
+				return fmt.Errorf("invalid --highlight-color: %w", err)
+			}
+			hw := &highlightWriter{w: w, re: re, clr: clr}
+			// The output ends with a newline so this only writes
+			// anything if it was interrupted.
+			defer hw.flush()
+			w = hw
+		}
+
+		writeSize, err := parseSize(*writeBuffer)
+		if err != nil {
+			return fmt.Errorf("invalid --write-buffer: %w", err)
+		}
+		if *unbuffered {
+			writeSize = 0
+		}
+
+		var status *exitStatus
+		if *exitStatusFlag {
+			status = new(exitStatus)
+		}
+
+		if flags.Changed("pointer") {
+			if err := runPointer(w, stream, args, *pointer, status); err != nil {
+				var ie *inputError
+				if errors.As(err, &ie) {
+					ie.notWritten = stdout != os.Stdout
+				}
+				return err
+			}
+			return status.err()
+		}
+		if *logs {
+			return runLogs(w, stream, args, writeSize)
+		}
+		if *ndjson {
+			invalid, err := runNDJSON(w, os.Stderr, stream, args, writeSize, captureError)
+			if err == nil && invalid > 0 {
+				fmt.Fprintf(os.Stderr, "skipped %d invalid lines\n", invalid)
+				return &inputError{failed: invalid, reported: true}
+			}
+			return err
+		}
+
 		if len(args) == 0 {
-			sr := statReader{f: os.Stdin}
-			stream.Reset(&sr)
-			nw, err := stream.WriteTo(os.Stdout)
+			sr := statReader{r: os.Stdin}
+			prog.start("", -1)
+			r, err := decompress(prog.reader(&sr))
 			if err != nil {
 				return err
 			}
+			stream.Reset(r)
+			out := newBufferedWriter(w, writeSize)
+			nw, err := stream.WriteTo(out)
+			if ferr := out.Flush(); ferr != nil && err == nil {
+				return ferr
+			}
+			if err != nil {
+				reportError(os.Stderr, "", err)
+				captureError("", err)
+				return &inputError{failed: 1, total: 1, reported: true}
+			}
 			statsFn(sr.n, nw)
-			return err
+			status.update(stream)
+			return status.err()
 		}
 
 		var read, written int64
-		out := bufio.NewWriterSize(os.Stdout, 96*1024)
+		failed := 0
+		out := newBufferedWriter(w, writeSize)
 		for _, name := range args {
-			nr, nw, err := streamFile(name, stream, out)
+			if lines != nil {
+				if err := out.Flush(); err != nil {
+					return err
+				}
+				if err := lines.reset(); err != nil {
+					return err
+				}
+			}
+			nr, nw, err := streamFile(name, stream, out, prog)
+			prog.clear()
+			status.update(stream)
 			read += nr
 			written += nw
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+				reportError(os.Stderr, name, err)
+				captureError(name, err)
+				failed++
 				continue
 			}
+			if *recursive {
+				fmt.Fprintf(os.Stderr, "%s: ok\n", displayName(name))
+			}
 		}
 		if err := out.Flush(); err != nil {
 			return err
 		}
 		statsFn(read, written)
-		return nil
+		if *recursive {
+			files := "files"
+			if len(args) == 1 {
+				files = "file"
+			}
+			fmt.Fprintf(os.Stderr, "%d %s, %d failed\n", len(args), files, failed)
+		}
+		if failed > 0 {
+			return &inputError{
+				failed:     failed,
+				total:      len(args),
+				reported:   *recursive || len(args) == 1,
+				notWritten: stdout != os.Stdout,
+			}
+		}
+		return status.err()
 	}
 
-	if err := root.Execute(); err != nil {
-		os.Exit(1)
+	root.RunE = func(cmd *cobra.Command, args []string) error {
+		if !*noConfig {
+			if err := applyConfig(cmd); err != nil {
+				return err
+			}
+		}
+		// The arguments passed by git are file names, even if they do
+		// not exist, such as the name of a deleted .babelrc.json.
+		var err error
+		if !*gitDiff && !*gitTextconv {
+			if filter, args, err = filterArg(args, *recursive); err != nil {
+				return err
+			}
+		}
+		if *filesFromStdin || *nullFiles {
+			if len(args) != 0 {
+				return errors.New("file arguments cannot be used with --files-from-stdin or --null")
+			}
+			delim := byte('\n')
+			if *nullFiles {
+				delim = 0
+			}
+			if args, err = readFileList(os.Stdin, delim); err != nil {
+				return fmt.Errorf("reading file names: %w", err)
+			}
+			if len(args) == 0 {
+				// Don't fall back to reading STDIN.
+				return errors.New("no file names read from STDIN")
+			}
+		}
+		if args, err = expandArgs(args, *recursive, *glob); err != nil {
+			return err
+		}
+		for i, name := range args {
+			if name == "-" {
+				args[i] = "" // STDIN
+			}
+		}
+		if *output == "" {
+			err = run(os.Stdout, "STDOUT", args)
+		} else {
+			err = writeOutputFile(*output, func(f *os.File) error {
+				return run(f, *output, args)
+			})
+		}
+		return silenceExit(cmd, err)
 	}
+
+	root.AddCommand(newLintCommand())
+	root.AddCommand(newDiffCommand())
+	root.AddCommand(newMerge3Command())
+	root.AddCommand(newPatchCommand())
+	root.AddCommand(newMergePatchCommand())
+	root.AddCommand(newCanonicalizeCommand())
+	root.AddCommand(newInferSchemaCommand())
+	root.AddCommand(newSelfUpdateCommand())
+	root.AddCommand(newDocsCommand())
+	root.AddCommand(newReplayCommand())
+	root.AddCommand(newVersionCommand())
+	silenceSubcommands(&root)
+	return &root
 }
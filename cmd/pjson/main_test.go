@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// execute runs pjson with args, with STDOUT discarded, and returns its
+// exit status.
+func execute(t *testing.T, args ...string) int {
+	t.Helper()
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+	stdout := os.Stdout
+	os.Stdout = null
+	defer func() { os.Stdout = stdout }()
+
+	root := newRootCmd()
+	root.SetArgs(args)
+	root.SetOut(io.Discard)
+	root.SetErr(io.Discard)
+	if err := root.Execute(); err != nil {
+		return exitCode(err)
+	}
+	return 0
+}
+
+func writeFile(t *testing.T, dir, name, data string) string {
+	t.Helper()
+	name = filepath.Join(dir, name)
+	if err := os.WriteFile(name, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errors.New("usage"), 1},
+		{&inputError{failed: 1, total: 2}, exitInputError},
+		{fmt.Errorf("wrapped: %w", &inputError{}), exitInputError},
+		{exitStatusError(1), 1},
+		{exitStatusError(exitInputError), exitInputError},
+		{&statusError{status: exitInputError, err: errors.New("read")}, exitInputError},
+	}
+	for _, test := range tests {
+		if got := exitCode(test.err); got != test.want {
+			t.Errorf("exitCode(%#v) = %d; want: %d", test.err, got, test.want)
+		}
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	dir := t.TempDir()
+	valid := writeFile(t, dir, "valid.json", `{"b": 1, "a": 2}`)
+	sorted := writeFile(t, dir, "sorted.json", `{"a": 1, "b": 2}`)
+	invalid := writeFile(t, dir, "invalid.json", `{"a": }`)
+	missing := filepath.Join(dir, "missing.json")
+	testOp := writeFile(t, dir, "test.json", `[{"op": "test", "path": "/a", "value": 2}]`)
+	notOps := writeFile(t, dir, "ops.json", `{"op": "remove"}`)
+	dupKeys := writeFile(t, dir, "dup.json", `{"a": 1, "a": 2}`)
+	out := filepath.Join(dir, "out.json")
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{valid}, 0},
+		{[]string{valid, sorted}, 0},
+		{[]string{invalid}, exitInputError},
+		{[]string{valid, invalid}, exitInputError},
+		{[]string{missing}, exitInputError},
+		{[]string{"--no-such-flag", valid}, 1},
+		{[]string{"--validate", valid}, 0},
+		{[]string{"--validate", valid, invalid}, exitInputError},
+		{[]string{"--check-sorted", sorted}, 0},
+		{[]string{"--check-sorted", valid}, 1},
+		{[]string{"--pointer", "/a", valid}, 0},
+		{[]string{"--pointer", "/c", valid}, exitInputError},
+		// The name of a deleted file is not a filter.
+		{[]string{"--git-diff", ".deleted.json", valid, "abc", "100644", os.DevNull, "0000", "100644"}, 0},
+		{[]string{"--git-textconv", ".missing.json"}, exitInputError},
+		{[]string{"diff", "-q", valid, sorted}, 1},
+		{[]string{"diff", "-q", valid, valid}, 0},
+		{[]string{"diff", "-q", valid, missing}, exitInputError},
+		{[]string{"diff", "-q", valid}, exitInputError},
+		{[]string{"canonicalize", valid}, 0},
+		{[]string{"canonicalize", invalid}, exitInputError},
+		{[]string{"canonicalize", missing}, exitInputError},
+		{[]string{"infer-schema", valid, sorted}, 0},
+		{[]string{"infer-schema", valid, invalid}, exitInputError},
+		{[]string{"infer-schema", missing}, exitInputError},
+		{[]string{"patch", valid, testOp}, 0},
+		{[]string{"patch", sorted, testOp}, 1},
+		{[]string{"patch", "-i", valid, missing}, exitInputError},
+		{[]string{"patch", invalid, testOp}, exitInputError},
+		{[]string{"patch", valid, invalid}, exitInputError},
+		{[]string{"patch", valid, notOps}, exitInputError},
+		{[]string{"patch", valid}, 1},
+		{[]string{"merge-patch", valid, sorted}, 0},
+		{[]string{"merge-patch", invalid, sorted}, exitInputError},
+		{[]string{"merge-patch", valid, invalid}, exitInputError},
+		{[]string{"merge-patch", valid, missing}, exitInputError},
+		{[]string{"lint", valid}, 0},
+		{[]string{"lint", "--strict", valid}, 0},
+		{[]string{"lint", dupKeys}, 0},
+		{[]string{"lint", "--strict", dupKeys}, 1},
+		{[]string{"lint", valid, invalid}, exitInputError},
+		{[]string{"lint", "--strict", dupKeys, invalid}, exitInputError},
+		{[]string{"lint", missing}, exitInputError},
+	}
+	for _, test := range tests {
+		args := test.args
+		root := newRootCmd()
+		if cmd, _, _ := root.Find(args); cmd == root {
+			// Write the output of the root command to a file.
+			args = append([]string{"--no-config", "-o", out}, args...)
+		}
+		if got := execute(t, args...); got != test.want {
+			t.Errorf("pjson %q: exit status = %d; want: %d", test.args, got, test.want)
+		}
+	}
+}
+
+func TestOutputFile(t *testing.T) {
+	dir := t.TempDir()
+	valid := writeFile(t, dir, "valid.json", `{"a":[1,2]}`)
+	invalid := writeFile(t, dir, "invalid.json", `{"a": }`)
+	out := writeFile(t, dir, "out.json", "old\n")
+	if err := os.Chmod(out, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	readOut := func() string {
+		t.Helper()
+		b, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	checkTemp := func() {
+		t.Helper()
+		tmp, err := filepath.Glob(filepath.Join(dir, ".out.json.tmp*"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tmp) != 0 {
+			t.Errorf("temporary files were not removed: %q", tmp)
+		}
+	}
+
+	// Invalid input does not replace the file.
+	if got := execute(t, "--no-config", "-o", out, valid, invalid); got != exitInputError {
+		t.Errorf("exit status = %d; want: %d", got, exitInputError)
+	}
+	if got := readOut(); got != "old\n" {
+		t.Errorf("output of invalid input = %q; want: %q", got, "old\n")
+	}
+	checkTemp()
+
+	if got := execute(t, "--no-config", "-o", out, "-c", valid); got != 0 {
+		t.Errorf("exit status = %d; want: 0", got)
+	}
+	if got, want := readOut(), "{\"a\":[1,2]}\n"; got != want {
+		t.Errorf("output = %q; want: %q", got, want)
+	}
+	if fi, err := os.Stat(out); err != nil {
+		t.Fatal(err)
+	} else if fi.Mode().Perm() != 0600 {
+		t.Errorf("permissions = %v; want: %v", fi.Mode().Perm(), os.FileMode(0600))
+	}
+	checkTemp()
+
+	// The report of --validate is written even though the exit status
+	// is not zero.
+	if got := execute(t, "--no-config", "-o", out, "--validate", valid, invalid); got != exitInputError {
+		t.Errorf("exit status = %d; want: %d", got, exitInputError)
+	}
+	if got := readOut(); got == "{\"a\":[1,2]}\n" || got == "" {
+		t.Errorf("--validate report was not written: %q", got)
+	}
+	checkTemp()
+}
+
+func TestOutputFileColor(t *testing.T) {
+	t.Setenv("CLICOLOR_FORCE", "1")
+	t.Setenv("NO_COLOR", "")
+	dir := t.TempDir()
+	valid := writeFile(t, dir, "valid.json", `{"a":[1,2]}`)
+	out := filepath.Join(dir, "out.json")
+	for _, color := range []bool{false, true} {
+		args := []string{"--no-config", "-o", out, valid}
+		if color {
+			args = append(args, "-C")
+		}
+		if got := execute(t, args...); got != 0 {
+			t.Fatalf("pjson %q: exit status = %d; want: 0", args, got)
+		}
+		b, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := bytes.Contains(b, []byte("\x1b[")); got != color {
+			t.Errorf("pjson %q: colored = %t; want: %t\n%q", args, got, color, b)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/charlievieth/pjson"
+	"github.com/charlievieth/pjson/termcolor"
+	"github.com/spf13/cobra"
+)
+
+// writeConflicts writes the base, ours and theirs versions of each of
+// conflicts to w.
+func writeConflicts(w io.Writer, conf *pjson.IndentConfig, conflicts []pjson.MergeConflict, colored bool) error {
+	out := bufio.NewWriter(w)
+	var buf []byte
+	for _, c := range conflicts {
+		buf = append(buf[:0], "conflict: "...)
+		buf = conf.AppendPath(buf, c.Path, false)
+		buf = append(buf, '\n')
+		for _, v := range []struct {
+			label string
+			value []byte
+		}{
+			{"  base:   ", c.Base},
+			{"  ours:   ", c.Ours},
+			{"  theirs: ", c.Theirs},
+		} {
+			buf = append(buf, v.label...)
+			if v.value == nil {
+				buf = append(buf, "(absent)"...)
+			} else {
+				clr := conf.ValueColor(v.value)
+				buf = clr.Append(buf)
+				if colored {
+					buf = pjson.Sanitize(buf, v.value)
+				} else {
+					buf = append(buf, v.value...)
+				}
+				buf = append(buf, clr.Reset()...)
+			}
+			buf = append(buf, '\n')
+		}
+		if _, err := out.Write(buf); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+// runMerge3 writes the three-way merge of the named files to w and the
+// conflicts, if any, to errw. It reports whether the merge was clean.
+func runMerge3(w, errw io.Writer, conf *pjson.IndentConfig, base, ours, theirs string, colored bool) (bool, error) {
+	var docs [3][]byte
+	for i, name := range []string{base, ours, theirs} {
+		data, err := readJSON(name)
+		if err != nil {
+			return false, err
+		}
+		docs[i] = data
+	}
+	r, err := pjson.Merge3(docs[0], docs[1], docs[2])
+	if err != nil {
+		return false, err
+	}
+	if err := writeConflicts(errw, conf, r.Conflicts, colored); err != nil {
+		return false, err
+	}
+	var buf bytes.Buffer
+	if err := r.Format(&buf, conf, "", "    "); err != nil {
+		return false, err
+	}
+	if b := buf.Bytes(); len(b) > 0 && b[len(b)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	_, err = buf.WriteTo(w)
+	return len(r.Conflicts) == 0, err
+}
+
+func newMerge3Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "merge3 [flags] base.json ours.json theirs.json",
+		Short: "Merge the changes two JSON documents made to a common base",
+		Long: "Merge the changes ours.json and theirs.json made to base.json, object\n" +
+			"member by object member, and print the result. Members changed\n" +
+			"differently by both are printed between git style conflict markers\n" +
+			"and the base, ours and theirs version of each conflict is printed\n" +
+			"to STDERR. The exit status is 1 if there are conflicts and 2 if there\n" +
+			"was an error, such as a file that is not valid JSON.",
+		Args: cobra.ExactArgs(3),
+	}
+	forceColor := cmd.Flags().BoolP("color", "C", false,
+		"Colorize the output even if not writing to a terminal.")
+	monochrome := cmd.Flags().BoolP("monochrome", "M", false,
+		"Do not colorize the output, even if writing to a terminal.")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		var conf pjson.IndentConfig
+		colored := termcolor.Decide(os.Stdout, *forceColor, *monochrome).Enabled
+		if colored {
+			conf = defaultColors()
+		}
+		clean, err := runMerge3(os.Stdout, os.Stderr, &conf, args[0], args[1], args[2], colored)
+		if err != nil {
+			return &statusError{status: exitInputError, err: err}
+		}
+		if !clean {
+			return exitStatusError(1)
+		}
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/charlievieth/pjson"
+)
+
+// openInput opens the named file or URL, or returns STDIN if name is
+// empty. The input is decompressed if it is compressed.
+func openInput(name string) (io.ReadCloser, error) {
+	var f io.ReadCloser = io.NopCloser(os.Stdin)
+	var err error
+	switch {
+	case isURL(name):
+		f, _, err = openURL(name)
+	case name != "":
+		f, err = os.Open(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r, err := decompress(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", displayName(name), err)
+	}
+	return decompressReader{r, f}, nil
+}
+
+// runUnwrapArray writes the elements of the top-level array of each of
+// the named files (or STDIN if there are none) to w, one per line.
+func runUnwrapArray(w io.Writer, names []string) error {
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	for _, name := range names {
+		f, err := openInput(name)
+		if err != nil {
+			return err
+		}
+		err = pjson.ArrayToLines(w, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", displayName(name), err)
+		}
+	}
+	return nil
+}
+
+// appendRecordHeader appends the header written before each value by
+// --record-headers to dst.
+func appendRecordHeader(dst []byte, v pjson.ValueInfo) []byte {
+	dst = append(dst, "--- record "...)
+	dst = strconv.AppendInt(dst, v.Index+1, 10)
+	dst = append(dst, " ("...)
+	dst = strconv.AppendInt(dst, int64(v.Size), 10)
+	return append(dst, " bytes) ---\n"...)
+}
+
+// runNDJSON formats each line of the named files (or STDIN if there are
+// none) as an independent JSON document with stream and writes it to w.
+// Blank lines are skipped. Lines that are not valid JSON are reported to
+// errw, with their line number, and skipped. It returns the number of
+// invalid lines. The output is buffered with a buffer of bufSize bytes
+// (see newBufferedWriter).
+func runNDJSON(w, errw io.Writer, stream *pjson.Stream, names []string, bufSize int, onError func(name string, err error)) (int, error) {
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	out := newBufferedWriter(w, bufSize)
+	stream.SetAtomic(true) // write nothing for an invalid line
+	invalid := 0
+	var line []byte
+	for _, name := range names {
+		f, err := openInput(name)
+		if err != nil {
+			return invalid, err
+		}
+		r := bufio.NewReader(f)
+		for n := 1; ; n++ {
+			line = line[:0]
+			var err error
+			for {
+				var b []byte
+				b, err = r.ReadSlice('\n')
+				line = append(line, b...)
+				if err != bufio.ErrBufferFull {
+					break
+				}
+			}
+			if len(bytes.TrimSpace(line)) != 0 {
+				stream.Reset(bytes.NewReader(line))
+				if _, ew := stream.WriteTo(out); ew != nil {
+					invalid++
+					var se *pjson.StreamError
+					if errors.As(ew, &se) {
+						fmt.Fprintf(errw, "error: %s:%d:%d: %v\n", displayName(name), n, se.Offset, se.Err)
+					} else {
+						fmt.Fprintf(errw, "error: %s:%d: %v\n", displayName(name), n, ew)
+					}
+					onError(name, ew)
+				}
+			}
+			if err != nil {
+				if err != io.EOF {
+					f.Close()
+					return invalid, err
+				}
+				break
+			}
+		}
+		f.Close()
+	}
+	return invalid, out.Flush()
+}
+
+// runLogs formats the first JSON object of each line of the named files
+// (or STDIN if there are none) with stream and writes it, and the text
+// before and after it, to w. Lines without an object are written as they
+// are. The output is buffered with a buffer of bufSize bytes (see
+// newBufferedWriter).
+func runLogs(w io.Writer, stream *pjson.Stream, names []string, bufSize int) error {
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	out := newBufferedWriter(w, bufSize)
+	var line []byte
+	var buf bytes.Buffer
+	for _, name := range names {
+		f, err := openInput(name)
+		if err != nil {
+			return err
+		}
+		r := bufio.NewReader(f)
+		for {
+			line = line[:0]
+			var err error
+			for {
+				var b []byte
+				b, err = r.ReadSlice('\n')
+				line = append(line, b...)
+				if err != bufio.ErrBufferFull {
+					break
+				}
+			}
+			if start, end := pjson.LocateObject(line); start != -1 {
+				buf.Reset()
+				stream.Reset(bytes.NewReader(line[start:end]))
+				if _, ew := stream.WriteTo(&buf); ew == nil {
+					out.Write(line[:start])
+					out.Write(bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}))
+					line = line[end:]
+				}
+			}
+			out.Write(line)
+			if err != nil {
+				f.Close()
+				if err != io.EOF {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return out.Flush()
+}
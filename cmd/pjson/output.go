@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// parseSize parses a size in bytes, such as "4096", "64K", "16M" or "1G".
+// The suffixes are powers of 1024 and may be followed by "i", "B" or "iB",
+// as in "64KiB".
+func parseSize(s string) (int, error) {
+	num := strings.TrimSuffix(strings.ToUpper(s), "B")
+	shift := 0
+	for i, unit := range []string{"K", "M", "G"} {
+		n := len(num)
+		if strings.HasSuffix(num, unit+"I") {
+			n -= 2
+		} else if strings.HasSuffix(num, unit) {
+			n--
+		}
+		if n != len(num) {
+			num, shift = num[:n], 10*(i+1)
+			break
+		}
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n < 0 || n > (1<<62)>>shift {
+		return 0, fmt.Errorf("%q is not a size", s)
+	}
+	return int(n << shift), nil
+}
+
+// A bufferedWriter buffers the output written to an io.Writer until it
+// is flushed.
+type bufferedWriter interface {
+	io.Writer
+	Flush() error
+}
+
+// newBufferedWriter returns a bufferedWriter with a buffer of size bytes
+// that writes to w. If size is 0 each write is flushed to w immediately.
+// A Stream writes each value with a single write so the output is not
+// delayed, as with --unbuffered.
+func newBufferedWriter(w io.Writer, size int) bufferedWriter {
+	if size <= 0 {
+		return flushWriter{bufio.NewWriter(w)}
+	}
+	return bufio.NewWriterSize(w, size)
+}
+
+// A flushWriter flushes each write.
+type flushWriter struct {
+	*bufio.Writer
+}
+
+func (w flushWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	if err == nil {
+		err = w.Flush()
+	}
+	return n, err
+}
+
+// writeOutputFile calls fn with a temporary file in the directory of name
+// and renames it to name if fn succeeds, so name is never left truncated
+// or partially written. If name exists the temporary file is given its
+// permissions. An exitStatusError only sets the exit status of complete
+// output, such as the report of --validate, so the file is still renamed.
+func writeOutputFile(name string, fn func(f *os.File) error) error {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp) // no-op once renamed
+
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(name); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	err = f.Chmod(mode)
+	if err == nil {
+		err = fn(f)
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	var es exitStatusError
+	if err != nil && !errors.As(err, &es) {
+		return err
+	}
+	if e := os.Rename(tmp, name); e != nil {
+		return e
+	}
+	return err
+}
@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{"0", 0, true},
+		{"4096", 4096, true},
+		{"4096B", 4096, true},
+		{"64K", 64 << 10, true},
+		{"64k", 64 << 10, true},
+		{"64KB", 64 << 10, true},
+		{"64Ki", 64 << 10, true},
+		{"64KiB", 64 << 10, true},
+		{"16M", 16 << 20, true},
+		{"1G", 1 << 30, true},
+		{"1gib", 1 << 30, true},
+		{"", 0, false},
+		{"K", 0, false},
+		{"1I", 0, false},
+		{"1iB", 0, false},
+		{"1BK", 0, false},
+		{"1KBB", 0, false},
+		{"1KK", 0, false},
+		{"1T", 0, false},
+		{"-1K", 0, false},
+	}
+	for _, test := range tests {
+		got, err := parseSize(test.in)
+		if (err == nil) != test.ok || got != test.want {
+			t.Errorf("parseSize(%q) = %d, %v; want: %d, ok: %t", test.in, got, err, test.want, test.ok)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/charlievieth/pjson"
+)
+
+type countWriter struct {
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// colorOverhead returns the size of data formatted by stream without color
+// and the number of bytes of escape sequences conf adds to it. The config
+// of stream is set to conf.
+func colorOverhead(conf *pjson.IndentConfig, stream *pjson.Stream, data []byte) (plain, overhead int64, err error) {
+	overhead, err = conf.ColorOverhead(data)
+	if err != nil {
+		return 0, 0, err
+	}
+	var w countWriter
+	stream.SetConfig(&pjson.IndentConfig{})
+	stream.Reset(bytes.NewReader(data))
+	_, err = stream.WriteTo(&w)
+	stream.SetConfig(conf)
+	return w.n, overhead, err
+}
+
+func percent(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
+
+// runColorReport writes the color overhead of each of the named files (or
+// STDIN if there are none) to w instead of formatting them.
+func runColorReport(w io.Writer, conf *pjson.IndentConfig, stream *pjson.Stream, names []string) error {
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	out := bufio.NewWriter(w)
+	for _, name := range names {
+		data, err := readInput(name)
+		if err != nil {
+			out.Flush()
+			return err
+		}
+		plain, overhead, err := colorOverhead(conf, stream, data)
+		if err != nil {
+			out.Flush()
+			return fmt.Errorf("%s: %w", displayName(name), err)
+		}
+		fmt.Fprintf(out, "%s: %d bytes, %d bytes of color (%.1f%%)\n",
+			displayName(name), plain, overhead, percent(overhead, plain))
+	}
+	return out.Flush()
+}
+
+// runColorBudget formats each of the named files (or STDIN if there are
+// none) with stream, but disables color for any input where it would add
+// more than max percent to the size of the output.
+func runColorBudget(w io.Writer, conf *pjson.IndentConfig, stream *pjson.Stream, names []string, max float64) error {
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	out := bufio.NewWriterSize(w, 96*1024)
+	for _, name := range names {
+		data, err := readInput(name)
+		if err == nil {
+			var plain, overhead int64
+			plain, overhead, err = colorOverhead(conf, stream, data)
+			if err == nil {
+				if percent(overhead, plain) > max {
+					stream.SetConfig(&pjson.IndentConfig{})
+				}
+				stream.Reset(bytes.NewReader(data))
+				_, err = stream.WriteTo(out)
+				stream.SetConfig(conf)
+			}
+		}
+		if err != nil {
+			out.Flush()
+			return fmt.Errorf("%s: %w", displayName(name), err)
+		}
+	}
+	return out.Flush()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/charlievieth/pjson"
+	"github.com/charlievieth/pjson/termcolor"
+	"github.com/spf13/cobra"
+)
+
+// runPatch applies the patch in the file patchName to the document in the
+// file docName with apply and returns the result indented with conf.
+// Files that cannot be read or are not valid, including patches that are
+// not a list of operations, exit with status 2 and patches that cannot be
+// applied with status 1.
+func runPatch(conf *pjson.IndentConfig, apply func(doc, patch []byte) ([]byte, error), docName, patchName string) ([]byte, error) {
+	doc, err := readJSON(docName)
+	if err != nil {
+		return nil, &statusError{status: exitInputError, err: err}
+	}
+	patch, err := readJSON(patchName)
+	if err != nil {
+		return nil, &statusError{status: exitInputError, err: err}
+	}
+	out, err := apply(doc, patch)
+	if err != nil {
+		err = fmt.Errorf("%s: %w", displayName(patchName), err)
+		if isDecodeError(err) {
+			return nil, &statusError{status: exitInputError, err: err}
+		}
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := conf.Indent(&buf, out, "", "    "); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// isDecodeError reports whether err is an error decoding JSON, not one
+// applying a patch.
+func isDecodeError(err error) bool {
+	var se *pjson.SyntaxError
+	var te *pjson.UnmarshalTypeError
+	return errors.As(err, &se) || errors.As(err, &te)
+}
+
+func newPatchCommand() *cobra.Command {
+	return patchCommand(&cobra.Command{
+		Use:   "patch [flags] doc.json patch.json",
+		Short: "Apply a JSON Patch to a JSON document",
+		Long: "Apply the JSON Patch (RFC 6902) in patch.json to doc.json and print\n" +
+			"the result. The operations are applied in order and nothing is\n" +
+			"printed, or written, if any of them fails. With --in-place doc.json\n" +
+			"is replaced with the result, which is never colorized.\n\n" +
+			"The exit status is 2 if doc.json or patch.json cannot be read or is\n" +
+			"not valid, and 1 if an operation fails, such as a failed test.",
+	}, pjson.ApplyPatch)
+}
+
+func newMergePatchCommand() *cobra.Command {
+	return patchCommand(&cobra.Command{
+		Use:   "merge-patch [flags] doc.json patch.json",
+		Short: "Apply a JSON Merge Patch to a JSON document",
+		Long: "Apply the JSON Merge Patch (RFC 7386) in patch.json to doc.json and\n" +
+			"print the result. Members of the patch that are null are removed\n" +
+			"from the document, objects are merged and other values replace the\n" +
+			"member of the document. With --in-place doc.json is replaced with\n" +
+			"the result, which is never colorized.\n\n" +
+			"The exit status is 2 if doc.json or patch.json cannot be read or is\n" +
+			"not valid.",
+	}, pjson.ApplyMergePatch)
+}
+
+// patchCommand adds the flags and the run function of a command that
+// applies a patch with apply to cmd.
+func patchCommand(cmd *cobra.Command, apply func(doc, patch []byte) ([]byte, error)) *cobra.Command {
+	cmd.Args = cobra.ExactArgs(2)
+	forceColor := cmd.Flags().BoolP("color", "C", false,
+		"Colorize the output even if not writing to a terminal.")
+	monochrome := cmd.Flags().BoolP("monochrome", "M", false,
+		"Do not colorize the output, even if writing to a terminal.")
+	inPlace := cmd.Flags().BoolP("in-place", "i", false,
+		"Write the result to doc.json instead of STDOUT.")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if *inPlace && isURL(args[0]) {
+			return fmt.Errorf("--in-place: cannot write to %s", displayName(args[0]))
+		}
+		// The arguments are valid, so errors from here on are not usage
+		// errors.
+		cmd.SilenceUsage = true
+		var conf pjson.IndentConfig
+		if !*inPlace && termcolor.Decide(os.Stdout, *forceColor, *monochrome).Enabled {
+			conf = defaultColors()
+		}
+		out, err := runPatch(&conf, apply, args[0], args[1])
+		if err != nil {
+			return err
+		}
+		if *inPlace {
+			return writeOutputFile(args[0], func(f *os.File) error {
+				_, err := f.Write(out)
+				return err
+			})
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	return cmd
+}
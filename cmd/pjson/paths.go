@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charlievieth/pjson"
+)
+
+// isLeaf reports whether the JSON value of kind is a scalar or an empty
+// object or array.
+func isLeaf(kind pjson.Kind, value []byte) bool {
+	if kind != pjson.KindObject && kind != pjson.KindArray {
+		return true
+	}
+	return len(bytes.TrimSpace(value[1:len(value)-1])) == 0
+}
+
+// writePaths writes the path of every leaf value in data to w, one per
+// line. If values is true the leaf value is written after an "=". If safe
+// is true control characters in strings are escaped (see pjson.Sanitize).
+func writePaths(w *bufio.Writer, conf *pjson.IndentConfig, data []byte, pointer, values, safe bool) error {
+	var buf []byte
+	var werr error
+	err := pjson.Walk(data, func(path pjson.Path, kind pjson.Kind, value []byte) bool {
+		if !isLeaf(kind, value) {
+			return true
+		}
+		buf = conf.AppendPath(buf[:0], path, pointer)
+		if values {
+			if kind == pjson.KindObject || kind == pjson.KindArray {
+				value = []byte{value[0], value[0] + 2} // '{' + 2 == '}'
+			}
+			buf = append(buf, " = "...)
+			clr := conf.ValueColor(value)
+			buf = clr.Append(buf)
+			if safe {
+				buf = pjson.Sanitize(buf, value)
+			} else {
+				buf = append(buf, value...)
+			}
+			buf = append(buf, clr.Reset()...)
+		}
+		buf = append(buf, '\n')
+		if _, werr = w.Write(buf); werr != nil {
+			return false
+		}
+		return true
+	})
+	if werr != nil {
+		return werr
+	}
+	return err
+}
+
+// runPaths writes the leaf paths of each of the named files (or STDIN if
+// there are none) to w.
+func runPaths(w io.Writer, conf *pjson.IndentConfig, names []string, pointer, values, safe bool) error {
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	out := bufio.NewWriter(w)
+	for _, name := range names {
+		data, err := readInput(name)
+		if err == nil {
+			err = writePaths(out, conf, data, pointer, values, safe)
+		}
+		if err != nil {
+			out.Flush()
+			return fmt.Errorf("%s: %w", displayName(name), err)
+		}
+	}
+	return out.Flush()
+}
+
+// shellSafe reports whether c does not need to be quoted in a POSIX shell
+// word.
+func shellSafe(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		strings.IndexByte("_-.,:/@%+[]", c) != -1
+}
+
+// appendShellQuoted appends s to dst, in single quotes unless every byte
+// of s is shellSafe, so a POSIX shell reads it as a single word.
+func appendShellQuoted(dst []byte, s string) []byte {
+	quote := s == ""
+	for i := 0; i < len(s) && !quote; i++ {
+		quote = !shellSafe(s[i])
+	}
+	if !quote {
+		return append(dst, s...)
+	}
+	dst = append(dst, '\'')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\'' {
+			dst = append(dst, `'\''`...)
+		} else {
+			dst = append(dst, s[i])
+		}
+	}
+	return append(dst, '\'')
+}
+
+// runKV writes a "path=value" line for every leaf value of each of the
+// named files (or STDIN if there are none) to w. Strings are unquoted and
+// paths and values are quoted for a POSIX shell, if needed.
+func runKV(w io.Writer, names []string) error {
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	out := bufio.NewWriter(w)
+	var buf []byte
+	for _, name := range names {
+		data, err := readInput(name)
+		if err == nil {
+			err = pjson.Walk(data, func(path pjson.Path, kind pjson.Kind, value []byte) bool {
+				if !isLeaf(kind, value) {
+					return true
+				}
+				buf = appendShellQuoted(buf[:0], path.String())
+				buf = append(buf, '=')
+				switch kind {
+				case pjson.KindString:
+					var s string
+					if pjson.Unmarshal(value, &s) == nil {
+						buf = appendShellQuoted(buf, s)
+					}
+				case pjson.KindObject, pjson.KindArray:
+					buf = appendShellQuoted(buf, string([]byte{value[0], value[0] + 2}))
+				default:
+					buf = append(buf, value...)
+				}
+				buf = append(buf, '\n')
+				out.Write(buf)
+				return true
+			})
+		}
+		if err != nil {
+			out.Flush()
+			return fmt.Errorf("%s: %w", displayName(name), err)
+		}
+	}
+	return out.Flush()
+}
+
+// runKeys writes the distinct key paths of the named files (or STDIN if
+// there are none) to w in the order they first appear.
+func runKeys(w io.Writer, names []string) error {
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	out := bufio.NewWriter(w)
+	seen := make(map[string]bool)
+	for _, name := range names {
+		data, err := readInput(name)
+		var paths []string
+		if err == nil {
+			paths, err = pjson.KeyPaths(data)
+		}
+		if err != nil {
+			out.Flush()
+			return fmt.Errorf("%s: %w", displayName(name), err)
+		}
+		for _, p := range paths {
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			out.WriteString(p)
+			out.WriteByte('\n')
+		}
+	}
+	return out.Flush()
+}
+
+// runSummary writes a tree of the paths of all of the values in the named
+// files (or STDIN if there are none), with the types, number and size of
+// the values at each, to w.
+func runSummary(w io.Writer, names []string) error {
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	var docs [][]byte
+	for _, name := range names {
+		values, err := readValues(name)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, values...)
+	}
+	entries, err := pjson.Summarize(docs...)
+	if err != nil {
+		return err
+	}
+	out := bufio.NewWriter(w)
+	for i := range entries {
+		e := &entries[i]
+		for j := 0; j < e.Depth; j++ {
+			out.WriteString("  ")
+		}
+		out.WriteString(e.String())
+		out.WriteByte('\n')
+	}
+	return out.Flush()
+}
+
+// displayName returns the name used for the input file name in messages.
+func displayName(name string) string {
+	if name == "" {
+		return "<stdin>"
+	}
+	return name
+}
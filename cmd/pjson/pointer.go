@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/charlievieth/pjson"
+)
+
+// runPointer formats the value at the JSON Pointer pointer in each of the
+// named files (or STDIN if there are none) with stream and writes it to w.
+// Only the value is read into memory. The values written are recorded in
+// status. Inputs that cannot be read, or have no value at pointer, are
+// reported to STDERR and skipped.
+func runPointer(w io.Writer, stream *pjson.Stream, names []string, pointer string, status *exitStatus) error {
+	if _, err := pjson.ParsePointer(pointer); err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	out := bufio.NewWriter(w)
+	failed := 0
+	for _, name := range names {
+		f, err := openInput(name)
+		if err == nil {
+			var value []byte
+			value, err = pjson.ExtractPointer(f, pointer)
+			f.Close()
+			if err == nil {
+				stream.Reset(bytes.NewReader(value))
+				_, err = stream.WriteTo(out)
+				status.update(stream)
+			}
+		}
+		if err != nil {
+			reportError(os.Stderr, name, err)
+			failed++
+		}
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return &inputError{failed: failed, total: len(names), reported: true}
+	}
+	return nil
+}
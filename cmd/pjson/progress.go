@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charlievieth/pjson/termcolor"
+)
+
+// A progress reports how much of each input has been read to the terminal
+// with OSC 9;4 progress sequences, by setting the terminal title or with a
+// progress bar on STDERR. A nil *progress reports nothing.
+type progress struct {
+	w       io.Writer // the terminal
+	title   bool      // set the title, else use OSC 9;4
+	bar     bool      // draw a progress bar
+	started bool      // the title was saved, or the bar drawn
+	begin   time.Time // when reading the input started
+	name    string    // input being read
+	total   int64     // size of the input or -1 if unknown
+	n       int64     // bytes read
+	percent int       // last percent reported
+	buf     []byte
+}
+
+// newProgress returns a progress that reports in mode: "osc", "title" or
+// "bar". It returns nil if mode is empty or neither STDERR nor STDOUT is a
+// terminal, or STDERR is not a terminal for "bar".
+func newProgress(mode string) (*progress, error) {
+	var title bool
+	switch mode {
+	case "":
+		return nil, nil
+	case "osc":
+	case "title":
+		title = true
+	case "bar":
+		if !termcolor.IsTerminal(int(os.Stderr.Fd())) {
+			return nil, nil
+		}
+		return &progress{w: os.Stderr, bar: true}, nil
+	default:
+		return nil, fmt.Errorf("invalid terminal progress: %q", mode)
+	}
+	var w io.Writer
+	switch {
+	case termcolor.IsTerminal(int(os.Stderr.Fd())):
+		w = os.Stderr
+	case termcolor.IsTerminal(int(os.Stdout.Fd())):
+		w = os.Stdout
+	default:
+		return nil, nil
+	}
+	return &progress{w: w, title: title}, nil
+}
+
+// start begins reporting the progress of reading the input named name of
+// size total, which is -1 if unknown.
+func (p *progress) start(name string, total int64) {
+	if p == nil {
+		return
+	}
+	if p.title && !p.started {
+		p.started = true
+		io.WriteString(p.w, termcolor.PushTitle)
+	}
+	p.name = displayName(name)
+	p.total = total
+	p.n = 0
+	p.percent = -1
+	p.begin = time.Now()
+	p.report()
+}
+
+// reader returns r with the bytes read from it counted as progress.
+func (p *progress) reader(r io.Reader) io.Reader {
+	if p == nil {
+		return r
+	}
+	return &progressReader{r: r, p: p}
+}
+
+func (p *progress) report() {
+	percent := -1
+	if p.total > 0 {
+		percent = int(p.n * 100 / p.total)
+	}
+	if percent == p.percent && p.n > 0 {
+		return
+	}
+	p.percent = percent
+	switch {
+	case p.bar && percent < 0:
+		return // nothing to show until the size is known
+	case p.bar:
+		p.buf = p.appendBar(p.buf[:0], percent)
+		p.started = true
+	case p.title && percent < 0:
+		p.buf = termcolor.AppendTitle(p.buf[:0], "pjson: "+p.name)
+	case p.title:
+		p.buf = termcolor.AppendTitle(p.buf[:0], "pjson: "+p.name+" "+strconv.Itoa(percent)+"%")
+	case percent < 0:
+		p.buf = termcolor.AppendProgress(p.buf[:0], termcolor.ProgressIndeterminate, 0)
+	default:
+		p.buf = termcolor.AppendProgress(p.buf[:0], termcolor.ProgressNormal, percent)
+	}
+	p.w.Write(p.buf)
+}
+
+// barWidth is the number of characters in the progress bar.
+const barWidth = 30
+
+// appendBar appends the progress bar of the current input, such as
+//
+//	file.json  42% [============                  ] 118.25 MB/s
+//
+// to dst. It begins by erasing the line, so it replaces the previous bar.
+func (p *progress) appendBar(dst []byte, percent int) []byte {
+	dst = append(dst, "\r\x1b[K"...)
+	dst = append(dst, p.name...)
+	dst = append(dst, ' ')
+	if percent < 100 {
+		dst = append(dst, ' ')
+	}
+	if percent < 10 {
+		dst = append(dst, ' ')
+	}
+	dst = strconv.AppendInt(dst, int64(percent), 10)
+	dst = append(dst, "% ["...)
+	n := percent * barWidth / 100
+	dst = append(dst, strings.Repeat("=", n)...)
+	dst = append(dst, strings.Repeat(" ", barWidth-n)...)
+	dst = append(dst, "] "...)
+	if d := time.Since(p.begin).Seconds(); d > 0 && p.n > 0 {
+		mb := float64(p.n) / float64(1024*1024)
+		dst = strconv.AppendFloat(dst, mb/d, 'f', 2, 64)
+		dst = append(dst, " MB/s"...)
+	}
+	return dst
+}
+
+// clear erases the progress bar, if it was drawn, so that messages can be
+// written to STDERR.
+func (p *progress) clear() {
+	if p == nil || !p.bar || !p.started {
+		return
+	}
+	p.started = false
+	p.percent = -1
+	io.WriteString(p.w, "\r\x1b[K")
+}
+
+// finish removes the progress indicator or restores the terminal title.
+func (p *progress) finish() {
+	if p == nil {
+		return
+	}
+	if p.bar {
+		p.clear()
+		return
+	}
+	if p.title {
+		if p.started {
+			io.WriteString(p.w, termcolor.PopTitle)
+		}
+		return
+	}
+	p.w.Write(termcolor.AppendProgress(p.buf[:0], termcolor.ProgressNone, 0))
+}
+
+type progressReader struct {
+	r io.Reader
+	p *progress
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	if n > 0 {
+		r.p.n += int64(n)
+		r.p.report()
+	}
+	return n, err
+}
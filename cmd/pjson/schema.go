@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/charlievieth/pjson"
+	"github.com/charlievieth/pjson/termcolor"
+	"github.com/spf13/cobra"
+)
+
+// readValues returns each of the JSON values in the named file, or STDIN
+// if name is empty, such as the lines of an NDJSON file.
+func readValues(name string) ([][]byte, error) {
+	f, err := openInput(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var values [][]byte
+	dec := pjson.NewDecoder(f)
+	for {
+		var v pjson.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			if errors.Is(err, io.EOF) {
+				return values, nil
+			}
+			return nil, fmt.Errorf("%s: %w", displayName(name), err)
+		}
+		values = append(values, v)
+	}
+}
+
+// runInferSchema writes the schema inferred from all of the values in the
+// named files, or STDIN if there are none, to w. Input that cannot be
+// read or is not valid exits with status 2.
+func runInferSchema(w io.Writer, conf *pjson.IndentConfig, names []string) error {
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	var docs [][]byte
+	for _, name := range names {
+		values, err := readValues(name)
+		if err != nil {
+			return &statusError{status: exitInputError, err: err}
+		}
+		docs = append(docs, values...)
+	}
+	schema, err := pjson.InferSchema(docs...)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := conf.Indent(&buf, schema, "", "    "); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+func newInferSchemaCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "infer-schema [flags] [file]...",
+		Short: "Print a draft JSON Schema describing JSON documents",
+		Long: "Print a draft JSON Schema (2020-12) that describes the shape of all\n" +
+			"of the values in the files, or STDIN: their types, the properties\n" +
+			"of objects, which properties are required and the types of array\n" +
+			"elements. A property is required if it is present in every object\n" +
+			"seen at its location. Formats, enums and bounds are not inferred.",
+	}
+	forceColor := cmd.Flags().BoolP("color", "C", false,
+		"Colorize the output even if not writing to a terminal.")
+	monochrome := cmd.Flags().BoolP("monochrome", "M", false,
+		"Do not colorize the output, even if writing to a terminal.")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		var conf pjson.IndentConfig
+		if termcolor.Decide(os.Stdout, *forceColor, *monochrome).Enabled {
+			conf = defaultColors()
+		}
+		return runInferSchema(os.Stdout, &conf, args)
+	}
+	return cmd
+}
+
+// runSchema validates each of the values in the named files, or STDIN if
+// there are none, against the JSON Schema in the file schemaName and
+// writes a line for each violation, and for each valid file if status is
+// true, to w. It reports whether all of the values were valid.
+func runSchema(w io.Writer, schemaName string, names []string, status bool) (bool, error) {
+	data, err := readInput(schemaName)
+	if err != nil {
+		return false, err
+	}
+	schema, err := pjson.CompileSchema(data)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", displayName(schemaName), err)
+	}
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	valid := true
+	for _, name := range names {
+		values, err := readValues(name)
+		if err != nil {
+			valid = false
+			if _, err := fmt.Fprintln(w, err); err != nil {
+				return false, err
+			}
+			continue
+		}
+		ok := true
+		for i, v := range values {
+			violations, err := schema.Validate(v)
+			if err != nil {
+				return false, err // already validated by readValues
+			}
+			prefix := displayName(name)
+			if len(values) > 1 {
+				prefix += fmt.Sprintf(": value %d", i+1)
+			}
+			for j := range violations {
+				ok = false
+				if _, err := fmt.Fprintf(w, "%s: %v\n", prefix, &violations[j]); err != nil {
+					return false, err
+				}
+			}
+		}
+		if !ok {
+			valid = false
+		} else if status {
+			if _, err := fmt.Fprintf(w, "%s: ok\n", displayName(name)); err != nil {
+				return false, err
+			}
+		}
+	}
+	return valid, nil
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charlievieth/pjson"
+	"github.com/spf13/cobra"
+)
+
+// releasesURL is the GitHub API endpoint of the latest release.
+const releasesURL = "https://api.github.com/repos/charlievieth/pjson/releases/latest"
+
+// checksumsAsset is the name of the release asset that lists the SHA-256
+// checksum of the other assets in the format of sha256sum(1).
+const checksumsAsset = "checksums.txt"
+
+// maxReleaseSize limits the size of downloaded release assets.
+const maxReleaseSize = 64 * 1024 * 1024
+
+type releaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+type release struct {
+	TagName string         `json:"tag_name"`
+	Assets  []releaseAsset `json:"assets"`
+}
+
+func (r *release) asset(name string) (*releaseAsset, error) {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i], nil
+		}
+	}
+	return nil, fmt.Errorf("release %s has no asset named %q", r.TagName, name)
+}
+
+// binaryAsset returns the name of the release asset of the binary for
+// the current platform.
+func binaryAsset() string {
+	name := "pjson-" + runtime.GOOS + "-" + runtime.GOARCH
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+var updateClient = &http.Client{Timeout: 2 * time.Minute}
+
+// download returns the body of url, which may be at most max bytes.
+func download(url string, max int64) ([]byte, error) {
+	res, err := updateClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, res.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > max {
+		return nil, fmt.Errorf("GET %s: response exceeds %d bytes", url, max)
+	}
+	return data, nil
+}
+
+func latestRelease() (*release, error) {
+	data, err := download(releasesURL, 1024*1024)
+	if err != nil {
+		return nil, err
+	}
+	var r release
+	if err := pjson.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parsing release: %w", err)
+	}
+	if r.TagName == "" {
+		return nil, errors.New("parsing release: missing tag name")
+	}
+	return &r, nil
+}
+
+// lookupChecksum returns the SHA-256 checksum of the file name from the
+// sha256sum(1) output checksums.
+func lookupChecksum(checksums []byte, name string) ([]byte, error) {
+	sc := bufio.NewScanner(bytes.NewReader(checksums))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		// The file name is prefixed with '*' in binary mode.
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			sum, err := hex.DecodeString(fields[0])
+			if err != nil || len(sum) != sha256.Size {
+				return nil, fmt.Errorf("invalid checksum for %q: %q", name, fields[0])
+			}
+			return sum, nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("no checksum for %q", name)
+}
+
+// rename is os.Rename, replaced by tests.
+var rename = os.Rename
+
+// replaceExecutable atomically replaces the running executable with data.
+func replaceExecutable(data []byte) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	// A running executable cannot be replaced on Windows, but it can be
+	// renamed.
+	return replaceFile(exe, data, runtime.GOOS == "windows")
+}
+
+// replaceFile atomically replaces the file name with data, keeping its
+// permissions. If keepOld is true name is first renamed to name.old and
+// renamed back if it cannot be replaced.
+func replaceFile(name string, data []byte, keepOld bool) error {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	// Create the new file in the same directory so it can be renamed
+	// over the old one.
+	f, err := os.CreateTemp(filepath.Dir(name), ".pjson-update-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, fi.Mode().Perm()); err != nil {
+		return err
+	}
+	if !keepOld {
+		return rename(tmp, name)
+	}
+	old := name + ".old"
+	os.Remove(old)
+	if err := rename(name, old); err != nil {
+		return err
+	}
+	if err := rename(tmp, name); err != nil {
+		if rerr := rename(old, name); rerr != nil {
+			return fmt.Errorf("%w (restoring %s: %v)", err, name, rerr)
+		}
+		return err
+	}
+	return nil
+}
+
+// parseVersion parses the semantic version v, such as v1.2.3 or
+// v1.2.3-rc.1, without its build metadata.
+func parseVersion(v string) (num [3]int, pre string, ok bool) {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		v = v[:i]
+	}
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		v, pre = v[:i], v[i+1:]
+		if pre == "" {
+			return num, "", false
+		}
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return num, "", false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || p[0] == '+' {
+			return num, "", false
+		}
+		num[i] = n
+	}
+	return num, pre, true
+}
+
+// compareVersions returns -1, 0 or +1 as the semantic version a is less
+// than, equal to or greater than b. It reports false if either is not a
+// semantic version, such as "devel".
+func compareVersions(a, b string) (int, bool) {
+	na, pa, ok := parseVersion(a)
+	if !ok {
+		return 0, false
+	}
+	nb, pb, ok := parseVersion(b)
+	if !ok {
+		return 0, false
+	}
+	for i := range na {
+		if na[i] != nb[i] {
+			return sign(na[i] - nb[i]), true
+		}
+	}
+	// A pre-release precedes the release.
+	switch {
+	case pa == pb:
+		return 0, true
+	case pa == "":
+		return 1, true
+	case pb == "":
+		return -1, true
+	}
+	ia, ib := strings.Split(pa, "."), strings.Split(pb, ".")
+	for i := 0; i < len(ia) && i < len(ib); i++ {
+		if c := comparePrerelease(ia[i], ib[i]); c != 0 {
+			return c, true
+		}
+	}
+	return sign(len(ia) - len(ib)), true
+}
+
+// comparePrerelease compares the pre-release identifiers a and b: numeric
+// identifiers are compared numerically and precede the others, which are
+// compared lexically.
+func comparePrerelease(a, b string) int {
+	na, errA := strconv.ParseUint(a, 10, 64)
+	nb, errB := strconv.ParseUint(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		if na == nb {
+			return 0
+		}
+		if na < nb {
+			return -1
+		}
+		return 1
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// runSelfUpdate updates the running executable to the latest release,
+// unless it is already up to date or check is true.
+func runSelfUpdate(w io.Writer, check, force bool) error {
+	r, err := latestRelease()
+	if err != nil {
+		return err
+	}
+	// Never downgrade to an older release, unless forced.
+	newer := r.TagName != version
+	if c, ok := compareVersions(r.TagName, version); ok {
+		newer = c > 0
+	}
+	if !newer && !force {
+		fmt.Fprintf(w, "pjson %s is up to date (latest release: %s)\n", version, r.TagName)
+		return nil
+	}
+	if check {
+		fmt.Fprintf(w, "pjson %s is available (current version: %s)\n", r.TagName, version)
+		return nil
+	}
+	if version == "devel" && !force {
+		return errors.New("refusing to replace a development build, use --force to update anyway")
+	}
+
+	name := binaryAsset()
+	bin, err := r.asset(name)
+	if err != nil {
+		return err
+	}
+	sums, err := r.asset(checksumsAsset)
+	if err != nil {
+		return err
+	}
+	checksums, err := download(sums.URL, 1024*1024)
+	if err != nil {
+		return err
+	}
+	want, err := lookupChecksum(checksums, name)
+	if err != nil {
+		return err
+	}
+	data, err := download(bin.URL, maxReleaseSize)
+	if err != nil {
+		return err
+	}
+	if got := sha256.Sum256(data); !bytes.Equal(got[:], want) {
+		return fmt.Errorf("checksum mismatch for %s: got %x want %x", name, got, want)
+	}
+	if err := replaceExecutable(data); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "updated pjson %s => %s\n", version, r.TagName)
+	return nil
+}
+
+func newSelfUpdateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "self-update [flags]",
+		Short: "Update pjson to the latest release",
+		Long: "Download the latest release of pjson for this platform, verify its\n" +
+			"SHA-256 checksum against the checksums published with the release\n" +
+			"and replace the running executable with it. Releases older than the\n" +
+			"running version are not installed unless --force is given.\n\n" +
+			"The checksums are downloaded from the same release as the binary, so\n" +
+			"they detect a corrupted download but not a compromised release.",
+		Args: cobra.NoArgs,
+	}
+	check := cmd.Flags().Bool("check", false, "Only check if a new release is available.")
+	force := cmd.Flags().Bool("force", false, "Update even if the current version is the latest or newer.")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return runSelfUpdate(os.Stdout, *check, *force)
+	}
+	return cmd
+}
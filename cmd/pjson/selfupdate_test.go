@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLookupChecksum(t *testing.T) {
+	const sum = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	checksums := sum + "  pjson-linux-amd64\n" +
+		sum + " *pjson-windows-amd64.exe\n" +
+		"abc  pjson-darwin-arm64\n" +
+		"\n" +
+		"not a checksum line\n"
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"pjson-linux-amd64", true},
+		{"pjson-windows-amd64.exe", true},
+		{"pjson-darwin-arm64", false}, // invalid checksum
+		{"pjson-linux-arm64", false},  // missing
+		{"pjson-linux", false},
+	}
+	for _, test := range tests {
+		got, err := lookupChecksum([]byte(checksums), test.name)
+		if (err == nil) != test.ok {
+			t.Errorf("lookupChecksum(%q): error = %v; want ok: %t", test.name, err, test.ok)
+			continue
+		}
+		if test.ok && hex.EncodeToString(got) != sum {
+			t.Errorf("lookupChecksum(%q) = %x; want: %s", test.name, got, sum)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+		ok   bool
+	}{
+		{"v1.2.3", "v1.2.3", 0, true},
+		{"v1.2.3", "1.2.3", 0, true},
+		{"v1.2.4", "v1.2.3", 1, true},
+		{"v1.10.0", "v1.9.0", 1, true},
+		{"v1.2.3", "v2.0.0", -1, true},
+		{"v1.2.3-rc.1", "v1.2.3", -1, true},
+		{"v1.2.3-rc.2", "v1.2.3-rc.10", -1, true},
+		{"v1.2.3-rc.1", "v1.2.3-beta", 1, true},
+		{"v1.2.3-1", "v1.2.3-a", -1, true},
+		{"v1.2.3-rc", "v1.2.3-rc.1", -1, true},
+		{"v1.2.3+build", "v1.2.3", 0, true},
+		{"devel", "v1.2.3", 0, false},
+		{"v1.2.3", "v1.2", 0, false},
+		{"v1.2.3-", "v1.2.3", 0, false},
+	}
+	for _, test := range tests {
+		got, ok := compareVersions(test.a, test.b)
+		if got != test.want || ok != test.ok {
+			t.Errorf("compareVersions(%q, %q) = %d, %t; want: %d, %t",
+				test.a, test.b, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestReplaceFile(t *testing.T) {
+	readFile := func(name string) []byte {
+		t.Helper()
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	for _, keepOld := range []bool{false, true} {
+		dir := t.TempDir()
+		exe := filepath.Join(dir, "pjson")
+		if err := os.WriteFile(exe, []byte("old"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := replaceFile(exe, []byte("new"), keepOld); err != nil {
+			t.Fatal(err)
+		}
+		if got := readFile(exe); !bytes.Equal(got, []byte("new")) {
+			t.Errorf("keepOld %t: file = %q; want: %q", keepOld, got, "new")
+		}
+		if fi, err := os.Stat(exe); err != nil {
+			t.Fatal(err)
+		} else if fi.Mode().Perm() != 0755 {
+			t.Errorf("keepOld %t: permissions = %v; want: %v", keepOld, fi.Mode().Perm(), os.FileMode(0755))
+		}
+		if _, err := os.Stat(exe + ".old"); (err == nil) != keepOld {
+			t.Errorf("keepOld %t: stat %s.old: %v", keepOld, exe, err)
+		}
+	}
+}
+
+func TestReplaceFileRollback(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "pjson")
+	if err := os.WriteFile(exe, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	errRename := errors.New("rename failed")
+	defer func() { rename = os.Rename }()
+	rename = func(oldpath, newpath string) error {
+		if newpath == exe && oldpath != exe+".old" {
+			return errRename // replacing exe with the update
+		}
+		return os.Rename(oldpath, newpath)
+	}
+	if err := replaceFile(exe, []byte("new"), true); !errors.Is(err, errRename) {
+		t.Fatalf("replaceFile: error = %v; want: %v", err, errRename)
+	}
+	b, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatalf("executable was not restored: %v", err)
+	}
+	if string(b) != "old" {
+		t.Errorf("file = %q; want: %q", b, "old")
+	}
+	tmp, err := filepath.Glob(filepath.Join(dir, ".pjson-update-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tmp) != 0 {
+		t.Errorf("temporary files were not removed: %q", tmp)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// fetchOptions are the options used to fetch URL inputs.
+var fetchOptions struct {
+	Timeout time.Duration
+	Headers []string // "Name: value"
+}
+
+// isURL reports whether the input name is an HTTP or HTTPS URL.
+func isURL(name string) bool {
+	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
+}
+
+// openURL fetches url and returns its body and its length, or -1 if it is
+// unknown.
+func openURL(url string) (io.ReadCloser, int64, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "pjson/"+version)
+	for _, h := range fetchOptions.Headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, 0, fmt.Errorf("invalid header: %q (want \"Name: value\")", h)
+		}
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	client := http.Client{Timeout: fetchOptions.Timeout}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		res.Body.Close()
+		return nil, 0, fmt.Errorf("HTTP status %s", res.Status)
+	}
+	return res.Body, res.ContentLength, nil
+}
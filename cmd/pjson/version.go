@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// setVersion sets version from the build info of the binary if it was not
+// set when building and pjson was installed with go install, which
+// records the version of the module. Builds of modified checkouts are
+// left as "devel".
+func setVersion() {
+	if version != "devel" {
+		return
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	v := bi.Main.Version
+	if v != "" && v != "(devel)" && !strings.HasSuffix(v, "+dirty") {
+		version = v
+	}
+}
+
+// writeVersion writes the version of pjson to w followed by the VCS
+// revision and time of the commit it was built from, if known, and the
+// Go version and platform it was built for.
+func writeVersion(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "pjson %s\n", version); err != nil {
+		return err
+	}
+	var revision, time string
+	modified := false
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.time":
+				time = s.Value
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+	}
+	if revision != "" {
+		if modified {
+			revision += " (modified)"
+		}
+		if _, err := fmt.Fprintf(w, "revision: %s\n", revision); err != nil {
+			return err
+		}
+	}
+	if time != "" {
+		if _, err := fmt.Fprintf(w, "date:     %s\n", time); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "go:       %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return err
+}
+
+func newVersionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Print the version of pjson and how it was built",
+		Long: "Print the version of pjson, the VCS revision and date of the commit\n" +
+			"it was built from, if known, and the Go version and platform it was\n" +
+			"built for. Include this in bug reports. Same as pjson --version.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return writeVersion(cmd.OutOrStdout())
+		},
+	}
+}
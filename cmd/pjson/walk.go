@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// readFileList returns the file names read from r, which are separated
+// by delim. Empty names are skipped and if delim is a newline a trailing
+// carriage return is removed from each name.
+func readFileList(r io.Reader, delim byte) ([]string, error) {
+	var names []string
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.ReadBytes(delim)
+		b = bytes.TrimSuffix(b, []byte{delim})
+		if delim == '\n' {
+			b = bytes.TrimSuffix(b, []byte{'\r'})
+		}
+		if len(b) != 0 {
+			names = append(names, string(b))
+		}
+		if err == io.EOF {
+			return names, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// expandArgs replaces each directory in args with the files in it, and in
+// its subdirectories, whose base name matches pattern if recursive is
+// true. Directories are walked in lexical order. Other arguments, such as
+// files that do not match pattern and URLs, are kept as they are.
+func expandArgs(args []string, recursive bool, pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid --glob: %q: %w", pattern, err)
+	}
+	var names []string
+	for _, arg := range args {
+		if isURL(arg) {
+			names = append(names, arg)
+			continue
+		}
+		fi, err := os.Stat(arg)
+		if err != nil || !fi.IsDir() {
+			names = append(names, arg) // reported when opened
+			continue
+		}
+		if !recursive {
+			return nil, fmt.Errorf("%s: is a directory (use -R to read the files in it)", arg)
+		}
+		n := len(names)
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			if ok, _ := filepath.Match(pattern, d.Name()); ok {
+				names = append(names, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == n && len(args) > 1 {
+			fmt.Fprintf(os.Stderr, "warning: %s: no files matching %q\n", arg, pattern)
+		}
+	}
+	if len(names) == 0 && len(args) != 0 {
+		// Don't fall back to reading STDIN.
+		return nil, fmt.Errorf("no files matching %q", pattern)
+	}
+	return names, nil
+}
@@ -0,0 +1,46 @@
+package pjson
+
+import "bytes"
+
+// elision replaces the members or elements of an object or array deeper
+// than the maximum depth of a Stream.
+const elision = "..."
+
+// skipContainer steps scan over the object or array whose opening bracket
+// at src[start] it just stepped. It returns the index of the closing
+// bracket and the number of members or elements. It returns false if src
+// ends first or is invalid, in which case the scanner has the error.
+func skipContainer(scan *Scanner, src []byte, start int) (end, n int, ok bool) {
+	level := len(scan.parseState)
+	for i := start + 1; i < len(src); i++ {
+		c := src[i]
+		v := scan.Step(c)
+		switch {
+		case v == ScanError:
+			return i, n, false
+		case v == ScanSkipSpace:
+			continue
+		case len(scan.parseState) < level:
+			return i, n, true
+		}
+		if n == 0 {
+			n = 1
+		}
+		if c == ',' && v != ScanContinue && len(scan.parseState) == level {
+			n++
+		}
+	}
+	return len(src), n, false
+}
+
+// writeElided writes the object or array src[start:end+1], whose members
+// or elements are elided if n is not zero, to dst.
+func writeElided(emit emitter, dst *bytes.Buffer, src []byte, start, end, n int) {
+	emit.begin(dst, classPunct)
+	dst.WriteByte(src[start])
+	if n > 0 {
+		dst.WriteString(elision)
+	}
+	dst.WriteByte(src[end])
+	emit.end(dst, classPunct)
+}
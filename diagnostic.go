@@ -0,0 +1,94 @@
+package pjson
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+)
+
+// Severity is the severity of a Diagnostic.
+type Severity int8
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+var severityStrs = [...]string{
+	"error",
+	"warning",
+}
+
+func (s Severity) String() string {
+	if uint(s) < uint(len(severityStrs)) {
+		return severityStrs[s]
+	}
+	return "Severity(" + strconv.Itoa(int(s)) + ")"
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	if uint(s) < uint(len(severityStrs)) {
+		return []byte(severityStrs[s]), nil
+	}
+	return nil, errors.New("pjson: invalid Severity: " + strconv.Itoa(int(s)))
+}
+
+// A Diagnostic describes a syntax error or questionable construct found
+// in a JSON document.
+type Diagnostic struct {
+	File     string   `json:"file,omitempty"`
+	Line     int      `json:"line"`   // 1-based line number
+	Col      int      `json:"col"`    // 1-based column (in bytes)
+	Offset   int64    `json:"offset"` // 0-based byte offset
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	Path     string   `json:"path,omitempty"` // path of the value (see Path.String)
+	Rule     string   `json:"rule,omitempty"` // name of the lint Rule
+}
+
+func (d *Diagnostic) String() string {
+	s := strconv.Itoa(d.Line) + ":" + strconv.Itoa(d.Col) + ": " +
+		d.Severity.String() + ": " + d.Message
+	if d.File != "" {
+		s = d.File + ":" + s
+	}
+	if d.Path != "" {
+		s += " (" + d.Path + ")"
+	}
+	return s
+}
+
+// LintMaxDepth is the nesting depth after which Diagnose reports
+// a warning.
+const LintMaxDepth = 64
+
+// maxSafeInteger is the largest integer that can be represented exactly
+// by an IEEE 754 double (2^53).
+const maxSafeInteger = 1 << 53
+
+// Diagnose returns the syntax errors in data (up to maxErrors, see
+// ValidateAll) and, if data is syntactically valid, warnings for
+// duplicate object keys, integers that cannot be represented exactly
+// by a float64, strings that look like numbers or booleans and values
+// nested deeper than LintMaxDepth.
+func Diagnose(data []byte, maxErrors int) []Diagnostic {
+	diags := syntaxFindings(data, maxErrors)
+	if len(diags) == 0 {
+		diags = lint(data, DefaultRules())
+	}
+	setLineCol(data, diags)
+	return diags
+}
+
+// setLineCol sets the Line and Col fields of diags from their Offset.
+func setLineCol(data []byte, diags []Diagnostic) {
+	for i := range diags {
+		d := &diags[i]
+		off := int(d.Offset)
+		if off > len(data) {
+			off = len(data)
+		}
+		d.Line = bytes.Count(data[:off], []byte{'\n'}) + 1
+		d.Col = off - bytes.LastIndexByte(data[:off], '\n')
+	}
+}
@@ -0,0 +1,70 @@
+package pjson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiagnose(t *testing.T) {
+	deep := strings.Repeat("[", LintMaxDepth+1) + strings.Repeat("]", LintMaxDepth+1)
+	tests := []struct {
+		data string
+		want []Diagnostic
+	}{
+		{`{"a":[1,2.5,-3e100]}`, nil},
+		{"[1,\n 2 3]", []Diagnostic{
+			{Line: 2, Col: 4, Offset: 7, Severity: SeverityError,
+				Message: "invalid character '3' after array element", Rule: "syntax"},
+		}},
+		{"{\"a\": 1,\n \"b\": {\"a\": 1},\n \"\\u0061\": 2}", []Diagnostic{
+			{Line: 3, Col: 2, Offset: 26, Severity: SeverityWarning,
+				Message: `duplicate object key "a"`, Path: ".a", Rule: "duplicate-keys"},
+		}},
+		{`[9007199254740992, 9007199254740993, -9007199254740993, 1e20]`, []Diagnostic{
+			{Line: 1, Col: 20, Offset: 19, Severity: SeverityWarning,
+				Message: "integer 9007199254740993 exceeds 2^53 and may lose precision", Path: ".[1]", Rule: "large-integers"},
+			{Line: 1, Col: 38, Offset: 37, Severity: SeverityWarning,
+				Message: "integer -9007199254740993 exceeds 2^53 and may lose precision", Path: ".[2]", Rule: "large-integers"},
+		}},
+		{`12345678901234567890`, []Diagnostic{
+			{Line: 1, Col: 1, Offset: 0, Severity: SeverityWarning,
+				Message: "integer 12345678901234567890 exceeds 2^53 and may lose precision", Path: ".", Rule: "large-integers"},
+		}},
+		{deep, []Diagnostic{
+			{Line: 1, Col: LintMaxDepth + 1, Offset: LintMaxDepth, Severity: SeverityWarning,
+				Message: "nesting depth exceeds 64", Path: "." + strings.Repeat("[0]", LintMaxDepth), Rule: "max-depth"},
+		}},
+		{`{"id": "007", "n": "12", "f": " -4.5e6 ", "b": ["True", "false"], "s": "1a", "e": ""}`, []Diagnostic{
+			{Line: 1, Col: 20, Offset: 19, Severity: SeverityWarning,
+				Message: `string "12" looks like a number`, Path: ".n", Rule: "numeric-strings"},
+			{Line: 1, Col: 31, Offset: 30, Severity: SeverityWarning,
+				Message: `string " -4.5e6 " looks like a number`, Path: ".f", Rule: "numeric-strings"},
+			{Line: 1, Col: 49, Offset: 48, Severity: SeverityWarning,
+				Message: `string "True" looks like a boolean`, Path: ".b[0]", Rule: "numeric-strings"},
+			{Line: 1, Col: 57, Offset: 56, Severity: SeverityWarning,
+				Message: `string "false" looks like a boolean`, Path: ".b[1]", Rule: "numeric-strings"},
+		}},
+	}
+	for _, test := range tests {
+		got := Diagnose([]byte(test.data), 0)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Diagnose(%.40q):\ngot:  %+v\nwant: %+v", test.data, got, test.want)
+		}
+	}
+}
+
+func TestDiagnosticMarshal(t *testing.T) {
+	d := Diagnostic{File: "a.json", Line: 1, Col: 2, Offset: 1, Severity: SeverityWarning, Message: "msg", Path: ".a"}
+	b, err := Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"file":"a.json","line":1,"col":2,"offset":1,"severity":"warning","message":"msg","path":".a"}`
+	if string(b) != want {
+		t.Errorf("Marshal() = %s; want: %s", b, want)
+	}
+	if s := d.String(); s != "a.json:1:2: warning: msg (.a)" {
+		t.Errorf("String() = %q; want: %q", s, "a.json:1:2: warning: msg (.a)")
+	}
+}
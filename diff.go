@@ -0,0 +1,431 @@
+package pjson
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"strconv"
+)
+
+// A DiffOp is the type of a Change.
+type DiffOp uint8
+
+const (
+	// DiffAdd is a value that is only present in the new document.
+	DiffAdd DiffOp = iota
+	// DiffRemove is a value that is only present in the old document.
+	DiffRemove
+	// DiffReplace is a value that differs between the documents.
+	DiffReplace
+)
+
+var diffOpStrs = [...]string{
+	"add",
+	"remove",
+	"replace",
+}
+
+func (op DiffOp) String() string {
+	if uint(op) < uint(len(diffOpStrs)) {
+		return diffOpStrs[op]
+	}
+	return "DiffOp(" + strconv.Itoa(int(op)) + ")"
+}
+
+// A Change is a difference between two JSON documents. The array indices
+// of Path are those of the old document, except for DiffAdd which uses the
+// indices of the new document.
+type Change struct {
+	Op   DiffOp
+	Path Path
+	Old  []byte // compact encoding of the old value, nil for DiffAdd
+	New  []byte // compact encoding of the new value, nil for DiffRemove
+
+	// patchPath is the path of the change when the preceding changes
+	// have been applied, as used by JSON Patch.
+	patchPath Path
+}
+
+// DiffOptions configure Diff. The zero value is an order-insensitive
+// semantic diff.
+type DiffOptions struct {
+	// PreserveOrder makes the order of object members and the encoding
+	// of scalars significant, so documents that are equal when decoded
+	// but not byte for byte, such as `{"a":1,"b":2}` and `{"b":2,"a":1}`
+	// or 1.0 and 1, differ. An object whose members are reordered is
+	// reported as a single DiffReplace.
+	PreserveOrder bool
+
+	// ArrayKey is the name of a field that identifies the objects of an
+	// array, such as "id". If set, array elements are aligned by the
+	// value of the field, so an element whose fields change is reported
+	// by its changes rather than as removed and added.
+	ArrayKey string
+}
+
+// Diff returns the changes that transform the JSON document a into b, in
+// document order. By default documents are compared semantically: the
+// order of object members and the formatting and encoding of values are
+// ignored, so 1.0 equals 1 and "\u0061" equals "a". Duplicate object
+// keys are resolved as by Unmarshal, the last one wins.
+//
+// Array elements are aligned by their longest common subsequence, so
+// inserting or removing an element does not report every following
+// element as changed. Unaligned elements between two aligned ones are
+// compared pairwise. A nil opts is the same as the zero DiffOptions.
+func Diff(a, b []byte, opts *DiffOptions) ([]Change, error) {
+	if opts == nil {
+		opts = new(DiffOptions)
+	}
+	var ca, cb bytes.Buffer
+	if err := compact(&ca, a, false); err != nil {
+		return nil, err
+	}
+	if err := compact(&cb, b, false); err != nil {
+		return nil, err
+	}
+	d := differ{opts: opts}
+	d.diff(nil, nil, ca.Bytes(), cb.Bytes())
+	return d.changes, nil
+}
+
+type differ struct {
+	opts    *DiffOptions
+	changes []Change
+}
+
+// add records a change at path. The path of the change in the document
+// with the preceding changes applied is ppath.
+func (d *differ) add(op DiffOp, path, ppath Path, old, new []byte) {
+	d.changes = append(d.changes, Change{
+		Op:        op,
+		Path:      append(Path(nil), path...),
+		Old:       old,
+		New:       new,
+		patchPath: append(Path(nil), ppath...),
+	})
+}
+
+// diff records the changes between the valid compact JSON values a and b.
+func (d *differ) diff(path, ppath Path, a, b []byte) {
+	ka, kb := kindOf(a[0]), kindOf(b[0])
+	switch {
+	case ka != kb:
+		d.add(DiffReplace, path, ppath, a, b)
+	case ka == KindObject:
+		d.diffObjects(path, ppath, a, b)
+	case ka == KindArray:
+		d.diffArrays(path, ppath, a, b)
+	case !d.scalarsEqual(ka, a, b):
+		d.add(DiffReplace, path, ppath, a, b)
+	}
+}
+
+// lastMembers returns the members of object src with duplicate keys
+// removed, the last one wins, and the index of each key.
+func lastMembers(src []byte) ([]member, map[string]int) {
+	members := objectMembers(nil, src)
+	index := make(map[string]int, len(members))
+	for i, m := range members {
+		index[m.key] = i
+	}
+	if len(index) == len(members) {
+		return members, index
+	}
+	unique := members[:0]
+	for i, m := range members {
+		if index[m.key] == i {
+			index[m.key] = len(unique)
+			unique = append(unique, m)
+		}
+	}
+	return unique, index
+}
+
+func (d *differ) diffObjects(path, ppath Path, a, b []byte) {
+	ma, ia := lastMembers(a)
+	mb, ib := lastMembers(b)
+	if d.opts.PreserveOrder && reordered(ma, mb, ib) {
+		d.add(DiffReplace, path, ppath, a, b)
+		return
+	}
+	path = append(path, PathElem{Index: -1})
+	ppath = append(ppath, PathElem{Index: -1})
+	for _, m := range ma {
+		path[len(path)-1].Key = m.key
+		ppath[len(ppath)-1].Key = m.key
+		if j, ok := ib[m.key]; ok {
+			d.diff(path, ppath, m.value, mb[j].value)
+		} else {
+			d.add(DiffRemove, path, ppath, m.value, nil)
+		}
+	}
+	for _, m := range mb {
+		if _, ok := ia[m.key]; !ok {
+			path[len(path)-1].Key = m.key
+			ppath[len(ppath)-1].Key = m.key
+			d.add(DiffAdd, path, ppath, nil, m.value)
+		}
+	}
+}
+
+// reordered reports whether the keys common to the members ma and mb
+// appear in a different order. The index of each key of mb is ib.
+func reordered(ma, mb []member, ib map[string]int) bool {
+	last := -1
+	for _, m := range ma {
+		if j, ok := ib[m.key]; ok {
+			if j < last {
+				return true
+			}
+			last = j
+		}
+	}
+	return false
+}
+
+func (d *differ) diffArrays(path, ppath Path, a, b []byte) {
+	ea := arrayElems(nil, a)
+	eb := arrayElems(nil, b)
+	matches := alignArrays(d.fingerprints(ea), d.fingerprints(eb))
+	matches = append(matches, [2]int{len(ea), len(eb)})
+
+	path = append(path, PathElem{})
+	ppath = append(ppath, PathElem{})
+	i, j := 0, 0
+	for _, m := range matches {
+		d.diffGap(path, ppath, ea[i:m[0]], eb[j:m[1]], i, j)
+		if m[0] < len(ea) && d.opts.ArrayKey != "" {
+			// Elements matched by key may differ.
+			path[len(path)-1].Index = m[0]
+			ppath[len(ppath)-1].Index = m[1]
+			d.diff(path, ppath, ea[m[0]], eb[m[1]])
+		}
+		i, j = m[0]+1, m[1]+1
+	}
+}
+
+// diffGap records the changes between the unaligned elements ea and eb
+// of an array, which begin at index i and j of their arrays. The last
+// elements of path and ppath are the array index.
+func (d *differ) diffGap(path, ppath Path, ea, eb [][]byte, i, j int) {
+	n := 0 // number of pairs
+	if d.opts.ArrayKey == "" {
+		for ; n < len(ea) && n < len(eb); n++ {
+			path[len(path)-1].Index = i + n
+			ppath[len(ppath)-1].Index = j + n
+			d.diff(path, ppath, ea[n], eb[n])
+		}
+	}
+	// Removed elements are at the current position in the patched
+	// array, after the preceding elements of eb.
+	ppath[len(ppath)-1].Index = j + n
+	for k := n; k < len(ea); k++ {
+		path[len(path)-1].Index = i + k
+		d.add(DiffRemove, path, ppath, ea[k], nil)
+	}
+	for k := n; k < len(eb); k++ {
+		path[len(path)-1].Index = j + k
+		ppath[len(ppath)-1].Index = j + k
+		d.add(DiffAdd, path, ppath, nil, eb[k])
+	}
+}
+
+// fingerprints returns a string for each element of an array that is
+// equal for elements that are equal or, if the ArrayKey option is set,
+// have the same key.
+func (d *differ) fingerprints(elems [][]byte) []string {
+	fps := make([]string, len(elems))
+	var buf []byte
+	for i, e := range elems {
+		buf = buf[:0]
+		if d.opts.ArrayKey != "" {
+			if v := memberValue(e, d.opts.ArrayKey); v != nil {
+				e = v
+				buf = append(buf, 'k')
+			} else {
+				buf = append(buf, 'v')
+			}
+		}
+		if d.opts.PreserveOrder {
+			buf = append(buf, e...)
+		} else {
+			buf = canonical(buf, e)
+		}
+		fps[i] = string(buf)
+	}
+	return fps
+}
+
+// memberValue returns the value of the last member of the compact JSON
+// object src named key, or nil if src is not an object or has no such
+// member.
+func memberValue(src []byte, key string) []byte {
+	if src[0] != '{' {
+		return nil
+	}
+	var value []byte
+	for _, m := range objectMembers(nil, src) {
+		if m.key == key {
+			value = m.value
+		}
+	}
+	return value
+}
+
+// canonical appends a form of the compact JSON value src to dst that is
+// the same for values that are semantically equal. It is not necessarily
+// valid JSON.
+func canonical(dst, src []byte) []byte {
+	switch kindOf(src[0]) {
+	case KindObject:
+		members, _ := lastMembers(src)
+		sort.Slice(members, func(i, j int) bool {
+			return members[i].key < members[j].key
+		})
+		dst = append(dst, '{')
+		for _, m := range members {
+			dst = strconv.AppendQuote(dst, m.key)
+			dst = append(dst, ':')
+			dst = canonical(dst, m.value)
+			dst = append(dst, ',')
+		}
+		return append(dst, '}')
+	case KindArray:
+		dst = append(dst, '[')
+		for _, e := range arrayElems(nil, src) {
+			dst = canonical(dst, e)
+			dst = append(dst, ',')
+		}
+		return append(dst, ']')
+	case KindString:
+		s, _ := unquote(src)
+		return strconv.AppendQuote(dst, s)
+	case KindNumber:
+		if f, _, err := big.ParseFloat(string(src), 10, diffPrec, big.ToNearestEven); err == nil {
+			return f.Append(dst, 'g', -1)
+		}
+	}
+	return append(dst, src...)
+}
+
+// maxLCSCells is the maximum size of the table used to find the longest
+// common subsequence of two arrays. Larger arrays are only aligned by
+// their common prefix and suffix.
+const maxLCSCells = 1 << 22
+
+// alignArrays returns the indices of the elements of x and y that are
+// aligned with each other, in ascending order.
+func alignArrays(x, y []string) [][2]int {
+	var matches [][2]int
+	// Common prefix
+	p := 0
+	for p < len(x) && p < len(y) && x[p] == y[p] {
+		matches = append(matches, [2]int{p, p})
+		p++
+	}
+	// Common suffix
+	s := 0
+	for s < len(x)-p && s < len(y)-p && x[len(x)-1-s] == y[len(y)-1-s] {
+		s++
+	}
+	x, y = x[p:len(x)-s], y[p:len(y)-s]
+	if len(x) > 0 && len(y) > 0 && (len(x)+1)*(len(y)+1) <= maxLCSCells {
+		for _, m := range lcs(x, y) {
+			matches = append(matches, [2]int{m[0] + p, m[1] + p})
+		}
+	}
+	for k := s; k > 0; k-- {
+		matches = append(matches, [2]int{p + len(x) + s - k, p + len(y) + s - k})
+	}
+	return matches
+}
+
+// lcs returns the indices of the elements of the longest common
+// subsequence of x and y.
+func lcs(x, y []string) [][2]int {
+	// t[i*w+j] is the length of the LCS of x[i:] and y[j:].
+	w := len(y) + 1
+	t := make([]int32, (len(x)+1)*w)
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			switch {
+			case x[i] == y[j]:
+				t[i*w+j] = t[(i+1)*w+j+1] + 1
+			case t[(i+1)*w+j] >= t[i*w+j+1]:
+				t[i*w+j] = t[(i+1)*w+j]
+			default:
+				t[i*w+j] = t[i*w+j+1]
+			}
+		}
+	}
+	var matches [][2]int
+	for i, j := 0, 0; i < len(x) && j < len(y); {
+		switch {
+		case x[i] == y[j]:
+			matches = append(matches, [2]int{i, j})
+			i++
+			j++
+		case t[(i+1)*w+j] >= t[i*w+j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return matches
+}
+
+// diffPrec is the precision, in bits, used to compare numbers.
+const diffPrec = 256
+
+func (d *differ) scalarsEqual(kind Kind, a, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	if d.opts.PreserveOrder {
+		return false
+	}
+	switch kind {
+	case KindString:
+		sa, _ := unquoteBytes(a)
+		sb, _ := unquoteBytes(b)
+		return bytes.Equal(sa, sb)
+	case KindNumber:
+		// Use more precision than a float64, which would conflate
+		// large integers.
+		x, _, errA := big.ParseFloat(string(a), 10, diffPrec, big.ToNearestEven)
+		y, _, errB := big.ParseFloat(string(b), 10, diffPrec, big.ToNearestEven)
+		return errA == nil && errB == nil && x.Cmp(y) == 0
+	}
+	return false
+}
+
+// AppendPatch appends the JSON Patch (RFC 6902) that applies changes, as
+// returned by Diff, to dst. The patch is a compact JSON array with one
+// operation per change.
+func AppendPatch(dst []byte, changes []Change) []byte {
+	e := newEncodeState()
+	e.WriteByte('[')
+	for i, c := range changes {
+		if i > 0 {
+			e.WriteByte(',')
+		}
+		path := c.patchPath
+		if path == nil {
+			path = c.Path
+		}
+		e.WriteString(`{"op":`)
+		e.string(c.Op.String(), false)
+		e.WriteString(`,"path":`)
+		e.string(path.Pointer(), false)
+		if c.Op != DiffRemove {
+			e.WriteString(`,"value":`)
+			e.Write(c.New)
+		}
+		e.WriteByte('}')
+	}
+	e.WriteByte(']')
+	dst = append(dst, e.Bytes()...)
+	encodeStatePool.Put(e)
+	return dst
+}
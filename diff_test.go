@@ -0,0 +1,204 @@
+package pjson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	type change struct {
+		Op       DiffOp
+		Path     string
+		Old, New string
+	}
+	tests := []struct {
+		a, b          string
+		preserveOrder bool
+		want          []change
+	}{
+		{a: `1`, b: `1`},
+		{a: `{"a": 1, "b": [1, 2]}`, b: `{"b":[1,2],"a":1}`},
+		{a: `{"a": 1.0, "b": "\u0061"}`, b: `{"a": 1, "b": "a"}`},
+		{a: `9007199254740993`, b: `9007199254740992`, want: []change{
+			{DiffReplace, ".", "9007199254740993", "9007199254740992"},
+		}},
+		{a: `1`, b: `"1"`, want: []change{{DiffReplace, ".", `1`, `"1"`}}},
+		{a: `{"a": 1, "a": 2}`, b: `{"a": 2}`},
+		{
+			a: `{"a": 1, "b": {"c": true, "d": null}, "e": [1, 2, 3]}`,
+			b: `{"f": {}, "e": [1, 4], "b": {"d": null, "c": false}}`,
+			want: []change{
+				{DiffRemove, ".a", "1", ""},
+				{DiffReplace, ".b.c", "true", "false"},
+				{DiffReplace, ".e[1]", "2", "4"},
+				{DiffRemove, ".e[2]", "3", ""},
+				{DiffAdd, ".f", "", "{}"},
+			},
+		},
+		{a: `[1]`, b: `[1, {"a": [2]}]`, want: []change{{DiffAdd, ".[1]", "", `{"a":[2]}`}}},
+
+		// PreserveOrder
+		{a: `{"a": 1, "b": 2}`, b: `{"a": 1, "b": 2}`, preserveOrder: true},
+		{a: `{"a": 1, "b": 2}`, b: `{"b": 2, "a": 1}`, preserveOrder: true, want: []change{
+			{DiffReplace, ".", `{"a":1,"b":2}`, `{"b":2,"a":1}`},
+		}},
+		{a: `{"a": 1, "b": 2}`, b: `{"a": 1, "c": 3, "b": 2}`, preserveOrder: true, want: []change{
+			{DiffAdd, ".c", "", "3"},
+		}},
+		{a: `[1.0, "\u0061"]`, b: `[1, "a"]`, preserveOrder: true, want: []change{
+			{DiffReplace, ".[0]", "1.0", "1"},
+			{DiffReplace, ".[1]", `"\u0061"`, `"a"`},
+		}},
+	}
+	for _, test := range tests {
+		changes, err := Diff([]byte(test.a), []byte(test.b), &DiffOptions{PreserveOrder: test.preserveOrder})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []change
+		for _, c := range changes {
+			got = append(got, change{c.Op, c.Path.String(), string(c.Old), string(c.New)})
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Diff(%s, %s, %t):\ngot:  %v\nwant: %v", test.a, test.b, test.preserveOrder, got, test.want)
+		}
+	}
+
+	if _, err := Diff([]byte(`{`), []byte(`{}`), nil); err == nil {
+		t.Error("Diff: expected error for invalid JSON")
+	}
+}
+
+func TestAppendPatch(t *testing.T) {
+	tests := []struct {
+		a, b, want string
+	}{
+		{`1`, `1`, `[]`},
+		{`1`, `{"a":1}`, `[{"op":"replace","path":"","value":{"a":1}}]`},
+		{
+			`{"a": 1, "b": {"c": true}, "e": [1, 2, 3]}`,
+			`{"e": [1, 4], "b": {"c": false}, "f/~": "x"}`,
+			`[{"op":"remove","path":"/a"},` +
+				`{"op":"replace","path":"/b/c","value":false},` +
+				`{"op":"replace","path":"/e/1","value":4},` +
+				`{"op":"remove","path":"/e/2"},` +
+				`{"op":"add","path":"/f~1~0","value":"x"}]`,
+		},
+		{
+			`[1, 2, 3, 4]`,
+			`[0]`,
+			`[{"op":"replace","path":"/0","value":0},` +
+				`{"op":"remove","path":"/1"},{"op":"remove","path":"/1"},{"op":"remove","path":"/1"}]`,
+		},
+		{`[[1]]`, `[[1, 2], 3]`, `[{"op":"add","path":"/0/1","value":2},{"op":"add","path":"/1","value":3}]`},
+		{`{"\u0007": 1}`, `{}`, `[{"op":"remove","path":"/\u0007"}]`},
+	}
+	for _, test := range tests {
+		changes, err := Diff([]byte(test.a), []byte(test.b), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := AppendPatch(nil, changes)
+		if string(got) != test.want {
+			t.Errorf("AppendPatch(Diff(%s, %s)):\ngot:  %s\nwant: %s", test.a, test.b, got, test.want)
+		}
+		if !Valid(got) {
+			t.Errorf("AppendPatch(Diff(%s, %s)): invalid JSON: %s", test.a, test.b, got)
+		}
+	}
+}
+
+func TestDiffArrays(t *testing.T) {
+	tests := []struct {
+		a, b      string
+		arrayKey  string
+		want      []string // changes
+		wantPatch string
+	}{
+		{
+			a:         `[1, 2, 3]`,
+			b:         `[0, 1, 2, 3]`,
+			want:      []string{`add .[0]: 0`},
+			wantPatch: `[{"op":"add","path":"/0","value":0}]`,
+		},
+		{
+			a:         `[1, 2, 3, 4]`,
+			b:         `[1, 3, 4.0]`,
+			want:      []string{`remove .[1]: 2`},
+			wantPatch: `[{"op":"remove","path":"/1"}]`,
+		},
+		{
+			a:    `[{"a": 1}, "x", {"b": 2}, "y"]`,
+			b:    `["w", {"a": 1}, {"b": 3}, "y", "z"]`,
+			want: []string{`add .[0]: "w"`, `replace .[1]: "x" -> {"b":3}`, `remove .[2]: {"b":2}`, `add .[4]: "z"`},
+			wantPatch: `[{"op":"add","path":"/0","value":"w"},` +
+				`{"op":"replace","path":"/2","value":{"b":3}},` +
+				`{"op":"remove","path":"/3"},` +
+				`{"op":"add","path":"/4","value":"z"}]`,
+		},
+		{
+			a:        `[{"id": 1, "v": 1}, {"id": 2}, {"id": 3}]`,
+			b:        `[{"id": 0}, {"id": 1, "v": 2}, {"id": 3}]`,
+			arrayKey: "id",
+			want:     []string{`add .[0]: {"id":0}`, `replace .[0].v: 1 -> 2`, `remove .[1]: {"id":2}`},
+			wantPatch: `[{"op":"add","path":"/0","value":{"id":0}},` +
+				`{"op":"replace","path":"/1/v","value":2},` +
+				`{"op":"remove","path":"/2"}]`,
+		},
+		{
+			a:    `[{"id": 1, "v": 1}, {"id": 2}, {"id": 3}]`,
+			b:    `[{"id": 0}, {"id": 1, "v": 2}, {"id": 3}]`,
+			want: []string{`replace .[0].id: 1 -> 0`, `remove .[0].v: 1`, `replace .[1].id: 2 -> 1`, `add .[1].v: 2`},
+			wantPatch: `[{"op":"replace","path":"/0/id","value":0},` +
+				`{"op":"remove","path":"/0/v"},` +
+				`{"op":"replace","path":"/1/id","value":1},` +
+				`{"op":"add","path":"/1/v","value":2}]`,
+		},
+	}
+	for _, test := range tests {
+		changes, err := Diff([]byte(test.a), []byte(test.b), &DiffOptions{ArrayKey: test.arrayKey})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, c := range changes {
+			s := c.Op.String() + " " + c.Path.String() + ": "
+			switch c.Op {
+			case DiffAdd:
+				s += string(c.New)
+			case DiffRemove:
+				s += string(c.Old)
+			default:
+				s += string(c.Old) + " -> " + string(c.New)
+			}
+			got = append(got, s)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Diff(%s, %s, %q):\ngot:  %q\nwant: %q", test.a, test.b, test.arrayKey, got, test.want)
+		}
+		if patch := AppendPatch(nil, changes); string(patch) != test.wantPatch {
+			t.Errorf("AppendPatch(Diff(%s, %s, %q)):\ngot:  %s\nwant: %s", test.a, test.b, test.arrayKey, patch, test.wantPatch)
+		}
+	}
+}
+
+func TestAlignArrays(t *testing.T) {
+	split := func(s string) []string { return strings.Split(s, "") }
+	tests := []struct {
+		x, y string
+		want [][2]int
+	}{
+		{"", "", nil},
+		{"abc", "", nil},
+		{"abc", "abc", [][2]int{{0, 0}, {1, 1}, {2, 2}}},
+		{"abcd", "axcyd", [][2]int{{0, 0}, {2, 2}, {3, 4}}},
+		{"xaby", "abz", [][2]int{{1, 0}, {2, 1}}},
+	}
+	for _, test := range tests {
+		got := alignArrays(split(test.x), split(test.y))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("alignArrays(%q, %q) = %v; want: %v", test.x, test.y, got, test.want)
+		}
+	}
+}
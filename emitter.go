@@ -0,0 +1,148 @@
+package pjson
+
+import (
+	"io"
+
+	"github.com/charlievieth/pjson/termcolor"
+)
+
+// tokenClass is the role of a token in a JSON document.
+type tokenClass uint8
+
+const (
+	classNone     tokenClass = iota // top-level literal
+	classKey                        // object key
+	classString                     // string value
+	classNumber                     // number value
+	classTrue                       // true
+	classFalse                      // false
+	classNull                       // null
+	classPunct                      // {, }, [, ], ',' and ':'
+	classBlankKey                   // empty or white space object key
+
+	numTokenClasses
+)
+
+// literalClass returns the class of the literal beginning with c that was
+// scanned in parse state state.
+func literalClass(state ParseState, c byte) tokenClass {
+	switch state {
+	case ParseObjectKey:
+		return classKey
+	case ParseObjectValue, ParseArrayValue:
+		switch c {
+		case '"':
+			return classString
+		case 'n':
+			return classNull
+		case 't':
+			return classTrue
+		case 'f':
+			return classFalse
+		default:
+			return classNumber
+		}
+	}
+	return classNone
+}
+
+// color returns the color conf uses for tokens of class.
+func (conf *IndentConfig) color(class tokenClass) *termcolor.Color {
+	switch class {
+	case classKey:
+		return conf.Keyword
+	case classString:
+		return conf.String
+	case classNumber:
+		return conf.Numeric
+	case classTrue:
+		return conf.True
+	case classFalse:
+		return conf.False
+	case classNull:
+		return conf.Null
+	case classPunct:
+		return conf.Punctuation
+	case classBlankKey:
+		if conf.EmptyKey.IsZero() {
+			return conf.Keyword
+		}
+		return conf.EmptyKey
+	}
+	return nil
+}
+
+// keyClass returns classBlankKey if the key lit should be highlighted
+// with the EmptyKey color, otherwise it returns class.
+func (conf *IndentConfig) keyClass(class tokenClass, lit []byte) tokenClass {
+	if class == classKey && !conf.EmptyKey.IsZero() && isBlankKey(lit) {
+		return classBlankKey
+	}
+	return class
+}
+
+// isPlain reports whether conf does not add any color to its output.
+func (conf *IndentConfig) isPlain() bool {
+	if !termcolor.Enabled() {
+		return true
+	}
+	for class := classKey; class < numTokenClasses; class++ {
+		if !conf.color(class).IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// emitWriter is the destination of an emitter and is implemented by both
+// *bytes.Buffer and *bufio.Writer.
+type emitWriter interface {
+	io.Writer
+	io.ByteWriter
+	io.StringWriter
+}
+
+// An emitter writes the markup surrounding each token written by the
+// formatting loops.
+type emitter interface {
+	// begin is called before a token of class is written.
+	begin(w emitWriter, class tokenClass)
+	// end is called after a token of class is written.
+	end(w emitWriter, class tokenClass) error
+}
+
+// emitter returns the emitter used to format JSON with conf.
+func (conf *IndentConfig) emitter() emitter {
+	if conf.isPlain() {
+		return plainEmitter{}
+	}
+	return ansiEmitter{conf}
+}
+
+// plainEmitter writes tokens without any markup.
+type plainEmitter struct{}
+
+func (plainEmitter) begin(emitWriter, tokenClass)     {}
+func (plainEmitter) end(emitWriter, tokenClass) error { return nil }
+
+// ansiEmitter surrounds tokens with the ANSI escape sequences of the
+// colors of conf.
+type ansiEmitter struct {
+	conf *IndentConfig
+}
+
+func (e ansiEmitter) begin(w emitWriter, class tokenClass) {
+	w.WriteString(e.conf.color(class).Format())
+}
+
+func (e ansiEmitter) end(w emitWriter, class tokenClass) error {
+	_, err := w.WriteString(e.conf.color(class).Reset())
+	return err
+}
+
+// emitByte writes the token c of class to w.
+func emitByte(e emitter, w emitWriter, class tokenClass, c byte) {
+	e.begin(w, class)
+	w.WriteByte(c)
+	e.end(w, class)
+}
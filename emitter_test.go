@@ -0,0 +1,48 @@
+package pjson
+
+import (
+	"testing"
+
+	"github.com/charlievieth/pjson/termcolor"
+)
+
+func TestIndentConfigEmitter(t *testing.T) {
+	if _, ok := noColorIndentConfig.emitter().(plainEmitter); !ok {
+		t.Error("expected a plainEmitter for a config without colors")
+	}
+	if _, ok := DefaultIndentConfig.emitter().(ansiEmitter); !ok {
+		t.Error("expected an ansiEmitter for DefaultIndentConfig")
+	}
+	conf := IndentConfig{Punctuation: termcolor.Red}
+	if _, ok := conf.emitter().(ansiEmitter); !ok {
+		t.Error("expected an ansiEmitter for a config with one color")
+	}
+
+	termcolor.Disable()
+	defer termcolor.Enable()
+	if _, ok := DefaultIndentConfig.emitter().(plainEmitter); !ok {
+		t.Error("expected a plainEmitter when color is disabled")
+	}
+}
+
+func TestLiteralClass(t *testing.T) {
+	tests := []struct {
+		state ParseState
+		c     byte
+		want  tokenClass
+	}{
+		{-1, '"', classNone},
+		{ParseObjectKey, '"', classKey},
+		{ParseObjectValue, '"', classString},
+		{ParseArrayValue, '-', classNumber},
+		{ParseArrayValue, '1', classNumber},
+		{ParseObjectValue, 't', classTrue},
+		{ParseObjectValue, 'f', classFalse},
+		{ParseArrayValue, 'n', classNull},
+	}
+	for _, test := range tests {
+		if got := literalClass(test.state, test.c); got != test.want {
+			t.Errorf("literalClass(%d, %q) = %d; want: %d", test.state, test.c, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package pjson
+
+import "bytes"
+
+// ExpandStrings appends src to dst with each string value, not object key,
+// whose contents are a JSON object or array replaced by that object or
+// array, compacted. Strings in the expanded values are expanded too. This
+// makes JSON that was encoded as a string, as some loggers do, readable
+// when formatted. An error is returned if src is not valid JSON.
+func ExpandStrings(dst, src []byte) ([]byte, error) {
+	var h Hooks
+	var buf bytes.Buffer
+	h.OnScalar = func(_ Path, kind Kind, raw []byte) []byte {
+		if kind != KindString {
+			return raw
+		}
+		s, _ := unquoteBytes(raw)
+		s = bytes.TrimSpace(s)
+		if len(s) == 0 || s[0] != '{' && s[0] != '[' {
+			return raw
+		}
+		buf.Reset()
+		if compact(&buf, s, false) != nil {
+			return raw
+		}
+		expanded, err := h.apply(nil, buf.Bytes())
+		if err != nil {
+			return raw
+		}
+		return expanded
+	}
+	return h.apply(dst, src)
+}
@@ -0,0 +1,24 @@
+package pjson
+
+import "testing"
+
+func TestExpandStrings(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`{"msg": "{\"a\": [1, 2]}"}`, `{"msg": {"a":[1,2]}}`},
+		{`["[1, \"{\\\"b\\\": true}\"]"]`, `[[1,{"b":true}]]`},
+		{`{"n": "123", "s": "\"x\"", "e": "{invalid", "k": " [] "}`, `{"n": "123", "s": "\"x\"", "e": "{invalid", "k": []}`},
+		{`{"{\"key\": 1}": 2}`, `{"{\"key\": 1}": 2}`},
+	}
+	for _, test := range tests {
+		got, err := ExpandStrings(nil, []byte(test.in))
+		if err != nil {
+			t.Errorf("%s: %v", test.in, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("%s:\ngot:  %s\nwant: %s", test.in, got, test.want)
+		}
+	}
+}
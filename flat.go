@@ -0,0 +1,204 @@
+package pjson
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// flatNode is a node of the JSON document built by Unflatten. Object keys
+// are kept in the order they are first assigned.
+type flatNode struct {
+	kind   Kind
+	raw    []byte // compacted value of leaves
+	keys   []string
+	fields map[string]*flatNode
+	elems  []*flatNode
+}
+
+// child returns the child of n at path element e, creating it and
+// converting n to an object or array as needed.
+func (n *flatNode) child(e PathElem) (*flatNode, error) {
+	if n.raw != nil {
+		return nil, fmt.Errorf("cannot index %s value", n.kind)
+	}
+	if e.IsIndex() {
+		if n.kind != KindArray {
+			if n.kind != KindInvalid {
+				return nil, fmt.Errorf("cannot index %s with %d", n.kind, e.Index)
+			}
+			n.kind = KindArray
+		}
+		// Requiring elements in order bounds the memory used by a line.
+		if e.Index > len(n.elems) {
+			return nil, fmt.Errorf("index %d assigned before index %d", e.Index, len(n.elems))
+		}
+		if e.Index == len(n.elems) {
+			n.elems = append(n.elems, new(flatNode))
+		}
+		return n.elems[e.Index], nil
+	}
+	if n.kind != KindObject {
+		if n.kind != KindInvalid {
+			return nil, fmt.Errorf("cannot index %s with %q", n.kind, e.Key)
+		}
+		n.kind = KindObject
+	}
+	if n.fields == nil {
+		n.fields = make(map[string]*flatNode)
+	}
+	c := n.fields[e.Key]
+	if c == nil {
+		c = new(flatNode)
+		n.fields[e.Key] = c
+		n.keys = append(n.keys, e.Key)
+	}
+	return c, nil
+}
+
+// set assigns the JSON value raw to n. Empty objects and arrays only set
+// the kind of n so that they may be assigned before their children. Leaf
+// values may be reassigned, the last assignment wins.
+func (n *flatNode) set(raw []byte) error {
+	var buf bytes.Buffer
+	if err := Compact(&buf, raw); err != nil {
+		return err
+	}
+	value := buf.Bytes()
+	kind := kindOf(value[0])
+	if len(value) == 2 && (kind == KindObject || kind == KindArray) {
+		if n.raw != nil || (n.kind != KindInvalid && n.kind != kind) {
+			return fmt.Errorf("cannot assign %s to %s", kind, n.kind)
+		}
+		n.kind = kind
+		return nil
+	}
+	if n.kind != KindInvalid && n.raw == nil {
+		return fmt.Errorf("cannot assign %s to %s", kind, n.kind)
+	}
+	n.kind = kind
+	n.raw = value
+	return nil
+}
+
+func (n *flatNode) encode(e *encodeState) {
+	switch {
+	case n == nil || n.kind == KindInvalid:
+		e.WriteString("null")
+	case n.raw != nil:
+		e.Write(n.raw)
+	case n.kind == KindObject:
+		e.WriteByte('{')
+		for i, k := range n.keys {
+			if i > 0 {
+				e.WriteByte(',')
+			}
+			e.string(k, false)
+			e.WriteByte(':')
+			n.fields[k].encode(e)
+		}
+		e.WriteByte('}')
+	case n.kind == KindArray:
+		e.WriteByte('[')
+		for i, c := range n.elems {
+			if i > 0 {
+				e.WriteByte(',')
+			}
+			c.encode(e)
+		}
+		e.WriteByte(']')
+	}
+}
+
+// Unflatten reconstructs a JSON document from lines of `path = value`
+// assignments, the format written by the CLI's --paths --path-values mode
+// and by gron. Paths are parsed with ParsePath, or as JSON Pointers if
+// they are empty or begin with '/'. A numeric pointer token indexes an
+// array unless the value it indexes is already an object. Blank lines are
+// ignored and a trailing ';' is allowed after the value. The elements of
+// an array must be assigned in order, as they are written by --paths and
+// gron, though they may be assigned again. The returned JSON is compact.
+func Unflatten(data []byte) ([]byte, error) {
+	root := new(flatNode)
+	for lineno := 1; len(data) > 0; lineno++ {
+		var line []byte
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			line, data = data, nil
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if err := root.assign(line); err != nil {
+			return nil, fmt.Errorf("pjson: line %d: %w", lineno, err)
+		}
+	}
+	if root.kind == KindInvalid {
+		return nil, fmt.Errorf("pjson: no assignments found")
+	}
+	e := newEncodeState()
+	root.encode(e)
+	buf := append([]byte(nil), e.Bytes()...)
+	encodeStatePool.Put(e)
+	return buf, nil
+}
+
+func (n *flatNode) assign(line []byte) error {
+	var path, value []byte
+	if i := bytes.Index(line, []byte(" = ")); i >= 0 {
+		path, value = line[:i], line[i+len(" = "):]
+	} else if bytes.HasPrefix(line, []byte("= ")) {
+		value = line[len("= "):] // the root JSON Pointer, trimmed
+	} else {
+		return fmt.Errorf("invalid assignment: %q", line)
+	}
+	value = bytes.TrimSuffix(value, []byte{';'})
+	if !Valid(value) {
+		return fmt.Errorf("invalid JSON value: %s", strconv.Quote(string(value)))
+	}
+	if len(path) == 0 || path[0] == '/' {
+		tokens, err := ParsePointer(string(path))
+		if err != nil {
+			return err
+		}
+		for _, tok := range tokens {
+			e := PathElem{Key: tok, Index: -1}
+			if n.kind != KindObject {
+				if i, ok := pointerIndex(tok); ok {
+					e = PathElem{Index: i}
+				}
+			}
+			if n, err = n.child(e); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		return n.set(value)
+	}
+	p, err := ParsePath(string(path))
+	if err != nil {
+		return err
+	}
+	for _, e := range p {
+		if n, err = n.child(e); err != nil {
+			return fmt.Errorf("%s: %w", p.String(), err)
+		}
+	}
+	return n.set(value)
+}
+
+// pointerIndex returns the array index of the JSON Pointer reference
+// token tok, which must be "0" or a number without leading zeros.
+func pointerIndex(tok string) (int, bool) {
+	if tok == "" || len(tok) > 1 && tok[0] == '0' {
+		return 0, false
+	}
+	for i := 0; i < len(tok); i++ {
+		if tok[i] < '0' || tok[i] > '9' {
+			return 0, false
+		}
+	}
+	i, err := strconv.Atoi(tok)
+	return i, err == nil
+}
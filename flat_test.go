@@ -0,0 +1,78 @@
+package pjson
+
+import (
+	"testing"
+)
+
+func TestUnflatten(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{". = 1", `1`},
+		{".a = 1\n.b = \"x\"", `{"a":1,"b":"x"}`},
+		{".a[0] = null\n.a[1] = true\n.a[0] = 1", `{"a":[1,true]}`},
+		{"= 1", `1`},
+		{"/a/0 = 1\n/a/1/x y = 2\n/a/1/0 = 3\n/b~1c = {}\n/ = null", `{"a":[1,{"x y":2,"0":3}],"b/c":{},"":null}`},
+		{"/a = {}\n/a/0 = 1", `{"a":{"0":1}}`},
+		{"/01 = 1", `{"01":1}`},
+		{"json = {};\njson.a = [];\njson.a[0] = {};\njson.a[0].b = 1;\n", `{"a":[{"b":1}]}`},
+		{".e = {}\n.f = []\n\n", `{"e":{},"f":[]}`},
+		{`.x["a b"] = { "y" : 1 }`, `{"x":{"a b":{"y":1}}}`},
+		{".b = 1\n.a = 2\n.b = 3", `{"b":3,"a":2}`},
+		{`["<&>"] = "<&>"`, `{"<&>":"<&>"}`},
+	}
+	for _, test := range tests {
+		got, err := Unflatten([]byte(test.in))
+		if err != nil {
+			t.Errorf("Unflatten(%q): %v", test.in, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("Unflatten(%q) = %s; want: %s", test.in, got, test.want)
+		}
+	}
+
+	for _, in := range []string{
+		"",
+		".a",
+		".a = ",
+		".a = {",
+		"a = 1",
+		".a = 1\n.a.b = 2",
+		".a = 1\n.a[0] = 2",
+		".a = {}\n.a[0] = 2",
+		".a.b = 1\n.a = 2",
+		".a = []\n.a = {}",
+		".a[1] = true\n.a[0] = null",
+		".a[2] = 1",
+		".a[999999999] = 1",
+		"/a/1 = 1",
+		"/a~2 = 1",
+	} {
+		if _, err := Unflatten([]byte(in)); err == nil {
+			t.Errorf("Unflatten(%q): expected error", in)
+		}
+	}
+}
+
+func TestUnflattenRoundTrip(t *testing.T) {
+	const data = `{"a":[1,{"b c":"x","d":[]}],"e":{},"n":null,"s":"\u0000"}`
+	var flat []byte
+	err := Walk([]byte(data), func(path Path, kind Kind, value []byte) bool {
+		if (kind == KindObject || kind == KindArray) && len(value) > 2 {
+			return true
+		}
+		flat = append(flat, path.String()+" = "+string(value)+"\n"...)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Unflatten(flat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != data {
+		t.Errorf("Unflatten:\n%s\nwant:\n%s", got, data)
+	}
+}
@@ -3,14 +3,19 @@ module github.com/charlievieth/pjson
 go 1.18
 
 require (
+	github.com/klauspost/compress v1.15.12
 	github.com/spf13/cobra v1.6.0
+	golang.org/x/sys v0.1.0
 	golang.org/x/term v0.1.0
+	golang.org/x/text v0.4.0
 	golang.org/x/tools v0.2.0
 )
 
 require (
+	github.com/cpuguy83/go-md2man/v2 v2.0.2 // indirect
 	github.com/inconshreveable/mousetrap v1.0.1 // indirect
+	github.com/russross/blackfriday/v2 v2.1.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/mod v0.6.0 // indirect
-	golang.org/x/sys v0.1.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,78 @@
+package pjson
+
+import (
+	"regexp"
+
+	"github.com/charlievieth/pjson/termcolor"
+)
+
+// isWordByte reports whether c is part of a number, true, false or null.
+func isWordByte(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' ||
+		c == '-' || c == '+' || c == '.'
+}
+
+// Highlight appends the formatted JSON src, which may be colored, to dst
+// with the substrings of its keys and values that match re surrounded by
+// clr. The contents of strings are matched without their quotes. The
+// color of the token is restored after each match. Matches do not span
+// tokens, so src may be highlighted one line at a time.
+func Highlight(dst, src []byte, re *regexp.Regexp, clr *termcolor.Color) []byte {
+	var active []byte // last SGR sequence, restored after matches
+	for i := 0; i < len(src); {
+		c := src[i]
+		if n := termcolor.SGRLen(src[i:]); n > 0 {
+			active = src[i : i+n]
+			dst = append(dst, active...)
+			i += n
+			continue
+		}
+		var start, end int // token contents
+		quoted := false    // the token is followed by a closing quote
+		switch {
+		case c == '"':
+			start = i + 1
+			end = start
+			for end < len(src) && src[end] != '"' && src[end] != '\n' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end > len(src) {
+				end = len(src)
+			}
+			quoted = end < len(src) && src[end] == '"'
+		case isWordByte(c):
+			start = i
+			end = i + 1
+			for end < len(src) && isWordByte(src[end]) {
+				end++
+			}
+		default:
+			dst = append(dst, c)
+			i++
+			continue
+		}
+		dst = append(dst, src[i:start]...)
+		last := start
+		for _, m := range re.FindAllIndex(src[start:end], -1) {
+			if m[0] == m[1] {
+				continue // empty match
+			}
+			dst = append(dst, src[last:start+m[0]]...)
+			dst = clr.Append(dst)
+			dst = append(dst, src[start+m[0]:start+m[1]]...)
+			dst = append(dst, termcolor.Reset...)
+			dst = append(dst, active...)
+			last = start + m[1]
+		}
+		dst = append(dst, src[last:end]...)
+		i = end
+		if quoted {
+			dst = append(dst, '"')
+			i++
+		}
+	}
+	return dst
+}
@@ -0,0 +1,33 @@
+package pjson
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/charlievieth/pjson/termcolor"
+)
+
+func TestHighlight(t *testing.T) {
+	const hl = "\x1b[7m"
+	const reset = termcolor.Reset
+	clr := termcolor.NewColor(termcolor.ReverseVideo)
+	tests := []struct {
+		expr, in, want string
+	}{
+		{`err`, `{"error": "an error"}`, `{"` + hl + `err` + reset + `or": "an ` + hl + `err` + reset + `or"}`},
+		{`^a`, `["abc", "bac", true]`, `["` + hl + `a` + reset + `bc", "bac", true]`},
+		{`\d+`, `{"a1": 123, "b": -4.5}`, `{"a` + hl + `1` + reset + `": ` + hl + `123` + reset + `, "b": -` + hl + `4` + reset + `.` + hl + `5` + reset + `}`},
+		{`true|null`, `[true, "null", false]`, `[` + hl + `true` + reset + `, "` + hl + `null` + reset + `", false]`},
+		{`:`, `{"a": "b:c"}`, `{"a": "b` + hl + `:` + reset + `c"}`},
+		{`x*`, `["a"]`, `["a"]`},
+		{`\\"`, `["a\"b"]`, `["a` + hl + `\"` + reset + `b"]`},
+		// Colored input: the color of the token is restored.
+		{`b`, "\x1b[32m\"abc\"\x1b[0m", "\x1b[32m\"a" + hl + "b" + reset + "\x1b[32mc\"\x1b[0m"},
+	}
+	for _, test := range tests {
+		got := string(Highlight(nil, []byte(test.in), regexp.MustCompile(test.expr), clr))
+		if got != test.want {
+			t.Errorf("%s: %q:\ngot:  %q\nwant: %q", test.expr, test.in, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package pjson
+
+// Hooks are called by a Stream to transform object keys and scalar values
+// before they are formatted. This allows for transforms such as unit
+// conversion or localization without reimplementing the formatting loops.
+//
+// The raw bytes passed to a hook are the JSON encoding of the key or value
+// (strings include their quotes) and the returned bytes replace them in the
+// output, so they must also be valid JSON. The path and raw bytes are only
+// valid for the duration of the call.
+type Hooks struct {
+	// OnKey is called for each object key. The path is the path of the
+	// value the key names.
+	OnKey func(path Path, raw []byte) []byte
+
+	// OnScalar is called for each string, number, boolean and null value.
+	OnScalar func(path Path, kind Kind, raw []byte) []byte
+}
+
+func (h *Hooks) enabled() bool {
+	return h.OnKey != nil || h.OnScalar != nil
+}
+
+// apply appends src to dst with the keys and scalars of src replaced by
+// the output of h. An error is returned if src is not valid JSON.
+func (h *Hooks) apply(dst, src []byte) ([]byte, error) {
+	last := 0 // end of the src already copied to dst
+	err := visit(src, false, func(ev visitEvent, path Path, start, end int) bool {
+		if ev == visitKey || ev == visitLiteral {
+			dst = append(dst, src[last:start]...)
+			dst = append(dst, h.token(ev, path, src[start:end])...)
+			last = end
+		}
+		return true
+	})
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, src[last:]...), nil
+}
+
+// token returns the replacement of the key or literal raw.
+func (h *Hooks) token(ev visitEvent, path Path, raw []byte) []byte {
+	switch {
+	case ev == visitKey:
+		if h.OnKey != nil {
+			return h.OnKey(path, raw)
+		}
+	case h.OnScalar != nil:
+		return h.OnScalar(path, kindOf(raw[0]), raw)
+	}
+	return raw
+}
@@ -39,6 +39,7 @@ func compact(dst *bytes.Buffer, src []byte, escape bool) error {
 			dst.WriteByte(hex[src[i+2]&0xF])
 			start = i + 3
 		}
+		scan.bytes++
 		v := scan.step(scan, c)
 		if v >= ScanSkipSpace {
 			if v == ScanError {
@@ -196,3 +197,84 @@ func Indent(dst *bytes.Buffer, src []byte, prefix, indent string) error {
 	}
 	return nil
 }
+
+// indentPlain is the no-color fast path of IndentConfig.Indent. Unlike
+// Indent, space between values is always dropped.
+func indentPlain(dst *bytes.Buffer, src []byte, prefix, indent string) error {
+	origLen := dst.Len()
+	scan := newScanner()
+	defer freeScanner(scan)
+
+	allSpaces := isAllSpaces(indent)
+	needIndent := false
+	depth := 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		v := scan.Step(c)
+		if v == ScanSkipSpace {
+			i += scan.skipSpace(src[i+1:])
+			continue
+		}
+		if v == ScanError {
+			break
+		}
+		if needIndent && v != ScanEndObject && v != ScanEndArray {
+			needIndent = false
+			depth++
+			newline(dst, prefix, indent, depth, allSpaces)
+		}
+		if v == ScanBeginLiteral {
+			j := i
+			for i++; i < len(src); i++ {
+				c = src[i]
+				v = scan.Step(c)
+				if v != ScanContinue {
+					break
+				}
+			}
+			dst.Write(src[j:i])
+			// A top-level literal ends at the end of src.
+			if v == ScanSkipSpace {
+				i += scan.skipSpace(src[i+1:])
+				continue
+			}
+			if i == len(src) {
+				continue
+			}
+		}
+
+		// Add spacing around real punctuation.
+		switch c {
+		case '{', '[':
+			// delay indent so that empty object and array are formatted as {} and [].
+			needIndent = true
+			dst.WriteByte(c)
+
+		case ',':
+			dst.WriteByte(c)
+			newline(dst, prefix, indent, depth, allSpaces)
+
+		case ':':
+			dst.WriteByte(c)
+			dst.WriteByte(' ')
+
+		case '}', ']':
+			if needIndent {
+				// suppress indent in empty object/array
+				needIndent = false
+			} else {
+				depth--
+				newline(dst, prefix, indent, depth, allSpaces)
+			}
+			dst.WriteByte(c)
+
+		default:
+			dst.WriteByte(c)
+		}
+	}
+	if scan.EOF() == ScanError {
+		dst.Truncate(origLen)
+		return scan.Err()
+	}
+	return nil
+}
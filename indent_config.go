@@ -8,6 +8,7 @@ import (
 	"io"
 	"math"
 	"sync"
+	"unicode/utf8"
 
 	"github.com/charlievieth/pjson/termcolor"
 )
@@ -22,6 +23,9 @@ type IndentConfig struct {
 	String      *termcolor.Color
 	Numeric     *termcolor.Color
 	Punctuation *termcolor.Color
+	// EmptyKey is used by Indent, Compact and Stream to highlight object
+	// keys that are empty or only white space. If nil, Keyword is used.
+	EmptyKey *termcolor.Color
 	// TODO: remove this
 	// ConvertUnicode bool            // print escaped unicode
 }
@@ -60,6 +64,7 @@ var DefaultIndentConfig = IndentConfig{
 	String:      termcolor.Green,
 	Numeric:     termcolor.Magenta,
 	Punctuation: termcolor.Yellow,
+	EmptyKey:    termcolor.NewColor(termcolor.ReverseVideo, termcolor.FgBlue),
 }
 
 // JQIndentConfig matches the default color scheme of `jq`
@@ -75,19 +80,72 @@ var JQIndentConfig = IndentConfig{
 	Punctuation: termcolor.White,
 }
 
-func NewIndentConfig() *IndentConfig {
-	return nil
+// noColorIndentConfig is used when no IndentConfig is provided.
+var noColorIndentConfig IndentConfig
+
+// A ThemeOption modifies the colors of an IndentConfig.
+type ThemeOption func(conf *IndentConfig)
+
+// NewIndentConfig returns a copy of base with opts applied. If base is nil
+// the returned config starts without any colors.
+//
+//	conf := NewIndentConfig(&DefaultIndentConfig, WithString(termcolor.Cyan), WithBoldKeys())
+func NewIndentConfig(base *IndentConfig, opts ...ThemeOption) *IndentConfig {
+	conf := new(IndentConfig)
+	if base != nil {
+		*conf = *base
+	}
+	for _, opt := range opts {
+		opt(conf)
+	}
+	return conf
 }
 
-type byteStringWriter interface {
-	io.ByteWriter
-	io.StringWriter
+// WithNull sets the color of null.
+func WithNull(c *termcolor.Color) ThemeOption {
+	return func(conf *IndentConfig) { conf.Null = c }
 }
 
-func writeByte(dst byteStringWriter, color *termcolor.Color, ch byte) {
-	dst.WriteString(color.Format())
-	dst.WriteByte(ch)
-	dst.WriteString(color.Reset())
+// WithFalse sets the color of false.
+func WithFalse(c *termcolor.Color) ThemeOption {
+	return func(conf *IndentConfig) { conf.False = c }
+}
+
+// WithTrue sets the color of true.
+func WithTrue(c *termcolor.Color) ThemeOption {
+	return func(conf *IndentConfig) { conf.True = c }
+}
+
+// WithBool sets the color of both true and false.
+func WithBool(c *termcolor.Color) ThemeOption {
+	return func(conf *IndentConfig) { conf.True, conf.False = c, c }
+}
+
+// WithKeyword sets the color of object keys.
+func WithKeyword(c *termcolor.Color) ThemeOption {
+	return func(conf *IndentConfig) { conf.Keyword = c }
+}
+
+// WithString sets the color of strings.
+func WithString(c *termcolor.Color) ThemeOption {
+	return func(conf *IndentConfig) { conf.String = c }
+}
+
+// WithNumeric sets the color of numbers.
+func WithNumeric(c *termcolor.Color) ThemeOption {
+	return func(conf *IndentConfig) { conf.Numeric = c }
+}
+
+// WithPunctuation sets the color of braces, brackets, commas and colons.
+func WithPunctuation(c *termcolor.Color) ThemeOption {
+	return func(conf *IndentConfig) { conf.Punctuation = c }
+}
+
+// WithBoldKeys makes object keys bold while keeping their color.
+func WithBoldKeys() ThemeOption {
+	return func(conf *IndentConfig) {
+		conf.Keyword = termcolor.Combine(conf.Keyword, termcolor.NewColor(termcolor.Bold))
+	}
 }
 
 // func writeByteBufio(dst *bufio.Writer, color *termcolor.Color, ch byte) {
@@ -137,11 +195,18 @@ func isAllSpaces(indent string) bool {
 //
 // Disallow multiple JSON on the same line: `{}{}`
 //
+// If the input is invalid the output of the values before the error is
+// complete and the output of the invalid value stops before the byte
+// that caused the error. Every color written is reset, so the output
+// never leaves a terminal colored. Use a Stream with SetAtomic to write
+// nothing unless all of the input is valid.
+//
 // WARN WARN WARN
 func (conf *IndentConfig) IndentStream(wr io.Writer, rd io.Reader, prefix, indent string) error {
 	dst, r := newBuffers(wr, rd)
 	scan := newScanner()
 	defer freeBufioScanner(dst, r, scan)
+	emit := conf.emitter()
 
 	allSpaces := isAllSpaces(indent)
 	needIndent := false
@@ -156,6 +221,7 @@ func (conf *IndentConfig) IndentStream(wr io.Writer, rd io.Reader, prefix, inden
 		}
 		v := scan.Step(c)
 		if v == ScanSkipSpace {
+			scan.skipSpaceReader(r)
 			continue
 		}
 		if v == ScanError {
@@ -176,36 +242,13 @@ func (conf *IndentConfig) IndentStream(wr io.Writer, rd io.Reader, prefix, inden
 			depth++
 			newlineBufio(dst, prefix, indent, depth, allSpaces)
 		}
-		var clr *termcolor.Color
 		if v == ScanBeginLiteral {
-			switch scan.CurrentParseState() {
-			case ParseObjectKey:
-				// TODO: do we want to use different quote colors here?
-				clr = conf.Keyword
-			case ParseObjectValue, ParseArrayValue:
-				// TODO: use Quote color
-				switch c {
-				case '"':
-					clr = conf.String
-				case 'n':
-					clr = conf.Null
-				case 't':
-					clr = conf.True
-				case 'f':
-					clr = conf.False
-				default:
-					clr = conf.Numeric
-				}
-
-				// TODO: error here ???
-				// default:
-				// 	err = errors.New("pjson: invalid parse state")
-				// 	break
-			}
+			// TODO: use Quote color
+			class := literalClass(scan.CurrentParseState(), c)
 
 			// Instead of reading/writing byte-by-byte use the
 			// bytes the Reader already has buffered.
-			dst.WriteString(clr.Format())
+			emit.begin(dst, class)
 			dst.WriteByte(c)
 		InnerLoop:
 			for {
@@ -232,15 +275,21 @@ func (conf *IndentConfig) IndentStream(wr io.Writer, rd io.Reader, prefix, inden
 			}
 			// Check error from InnerLoop
 			if err != nil && err != bufio.ErrBufferFull {
+				// Close the color of the partial literal.
+				emit.end(dst, class)
 				break
 			}
 			// NOTE: we check some, but not all write errors since
 			// once the bufio.Writer encounters an error it will
 			// always return it.
-			if _, err = dst.WriteString(clr.Reset()); err != nil {
+			if err = emit.end(dst, class); err != nil {
+				break
+			}
+			if v == ScanError {
 				break
 			}
 			if v == ScanSkipSpace {
+				scan.skipSpaceReader(r)
 				continue
 			}
 		}
@@ -250,14 +299,14 @@ func (conf *IndentConfig) IndentStream(wr io.Writer, rd io.Reader, prefix, inden
 		case '{', '[':
 			// delay indent so that empty object and array are formatted as {} and [].
 			needIndent = true
-			writeByte(dst, conf.Punctuation, c)
+			emitByte(emit, dst, classPunct, c)
 
 		case ',':
-			writeByte(dst, conf.Punctuation, c)
+			emitByte(emit, dst, classPunct, c)
 			newlineBufio(dst, prefix, indent, depth, allSpaces)
 
 		case ':':
-			writeByte(dst, conf.Punctuation, c)
+			emitByte(emit, dst, classPunct, c)
 			dst.WriteByte(' ')
 
 		case '}', ']':
@@ -268,7 +317,7 @@ func (conf *IndentConfig) IndentStream(wr io.Writer, rd io.Reader, prefix, inden
 				depth--
 				newlineBufio(dst, prefix, indent, depth, allSpaces)
 			}
-			writeByte(dst, conf.Punctuation, c)
+			emitByte(emit, dst, classPunct, c)
 
 		default:
 			dst.WriteByte(c)
@@ -299,14 +348,40 @@ func (conf *IndentConfig) IndentStream(wr io.Writer, rd io.Reader, prefix, inden
 	return nil
 }
 
+// Indent appends to dst the indented and colorized form of the JSON value
+// src. If conf has no colors a dedicated plain path is used.
 func (conf *IndentConfig) Indent(dst *bytes.Buffer, src []byte, prefix, indent string) error {
+	if conf.isPlain() {
+		return indentPlain(dst, src, prefix, indent)
+	}
+	return conf.indent(dst, src, prefix, indent, false, -1)
+}
+
+// IndentAnnotated is like Indent but follows the closing bracket of each
+// non-empty object and array with a dim comment of the number of its
+// members or elements and its size in src, for example:
+//
+//	} // 42 keys, 18.3 KB
+//
+// The output is not valid JSON.
+func (conf *IndentConfig) IndentAnnotated(dst *bytes.Buffer, src []byte, prefix, indent string) error {
+	return conf.indent(dst, src, prefix, indent, true, -1)
+}
+
+// indent formats src like Indent, annotating it if annotate is true, with
+// the contents of objects and arrays nested more than elide levels deep
+// elided. No contents are elided if elide is negative.
+func (conf *IndentConfig) indent(dst *bytes.Buffer, src []byte, prefix, indent string, annotate bool, elide int) error {
 	origLen := dst.Len()
 	scan := newScanner()
 	defer freeScanner(scan)
+	emit := conf.emitter()
 
 	allSpaces := isAllSpaces(indent)
 	needIndent := false
 	depth := 0
+	var subtrees []subtree // open objects and arrays when annotating
+	var note annotation    // annotation written before the next newline
 	for i := 0; i < len(src); i++ {
 		c := src[i]
 		v := scan.Step(c)
@@ -316,6 +391,7 @@ func (conf *IndentConfig) Indent(dst *bytes.Buffer, src []byte, prefix, indent s
 			fmt.Printf("    %s\n", scan.parseState)
 		}
 		if v == ScanSkipSpace {
+			i += scan.skipSpace(src[i+1:])
 			continue
 		}
 		if v == ScanError {
@@ -326,35 +402,14 @@ func (conf *IndentConfig) Indent(dst *bytes.Buffer, src []byte, prefix, indent s
 			depth++
 			newline(dst, prefix, indent, depth, allSpaces)
 		}
-		var clr *termcolor.Color
 		// var quote *termcolor.Color
 		if v == ScanBeginLiteral {
-			switch scan.CurrentParseState() {
-			case ParseObjectKey:
-				clr = conf.Keyword
-				// WARN: quote handling
-				// if !conf.Quote.IsZero() && !clr.Equal(conf.Quote) {
-				// 	quote = conf.Quote
-				// }
-			case ParseObjectValue, ParseArrayValue:
-				// TODO: use Quote color
-				switch c {
-				case '"':
-					clr = conf.String
-					// WARN: quote handling
-					// if !conf.Quote.IsZero() && !clr.Equal(conf.Quote) {
-					// 	quote = conf.Quote
-					// }
-				case 'n':
-					clr = conf.Null
-				case 't':
-					clr = conf.True
-				case 'f':
-					clr = conf.False
-				default:
-					clr = conf.Numeric
-				}
-			}
+			// TODO: use Quote color
+			// WARN: quote handling
+			// if !conf.Quote.IsZero() && !clr.Equal(conf.Quote) {
+			// 	quote = conf.Quote
+			// }
+			class := literalClass(scan.CurrentParseState(), c)
 			// if quote != nil {
 			// 	writeByte(dst, quote, c)
 			// 	i++
@@ -372,9 +427,10 @@ func (conf *IndentConfig) Indent(dst *bytes.Buffer, src []byte, prefix, indent s
 					break
 				}
 			}
-			dst.WriteString(clr.Format())
+			class = conf.keyClass(class, src[j:i])
+			emit.begin(dst, class)
 			dst.Write(src[j:i])
-			dst.WriteString(clr.Reset())
+			emit.end(dst, class)
 			// WARN: quote handling
 			// if quote != nil {
 			// 	dst.Write(src[j : i-1])
@@ -384,7 +440,12 @@ func (conf *IndentConfig) Indent(dst *bytes.Buffer, src []byte, prefix, indent s
 			// 	dst.Write(src[j:i])
 			// 	dst.WriteString(clr.Reset())
 			// }
+			// A top-level literal ends at the end of src.
 			if v == ScanSkipSpace {
+				i += scan.skipSpace(src[i+1:])
+				continue
+			}
+			if i == len(src) {
 				continue
 			}
 		}
@@ -392,27 +453,59 @@ func (conf *IndentConfig) Indent(dst *bytes.Buffer, src []byte, prefix, indent s
 		// Add spacing around real punctuation.
 		switch c {
 		case '{', '[':
+			if elide >= 0 && len(scan.parseState) > elide {
+				end, n, ok := skipContainer(scan, src, i)
+				if !ok {
+					i = len(src) // the scanner has the error
+					break
+				}
+				writeElided(emit, dst, src, i, end, n)
+				if annotate && n > 0 {
+					note = annotation{object: c == '{', n: n, size: end + 1 - i}
+				}
+				i = end
+				break
+			}
 			// delay indent so that empty object and array are formatted as {} and [].
 			needIndent = true
-			writeByte(dst, conf.Punctuation, c)
+			emitByte(emit, dst, classPunct, c)
+			if annotate {
+				subtrees = append(subtrees, subtree{start: i})
+			}
 
 		case ',':
-			writeByte(dst, conf.Punctuation, c)
+			emitByte(emit, dst, classPunct, c)
+			if annotate {
+				subtrees[len(subtrees)-1].commas++
+				note = conf.writeAnnotation(dst, note)
+			}
 			newline(dst, prefix, indent, depth, allSpaces)
 
 		case ':':
-			writeByte(dst, conf.Punctuation, c)
+			emitByte(emit, dst, classPunct, c)
 			dst.WriteByte(' ')
 
 		case '}', ']':
+			empty := needIndent
 			if needIndent {
 				// suppress indent in empty object/array
 				needIndent = false
 			} else {
 				depth--
+				if annotate {
+					note = conf.writeAnnotation(dst, note)
+				}
 				newline(dst, prefix, indent, depth, allSpaces)
 			}
-			writeByte(dst, conf.Punctuation, c)
+			emitByte(emit, dst, classPunct, c)
+			if annotate {
+				t := subtrees[len(subtrees)-1]
+				subtrees = subtrees[:len(subtrees)-1]
+				if !empty {
+					// Written after the comma that may follow c.
+					note = annotation{object: c == '}', n: t.commas + 1, size: i + 1 - t.start}
+				}
+			}
 
 		default:
 			dst.WriteByte(c)
@@ -422,14 +515,38 @@ func (conf *IndentConfig) Indent(dst *bytes.Buffer, src []byte, prefix, indent s
 		dst.Truncate(origLen)
 		return scan.Err()
 	}
+	if annotate {
+		conf.writeAnnotation(dst, note)
+	}
 	return nil
 }
 
+// Separators that may be written between the values of a multi-value
+// compact stream.
+const (
+	SeparatorNewline = "\n"
+	SeparatorRS      = "\x1e" // ASCII record separator (RFC 7464)
+	SeparatorNUL     = "\x00"
+)
+
+// CompactStream writes the compact form of each JSON value read from rd
+// to wr. Each value is written on its own line.
 func (conf *IndentConfig) CompactStream(wr io.Writer, rd io.Reader) error {
+	return conf.CompactStreamSeparator(wr, rd, SeparatorNewline)
+}
+
+// CompactStreamSeparator is like CompactStream, but writes sep between
+// top-level values instead of a newline. The separator is never written
+// before the first value or after the last value.
+//
+// Errors leave the output in the same state as IndentStream.
+func (conf *IndentConfig) CompactStreamSeparator(wr io.Writer, rd io.Reader, sep string) error {
 	dst, r := newBuffers(wr, rd)
 	scan := newScanner()
 	defer freeBufioScanner(dst, r, scan)
+	emit := conf.emitter()
 
+	needSep := false // previous value is complete and a new one has not started
 	var err error
 	for {
 		var c byte
@@ -443,36 +560,32 @@ func (conf *IndentConfig) CompactStream(wr io.Writer, rd io.Reader) error {
 			fmt.Printf("'%c' %s\n", c, ScanStateString(v))
 			fmt.Printf("    %s\n", scan.parseState)
 		}
+		if v == ScanEnd {
+			// The top-level value ended before c: reset the scanner
+			// and re-read c as the start of the next value.
+			scan.Reset()
+			scan.bytes--
+			r.UnreadByte()
+			needSep = true
+			continue
+		}
 		if v == ScanSkipSpace {
+			scan.skipSpaceReader(r)
 			continue
 		}
 		if v == ScanError {
 			break
 		}
+		if needSep {
+			dst.WriteString(sep)
+			needSep = false
+		}
 		if v == ScanBeginLiteral {
-			var clr *termcolor.Color
-			switch scan.CurrentParseState() {
-			case ParseObjectKey:
-				// TODO: do we want to use different quote colors here?
-				clr = conf.Keyword
-			case ParseObjectValue, ParseArrayValue:
-				// TODO: use Quote color
-				switch c {
-				case '"':
-					clr = conf.String
-				case 'n':
-					clr = conf.Null
-				case 't':
-					clr = conf.True
-				case 'f':
-					clr = conf.False
-				default:
-					clr = conf.Numeric
-				}
-			}
+			// TODO: use Quote color
+			class := literalClass(scan.CurrentParseState(), c)
 			// Instead of reading/writing byte-by-byte use the
 			// bytes the Reader already has buffered.
-			dst.WriteString(clr.Format())
+			emit.begin(dst, class)
 			dst.WriteByte(c)
 		InnerLoop:
 			for {
@@ -490,7 +603,13 @@ func (conf *IndentConfig) CompactStream(wr io.Writer, rd io.Reader) error {
 					v = scan.Step(c)
 					if v != ScanContinue {
 						dst.Write(b[:i])
-						r.Discard(i + 1)
+						if v == ScanEnd {
+							// Leave c unread: it belongs to the next value.
+							scan.bytes--
+							r.Discard(i)
+						} else {
+							r.Discard(i + 1)
+						}
 						break InnerLoop
 					}
 				}
@@ -499,15 +618,26 @@ func (conf *IndentConfig) CompactStream(wr io.Writer, rd io.Reader) error {
 			}
 			// Check error from InnerLoop
 			if err != nil && err != bufio.ErrBufferFull {
+				// Close the color of the partial literal.
+				emit.end(dst, class)
 				break
 			}
 			// NOTE: we check some, but not all write errors since
 			// once the bufio.Writer encounters an error it will
 			// always return it.
-			if _, err = dst.WriteString(clr.Reset()); err != nil {
+			if err = emit.end(dst, class); err != nil {
+				break
+			}
+			if v == ScanError {
 				break
 			}
+			if v == ScanEnd {
+				scan.Reset()
+				needSep = true
+				continue
+			}
 			if v == ScanSkipSpace {
+				scan.skipSpaceReader(r)
 				continue
 			}
 		}
@@ -515,30 +645,42 @@ func (conf *IndentConfig) CompactStream(wr io.Writer, rd io.Reader) error {
 		// Colorize punctuation.
 		switch c {
 		case '{', '[', ',', ':', '}', ']':
-			// delay indent so that empty object and array are formatted as {} and [].
-			writeByte(dst, conf.Punctuation, c)
+			emitByte(emit, dst, classPunct, c)
 		default:
 			dst.WriteByte(c)
 		}
 	}
 
+	// Flush before checking for read/scan errors
+	ferr := dst.Flush()
+
 	if err != nil && err != io.EOF {
 		return err // TODO: wrap this error
 	}
 	// TODO: return both scan and write errors?
-	if scan.EOF() == ScanError {
+	if (scan.err != nil || !needSep) && scan.EOF() == ScanError {
 		return scan.err
 	}
-	if err := dst.Flush(); err != nil {
-		return err
-	}
-	return nil
+	return ferr
 }
 
+// Compact appends to dst the compact and colorized form of the JSON value
+// src. If conf has no colors this is the same as Compact.
 func (conf *IndentConfig) Compact(dst *bytes.Buffer, src []byte) error {
+	if conf.isPlain() {
+		return compact(dst, src, false)
+	}
+	return conf.compact(dst, src, -1)
+}
+
+// compact formats src like Compact with the contents of objects and arrays
+// nested more than elide levels deep elided. No contents are elided if
+// elide is negative.
+func (conf *IndentConfig) compact(dst *bytes.Buffer, src []byte, elide int) error {
 	origLen := dst.Len()
 	scan := newScanner()
 	defer freeScanner(scan)
+	emit := conf.emitter()
 
 	for i := 0; i < len(src); i++ {
 		c := src[i]
@@ -549,32 +691,18 @@ func (conf *IndentConfig) Compact(dst *bytes.Buffer, src []byte) error {
 			fmt.Printf("    %s\n", scan.parseState)
 		}
 		if v == ScanSkipSpace {
+			i += scan.skipSpace(src[i+1:])
+			continue
+		}
+		if v == ScanEnd {
 			continue
 		}
 		if v == ScanError {
 			break
 		}
 		if v == ScanBeginLiteral {
-			var clr *termcolor.Color
-			switch scan.CurrentParseState() {
-			case ParseObjectKey:
-				// TODO: do we want to use different quote colors here?
-				clr = conf.Keyword
-			case ParseObjectValue, ParseArrayValue:
-				// TODO: use Quote color
-				switch c {
-				case '"':
-					clr = conf.String
-				case 'n':
-					clr = conf.Null
-				case 't':
-					clr = conf.True
-				case 'f':
-					clr = conf.False
-				default:
-					clr = conf.Numeric
-				}
-			}
+			// TODO: use Quote color
+			class := literalClass(scan.CurrentParseState(), c)
 			j := i
 			for i++; i < len(src); i++ {
 				c = src[i]
@@ -583,19 +711,37 @@ func (conf *IndentConfig) Compact(dst *bytes.Buffer, src []byte) error {
 					break
 				}
 			}
-			dst.WriteString(clr.Format())
+			class = conf.keyClass(class, src[j:i])
+			emit.begin(dst, class)
 			dst.Write(src[j:i])
-			dst.WriteString(clr.Reset())
+			emit.end(dst, class)
+			// A top-level literal ends at the end of src.
 			if v == ScanSkipSpace {
+				i += scan.skipSpace(src[i+1:])
+				continue
+			}
+			if v == ScanEnd || i == len(src) {
 				continue
 			}
 		}
 
 		// Colorize punctuation.
 		switch c {
-		case '{', '[', ',', ':', '}', ']':
+		case '{', '[':
+			if elide >= 0 && len(scan.parseState) > elide {
+				end, n, ok := skipContainer(scan, src, i)
+				if !ok {
+					i = len(src) // the scanner has the error
+					break
+				}
+				writeElided(emit, dst, src, i, end, n)
+				i = end
+				break
+			}
+			emitByte(emit, dst, classPunct, c)
+		case ',', ':', '}', ']':
 			// delay indent so that empty object and array are formatted as {} and [].
-			writeByte(dst, conf.Punctuation, c)
+			emitByte(emit, dst, classPunct, c)
 		default:
 			dst.WriteByte(c)
 		}
@@ -612,16 +758,136 @@ type Stream struct {
 	// WARN: just use an io.Reader
 	r *bufio.Reader // TODO: lazily setup Reader?
 
-	scan    *Scanner      // TODO: don't use a pointer
-	conf    *IndentConfig // TODO: move to end of struct
-	buf     []byte
-	scanp   int   // start of unread data in buf
-	scanned int64 // amount of data already scanned
-	scratch bytes.Buffer
-	indent  string
-	prefix  string
-	newline string // WARN: use or remove
-	err     error
+	scan      *Scanner      // TODO: don't use a pointer
+	conf      *IndentConfig // TODO: move to end of struct
+	buf       []byte
+	scanp     int   // start of unread data in buf
+	scanned   int64 // amount of data already scanned
+	lines     int64 // newlines in the data already scanned
+	lineStart int64 // offset of the line after the last of them
+	scratch   bytes.Buffer
+	indent    string
+	prefix    string
+	newline   string // written after each value
+	delim     []byte // written between values
+	skip      []byte // delim without leading and trailing space
+	count     int64  // number of values written
+	header    func(dst []byte, v ValueInfo) []byte
+	headerBuf []byte   // header of the current value
+	rewrite   rewriter // hooks, redaction, truncation and escaping
+	rewritten []byte   // value rewritten by rewrite
+	order     KeyOrder // sort object keys if non-nil
+	sorter    keySorter
+	sortBuf   []byte         // value with sorted keys
+	expandBuf []byte         // value rewritten by ExpandStrings
+	expand    bool           // expand stringified JSON
+	sampleBuf []byte         // value rewritten by SampleArrays
+	sample    int            // array elements written, 0 for all
+	skipped   int64          // elements of the top-level array not written
+	lastFalsy bool           // the last top-level value was null or false
+	maxValue  int            // maximum size of a value read, 0 for none
+	readSize  int            // size of the read buffer, 0 for the default
+	writers   []streamWriter // additional writers used by WriteTo
+	plain     []byte         // value with color removed for plain writers
+	compact   bool
+	wrap      bool  // wrap values in an array
+	split     bool  // stream the elements of top-level arrays
+	inArray   bool  // reading the elements of a top-level array
+	elems     int64 // elements read from the top-level array
+	annotate  bool  // use IndentAnnotated
+	raw       bool  // write top-level strings unquoted
+	atomic    bool  // WriteTo writes nothing unless all values are valid
+	maxDepth  int   // nesting levels written, 0 for all
+	query     Selector
+	queryOut  [][]byte // values produced by query
+	atomicBuf bytes.Buffer
+	err       error
+}
+
+// A StreamError is an error reading or formatting a value of a Stream.
+// It locates the value in the stream, which may be the concatenation of
+// many values, by its index and offset.
+type StreamError struct {
+	Index  int64 // index of the value in the stream, starting at 0
+	Offset int64 // offset in the stream of the error, if known, else the value
+	Err    error
+
+	// Value is the input of the value, or as much of it as was read if
+	// reading the value failed. It is only valid until the stream is
+	// Reset.
+	Value []byte
+
+	// State is the parse state of the scanner, outermost first, when it
+	// found a syntax error in the value.
+	State []ParseState
+
+	// Line and Column locate the error in the stream, starting at 1.
+	// Column counts bytes.
+	Line, Column int64
+
+	source    []byte // line containing the error
+	sourceCol int    // index of the error in source
+}
+
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("value %d (offset %d): %v", e.Index, e.Offset, e.Err)
+}
+
+func (e *StreamError) Unwrap() error { return e.Err }
+
+// Source returns the line of input containing the error, as much of it as
+// was read and without its newline, and the index of the error in it. The
+// start of the line is omitted if it is no longer buffered.
+// Like Value, it is only valid until the stream is Reset.
+func (e *StreamError) Source() (line []byte, index int) {
+	return e.source, e.sourceCol
+}
+
+// valueError returns err, which occurred reading or formatting the value
+// raw at offset start, as a *StreamError. Syntax errors found by the
+// scanner are already relative to the start of the stream.
+func (s *Stream) valueError(err error, start int64, raw []byte) error {
+	off := start
+	if se, ok := err.(*SyntaxError); ok && se.Offset >= start {
+		off = se.Offset
+	}
+	e := &StreamError{Index: s.count, Offset: off, Err: err, Value: raw}
+	if err == s.scan.err {
+		e.State = append([]ParseState(nil), s.scan.parseState...)
+	}
+	s.locate(e)
+	return e
+}
+
+// locate sets the line, column and source line of e. The offset of a
+// syntax error is just past the offending byte.
+func (s *Stream) locate(e *StreamError) {
+	pos := e.Offset
+	if _, ok := e.Err.(*SyntaxError); ok && pos > 0 {
+		pos--
+	}
+	i := int(pos - s.scanned)
+	if i < 0 || i > len(s.buf) {
+		return
+	}
+	e.Line = s.lines + int64(bytes.Count(s.buf[:i], []byte{'\n'})) + 1
+	start := bytes.LastIndexByte(s.buf[:i], '\n') + 1
+	lineStart := s.scanned + int64(start)
+	if start == 0 {
+		lineStart = s.lineStart
+	}
+	e.Column = pos - lineStart + 1
+	end := len(s.buf)
+	if j := bytes.IndexByte(s.buf[i:], '\n'); j >= 0 {
+		end = i + j
+	}
+	e.source = s.buf[start:end]
+	e.sourceCol = i - start
+}
+
+type streamWriter struct {
+	w       io.Writer
+	colored bool
 }
 
 // TODO: swap arg positions
@@ -638,12 +904,21 @@ func NewStream(rd io.Reader, conf *IndentConfig) *Stream {
 }
 
 func (s *Stream) Reset(rd io.Reader) {
-	s.r.Reset(rd)
+	if s.readSize > 0 && s.r.Size() != s.readSize {
+		s.r = bufio.NewReaderSize(rd, s.readSize)
+	} else {
+		s.r.Reset(rd)
+	}
 	s.scan.Reset()
 	s.buf = s.buf[:0]
 	s.scanp = 0
 	s.scanned = 0
+	s.lines = 0
+	s.lineStart = 0
 	s.scratch.Reset()
+	s.count = 0
+	s.inArray = false
+	s.lastFalsy = false
 	s.err = nil
 }
 
@@ -662,14 +937,330 @@ func (s *Stream) SetIndent(prefix, indent string) {
 	s.indent = indent
 }
 
+// SetNewline sets the string written after each value, by default this
+// is a newline.
 func (s *Stream) SetNewline(newline string) {
 	s.newline = newline
 }
 
+// SetValueDelimiter sets the delimiter written between top-level values,
+// such as a blank line ("\n"), an ASCII record separator ("\x1e") or a
+// YAML-style document separator ("---\n"). The delimiter is also ignored
+// when it occurs between values in the input.
+func (s *Stream) SetValueDelimiter(d []byte) {
+	s.delim = append(s.delim[:0], d...)
+	s.skip = bytes.TrimFunc(s.delim, func(r rune) bool {
+		return r < utf8.RuneSelf && isSpace(byte(r))
+	})
+}
+
+// skipDelimiters advances past any space and value delimiters at the
+// start of the unread input.
+func (s *Stream) skipDelimiters() {
+	if len(s.skip) == 0 {
+		return
+	}
+	for {
+		if _, err := s.peek(); err != nil {
+			return
+		}
+		for len(s.buf)-s.scanp < len(s.skip) && bytes.HasPrefix(s.skip, s.buf[s.scanp:]) {
+			if err := s.refill(); err != nil {
+				break
+			}
+		}
+		if !bytes.HasPrefix(s.buf[s.scanp:], s.skip) {
+			return
+		}
+		s.scanp += len(s.skip)
+	}
+}
+
+// A ValueInfo describes a value read by a Stream.
+type ValueInfo struct {
+	Index  int64 // index of the value in the stream, starting at 0
+	Offset int64 // offset of the value in the stream
+	Size   int   // size of the value in the input, in bytes
+}
+
+// ValueIndex returns the index, starting at 0, of the value most recently
+// returned by Next or -1 if no value has been returned.
+func (s *Stream) ValueIndex() int64 {
+	return s.count - 1
+}
+
+// LastValueFalsy reports whether the value most recently returned by Next
+// was null or false, as checked by jq -e. Arrays wrapped by SetWrapArray
+// or streamed by SetStreamArrays are the top-level values, not their
+// elements.
+func (s *Stream) LastValueFalsy() bool {
+	return s.lastFalsy
+}
+
+// SetValueHeader sets a function that appends a header, such as the
+// index and size of the value, to dst. The header is written before each
+// value, after the value delimiter, so the output is not valid JSON unless
+// the header is. A nil function disables headers.
+func (s *Stream) SetValueHeader(fn func(dst []byte, v ValueInfo) []byte) {
+	s.header = fn
+	s.headerBuf = s.headerBuf[:0]
+}
+
+// valueInfo returns the ValueInfo of the n byte value that was read at
+// offset start and ends at s.scanp.
+func (s *Stream) valueInfo(start int64, n int) ValueInfo {
+	raw := s.buf[s.scanp-n : s.scanp]
+	i := 0
+	for i < len(raw) && isSpace(raw[i]) {
+		i++
+	}
+	return ValueInfo{Index: s.count, Offset: start + int64(i), Size: n - i}
+}
+
+// SetStreamArrays sets whether top-level arrays are read and formatted
+// one element at a time, so that the memory used is bounded by the size
+// of the largest element instead of the array. The output is the same,
+// but each element is a separate value to Next, hooks and headers. It
+// has no effect when wrapping values (see SetWrapArray).
+func (s *Stream) SetStreamArrays(on bool) {
+	s.split = on
+}
+
+// SetCompact sets whether values are written in compact form. When compact
+// is true the indent and prefix are ignored and each value is written on
+// its own line.
+func (s *Stream) SetCompact(compact bool) {
+	s.compact = compact
+}
+
+// SetSortKeys sets the order the members of each object are written in.
+// If order is nil, the default, members are written in input order.
+func (s *Stream) SetSortKeys(order KeyOrder) {
+	s.order = order
+	s.sorter.order = order
+}
+
+// SetHooks sets the hooks used to transform the keys and scalar values
+// of each value before it is formatted.
+func (s *Stream) SetHooks(h Hooks) {
+	s.rewrite.hooks = h
+}
+
+// SetEscapeHTML sets whether the characters <, >, &, U+2028 and U+2029
+// in strings are escaped so that the output is safe to embed in HTML
+// <script> tags, see HTMLEscape.
+func (s *Stream) SetEscapeHTML(on bool) {
+	s.rewrite.html = on
+}
+
+// SetASCII sets whether non-ASCII characters in strings are escaped, see
+// EscapeASCII.
+func (s *Stream) SetASCII(on bool) {
+	s.rewrite.ascii = on
+}
+
+// SetUnescape sets whether \u escapes in strings are decoded to UTF-8,
+// see UnescapeUnicode.
+func (s *Stream) SetUnescape(on bool) {
+	s.rewrite.unescape = on
+}
+
+// SetRedact sets the keys of object members whose values are replaced by
+// the string replacement, see Redact. If keys is empty nothing is
+// redacted.
+func (s *Stream) SetRedact(keys []string, replacement string) {
+	s.rewrite.setRedact(keys, replacement)
+}
+
+// SetExpandStrings sets whether string values that contain a JSON object
+// or array are replaced by it, see ExpandStrings.
+func (s *Stream) SetExpandStrings(on bool) {
+	s.expand = on
+}
+
+// SetSample sets the number of elements of each array that are written.
+// The rest are replaced by a string element counting them, such as
+// "… 1,234,567 more elements", see SampleArrays. The elements of top-level
+// arrays streamed with SetStreamArrays are counted as they are read, so
+// they are not kept in memory. Zero, the default, writes every element.
+func (s *Stream) SetSample(n int) {
+	if n < 0 {
+		n = 0
+	}
+	s.sample = n
+}
+
+// SetMaxString sets the number of characters string values are truncated
+// to, see TruncateStrings. Zero, the default, disables truncation.
+func (s *Stream) SetMaxString(n int) {
+	s.rewrite.maxString = n
+}
+
+// SetReadBuffer sets the size of the buffer used to read the input, the
+// default is 4096 bytes. It takes effect at the next call to Reset.
+func (s *Stream) SetReadBuffer(size int) {
+	s.readSize = size
+}
+
+// ErrValueTooLarge is the error, wrapped in a StreamError, when a value is
+// larger than the size set with SetMaxValueSize.
+var ErrValueTooLarge = errors.New("pjson: value too large")
+
+// SetMaxValueSize sets the maximum size, in bytes, of a value read from
+// the input. Reading a larger value fails with ErrValueTooLarge, instead
+// of buffering the whole value. When streaming the elements of top-level
+// arrays (see SetStreamArrays) the limit applies to each element. Zero,
+// the default, sets no limit.
+func (s *Stream) SetMaxValueSize(n int) {
+	s.maxValue = n
+}
+
+// SetSanitize sets whether characters in strings that a terminal could
+// interpret as a control sequence are escaped. See Sanitize.
+func (s *Stream) SetSanitize(sanitize bool) {
+	s.rewrite.safe = sanitize
+}
+
+// AddWriter adds a writer that WriteTo writes each value to in addition to
+// its argument. If colored is false color escape sequences are removed
+// from the output written to w. This allows writing colored output to a
+// terminal and plain output to a log file without formatting twice.
+func (s *Stream) AddWriter(w io.Writer, colored bool) {
+	s.writers = append(s.writers, streamWriter{w: w, colored: colored})
+}
+
+// write writes b to w and returns the number of bytes written.
+func write(w io.Writer, b []byte) (int, error) {
+	n, err := w.Write(b)
+	if err == nil && n < len(b) {
+		err = io.ErrShortWrite
+	}
+	return n, err
+}
+
+// writeOthers writes the value b to the writers added with AddWriter.
+func (s *Stream) writeOthers(b []byte) error {
+	plain := false
+	for _, sw := range s.writers {
+		out := b
+		if !sw.colored {
+			if !plain {
+				s.plain = termcolor.Strip(s.plain[:0], b)
+				plain = true
+			}
+			out = s.plain
+		}
+		if _, err := write(sw.w, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetWrapArray sets whether the values of the stream are written as the
+// elements of a single array, the inverse of ArrayToLines. The output is
+// still streamed: each value returned by Next begins with the '[' or ','
+// that precedes it and WriteTo writes the closing ']' once the input is
+// exhausted. The value delimiter is not written when wrapping values.
+func (s *Stream) SetWrapArray(wrap bool) {
+	s.wrap = wrap
+}
+
+// SetAnnotate sets whether indented values are formatted with
+// IndentAnnotated. It has no effect on compact values.
+func (s *Stream) SetAnnotate(annotate bool) {
+	s.annotate = annotate
+}
+
+// SetQuery sets the query, such as a Query or JSONPath, applied to each
+// value before it is formatted. Each value the query selects, if any, is
+// written as a separate value. Top-level arrays are not streamed (see
+// SetStreamArrays) while a query is set. If q is nil, the default, values
+// are written as they are read.
+func (s *Stream) SetQuery(q Selector) {
+	s.query = q
+}
+
+// SetMaxDepth sets the number of nesting levels of each value that are
+// written. The contents of deeper objects and arrays are replaced with
+// "...", as in {...} and [...], so the output is not valid JSON. If depth
+// is 0, the default, every level is written.
+func (s *Stream) SetMaxDepth(depth int) {
+	if depth < 0 {
+		depth = 0
+	}
+	s.maxDepth = depth
+}
+
+// elide returns the nesting level of the value being formatted whose
+// contents are elided, or -1 if none are.
+func (s *Stream) elide() int {
+	switch {
+	case s.maxDepth == 0:
+		return -1
+	case s.wrap || s.inArray:
+		// The value is an element of the array written by the Stream.
+		return s.maxDepth - 1
+	}
+	return s.maxDepth
+}
+
+// SetRawStrings sets whether top-level strings are written as their
+// unquoted contents, without escapes, instead of as JSON, like "jq -r".
+// Strings in arrays wrapped by SetWrapArray or streamed by
+// SetStreamArrays are not top-level values. If SetSanitize is enabled
+// control characters other than newline and tab are written as \u escapes.
+func (s *Stream) SetRawStrings(raw bool) {
+	s.raw = raw
+}
+
+// SetAtomic sets whether WriteTo buffers its output and writes nothing
+// unless every value of the input is valid. Otherwise each value is
+// written once it is formatted, so the values before an invalid value are
+// written and, with SetWrapArray or SetStreamArrays, the array enclosing
+// them is left open.
+func (s *Stream) SetAtomic(atomic bool) {
+	s.atomic = atomic
+}
+
+// More reports whether there is another JSON value in the input stream.
+func (s *Stream) More() bool {
+	if s.err != nil {
+		return false
+	}
+	s.skipDelimiters()
+	_, err := s.peek()
+	return err == nil
+}
+
+func (s *Stream) peek() (byte, error) {
+	var err error
+	for {
+		for i := s.scanp; i < len(s.buf); i++ {
+			c := s.buf[i]
+			if isSpace(c) {
+				continue
+			}
+			s.scanp = i
+			return c, nil
+		}
+		// buffer has been scanned, now report any error
+		if err != nil {
+			return 0, err
+		}
+		err = s.refill()
+	}
+}
+
 func (dec *Stream) refill() error {
 	// Make room to read more into the buffer.
 	// First slide down data already consumed.
 	if dec.scanp > 0 {
+		consumed := dec.buf[:dec.scanp]
+		if i := bytes.LastIndexByte(consumed, '\n'); i >= 0 {
+			dec.lines += int64(bytes.Count(consumed, []byte{'\n'}))
+			dec.lineStart = dec.scanned + int64(i) + 1
+		}
 		dec.scanned += int64(dec.scanp)
 		n := copy(dec.buf, dec.buf[dec.scanp:])
 		dec.buf = dec.buf[:n]
@@ -695,6 +1286,9 @@ func (dec *Stream) refill() error {
 // It returns the length of the encoding.
 func (dec *Stream) readValue() (int, error) {
 	dec.scan.Reset()
+	// Count bytes from the start of the stream so that the offset of
+	// syntax errors is not relative to the value.
+	dec.scan.bytes = dec.scanned + int64(dec.scanp)
 
 	scanp := dec.scanp
 	var err error
@@ -708,6 +1302,8 @@ Input:
 			c := dec.buf[scanp]
 			dec.scan.bytes++
 			switch dec.scan.step(dec.scan, c) {
+			case ScanSkipSpace:
+				scanp += dec.scan.skipSpace(dec.buf[scanp+1:])
 			case ScanEnd:
 				// scanEnd is delayed one byte so we decrement
 				// the scanner bytes count by 1 to ensure that
@@ -744,6 +1340,11 @@ Input:
 		}
 
 		n := scanp - dec.scanp
+		if dec.maxValue > 0 && n > dec.maxValue {
+			err = fmt.Errorf("%w: more than %d bytes", ErrValueTooLarge, dec.maxValue)
+			dec.err = err
+			return 0, err
+		}
 		err = dec.refill()
 		scanp = dec.scanp + n
 	}
@@ -762,31 +1363,198 @@ func (s *Stream) Next() ([]byte, error) {
 	// }
 	// WARN WARN WARN WARN WARN WARN WARN
 
+	if !s.inArray {
+		s.skipDelimiters()
+	}
+	if s.split && !s.wrap && s.query == nil {
+		if out, err := s.nextElement(); out != nil || err != nil {
+			return out, err
+		}
+	}
+	start := s.scanned + int64(s.scanp)
 	n, err := s.readValue()
 	if err != nil {
+		if err == io.EOF && s.inArray {
+			err = io.ErrUnexpectedEOF
+		}
+		if err == io.ErrUnexpectedEOF {
+			start = s.scanned + int64(len(s.buf)) // end of input
+		}
+		if err != io.EOF {
+			err = s.valueError(err, start, s.buf[s.scanp:])
+			s.err = err
+		}
 		return nil, err
 	}
 	val := s.buf[s.scanp : s.scanp+n]
 	s.scanp += n
+	if s.inArray || s.raw {
+		val = bytes.TrimLeftFunc(val, func(r rune) bool {
+			return r < utf8.RuneSelf && isSpace(byte(r))
+		})
+	}
+	// A query may produce any number of values, which are written as
+	// separate values.
+	vals := append(s.queryOut[:0], val)
+	if s.query != nil {
+		if vals, err = s.query.Apply(vals[:0], val); err != nil {
+			return nil, s.valueError(err, start, s.buf[s.scanp-n:s.scanp])
+		}
+	}
+	s.queryOut = vals
 
 	s.scratch.Reset()
-	if err := s.conf.Indent(&s.scratch, val, s.prefix, s.indent); err != nil {
-		// panic(fmt.Sprintf("error: %v n: %d scanp: %d\n###\n%q\n###", err, n, s.scanp, val))
-		return nil, err
+	for _, val := range vals {
+		if err := s.format(val, start, n); err != nil {
+			return nil, err
+		}
 	}
-	s.scratch.WriteByte('\n')
 	out := make([]byte, s.scratch.Len())
 	copy(out, s.scratch.Bytes())
 	return out, nil
 }
 
+// format appends the value val, read from the n bytes of input at offset
+// start, to s.scratch.
+func (s *Stream) format(val []byte, start int64, n int) error {
+	if s.inArray && s.sample > 0 && s.elems >= int64(s.sample) {
+		s.skipped++ // counted at the end of the array
+		return nil
+	}
+	if v := bytes.TrimLeft(val, " \t\r\n"); !s.inArray && !s.wrap && len(v) > 0 {
+		s.lastFalsy = v[0] == 'n' || v[0] == 'f'
+	} else {
+		s.lastFalsy = false
+	}
+	var err error
+	if val, err = s.transform(val); err != nil {
+		return s.valueError(err, start, s.buf[s.scanp-n:s.scanp])
+	}
+	if s.header != nil {
+		s.headerBuf = s.header(s.headerBuf[:0], s.valueInfo(start, n))
+	}
+	prefix := s.prefix
+	switch {
+	case s.wrap:
+		s.scratch.Write(s.headerBuf)
+		c := byte(',')
+		if s.count == 0 {
+			c = '['
+		}
+		emitByte(s.conf.emitter(), &s.scratch, classPunct, c)
+		if !s.compact {
+			prefix += s.indent
+			s.scratch.WriteByte('\n')
+			s.scratch.WriteString(prefix)
+		}
+	case s.inArray:
+		if s.elems == 0 && s.count > 0 {
+			s.scratch.Write(s.delim)
+		}
+		s.scratch.Write(s.headerBuf)
+		c := byte(',')
+		if s.elems == 0 {
+			c = '['
+		}
+		emitByte(s.conf.emitter(), &s.scratch, classPunct, c)
+		if !s.compact {
+			prefix += s.indent
+			s.scratch.WriteByte('\n')
+			s.scratch.WriteString(prefix)
+		}
+		s.elems++
+	case s.count > 0:
+		s.scratch.Write(s.delim)
+		fallthrough
+	default:
+		s.scratch.Write(s.headerBuf)
+	}
+	switch {
+	case s.raw && val[0] == '"' && !s.wrap && !s.inArray:
+		raw, ok := unquoteBytes(val)
+		if !ok {
+			return s.valueError(errors.New("pjson: invalid string"), start, s.buf[s.scanp-n:s.scanp])
+		}
+		if s.rewrite.safe {
+			// Unquoting decodes the escapes that Sanitize wrote.
+			raw = sanitizeRaw(nil, raw)
+		}
+		s.scratch.Write(raw)
+	case s.inArray && val[0] != '{' && val[0] != '[':
+		// Color scalar elements, which are not colored as top-level values.
+		class := literalClass(ParseArrayValue, val[0])
+		emit := s.conf.emitter()
+		emit.begin(&s.scratch, class)
+		s.scratch.Write(val)
+		emit.end(&s.scratch, class)
+	case s.compact && s.maxDepth > 0:
+		err = s.conf.compact(&s.scratch, val, s.elide())
+	case s.compact:
+		err = s.conf.Compact(&s.scratch, val)
+	case s.annotate || s.maxDepth > 0:
+		err = s.conf.indent(&s.scratch, val, prefix, s.indent, s.annotate, s.elide())
+	default:
+		err = s.conf.Indent(&s.scratch, val, prefix, s.indent)
+	}
+	if err != nil {
+		// panic(fmt.Sprintf("error: %v n: %d scanp: %d\n###\n%q\n###", err, n, s.scanp, val))
+		return s.valueError(err, start, s.buf[s.scanp-n:s.scanp])
+	}
+	if !s.wrap && !s.inArray {
+		s.scratch.WriteString(s.newline)
+	}
+	s.count++
+	return nil
+}
+
+// transform applies the rewrites enabled on the stream to the value val,
+// in order: expanding strings, sampling arrays, sorting keys and then, in
+// a single pass over each key and literal, hooks, redacting, truncating
+// strings, unescaping, HTML escaping, ASCII escaping and sanitizing. They
+// are applied before val is formatted so that the compact and indented
+// output are the same, apart from white space.
+func (s *Stream) transform(val []byte) ([]byte, error) {
+	var err error
+	if s.expand {
+		if val, err = ExpandStrings(s.expandBuf[:0], val); err != nil {
+			return nil, err
+		}
+		s.expandBuf = val
+	}
+	if s.sample > 0 {
+		if val, err = SampleArrays(s.sampleBuf[:0], val, s.sample); err != nil {
+			return nil, err
+		}
+		s.sampleBuf = val
+	}
+	if s.order != nil {
+		if val, err = s.sorter.sort(s.sortBuf[:0], val); err != nil {
+			return nil, err
+		}
+		s.sortBuf = val
+	}
+	if s.rewrite.enabled() {
+		if val, err = s.rewrite.rewrite(s.rewritten[:0], val); err != nil {
+			return nil, err
+		}
+		s.rewritten = val
+	}
+	return val, nil
+}
+
 func (s *Stream) EOF() bool { return errors.Is(s.err, io.EOF) }
 
+// WriteTo writes each formatted value to wr and to any writers added with
+// AddWriter. The returned count only includes the bytes written to wr.
+//
 // WARN: don't return EOF
 func (s *Stream) WriteTo(wr io.Writer) (nn int64, err error) {
 	if s.err != nil {
 		return 0, s.err
 	}
+	if s.atomic {
+		return s.writeAtomic(wr)
+	}
 	// var nn int64 // WARN: use an int64
 	for {
 		b, en := s.Next()
@@ -794,14 +1562,15 @@ func (s *Stream) WriteTo(wr io.Writer) (nn int64, err error) {
 			if en != io.EOF {
 				err = en
 				s.err = en
+			} else if s.wrap {
+				n, ew := s.writeValue(wr, s.closeArray())
+				nn += int64(n)
+				err = ew
 			}
 			break
 		}
-		n, ew := wr.Write(b)
+		n, ew := s.writeValue(wr, b)
 		nn += int64(n)
-		if ew == nil && n < len(b) {
-			ew = io.ErrShortWrite
-		}
 		if ew != nil {
 			err = ew
 			break
@@ -810,6 +1579,142 @@ func (s *Stream) WriteTo(wr io.Writer) (nn int64, err error) {
 	return nn, err
 }
 
+// writeAtomic is WriteTo when the stream is atomic.
+func (s *Stream) writeAtomic(wr io.Writer) (int64, error) {
+	s.atomicBuf.Reset()
+	for {
+		b, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			s.err = err
+			return 0, err
+		}
+		s.atomicBuf.Write(b)
+	}
+	if s.wrap {
+		s.atomicBuf.Write(s.closeArray())
+	}
+	n, err := s.writeValue(wr, s.atomicBuf.Bytes())
+	if s.atomicBuf.Cap() > 4*1024*1024 {
+		s.atomicBuf = bytes.Buffer{} // don't retain large outputs
+	}
+	return int64(n), err
+}
+
+// writeValue writes b to wr and any writers added with AddWriter.
+func (s *Stream) writeValue(wr io.Writer, b []byte) (int, error) {
+	n, err := write(wr, b)
+	if err == nil && len(s.writers) > 0 {
+		err = s.writeOthers(b)
+	}
+	return n, err
+}
+
+// nextElement reads the punctuation before the next element of a
+// top-level array when streaming arrays (see SetStreamArrays). It returns
+// the formatted end of the array, if the array ended, or an error.
+// Otherwise, the element is read as a value.
+func (s *Stream) nextElement() ([]byte, error) {
+	for {
+		c, err := s.peek()
+		if err != nil {
+			if err == io.EOF && !s.inArray {
+				return nil, nil // let readValue report EOF
+			}
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			s.err = s.valueError(err, s.scanned+int64(len(s.buf)), s.buf[s.scanp:])
+			return nil, s.err
+		}
+		switch {
+		case !s.inArray:
+			if c != '[' {
+				return nil, nil
+			}
+			s.scanp++
+			s.inArray = true
+			s.elems = 0
+			s.skipped = 0
+		case c == ']':
+			s.scanp++
+			s.inArray = false
+			return s.endArray(), nil
+		case s.elems == 0:
+			return nil, nil
+		case c == ',':
+			s.scanp++
+			return nil, nil
+		default:
+			off := s.scanned + int64(s.scanp) + 1
+			s.err = s.valueError(&SyntaxError{"invalid character " + quoteChar(c) +
+				" after array element", off}, off, s.buf[s.scanp:])
+			return nil, s.err
+		}
+	}
+}
+
+// endArray returns the end of a top-level array streamed by nextElement.
+func (s *Stream) endArray() []byte {
+	s.scratch.Reset()
+	emit := s.conf.emitter()
+	if s.elems == 0 {
+		if s.count > 0 {
+			s.scratch.Write(s.delim)
+		}
+		emitByte(emit, &s.scratch, classPunct, '[')
+		s.count++
+	} else {
+		if s.skipped > 0 {
+			s.writeSkipped(emit)
+		}
+		if !s.compact {
+			s.scratch.WriteByte('\n')
+			s.scratch.WriteString(s.prefix)
+		}
+	}
+	emitByte(emit, &s.scratch, classPunct, ']')
+	s.scratch.WriteString(s.newline)
+	out := make([]byte, s.scratch.Len())
+	copy(out, s.scratch.Bytes())
+	return out
+}
+
+// writeSkipped writes the element that counts the elements of the
+// top-level array that were not written because of SetSample.
+func (s *Stream) writeSkipped(emit emitter) {
+	emitByte(emit, &s.scratch, classPunct, ',')
+	if !s.compact {
+		s.scratch.WriteByte('\n')
+		s.scratch.WriteString(s.prefix + s.indent)
+	}
+	s.sampleBuf = appendMoreElements(s.sampleBuf[:0], s.skipped)
+	mark := appendQuoted(nil, string(s.sampleBuf))
+	if s.rewrite.ascii {
+		mark = EscapeASCII(nil, mark)
+	}
+	emit.begin(&s.scratch, classString)
+	s.scratch.Write(mark)
+	emit.end(&s.scratch, classString)
+}
+
+// closeArray returns the end of the array written when wrapping values.
+func (s *Stream) closeArray() []byte {
+	s.scratch.Reset()
+	emit := s.conf.emitter()
+	if s.count == 0 {
+		emitByte(emit, &s.scratch, classPunct, '[')
+	} else if !s.compact {
+		s.scratch.WriteByte('\n')
+		s.scratch.WriteString(s.prefix)
+	}
+	emitByte(emit, &s.scratch, classPunct, ']')
+	s.scratch.WriteString(s.newline)
+	return s.scratch.Bytes()
+}
+
 // WARN: make sure we return io.EOF
 // WARN: what is the right signature for this?
 /*
@@ -9,8 +9,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -209,6 +211,518 @@ func TestIndentConfigStream(t *testing.T) {
 	testIndentConfigIndentGolden(t, true, fn)
 }
 
+func TestNewIndentConfig(t *testing.T) {
+	if conf := NewIndentConfig(nil); *conf != noColorIndentConfig {
+		t.Errorf("NewIndentConfig(nil) = %+v; want: %+v", *conf, noColorIndentConfig)
+	}
+
+	base := DefaultIndentConfig
+	conf := NewIndentConfig(&DefaultIndentConfig,
+		WithString(termcolor.Cyan),
+		WithBool(termcolor.Red),
+		WithBoldKeys(),
+	)
+	if DefaultIndentConfig != base {
+		t.Fatal("NewIndentConfig modified its base")
+	}
+	want := DefaultIndentConfig
+	want.String = termcolor.Cyan
+	want.True = termcolor.Red
+	want.False = termcolor.Red
+	want.Keyword = termcolor.NewColor(termcolor.Bold, termcolor.FgBlue)
+	if !conf.Keyword.Equal(want.Keyword) {
+		t.Errorf("Keyword = %s; want: %s", conf.Keyword, want.Keyword)
+	}
+	conf.Keyword = want.Keyword
+	if *conf != want {
+		t.Errorf("NewIndentConfig() = %+v; want: %+v", *conf, want)
+	}
+
+	if conf := NewIndentConfig(nil, WithBoldKeys()); !conf.Keyword.Equal(termcolor.NewColor(termcolor.Bold)) {
+		t.Errorf("WithBoldKeys() Keyword = %s; want: %s", conf.Keyword, termcolor.NewColor(termcolor.Bold))
+	}
+}
+
+// Test that the no-color fast path matches colored output with the color
+// removed.
+func TestIndentConfigPlain(t *testing.T) {
+	inputs := []string{
+		`1`,
+		` "s" `,
+		`{}`,
+		`[ ]`,
+		"{\n\t\"a\": [1, \"b\", true, false, null, {}, []],\n\t\"c\": {\"d\": -1.5e3}\n}\n",
+		`[[["a"]], {"b": {"c": null}}]`,
+	}
+	data, err := json.Marshal(indentTestMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs = append(inputs, string(data))
+	for _, in := range inputs {
+		var plain, colored bytes.Buffer
+		if err := noColorIndentConfig.Indent(&plain, []byte(in), ">", "  "); err != nil {
+			t.Fatal(err)
+		}
+		if err := DefaultIndentConfig.Indent(&colored, []byte(in), ">", "  "); err != nil {
+			t.Fatal(err)
+		}
+		if want := ansiRe.ReplaceAllString(colored.String(), ""); plain.String() != want {
+			t.Errorf("Indent(%q):\ngot:  %q\nwant: %q", in, plain.String(), want)
+		}
+
+		plain.Reset()
+		colored.Reset()
+		if err := noColorIndentConfig.Compact(&plain, []byte(in)); err != nil {
+			t.Fatal(err)
+		}
+		if err := DefaultIndentConfig.Compact(&colored, []byte(in)); err != nil {
+			t.Fatal(err)
+		}
+		if want := ansiRe.ReplaceAllString(colored.String(), ""); plain.String() != want {
+			t.Errorf("Compact(%q):\ngot:  %q\nwant: %q", in, plain.String(), want)
+		}
+	}
+
+	for _, in := range []string{`{`, `[1,]`, `1 }`, `{"a" 1}`} {
+		var plain, colored bytes.Buffer
+		plain.WriteString("x")
+		perr := noColorIndentConfig.Indent(&plain, []byte(in), "", "  ")
+		cerr := DefaultIndentConfig.Indent(&colored, []byte(in), "", "  ")
+		if perr == nil || perr.Error() != cerr.Error() {
+			t.Errorf("Indent(%q) error = %v; want: %v", in, perr, cerr)
+		}
+		if plain.String() != "x" {
+			t.Errorf("Indent(%q) should not modify dst on error: %q", in, plain.String())
+		}
+		perr = noColorIndentConfig.Compact(&plain, []byte(in))
+		cerr = DefaultIndentConfig.Compact(&colored, []byte(in))
+		if perr == nil || perr.Error() != cerr.Error() {
+			t.Errorf("Compact(%q) error = %v; want: %v", in, perr, cerr)
+		}
+	}
+}
+
+func TestIndentConfigEmptyKey(t *testing.T) {
+	const in = `{"": 1, " ": 2, "a": 3}`
+	hl := termcolor.NewColor(termcolor.ReverseVideo)
+	conf := NewIndentConfig(&DefaultIndentConfig, func(c *IndentConfig) { c.EmptyKey = hl })
+	key := func(c *termcolor.Color, s string) string { return c.Format() + s + c.Reset() }
+
+	var dst bytes.Buffer
+	if err := conf.Compact(&dst, []byte(in)); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{key(hl, `""`), key(hl, `" "`), key(conf.Keyword, `"a"`)} {
+		if !strings.Contains(dst.String(), want) {
+			t.Errorf("Compact() = %q; missing: %q", dst.String(), want)
+		}
+	}
+
+	// Disabled
+	conf.EmptyKey = nil
+	dst.Reset()
+	if err := conf.Indent(&dst, []byte(in), "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	if want := key(conf.Keyword, `""`); !strings.Contains(dst.String(), want) {
+		t.Errorf("Indent() = %q; missing: %q", dst.String(), want)
+	}
+}
+
+func TestStreamCompact(t *testing.T) {
+	const in = "{\n  \"a\": [1, 2, 3]\n}\n[ true,\tnull ] \"s\"\n\n  12"
+	const want = "{\"a\":[1,2,3]}\n[true,null]\n\"s\"\n12\n"
+
+	var noColor IndentConfig
+	s := NewStream(iotest.OneByteReader(strings.NewReader(in)), &noColor)
+	s.SetIndent("", "    ")
+	s.SetCompact(true)
+
+	var got []string
+	for s.More() {
+		b, err := s.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(b))
+	}
+	compareJSON(t, strings.Join(got, ""), want)
+	if _, err := s.Next(); err != io.EOF {
+		t.Errorf("Next() error = %v; want: %v", err, io.EOF)
+	}
+
+	// Compact output should match CompactStream for each value
+	var dst bytes.Buffer
+	s.Reset(strings.NewReader(in))
+	if _, err := s.WriteTo(&dst); err != nil {
+		t.Fatal(err)
+	}
+	compareJSON(t, dst.String(), want)
+}
+
+func TestIndentConfigIndented(t *testing.T) {
+	// Space skipped in bulk must not change the output.
+	const compact = `{"a":[1,"x",{"b":null}],"c":{},"d":[],"e":true}`
+	const indented = "{\n  \"a\" : [ 1,\r\n\t\"x\" ,\n   {\"b\":   null}  ],\n" +
+		"  \"c\": {  },\n  \"d\": [\n  ],\n  \"e\" :true\n}"
+	for _, conf := range []IndentConfig{DefaultIndentConfig, {}} {
+		var want, got bytes.Buffer
+		if err := conf.Indent(&want, []byte(compact), "", "  "); err != nil {
+			t.Fatal(err)
+		}
+		if err := conf.Indent(&got, []byte(indented), "", "  "); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("Indent: got: %q want: %q", got.String(), want.String())
+		}
+		got.Reset()
+		if err := conf.IndentStream(&got, iotest.HalfReader(strings.NewReader(indented)), "", "  "); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("IndentStream: got: %q want: %q", got.String(), want.String())
+		}
+		got.Reset()
+		s := NewStream(iotest.HalfReader(strings.NewReader(indented)), &conf)
+		s.SetIndent("", "  ")
+		if _, err := s.WriteTo(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want.String()+"\n" {
+			t.Errorf("Stream: got: %q want: %q", got.String(), want.String()+"\n")
+		}
+	}
+}
+
+func TestStreamError(t *testing.T) {
+	tests := []struct {
+		in     string
+		index  int64
+		offset int64
+	}{
+		{`{"a": x}`, 0, 7},
+		{`1 2 [true, nul]`, 2, 15},
+		{"{\"a\": 1}\n{\"b\": 2}\n{\"c\" 3}", 2, 24},
+		{`"a" [1, 2`, 1, 9},
+	}
+	var noColor IndentConfig
+	for _, test := range tests {
+		// Read one byte at a time so that the buffer is refilled.
+		s := NewStream(iotest.OneByteReader(strings.NewReader(test.in)), &noColor)
+		_, err := s.WriteTo(io.Discard)
+		var se *StreamError
+		if !errors.As(err, &se) {
+			t.Errorf("%q: error = %#v; want: *StreamError", test.in, err)
+			continue
+		}
+		if se.Index != test.index || se.Offset != test.offset {
+			t.Errorf("%q: error = %v; want: index %d offset %d", test.in, err,
+				test.index, test.offset)
+		}
+		if _, err := s.Next(); err != se {
+			t.Errorf("%q: Next() = %v; want: %v", test.in, err, se)
+		}
+	}
+
+	s := NewStream(strings.NewReader(`1 {"a": [x]}`), &noColorIndentConfig)
+	_, err := s.WriteTo(io.Discard)
+	var se *StreamError
+	if !errors.As(err, &se) {
+		t.Fatalf("error = %#v; want: *StreamError", err)
+	}
+	if got := strings.TrimSpace(string(se.Value)); got != `{"a": [x]}` {
+		t.Errorf("Value = %q; want: %q", got, `{"a": [x]}`)
+	}
+	if want := []ParseState{ParseObjectValue, ParseArrayValue}; !reflect.DeepEqual(se.State, want) {
+		t.Errorf("State = %v; want: %v", se.State, want)
+	}
+}
+
+func TestStreamErrorLocation(t *testing.T) {
+	tests := []struct {
+		in     string
+		line   int64
+		column int64
+		source string
+		index  int
+	}{
+		{`{"a": x}`, 1, 7, `{"a": x`, 6},
+		{"{\"a\": 1}\n{\"b\": 2}\n{\"c\" 3}", 3, 6, `{"c" 3`, 5},
+		{"[\n  1,\n  2,,\n  3\n]", 3, 5, "  2,,", 4},
+		{"[1,\n 2", 2, 3, " 2", 2},
+	}
+	for _, test := range tests {
+		// Read one byte at a time so that the buffer is refilled.
+		s := NewStream(iotest.OneByteReader(strings.NewReader(test.in)), &noColorIndentConfig)
+		_, err := s.WriteTo(io.Discard)
+		var se *StreamError
+		if !errors.As(err, &se) {
+			t.Errorf("%q: error = %#v; want: *StreamError", test.in, err)
+			continue
+		}
+		if se.Line != test.line || se.Column != test.column {
+			t.Errorf("%q: location = %d:%d; want: %d:%d", test.in, se.Line, se.Column,
+				test.line, test.column)
+		}
+		source, index := se.Source()
+		if string(source) != test.source || index != test.index {
+			t.Errorf("%q: Source() = %q, %d; want: %q, %d", test.in, source, index,
+				test.source, test.index)
+		}
+	}
+}
+
+func TestStreamValueDelimiter(t *testing.T) {
+	tests := []struct {
+		delim string
+		in    string
+		want  string
+	}{
+		{"", "[1] {}\n2", "[1]\n{}\n2\n"},
+		{"\n", "[1] {}\n2", "[1]\n\n{}\n\n2\n"},
+		{"\x1e", "\x1e[1]\n\x1e{}\x1e\x1e2\n\x1e", "[1]\n\x1e{}\n\x1e2\n"},
+		{"\x00", "[1]\x00{}\x002\x00", "[1]\n\x00{}\n\x002\n"},
+		{"---\n", "---\n[1]\n---\n{}\n  ---  \n2\n", "[1]\n---\n{}\n---\n2\n"},
+	}
+	readers := []func(io.Reader) io.Reader{
+		func(r io.Reader) io.Reader { return r },
+		iotest.OneByteReader,
+		iotest.DataErrReader,
+	}
+	for _, test := range tests {
+		for _, fn := range readers {
+			var conf IndentConfig
+			s := NewStream(fn(strings.NewReader(test.in)), &conf)
+			s.SetCompact(true)
+			s.SetValueDelimiter([]byte(test.delim))
+			var dst bytes.Buffer
+			if _, err := s.WriteTo(&dst); err != nil {
+				t.Errorf("%q: %v", test.delim, err)
+				continue
+			}
+			if got := dst.String(); got != test.want {
+				t.Errorf("%q: got: %q want: %q", test.delim, got, test.want)
+			}
+		}
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		var conf IndentConfig
+		s := NewStream(strings.NewReader("[1]\n--\n[2]"), &conf)
+		s.SetValueDelimiter([]byte("---\n"))
+		if _, err := s.WriteTo(io.Discard); err == nil {
+			t.Error("expected an error for a partial delimiter")
+		}
+	})
+}
+
+func TestStreamValueHeader(t *testing.T) {
+	const in = "{\"a\": 1}\n  [1, 2]\n\"s\""
+	header := func(dst []byte, v ValueInfo) []byte {
+		return append(dst, fmt.Sprintf("# %d %d %d\n", v.Index, v.Offset, v.Size)...)
+	}
+	tests := []struct {
+		wrap bool
+		want string
+	}{
+		{false, "# 0 0 8\n{\"a\":1}\n# 1 11 6\n[1,2]\n# 2 18 3\n\"s\"\n"},
+		{true, "# 0 0 8\n[{\"a\":1}# 1 11 6\n,[1,2]# 2 18 3\n,\"s\"]\n"},
+	}
+	for _, test := range tests {
+		var conf IndentConfig
+		s := NewStream(iotest.OneByteReader(strings.NewReader(in)), &conf)
+		s.SetCompact(true)
+		s.SetWrapArray(test.wrap)
+		s.SetValueHeader(header)
+		if i := s.ValueIndex(); i != -1 {
+			t.Errorf("ValueIndex() = %d; want: -1", i)
+		}
+		var dst bytes.Buffer
+		for i := int64(0); s.More(); i++ {
+			b, err := s.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			dst.Write(b)
+			if n := s.ValueIndex(); n != i {
+				t.Errorf("ValueIndex() = %d; want: %d", n, i)
+			}
+		}
+		if test.wrap {
+			dst.Write(s.closeArray())
+		}
+		if got := dst.String(); got != test.want {
+			t.Errorf("wrap=%t: got: %q want: %q", test.wrap, got, test.want)
+		}
+	}
+}
+
+func TestStreamHooks(t *testing.T) {
+	const in = `{"size_kb": 2, "tags": ["a", "b"], "ok": true}` + "\n" + `"s" 3`
+	const want = "{\"size_bytes\":2048,\"tags\":[\"A\",\"b\"],\"ok\":\"✅\"}\n\"s\"\n3\n"
+
+	var paths []string
+	hooks := Hooks{
+		OnKey: func(path Path, raw []byte) []byte {
+			if string(raw) == `"size_kb"` {
+				return []byte(`"size_bytes"`)
+			}
+			return raw
+		},
+		OnScalar: func(path Path, kind Kind, raw []byte) []byte {
+			paths = append(paths, path.String()+" "+kind.String())
+			switch path.String() {
+			case ".size_kb":
+				n, _ := strconv.Atoi(string(raw))
+				return strconv.AppendInt(nil, int64(n)*1024, 10)
+			case ".tags[0]":
+				return bytes.ToUpper(raw)
+			case ".ok":
+				return []byte(`"✅"`)
+			}
+			return raw
+		},
+	}
+	var conf IndentConfig
+	s := NewStream(iotest.OneByteReader(strings.NewReader(in)), &conf)
+	s.SetCompact(true)
+	s.SetHooks(hooks)
+	var dst bytes.Buffer
+	if _, err := s.WriteTo(&dst); err != nil {
+		t.Fatal(err)
+	}
+	if got := dst.String(); got != want {
+		t.Errorf("got: %q want: %q", got, want)
+	}
+	wantPaths := []string{
+		".size_kb number", ".tags[0] string", ".tags[1] string",
+		".ok bool", ". string", ". number",
+	}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("paths = %q; want: %q", paths, wantPaths)
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		s := NewStream(strings.NewReader(`{"a": 1}`), &conf)
+		s.SetHooks(Hooks{OnScalar: func(Path, Kind, []byte) []byte {
+			return []byte("invalid")
+		}})
+		if _, err := s.WriteTo(io.Discard); err == nil {
+			t.Error("expected an error when a hook returns invalid JSON")
+		}
+	})
+}
+
+func TestStreamWrapArray(t *testing.T) {
+	tests := []struct {
+		in       string
+		indented string
+		compact  string
+	}{
+		{"", "[]\n", "[]\n"},
+		{"1", "[\n  1\n]\n", "[1]\n"},
+		{"1\n\"a\"\n{\"b\": [2]}\n[]\n", "[\n  1,\n  \"a\",\n  {\n    \"b\": [\n      2\n    ]\n  },\n  []\n]\n", "[1,\"a\",{\"b\":[2]},[]]\n"},
+	}
+	for _, test := range tests {
+		for _, compact := range []bool{false, true} {
+			s := NewStream(iotest.OneByteReader(strings.NewReader(test.in)), &noColorIndentConfig)
+			s.SetIndent("", "  ")
+			s.SetCompact(compact)
+			s.SetWrapArray(true)
+			var dst bytes.Buffer
+			if _, err := s.WriteTo(&dst); err != nil {
+				t.Fatal(err)
+			}
+			want := test.indented
+			if compact {
+				want = test.compact
+			}
+			if got := dst.String(); got != want {
+				t.Errorf("%q: compact: %t\ngot:  %q\nwant: %q", test.in, compact, got, want)
+			}
+			if !Valid(dst.Bytes()) {
+				t.Errorf("%q: invalid JSON: %q", test.in, dst.String())
+			}
+
+			// Round trip with ArrayToLines
+			var lines bytes.Buffer
+			if err := ArrayToLines(&lines, &dst); err != nil {
+				t.Fatal(err)
+			}
+			var want2 bytes.Buffer
+			if err := noColorIndentConfig.CompactStream(&want2, strings.NewReader(test.in)); err != nil && test.in != "" {
+				t.Fatal(err)
+			}
+			if want2.Len() > 0 {
+				want2.WriteByte('\n')
+			}
+			if lines.String() != want2.String() {
+				t.Errorf("%q: ArrayToLines = %q; want: %q", test.in, lines.String(), want2.String())
+			}
+		}
+	}
+
+	t.Run("Color", func(t *testing.T) {
+		s := NewStream(strings.NewReader("1 2"), &DefaultIndentConfig)
+		s.SetWrapArray(true)
+		s.SetCompact(true)
+		var dst bytes.Buffer
+		if _, err := s.WriteTo(&dst); err != nil {
+			t.Fatal(err)
+		}
+		p := DefaultIndentConfig.Punctuation
+		want := p.Format() + "[" + p.Reset() + "1" + p.Format() + "," + p.Reset() +
+			"2" + p.Format() + "]" + p.Reset() + "\n"
+		if got := dst.String(); got != want {
+			t.Errorf("got: %q want: %q", got, want)
+		}
+	})
+}
+
+func TestStreamAddWriter(t *testing.T) {
+	const in = `{"a": [1, "b", true, null]} [2]`
+	var colored, plain, plainIndent bytes.Buffer
+
+	s := NewStream(strings.NewReader(in), &noColorIndentConfig)
+	s.SetIndent("", "    ")
+	if _, err := s.WriteTo(&plainIndent); err != nil {
+		t.Fatal(err)
+	}
+
+	s = NewStream(strings.NewReader(in), &DefaultIndentConfig)
+	s.SetIndent("", "    ")
+	var other bytes.Buffer
+	s.AddWriter(&plain, false)
+	s.AddWriter(&other, true)
+	n, err := s.WriteTo(&colored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(colored.Len()) {
+		t.Errorf("WriteTo() = %d; want: %d", n, colored.Len())
+	}
+	if !ansiRe.MatchString(colored.String()) {
+		t.Errorf("expected colored output: %q", colored.String())
+	}
+	if other.String() != colored.String() {
+		t.Errorf("colored writer: got: %q want: %q", other.String(), colored.String())
+	}
+	if plain.String() != plainIndent.String() {
+		t.Errorf("plain writer: got: %q want: %q", plain.String(), plainIndent.String())
+	}
+
+	t.Run("Error", func(t *testing.T) {
+		s := NewStream(strings.NewReader(in), &DefaultIndentConfig)
+		want := errors.New("test error")
+		s.AddWriter(&errWriter{want}, false)
+		if _, err := s.WriteTo(io.Discard); err != want {
+			t.Errorf("WriteTo() error = %v; want: %v", err, want)
+		}
+	})
+}
+
 var indentTestMap = map[string]interface{}{
 	"key😃":         "abcd",
 	"key👾":         "☺☻☹",
@@ -368,6 +882,74 @@ func TestIndentConfigNoColor(t *testing.T) {
 	})
 }
 
+func TestIndentConfigCompactStreamMultipleValues(t *testing.T) {
+	long := strings.Repeat("x", 5000) // larger than the bufio.Reader buffer
+	values := []string{
+		`{"a":[1,2,3]}`,
+		`[true,null]`,
+		`"s"`,
+		`12`,
+		`-1.5e3`,
+		`{}`,
+		`"` + long + `"`,
+		`false`,
+		`[{"` + long + `":1}]`,
+		`null`,
+	}
+	inputs := map[string]string{
+		"Newline":    strings.Join(values, "\n") + "\n",
+		"Space":      strings.Join(values, " "),
+		"BlankLines": "\n\n" + strings.Join(values, "\n\n\n") + "\n\n",
+		"Indented":   " \t" + strings.Join(values, "\r\n\t  "),
+		"Adjacent":   `{"a":[1,2,3]}[true,null]"s"12 -1.5e3{}"` + long + `"false[{"` + long + `":1}]null`,
+	}
+	readers := map[string]func(io.Reader) io.Reader{
+		"Reader":     func(r io.Reader) io.Reader { return r },
+		"OneByte":    iotest.OneByteReader,
+		"HalfRead":   iotest.HalfReader,
+		"DataErr":    iotest.DataErrReader,
+		"OneByteErr": func(r io.Reader) io.Reader { return iotest.OneByteReader(iotest.DataErrReader(r)) },
+	}
+	for _, sep := range []string{SeparatorNewline, SeparatorRS, SeparatorNUL, "\n---\n"} {
+		want := strings.Join(values, sep)
+		for inName, in := range inputs {
+			for rdName, fn := range readers {
+				var conf IndentConfig
+				var dst bytes.Buffer
+				err := conf.CompactStreamSeparator(&dst, fn(strings.NewReader(in)), sep)
+				if err != nil {
+					t.Errorf("%q/%s/%s: %v", sep, inName, rdName, err)
+					continue
+				}
+				if got := dst.String(); got != want {
+					t.Errorf("%q/%s/%s: got:\n%.200q\nwant:\n%.200q", sep, inName, rdName, got, want)
+				}
+			}
+		}
+	}
+
+	t.Run("Color", func(t *testing.T) {
+		in := inputs["Adjacent"]
+		var dst bytes.Buffer
+		if err := DefaultIndentConfig.CompactStream(&dst, strings.NewReader(in)); err != nil {
+			t.Fatal(err)
+		}
+		got := ansiRe.ReplaceAllString(dst.String(), "")
+		if want := strings.Join(values, "\n"); got != want {
+			t.Errorf("got:\n%.200q\nwant:\n%.200q", got, want)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, in := range []string{"", "  ", "[1] {", "[1]\n]", `{"a":1} {"b"}`} {
+			var conf IndentConfig
+			if err := conf.CompactStream(io.Discard, strings.NewReader(in)); err == nil {
+				t.Errorf("%q: expected an error", in)
+			}
+		}
+	})
+}
+
 func testIndentConfigIndentStream(t *testing.T, conf *IndentConfig, value interface{}) {
 	data, err := json.Marshal(value)
 	if err != nil {
@@ -653,6 +1235,15 @@ func BenchmarkIndentConfigIndent_Indent(b *testing.B) {
 			conf.Indent(&dst, codeJSON, "", "    ")
 		}
 	})
+	b.Run("NoColor", func(b *testing.B) {
+		b.SetBytes(int64(len(codeJSON)))
+		var dst bytes.Buffer
+		var conf IndentConfig
+		for i := 0; i < b.N; i++ {
+			dst.Reset()
+			conf.Indent(&dst, codeJSON, "", "    ")
+		}
+	})
 	b.Run("Baseline", func(b *testing.B) {
 		b.SetBytes(int64(len(codeJSON)))
 		var dst bytes.Buffer
@@ -663,6 +1254,67 @@ func BenchmarkIndentConfigIndent_Indent(b *testing.B) {
 	})
 }
 
+// Inputs that are already indented are mostly space, which the
+// formatters skip in bulk.
+func BenchmarkIndentConfigIndent_Indented(b *testing.B) {
+	if codeJSON == nil {
+		b.StopTimer()
+		codeInit()
+		b.StartTimer()
+	}
+	var buf bytes.Buffer
+	if err := Indent(&buf, codeJSON, "", "    "); err != nil {
+		b.Fatal(err)
+	}
+	src := buf.Bytes()
+	b.ResetTimer()
+
+	b.Run("Color", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(src)))
+		var dst bytes.Buffer
+		conf := DefaultIndentConfig
+		for i := 0; i < b.N; i++ {
+			dst.Reset()
+			conf.Indent(&dst, src, "", "    ")
+		}
+	})
+	b.Run("NoColor", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(src)))
+		var dst bytes.Buffer
+		var conf IndentConfig
+		for i := 0; i < b.N; i++ {
+			dst.Reset()
+			conf.Indent(&dst, src, "", "    ")
+		}
+	})
+	b.Run("IndentStream", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(src)))
+		r := bytes.NewReader(src)
+		conf := DefaultIndentConfig
+		for i := 0; i < b.N; i++ {
+			r.Reset(src)
+			conf.IndentStream(io.Discard, r, "", "    ")
+		}
+	})
+	b.Run("Stream", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(src)))
+		r := bytes.NewReader(src)
+		s := NewStream(r, &DefaultIndentConfig)
+		s.SetIndent("", "    ")
+		for i := 0; i < b.N; i++ {
+			r.Reset(src)
+			s.Reset(r)
+			if _, err := s.WriteTo(io.Discard); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
 func BenchmarkIndentConfigIndent_IndentStream(b *testing.B) {
 	b.ReportAllocs()
 	if codeJSON == nil {
@@ -735,6 +1387,16 @@ func BenchmarkIndentConfigIndent_Compact(b *testing.B) {
 		}
 	})
 
+	b.Run("NoColor", func(b *testing.B) {
+		b.SetBytes(int64(len(codeJSON)))
+		var conf IndentConfig
+		var dst bytes.Buffer
+		for i := 0; i < b.N; i++ {
+			dst.Reset()
+			conf.Compact(&dst, codeJSON)
+		}
+	})
+
 	b.Run("Baseline", func(b *testing.B) {
 		b.SetBytes(int64(len(codeJSON)))
 		var dst bytes.Buffer
@@ -808,3 +1470,315 @@ func BenchmarkStreamNext_Simple(b *testing.B) {
 	}
 	benchmarkStreamNext(b, data)
 }
+
+func TestStreamSortKeys(t *testing.T) {
+	in := `{"b": 1, "a": {"y": [{"d": 1, "c": 2}], "x": 2}} {"k10": 1, "k9": 2}`
+	s := NewStream(iotest.OneByteReader(strings.NewReader(in)), &noColorIndentConfig)
+	s.SetCompact(true)
+	s.SetSortKeys(NaturalOrder)
+	var dst bytes.Buffer
+	if _, err := s.WriteTo(&dst); err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"a":{"x":2,"y":[{"c":2,"d":1}]},"b":1}` + "\n" + `{"k9":2,"k10":1}` + "\n"
+	if got := dst.String(); got != want {
+		t.Errorf("got:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestStreamArrays(t *testing.T) {
+	inputs := []string{
+		`[{"b": 1, "a": [3, 2]}, 1, "s", [], {}]`,
+		`[]`,
+		` [ ] [1] 2 [[1, 2], {"y": 1, "x": {"d": 1, "c": 2}}] {"k": [1]}`,
+		"[1,\n2\n]\n\n[\ttrue ,null]",
+	}
+	confs := []IndentConfig{DefaultIndentConfig, {}}
+	for _, in := range inputs {
+		for _, conf := range confs {
+			for _, compact := range []bool{false, true} {
+				var want, got bytes.Buffer
+				for i, split := range []bool{false, true} {
+					s := NewStream(iotest.OneByteReader(strings.NewReader(in)), &conf)
+					s.SetIndent(">", "  ")
+					s.SetCompact(compact)
+					s.SetSortKeys(LexicalOrder)
+					s.SetValueDelimiter([]byte("--\n"))
+					s.SetStreamArrays(split)
+					dst := &want
+					if i == 1 {
+						dst = &got
+					}
+					if _, err := s.WriteTo(dst); err != nil {
+						t.Fatalf("%q: %v", in, err)
+					}
+				}
+				if got.String() != want.String() {
+					t.Errorf("%q (compact=%t):\ngot:  %q\nwant: %q", in, compact, got.String(), want.String())
+				}
+			}
+		}
+	}
+
+	for _, in := range []string{`[1,]`, `[1 2]`, `[1,`, `[`, `[1}`, `[{]`} {
+		s := NewStream(strings.NewReader(in), &noColorIndentConfig)
+		s.SetStreamArrays(true)
+		if _, err := s.WriteTo(io.Discard); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestStreamCompactEscapeHTML(t *testing.T) {
+	const in = "{\"z\": \"</script>\", \"a\": {\"b&c\": \"\u2028\"}}"
+	const want = `{"a":{"b\u0026c":"\u2028"},"z":"\u003c/script\u003e"}`
+	// The compact and indented output must only differ in white space.
+	for _, compact := range []bool{true, false} {
+		s := NewStream(strings.NewReader(in), &noColorIndentConfig)
+		s.SetIndent("", "  ")
+		s.SetCompact(compact)
+		s.SetSortKeys(LexicalOrder)
+		s.SetEscapeHTML(true)
+		var dst bytes.Buffer
+		if _, err := s.WriteTo(&dst); err != nil {
+			t.Fatal(err)
+		}
+		var got bytes.Buffer
+		if err := Compact(&got, dst.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want {
+			t.Errorf("compact=%t: got:  %q\nwant: %q", compact, got.String(), want)
+		}
+	}
+}
+
+func TestIndentStreamErrorResetsColor(t *testing.T) {
+	readErr := errors.New("read error")
+	readers := map[string]func() io.Reader{
+		"Syntax": func() io.Reader { return strings.NewReader("[1, \"a\x01b\"]") },
+		"Read": func() io.Reader {
+			return io.MultiReader(strings.NewReader(`{"a": "abc`), iotest.ErrReader(readErr))
+		},
+	}
+	for name, fn := range readers {
+		for _, compact := range []bool{false, true} {
+			conf := DefaultIndentConfig
+			var dst bytes.Buffer
+			var err error
+			if compact {
+				err = conf.CompactStream(&dst, fn())
+			} else {
+				err = conf.IndentStream(&dst, fn(), "", "  ")
+			}
+			if err == nil {
+				t.Errorf("%s/%t: expected an error", name, compact)
+			}
+			if got := dst.String(); !strings.HasSuffix(got, termcolor.Reset) || strings.Contains(got, "\x01") {
+				t.Errorf("%s/%t: output not reset or includes the invalid byte: %q", name, compact, got)
+			}
+		}
+	}
+}
+
+func TestStreamAtomic(t *testing.T) {
+	for _, wrap := range []bool{false, true} {
+		s := NewStream(strings.NewReader(`{"a":1} {"b" 2}`), &DefaultIndentConfig)
+		s.SetAtomic(true)
+		s.SetWrapArray(wrap)
+		var dst bytes.Buffer
+		if _, err := s.WriteTo(&dst); err == nil {
+			t.Errorf("%t: expected an error", wrap)
+		}
+		if dst.Len() != 0 {
+			t.Errorf("%t: wrote output for invalid input: %q", wrap, dst.String())
+		}
+
+		const in = `{"a":1} [2] "c"`
+		var want bytes.Buffer
+		s = NewStream(strings.NewReader(in), &DefaultIndentConfig)
+		s.SetWrapArray(wrap)
+		if _, err := s.WriteTo(&want); err != nil {
+			t.Fatal(err)
+		}
+		s.Reset(strings.NewReader(in))
+		s.SetAtomic(true)
+		n, err := s.WriteTo(&dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dst.String() != want.String() || n != int64(want.Len()) {
+			t.Errorf("%t: got: %d %q; want: %q", wrap, n, dst.String(), want.String())
+		}
+	}
+}
+
+func TestStreamRawStrings(t *testing.T) {
+	in := `"a\tbé" {"x":"y"} "\"q\"" ["z"]`
+	want := "a\tbé\n{\n  \"x\": \"y\"\n}\n\"q\"\n[\n  \"z\"\n]\n"
+	s := NewStream(iotest.OneByteReader(strings.NewReader(in)), &noColorIndentConfig)
+	s.SetIndent("", "  ")
+	s.SetRawStrings(true)
+	var dst bytes.Buffer
+	if _, err := s.WriteTo(&dst); err != nil {
+		t.Fatal(err)
+	}
+	if got := dst.String(); got != want {
+		t.Errorf("got: %q; want: %q", got, want)
+	}
+}
+
+func TestStreamMaxDepth(t *testing.T) {
+	const in = `{"a": 1, "b": {"c": [1, [2]], "d": {}}, "e": [], "f": [{"g": "}"}]}`
+	tests := []struct {
+		depth    int
+		compact  bool
+		annotate bool
+		split    bool
+		want     string
+	}{
+		{depth: 0, compact: true, want: `{"a":1,"b":{"c":[1,[2]],"d":{}},"e":[],"f":[{"g":"}"}]}` + "\n"},
+		{depth: 1, compact: true, want: `{"a":1,"b":{...},"e":[],"f":[...]}` + "\n"},
+		{depth: 2, compact: true, want: `{"a":1,"b":{"c":[...],"d":{}},"e":[],"f":[{...}]}` + "\n"},
+		{depth: 1, want: "{\n  \"a\": 1,\n  \"b\": {...},\n  \"e\": [],\n  \"f\": [...]\n}\n"},
+		{depth: 1, annotate: true, want: "{\n  \"a\": 1,\n  \"b\": {...}, // 2 keys, 24 B\n" +
+			"  \"e\": [],\n  \"f\": [...] // 1 item, 12 B\n} // 4 keys, 67 B\n"},
+		{depth: 1, split: true, want: "[\n  {...}\n]\n"},
+	}
+	for _, test := range tests {
+		src := in
+		if test.split {
+			src = "[" + in + "]"
+		}
+		s := NewStream(iotest.OneByteReader(strings.NewReader(src)), &noColorIndentConfig)
+		s.SetIndent("", "  ")
+		s.SetCompact(test.compact)
+		s.SetAnnotate(test.annotate)
+		s.SetStreamArrays(test.split)
+		s.SetMaxDepth(test.depth)
+		var dst bytes.Buffer
+		if _, err := s.WriteTo(&dst); err != nil {
+			t.Errorf("%+v: %v", test, err)
+			continue
+		}
+		if got := dst.String(); got != test.want {
+			t.Errorf("%+v:\ngot:  %q\nwant: %q", test, got, test.want)
+		}
+	}
+
+	// Elided values are still validated.
+	s := NewStream(strings.NewReader(`{"a": {"b": [x]}}`), &noColorIndentConfig)
+	s.SetMaxDepth(1)
+	if _, err := s.WriteTo(io.Discard); err == nil {
+		t.Error("expected an error for invalid elided value")
+	}
+}
+
+func TestStreamMaxValueSize(t *testing.T) {
+	large := `{"a": "` + strings.Repeat("x", 4096) + `"}`
+	s := NewStream(iotest.OneByteReader(strings.NewReader(`[1] `+large)), &noColorIndentConfig)
+	s.SetCompact(true)
+	s.SetMaxValueSize(1024)
+	var dst bytes.Buffer
+	_, err := s.WriteTo(&dst)
+	var se *StreamError
+	if !errors.As(err, &se) || !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("got error: %v; want: %v", err, ErrValueTooLarge)
+	}
+	if se.Index != 1 {
+		t.Errorf("Index = %d; want: 1", se.Index)
+	}
+	if got := dst.String(); got != "[1]\n" {
+		t.Errorf("got: %q; want: %q", got, "[1]\n")
+	}
+
+	// The limit applies to each element of streamed arrays.
+	s.Reset(strings.NewReader(`[` + large + `,` + large + `]`))
+	s.SetStreamArrays(true)
+	s.SetMaxValueSize(8192)
+	if _, err := s.WriteTo(io.Discard); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStreamReadBuffer(t *testing.T) {
+	const in = `{"a": 1} [2, 3] "b"`
+	s := NewStream(nil, &noColorIndentConfig)
+	s.SetCompact(true)
+	for _, size := range []int{16, 64 * 1024} {
+		s.SetReadBuffer(size)
+		s.Reset(strings.NewReader(in))
+		var dst bytes.Buffer
+		if _, err := s.WriteTo(&dst); err != nil {
+			t.Fatal(err)
+		}
+		if got := s.r.Size(); got != size {
+			t.Errorf("read buffer size = %d; want: %d", got, size)
+		}
+		if want := "{\"a\":1}\n[2,3]\n\"b\"\n"; dst.String() != want {
+			t.Errorf("%d: got: %q; want: %q", size, dst.String(), want)
+		}
+	}
+}
+
+func TestStreamSample(t *testing.T) {
+	const in = `[{"a": [1, 2, 3]}, [4], 5, 6] [1]`
+	tests := []struct {
+		compact bool
+		want    string
+	}{
+		{true, `[{"a":[1,"… 2 more elements"]},"… 3 more elements"]` + "\n[1]\n"},
+		{false, "[\n  {\n    \"a\": [\n      1,\n      \"… 2 more elements\"\n    ]\n  },\n" +
+			"  \"… 3 more elements\"\n]\n[\n  1\n]\n"},
+	}
+	for _, test := range tests {
+		for _, split := range []bool{false, true} {
+			s := NewStream(iotest.OneByteReader(strings.NewReader(in)), &noColorIndentConfig)
+			s.SetIndent("", "  ")
+			s.SetCompact(test.compact)
+			s.SetStreamArrays(split)
+			s.SetSample(1)
+			var dst bytes.Buffer
+			if _, err := s.WriteTo(&dst); err != nil {
+				t.Fatal(err)
+			}
+			if got := dst.String(); got != test.want {
+				t.Errorf("compact: %t split: %t:\ngot:  %q\nwant: %q", test.compact, split, got, test.want)
+			}
+		}
+	}
+}
+
+func TestStreamLastValueFalsy(t *testing.T) {
+	tests := []struct {
+		in    string
+		query string
+		split bool
+		want  bool
+	}{
+		{`1 null`, "", false, true},
+		{`null 1`, "", false, false},
+		{`true false`, "", false, true},
+		{`{"a": false}`, "", false, false},
+		{`{"a": false}`, ".a", false, true},
+		{`{"a": [1, null]}`, ".a[]", false, true},
+		{`[1, null]`, "", true, false},
+	}
+	for _, test := range tests {
+		s := NewStream(strings.NewReader(test.in), &noColorIndentConfig)
+		s.SetStreamArrays(test.split)
+		if test.query != "" {
+			q, err := ParseQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.SetQuery(q)
+		}
+		if _, err := s.WriteTo(io.Discard); err != nil {
+			t.Fatal(err)
+		}
+		if got := s.LastValueFalsy(); got != test.want {
+			t.Errorf("%s %s: LastValueFalsy() = %t; want: %t", test.in, test.query, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,787 @@
+package pjson
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// A JSONPath is a query in the JSONPath syntax of RFC 9535, such as
+// `$.store.book[?@.price < 10].title`. All selectors are supported: names,
+// wildcards, indices, slices and filters, and child and descendant
+// segments. Filters may compare singular queries and literals and test
+// for the existence of a query, combined with !, && and ||; the function
+// extensions are not supported.
+type JSONPath struct {
+	expr     string
+	segments []pathSegment
+}
+
+// A pathSegment is a child segment, or a descendant segment if desc is
+// true, with its selectors.
+type pathSegment struct {
+	desc      bool
+	selectors []pathSelector
+}
+
+type selectorKind int8
+
+const (
+	selectName selectorKind = iota
+	selectWildcard
+	selectIndex
+	selectSlice
+	selectFilter
+)
+
+type pathSelector struct {
+	kind   selectorKind
+	name   string
+	index  int   // index, or the start of a slice
+	end    int   // end of a slice
+	step   int   // step of a slice
+	bounds uint8 // hasStart | hasEnd of a slice
+	filter pathExpr
+}
+
+const (
+	hasStart = 1 << iota
+	hasEnd
+)
+
+// String returns the expression p was parsed from.
+func (p *JSONPath) String() string { return p.expr }
+
+// ParseJSONPath parses the JSONPath query expr, which must begin with the
+// root identifier "$".
+func ParseJSONPath(expr string) (*JSONPath, error) {
+	ps := pathParser{s: expr}
+	ps.space()
+	if !ps.consume("$") {
+		return nil, ps.errorf("expected '$'")
+	}
+	segments, err := ps.segments()
+	if err == nil {
+		ps.space()
+		if !ps.eof() {
+			err = ps.errorf("unexpected %q", ps.s[ps.i:])
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("pjson: invalid JSONPath %q: %w", expr, err)
+	}
+	return &JSONPath{expr: expr, segments: segments}, nil
+}
+
+// Apply appends the values of the nodes selected by p in the JSON value
+// src to dst and returns the extended slice. The values are compact JSON
+// and appended in document order for each segment, as RFC 9535 requires.
+func (p *JSONPath) Apply(dst [][]byte, src []byte) ([][]byte, error) {
+	var buf bytes.Buffer
+	if err := compact(&buf, src, false); err != nil {
+		return dst, err
+	}
+	root := buf.Bytes()
+	return append(dst, selectPath(p.segments, root, root)...), nil
+}
+
+// selectPath returns the nodes selected by segments starting at node.
+func selectPath(segments []pathSegment, root, node []byte) [][]byte {
+	nodes := [][]byte{node}
+	for _, seg := range segments {
+		var next [][]byte
+		for _, n := range nodes {
+			if seg.desc {
+				next = seg.selectDescendants(next, root, n)
+			} else {
+				next = seg.selectChildren(next, root, n)
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+func (seg *pathSegment) selectChildren(dst [][]byte, root, node []byte) [][]byte {
+	for i := range seg.selectors {
+		dst = seg.selectors[i].apply(dst, root, node)
+	}
+	return dst
+}
+
+// selectDescendants applies the selectors of seg to node and each of its
+// descendants, in document order.
+func (seg *pathSegment) selectDescendants(dst [][]byte, root, node []byte) [][]byte {
+	dst = seg.selectChildren(dst, root, node)
+	switch kindOf(node[0]) {
+	case KindArray:
+		for _, e := range arrayElems(nil, node) {
+			dst = seg.selectDescendants(dst, root, e)
+		}
+	case KindObject:
+		for _, m := range objectMembers(nil, node) {
+			dst = seg.selectDescendants(dst, root, m.value)
+		}
+	}
+	return dst
+}
+
+func (sel *pathSelector) apply(dst [][]byte, root, node []byte) [][]byte {
+	switch kind := kindOf(node[0]); sel.kind {
+	case selectName:
+		if kind == KindObject {
+			if v := memberValue(node, sel.name); v != nil {
+				dst = append(dst, v)
+			}
+		}
+	case selectWildcard:
+		dst = appendChildren(dst, node)
+	case selectIndex:
+		if kind == KindArray {
+			elems := arrayElems(nil, node)
+			i := sel.index
+			if i < 0 {
+				i += len(elems)
+			}
+			if 0 <= i && i < len(elems) {
+				dst = append(dst, elems[i])
+			}
+		}
+	case selectSlice:
+		if kind == KindArray {
+			elems := arrayElems(nil, node)
+			for _, i := range sel.sliceIndices(len(elems)) {
+				dst = append(dst, elems[i])
+			}
+		}
+	case selectFilter:
+		for _, child := range appendChildren(nil, node) {
+			if sel.filter.test(root, child) {
+				dst = append(dst, child)
+			}
+		}
+	}
+	return dst
+}
+
+// appendChildren appends the elements or member values of node to dst.
+func appendChildren(dst [][]byte, node []byte) [][]byte {
+	switch kindOf(node[0]) {
+	case KindArray:
+		dst = arrayElems(dst, node)
+	case KindObject:
+		for _, m := range objectMembers(nil, node) {
+			dst = append(dst, m.value)
+		}
+	}
+	return dst
+}
+
+// sliceIndices returns the indices selected by the slice sel of an array
+// of length n, as defined by RFC 9535 section 2.3.4.2.
+func (sel *pathSelector) sliceIndices(n int) []int {
+	step := sel.step
+	if step == 0 {
+		return nil
+	}
+	normalize := func(i int) int {
+		if i < 0 {
+			return n + i
+		}
+		return i
+	}
+	clamp := func(i, lo, hi int) int {
+		if i < lo {
+			return lo
+		}
+		if i > hi {
+			return hi
+		}
+		return i
+	}
+	start, end := 0, n
+	if step < 0 {
+		start, end = n-1, -n-1
+	}
+	if sel.bounds&hasStart != 0 {
+		start = sel.index
+	}
+	if sel.bounds&hasEnd != 0 {
+		end = sel.end
+	}
+	var indices []int
+	if step > 0 {
+		lower := clamp(normalize(start), 0, n)
+		upper := clamp(normalize(end), 0, n)
+		for i := lower; i < upper; i += step {
+			indices = append(indices, i)
+		}
+	} else {
+		upper := clamp(normalize(start), -1, n-1)
+		lower := clamp(normalize(end), -1, n-1)
+		for i := upper; lower < i; i += step {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// A pathExpr is a filter expression.
+type pathExpr interface {
+	test(root, node []byte) bool
+}
+
+type (
+	orExpr  []pathExpr
+	andExpr []pathExpr
+	notExpr struct{ x pathExpr }
+
+	// existExpr tests that the query selects at least one node.
+	existExpr struct{ q *filterQuery }
+
+	compareExpr struct {
+		op          string
+		left, right pathOperand
+	}
+)
+
+func (e orExpr) test(root, node []byte) bool {
+	for _, x := range e {
+		if x.test(root, node) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e andExpr) test(root, node []byte) bool {
+	for _, x := range e {
+		if !x.test(root, node) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e notExpr) test(root, node []byte) bool { return !e.x.test(root, node) }
+
+func (e existExpr) test(root, node []byte) bool {
+	return len(e.q.eval(root, node)) > 0
+}
+
+// A filterQuery is a query relative to the current node (@) or the root
+// ($) in a filter.
+type filterQuery struct {
+	relative bool
+	segments []pathSegment
+}
+
+func (q *filterQuery) eval(root, node []byte) [][]byte {
+	if !q.relative {
+		node = root
+	}
+	return selectPath(q.segments, root, node)
+}
+
+// A pathOperand is a literal or a singular query of a comparison.
+type pathOperand struct {
+	literal []byte // compact JSON
+	query   *filterQuery
+}
+
+// value returns the value of c or nil if it is a query that selects
+// nothing.
+func (c *pathOperand) value(root, node []byte) []byte {
+	if c.query == nil {
+		return c.literal
+	}
+	if nodes := c.query.eval(root, node); len(nodes) == 1 {
+		return nodes[0]
+	}
+	return nil
+}
+
+func (e *compareExpr) test(root, node []byte) bool {
+	a := e.left.value(root, node)
+	b := e.right.value(root, node)
+	switch e.op {
+	case "==":
+		return pathEqual(a, b)
+	case "!=":
+		return !pathEqual(a, b)
+	case "<":
+		return pathLess(a, b)
+	case ">":
+		return pathLess(b, a)
+	case "<=":
+		return pathLess(a, b) || pathEqual(a, b)
+	case ">=":
+		return pathLess(b, a) || pathEqual(a, b)
+	}
+	return false
+}
+
+// pathEqual reports whether the values a and b, which are nil if absent,
+// are equal.
+func pathEqual(a, b []byte) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if kindOf(a[0]) != kindOf(b[0]) {
+		return false
+	}
+	if kindOf(a[0]) == KindNumber {
+		x, y, ok := parseNumbers(a, b)
+		return ok && x.Cmp(y) == 0
+	}
+	return bytes.Equal(canonical(nil, a), canonical(nil, b))
+}
+
+// pathLess reports whether a is less than b. Only numbers and strings are
+// ordered.
+func pathLess(a, b []byte) bool {
+	if a == nil || b == nil || kindOf(a[0]) != kindOf(b[0]) {
+		return false
+	}
+	switch kindOf(a[0]) {
+	case KindNumber:
+		x, y, ok := parseNumbers(a, b)
+		return ok && x.Cmp(y) < 0
+	case KindString:
+		// Compare by code point, which is the order of UTF-8 bytes.
+		sa, _ := unquote(a)
+		sb, _ := unquote(b)
+		return sa < sb
+	}
+	return false
+}
+
+func parseNumbers(a, b []byte) (x, y *big.Float, ok bool) {
+	x, _, errA := big.ParseFloat(string(a), 10, diffPrec, big.ToNearestEven)
+	y, _, errB := big.ParseFloat(string(b), 10, diffPrec, big.ToNearestEven)
+	return x, y, errA == nil && errB == nil
+}
+
+// pathParser parses JSONPath queries.
+type pathParser struct {
+	s string
+	i int
+}
+
+func (p *pathParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("offset %d: "+format, append([]interface{}{p.i}, args...)...)
+}
+
+func (p *pathParser) eof() bool { return p.i >= len(p.s) }
+
+func (p *pathParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.i]
+}
+
+func (p *pathParser) consume(prefix string) bool {
+	if strings.HasPrefix(p.s[p.i:], prefix) {
+		p.i += len(prefix)
+		return true
+	}
+	return false
+}
+
+func (p *pathParser) space() {
+	for !p.eof() && strings.IndexByte(" \t\n\r", p.s[p.i]) >= 0 {
+		p.i++
+	}
+}
+
+// segments parses the segments that follow a root or current node
+// identifier.
+func (p *pathParser) segments() ([]pathSegment, error) {
+	var segments []pathSegment
+	for {
+		// Blank space may precede a segment but is part of the
+		// enclosing filter if no segment follows.
+		save := p.i
+		p.space()
+		var seg pathSegment
+		switch {
+		case p.consume(".."):
+			seg.desc = true
+			if p.peek() == '[' {
+				break
+			}
+			sel, err := p.shorthand()
+			if err != nil {
+				return nil, err
+			}
+			seg.selectors = []pathSelector{sel}
+			segments = append(segments, seg)
+			continue
+		case p.consume("."):
+			sel, err := p.shorthand()
+			if err != nil {
+				return nil, err
+			}
+			seg.selectors = []pathSelector{sel}
+			segments = append(segments, seg)
+			continue
+		case p.peek() == '[':
+		default:
+			p.i = save
+			return segments, nil
+		}
+		sels, err := p.bracketed()
+		if err != nil {
+			return nil, err
+		}
+		seg.selectors = sels
+		segments = append(segments, seg)
+	}
+}
+
+func isNameFirst(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c >= utf8.RuneSelf
+}
+
+// shorthand parses the wildcard or member name that follows a '.'.
+func (p *pathParser) shorthand() (pathSelector, error) {
+	if p.consume("*") {
+		return pathSelector{kind: selectWildcard}, nil
+	}
+	start := p.i
+	if p.eof() || !isNameFirst(p.s[p.i]) {
+		return pathSelector{}, p.errorf("expected a member name or '*'")
+	}
+	for !p.eof() && (isNameFirst(p.s[p.i]) || '0' <= p.s[p.i] && p.s[p.i] <= '9') {
+		p.i++
+	}
+	return pathSelector{kind: selectName, name: p.s[start:p.i]}, nil
+}
+
+// bracketed parses a bracketed selection: a comma separated list of
+// selectors.
+func (p *pathParser) bracketed() ([]pathSelector, error) {
+	p.consume("[")
+	var sels []pathSelector
+	for {
+		p.space()
+		sel, err := p.selector()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+		p.space()
+		if p.consume("]") {
+			return sels, nil
+		}
+		if !p.consume(",") {
+			return nil, p.errorf("expected ',' or ']'")
+		}
+	}
+}
+
+func (p *pathParser) selector() (pathSelector, error) {
+	switch c := p.peek(); {
+	case c == '\'' || c == '"':
+		name, err := p.str()
+		return pathSelector{kind: selectName, name: name}, err
+	case c == '*':
+		p.i++
+		return pathSelector{kind: selectWildcard}, nil
+	case c == '?':
+		p.i++
+		x, err := p.logicalOr()
+		return pathSelector{kind: selectFilter, filter: x}, err
+	}
+	sel := pathSelector{kind: selectIndex, step: 1}
+	if n, ok, err := p.integer(); err != nil {
+		return sel, err
+	} else if ok {
+		sel.index = n
+		sel.bounds |= hasStart
+	}
+	p.space()
+	if !p.consume(":") {
+		if sel.bounds&hasStart == 0 {
+			return sel, p.errorf("expected a selector")
+		}
+		return sel, nil
+	}
+	sel.kind = selectSlice
+	p.space()
+	if n, ok, err := p.integer(); err != nil {
+		return sel, err
+	} else if ok {
+		sel.end = n
+		sel.bounds |= hasEnd
+	}
+	p.space()
+	if p.consume(":") {
+		p.space()
+		if n, ok, err := p.integer(); err != nil {
+			return sel, err
+		} else if ok {
+			sel.step = n
+		}
+	}
+	return sel, nil
+}
+
+// integer parses an integer, if there is one.
+func (p *pathParser) integer() (int, bool, error) {
+	start := p.i
+	p.consume("-")
+	for !p.eof() && '0' <= p.s[p.i] && p.s[p.i] <= '9' {
+		p.i++
+	}
+	if p.i == start {
+		return 0, false, nil
+	}
+	s := p.s[start:p.i]
+	if s == "-0" || len(s) > 1 && s[0] == '0' || strings.HasPrefix(s, "-0") {
+		return 0, false, p.errorf("invalid integer %q", s)
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false, p.errorf("invalid integer %q", s)
+	}
+	return n, true, nil
+}
+
+// str parses a single or double quoted string literal.
+func (p *pathParser) str() (string, error) {
+	quote := p.s[p.i]
+	p.i++
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.s[p.i]
+		p.i++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c < ' ':
+			return "", p.errorf("control character in string")
+		case c != '\\':
+			b.WriteByte(c)
+			continue
+		}
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		c = p.s[p.i]
+		p.i++
+		switch c {
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '/', '\\':
+			b.WriteByte(c)
+		case '\'', '"':
+			if c != quote {
+				return "", p.errorf("invalid escape \\%c", c)
+			}
+			b.WriteByte(c)
+		case 'u':
+			r, err := p.hex4()
+			if err != nil {
+				return "", err
+			}
+			if utf16.IsSurrogate(r) {
+				if !p.consume(`\u`) {
+					return "", p.errorf("invalid surrogate pair")
+				}
+				r2, err := p.hex4()
+				if err != nil {
+					return "", err
+				}
+				if r = utf16.DecodeRune(r, r2); r == utf8.RuneError {
+					return "", p.errorf("invalid surrogate pair")
+				}
+			}
+			b.WriteRune(r)
+		default:
+			return "", p.errorf("invalid escape \\%c", c)
+		}
+	}
+}
+
+func (p *pathParser) hex4() (rune, error) {
+	if p.i+4 > len(p.s) {
+		return 0, p.errorf("invalid \\u escape")
+	}
+	n, err := strconv.ParseUint(p.s[p.i:p.i+4], 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid \\u escape")
+	}
+	p.i += 4
+	return rune(n), nil
+}
+
+func (p *pathParser) logicalOr() (pathExpr, error) {
+	var or orExpr
+	for {
+		x, err := p.logicalAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, x)
+		p.space()
+		if !p.consume("||") {
+			break
+		}
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *pathParser) logicalAnd() (pathExpr, error) {
+	var and andExpr
+	for {
+		x, err := p.basic()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, x)
+		p.space()
+		if !p.consume("&&") {
+			break
+		}
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+// basic parses a parenthesized expression, a comparison or a test, any of
+// which may be negated with '!' unless it is a comparison.
+func (p *pathParser) basic() (pathExpr, error) {
+	p.space()
+	if p.consume("!") {
+		p.space()
+		x, err := p.negatable()
+		return notExpr{x}, err
+	}
+	if p.peek() == '(' {
+		return p.negatable()
+	}
+	left, err := p.pathOperand()
+	if err != nil {
+		return nil, err
+	}
+	p.space()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(op) {
+			p.space()
+			right, err := p.pathOperand()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.checkComparable(left); err != nil {
+				return nil, err
+			}
+			if err := p.checkComparable(right); err != nil {
+				return nil, err
+			}
+			return &compareExpr{op: op, left: left, right: right}, nil
+		}
+	}
+	if left.query == nil {
+		return nil, p.errorf("expected a comparison operator")
+	}
+	return existExpr{left.query}, nil
+}
+
+// negatable parses a parenthesized expression or test that follows '!'.
+func (p *pathParser) negatable() (pathExpr, error) {
+	if p.consume("(") {
+		x, err := p.logicalOr()
+		if err != nil {
+			return nil, err
+		}
+		p.space()
+		if !p.consume(")") {
+			return nil, p.errorf("expected ')'")
+		}
+		return x, nil
+	}
+	c, err := p.pathOperand()
+	if err != nil {
+		return nil, err
+	}
+	if c.query == nil {
+		return nil, p.errorf("expected a query after '!'")
+	}
+	return existExpr{c.query}, nil
+}
+
+// checkComparable returns an error if the query of c may select more
+// than one node.
+func (p *pathParser) checkComparable(c pathOperand) error {
+	if c.query == nil {
+		return nil
+	}
+	for _, seg := range c.query.segments {
+		if seg.desc || len(seg.selectors) != 1 {
+			return p.errorf("comparison of a query that is not singular")
+		}
+		if k := seg.selectors[0].kind; k != selectName && k != selectIndex {
+			return p.errorf("comparison of a query that is not singular")
+		}
+	}
+	return nil
+}
+
+// pathOperand parses a literal or a filter query.
+func (p *pathParser) pathOperand() (pathOperand, error) {
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		p.i++
+		segments, err := p.segments()
+		return pathOperand{query: &filterQuery{relative: c == '@', segments: segments}}, err
+	case c == '\'' || c == '"':
+		s, err := p.str()
+		return pathOperand{literal: appendQuoted(nil, s)}, err
+	case c == '-' || '0' <= c && c <= '9':
+		start := p.i
+		p.consume("-")
+		for !p.eof() && strings.IndexByte("0123456789.eE+-", p.s[p.i]) >= 0 {
+			p.i++
+		}
+		lit := p.s[start:p.i]
+		if !Valid([]byte(lit)) {
+			return pathOperand{}, p.errorf("invalid number %q", lit)
+		}
+		return pathOperand{literal: []byte(lit)}, nil
+	}
+	for _, lit := range []string{"true", "false", "null"} {
+		if p.consume(lit) {
+			return pathOperand{literal: []byte(lit)}, nil
+		}
+	}
+	start := p.i
+	for !p.eof() && isNameFirst(p.s[p.i]) {
+		p.i++
+	}
+	if p.i > start && p.peek() == '(' {
+		return pathOperand{}, p.errorf("unsupported function %s()", p.s[start:p.i])
+	}
+	return pathOperand{}, p.errorf("expected a query or literal")
+}
@@ -0,0 +1,105 @@
+package pjson
+
+import (
+	"bytes"
+	"testing"
+)
+
+// jsonPathStore is the example document of RFC 9535 section 1.5.
+const jsonPathStore = `{ "store": {
+    "book": [
+      { "category": "reference", "author": "Nigel Rees",
+        "title": "Sayings of the Century", "price": 8.95 },
+      { "category": "fiction", "author": "Evelyn Waugh",
+        "title": "Sword of Honour", "price": 12.99 },
+      { "category": "fiction", "author": "Herman Melville",
+        "title": "Moby Dick", "isbn": "0-553-21311-3", "price": 8.99 },
+      { "category": "fiction", "author": "J. R. R. Tolkien",
+        "title": "The Lord of the Rings", "isbn": "0-395-19395-8",
+        "price": 22.99 }
+    ],
+    "bicycle": { "color": "red", "price": 399 }
+  } }`
+
+func TestJSONPath(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string // results joined by spaces
+	}{
+		{`$`, `{"store":{"book":[{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99},{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99},{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99}],"bicycle":{"color":"red","price":399}}}`},
+		{`$.store.book[*].author`, `"Nigel Rees" "Evelyn Waugh" "Herman Melville" "J. R. R. Tolkien"`},
+		{`$..author`, `"Nigel Rees" "Evelyn Waugh" "Herman Melville" "J. R. R. Tolkien"`},
+		{`$.store.*.color`, `"red"`},
+		{`$.store..price`, `8.95 12.99 8.99 22.99 399`},
+		{`$..book[2].title`, `"Moby Dick"`},
+		{`$..book[-1].title`, `"The Lord of the Rings"`},
+		{`$..book[0,1].price`, `8.95 12.99`},
+		{`$..book[:2].price`, `8.95 12.99`},
+		{`$..book[::-2].price`, `22.99 12.99`},
+		{`$..book[1:3:1].price`, `12.99 8.99`},
+		{`$..book[?@.isbn].title`, `"Moby Dick" "The Lord of the Rings"`},
+		{`$..book[?!@.isbn].price`, `8.95 12.99`},
+		{`$..book[?@.price<10].title`, `"Sayings of the Century" "Moby Dick"`},
+		{`$..book[?@.price < 10 && @.category == 'fiction'].title`, `"Moby Dick"`},
+		{`$..book[?(@.price > 20 || @.author == "Nigel Rees")].price`, `8.95 22.99`},
+		{`$..book[?@.price == $.store.bicycle.price]`, ``},
+		{`$.store["bicycle"]['color']`, `"red"`},
+		{`$.store.book[9]`, ``},
+		{`$.store.bicycle[0]`, ``},
+		{`$["st\u006fre"].bicycle.price`, `399`},
+	}
+	for _, test := range tests {
+		p, err := ParseJSONPath(test.expr)
+		if err != nil {
+			t.Errorf("ParseJSONPath(%q): %v", test.expr, err)
+			continue
+		}
+		vals, err := p.Apply(nil, []byte(jsonPathStore))
+		if err != nil {
+			t.Errorf("%q: Apply: %v", test.expr, err)
+			continue
+		}
+		if got := string(bytes.Join(vals, []byte(" "))); got != test.want {
+			t.Errorf("%q:\ngot:  %s\nwant: %s", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestJSONPathSlice(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`$[1:3]`, `1 2`},
+		{`$[5:]`, `5 6`},
+		{`$[-2:]`, `5 6`},
+		{`$[1:5:2]`, `1 3`},
+		{`$[5:1:-2]`, `5 3`},
+		{`$[::-1]`, `6 5 4 3 2 1 0`},
+		{`$[::0]`, ``},
+		{`$[10:]`, ``},
+	}
+	for _, test := range tests {
+		p, err := ParseJSONPath(test.expr)
+		if err != nil {
+			t.Errorf("ParseJSONPath(%q): %v", test.expr, err)
+			continue
+		}
+		vals, _ := p.Apply(nil, []byte(`[0, 1, 2, 3, 4, 5, 6]`))
+		if got := string(bytes.Join(vals, []byte(" "))); got != test.want {
+			t.Errorf("%q: got: %s; want: %s", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestParseJSONPathError(t *testing.T) {
+	for _, expr := range []string{
+		"", "a", "$.", "$[", "$[1", "$['a]", "$[01]", "$[-0]", "$.a b",
+		"$[?@.a == ]", "$[?@..a == 1]", "$[?@.* == 1]", "$[?length(@) == 1]",
+		`$["\q"]`, "$[?1]",
+	} {
+		if _, err := ParseJSONPath(expr); err == nil {
+			t.Errorf("ParseJSONPath(%q): expected an error", expr)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package pjson
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// A KeyOrder compares the object keys a and b and returns -1 if a sorts
+// before b, +1 if a sorts after b and 0 if they are equal.
+type KeyOrder func(a, b string) int
+
+// LexicalOrder orders keys by their bytes. This is the order used by
+// Marshal for maps.
+func LexicalOrder(a, b string) int { return strings.Compare(a, b) }
+
+// NaturalOrder orders keys lexically, except that runs of digits are
+// compared by their numeric value so "item2" sorts before "item10".
+func NaturalOrder(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if !isDigit(a[i]) || !isDigit(b[j]) {
+			if a[i] != b[j] {
+				return cmpByte(a[i], b[j])
+			}
+			i++
+			j++
+			continue
+		}
+		si, sj := i, j
+		for i < len(a) && isDigit(a[i]) {
+			i++
+		}
+		for j < len(b) && isDigit(b[j]) {
+			j++
+		}
+		da := strings.TrimLeft(a[si:i], "0")
+		db := strings.TrimLeft(b[sj:j], "0")
+		if len(da) != len(db) {
+			return cmpInt(len(da), len(db))
+		}
+		if c := strings.Compare(da, db); c != 0 {
+			return c
+		}
+	}
+	if c := cmpInt(len(a)-i, len(b)-j); c != 0 {
+		return c
+	}
+	// Break ties, such as "a01" and "a1", lexically.
+	return strings.Compare(a, b)
+}
+
+// NumericOrder orders keys that are JSON numbers, such as "2" and "1e3",
+// by their value before all other keys, which are ordered lexically.
+func NumericOrder(a, b string) int {
+	x, okA := parseNumericKey(a)
+	y, okB := parseNumericKey(b)
+	switch {
+	case okA && okB:
+		if x < y {
+			return -1
+		}
+		if x > y {
+			return 1
+		}
+	case okA:
+		return -1
+	case okB:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// CaseInsensitiveOrder orders keys lexically ignoring case, so "apple"
+// sorts before "Banana". Keys that differ only in case are ordered
+// lexically.
+func CaseInsensitiveOrder(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		r1, n1 := utf8.DecodeRuneInString(a[i:])
+		r2, n2 := utf8.DecodeRuneInString(b[j:])
+		if r1, r2 = unicode.ToLower(r1), unicode.ToLower(r2); r1 != r2 {
+			if r1 < r2 {
+				return -1
+			}
+			return 1
+		}
+		i += n1
+		j += n2
+	}
+	if c := cmpInt(len(a)-i, len(b)-j); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// CollateOrder returns a KeyOrder that orders keys by the Unicode
+// collation rules of the language tag, so that, for "de", "Äpfel"
+// sorts with "apple" rather than after "zebra". Keys that collate equally
+// are ordered lexically. The returned KeyOrder is not safe for concurrent
+// use.
+func CollateOrder(tag language.Tag) KeyOrder {
+	c := collate.New(tag)
+	return func(a, b string) int {
+		if n := c.CompareString(a, b); n != 0 {
+			return n
+		}
+		return strings.Compare(a, b)
+	}
+}
+
+func parseNumericKey(s string) (float64, bool) {
+	if !isValidNumber(s) {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+func cmpByte(a, b byte) int {
+	if a < b {
+		return -1
+	}
+	return 1
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// keyOrders are the KeyOrders returned by ParseKeyOrder.
+var keyOrders = map[string]KeyOrder{
+	"lexical": LexicalOrder,
+	"natural": NaturalOrder,
+	"numeric": NumericOrder,
+	"ci":      CaseInsensitiveOrder,
+}
+
+// ParseKeyOrder returns the KeyOrder with the given name: "lexical",
+// "natural", "numeric", "ci" (case-insensitive) or "collate:LANG", where
+// LANG is a BCP 47 language tag such as "de" or "sv".
+func ParseKeyOrder(name string) (KeyOrder, error) {
+	if order, ok := keyOrders[name]; ok {
+		return order, nil
+	}
+	if lang := strings.TrimPrefix(name, "collate:"); lang != name {
+		tag, err := language.Parse(lang)
+		if err != nil {
+			return nil, fmt.Errorf("pjson: invalid key order: %q: %w", name, err)
+		}
+		return CollateOrder(tag), nil
+	}
+	return nil, fmt.Errorf("pjson: invalid key order: %q (supported orders: "+
+		"lexical, natural, numeric, ci, collate:LANG)", name)
+}
+
+// SortKeys appends the compact encoding of the JSON value src with the
+// members of each object sorted by order to dst. Members with equal keys
+// retain their relative order. If order is nil LexicalOrder is used.
+func SortKeys(dst, src []byte, order KeyOrder) ([]byte, error) {
+	ks := keySorter{order: order}
+	return ks.sort(dst, src)
+}
+
+// A keySorter sorts the keys of objects. It retains its buffers between
+// calls to sort so that a Stream sorting many values does not allocate.
+type keySorter struct {
+	order   KeyOrder
+	buf     bytes.Buffer
+	members [][]member // members of the objects being sorted, by depth
+	depth   int
+}
+
+func (ks *keySorter) sort(dst, src []byte) ([]byte, error) {
+	if ks.order == nil {
+		ks.order = LexicalOrder
+	}
+	ks.buf.Reset()
+	if err := compact(&ks.buf, src, false); err != nil {
+		return dst, err
+	}
+	return ks.value(dst, ks.buf.Bytes()), nil
+}
+
+// member is an object member with its key unquoted.
+type member struct {
+	key   string
+	raw   []byte // quoted key
+	value []byte
+}
+
+// objectMembers appends the members of the valid compact JSON object src
+// to members.
+func objectMembers(members []member, src []byte) []member {
+	for i := 1; src[i] != '}'; {
+		n := valueEnd(src[i:])
+		m := member{raw: src[i : i+n]}
+		m.key, _ = unquote(m.raw)
+		i += n + 1 // skip ':'
+		n = valueEnd(src[i:])
+		m.value = src[i : i+n]
+		members = append(members, m)
+		i += n
+		if src[i] == ',' {
+			i++
+		}
+	}
+	return members
+}
+
+// arrayElems appends the elements of the valid compact JSON array src to
+// elems.
+func arrayElems(elems [][]byte, src []byte) [][]byte {
+	for i := 1; src[i] != ']'; {
+		n := valueEnd(src[i:])
+		elems = append(elems, src[i:i+n])
+		i += n
+		if src[i] == ',' {
+			i++
+		}
+	}
+	return elems
+}
+
+// value appends the value src, which must be valid compact JSON, with
+// sorted keys to dst.
+func (ks *keySorter) value(dst, src []byte) []byte {
+	switch src[0] {
+	case '[':
+		// Elements are sorted in place, without collecting them, so that
+		// large arrays do not need more memory.
+		dst = append(dst, '[')
+		for i := 1; src[i] != ']'; {
+			n := valueEnd(src[i:])
+			dst = ks.value(dst, src[i:i+n])
+			i += n
+			if src[i] == ',' {
+				dst = append(dst, ',')
+				i++
+			}
+		}
+		return append(dst, ']')
+	case '{':
+		// Only the members of the objects being sorted are buffered and
+		// their slices are reused for objects at the same depth.
+		if ks.depth == len(ks.members) {
+			ks.members = append(ks.members, nil)
+		}
+		members := objectMembers(ks.members[ks.depth][:0], src)
+		ks.members[ks.depth] = members
+		sort.SliceStable(members, func(i, j int) bool {
+			return ks.order(members[i].key, members[j].key) < 0
+		})
+		ks.depth++
+		dst = append(dst, '{')
+		for i, m := range members {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = append(dst, m.raw...)
+			dst = append(dst, ':')
+			dst = ks.value(dst, m.value)
+		}
+		ks.depth--
+		return append(dst, '}')
+	}
+	return append(dst, src...)
+}
+
+// valueEnd returns the length of the JSON value at the start of the valid
+// compact JSON src.
+func valueEnd(src []byte) int {
+	depth := 0
+	for i := 0; i < len(src); i++ {
+		switch src[i] {
+		case '"':
+			for i++; src[i] != '"'; i++ {
+				if src[i] == '\\' {
+					i++
+				}
+			}
+			if depth == 0 {
+				return i + 1
+			}
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth == 0 {
+				return i
+			}
+			if depth--; depth == 0 {
+				return i + 1
+			}
+		case ',', ':':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(src)
+}
+
+type sortedKeys struct {
+	order KeyOrder
+	keys  []*string         // greatest key of the enclosing objects so far
+	seen  []map[string]bool // keys of the enclosing objects, nil for arrays
+}
+
+// SortedKeysRule returns a Rule that reports object keys that are not
+// sorted by order, or LexicalOrder if order is nil. Duplicate keys, which
+// cannot be sorted, are reported as duplicates rather than as unsorted.
+// Unlike the DefaultRules, it checks a policy rather than a likely bug,
+// and is used to verify that documents are canonically ordered.
+func SortedKeysRule(order KeyOrder) Rule {
+	if order == nil {
+		order = LexicalOrder
+	}
+	return &sortedKeys{order: order}
+}
+
+func (*sortedKeys) Name() string { return "sorted-keys" }
+
+func (r *sortedKeys) Check(n *LintNode, report func(Severity, string)) {
+	switch {
+	case n.Event == LintBegin:
+		r.keys = append(r.keys, nil)
+		var seen map[string]bool
+		if n.Kind == KindObject {
+			seen = make(map[string]bool)
+		}
+		r.seen = append(r.seen, seen)
+	case n.Event == LintValue && (n.Kind == KindObject || n.Kind == KindArray):
+		r.keys = r.keys[:len(r.keys)-1]
+		r.seen = r.seen[:len(r.seen)-1]
+	case n.Event == LintKey:
+		key := n.Path[len(n.Path)-1].Key
+		seen := r.seen[len(r.seen)-1]
+		if seen[key] {
+			report(SeverityWarning, "duplicate object key "+strconv.Quote(key))
+			return
+		}
+		seen[key] = true
+		prev := r.keys[len(r.keys)-1]
+		if prev != nil && r.order(key, *prev) < 0 {
+			report(SeverityWarning, "object key "+strconv.Quote(key)+
+				" is not sorted (follows "+strconv.Quote(*prev)+")")
+			return // compare the next key with the greatest seen
+		}
+		r.keys[len(r.keys)-1] = &key
+	}
+}
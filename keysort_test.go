@@ -0,0 +1,117 @@
+package pjson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestSortedKeysRule(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{`{}`, nil},
+		{`{"": 1, "a": 2, "b": {"x": 1, "y": 2}}`, nil},
+		{`[{"a": 1, "b": 2}, {"a": 1, "b": 2}]`, nil},
+		{`{"b": 1, "a": 2}`, []string{`.a: object key "a" is not sorted (follows "b")`}},
+		{`{"a": {"d": 1, "c": 2}, "b": [{"z": 1, "y": 2}]}`, []string{
+			`.a.c: object key "c" is not sorted (follows "d")`,
+			`.b[0].y: object key "y" is not sorted (follows "z")`,
+		}},
+		// Keys are compared to the greatest preceding key.
+		{`{"c": 1, "a": 2, "b": 3, "d": 4}`, []string{
+			`.a: object key "a" is not sorted (follows "c")`,
+			`.b: object key "b" is not sorted (follows "c")`,
+		}},
+		// Escaped keys are compared by their value.
+		{`{"\u0062": 1, "a": 2}`, []string{`.a: object key "a" is not sorted (follows "b")`}},
+		// Duplicates are not reported as unsorted.
+		{`{"a": 1, "a": 2, "b": {"a": 3}, "a": 4}`, []string{
+			`.a: duplicate object key "a"`,
+			`.a: duplicate object key "a"`,
+		}},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, f := range Lint(strings.NewReader(test.in), SortedKeysRule(nil)) {
+			got = append(got, f.Path+": "+f.Message)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Lint(%s):\ngot:  %q\nwant: %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestKeyOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		order KeyOrder
+		keys  []string
+	}{
+		{"lexical", LexicalOrder, []string{"", "1", "10", "9", "A", "a", "item10", "item2"}},
+		{"natural", NaturalOrder, []string{"", "1", "9", "10", "A", "a", "a01", "a1", "a1b", "a2", "item2", "item10", "item10a"}},
+		{"ci", CaseInsensitiveOrder, []string{"", "A", "a", "Apple", "apple", "applE2", "B", "b", "item10", "item2", "Äpfel", "äpfel"}},
+		{"collate:de", CollateOrder(language.German), []string{"", "a", "A", "Äpfel", "apple", "Apple", "b", "B", "item10", "item2", "zebra"}},
+		{"numeric", NumericOrder, []string{"-1", "0.5", "1", "2", "10", "1e2", "", "01", "a", "item10", "item2"}},
+	}
+	for _, test := range tests {
+		for i := 0; i < len(test.keys); i++ {
+			for j := 0; j < len(test.keys); j++ {
+				a, b := test.keys[i], test.keys[j]
+				if got, want := test.order(a, b), cmpInt(i, j); got != want {
+					t.Errorf("%s(%q, %q) = %d; want: %d", test.name, a, b, got, want)
+				}
+			}
+		}
+	}
+
+	// Equal numbers are ordered lexically.
+	if got := NumericOrder("1e1", "10"); got != 1 {
+		t.Errorf("NumericOrder(%q, %q) = %d; want: %d", "1e1", "10", got, 1)
+	}
+}
+
+func TestSortKeys(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`1`, `1`},
+		{` "a" `, `"a"`},
+		{`{}`, `{}`},
+		{`[]`, `[]`},
+		{`{"b": 1, "a": 2}`, `{"a":2,"b":1}`},
+		{`{"b": [{"d": "}", "c": "{"}], "a": {"f": {}, "e": []}}`, `{"a":{"e":[],"f":{}},"b":[{"c":"{","d":"}"}]}`},
+		{`{"b": 1, "a": 2, "b": 3, "a": 4}`, `{"a":2,"a":4,"b":1,"b":3}`},
+		{`{"\"b": 1, "\"a\\": 2, "a": {"c,d": 3, "c": 4}}`, `{"\"a\\":2,"\"b":1,"a":{"c":4,"c,d":3}}`},
+		{`[{"b": 1, "a": 2}, [{"d": 1, "c": 2}], 3]`, `[{"a":2,"b":1},[{"c":2,"d":1}],3]`},
+	}
+	for _, test := range tests {
+		got, err := SortKeys(nil, []byte(test.in), nil)
+		if err != nil {
+			t.Errorf("SortKeys(%s): %v", test.in, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("SortKeys(%s) = %s; want: %s", test.in, got, test.want)
+		}
+	}
+	if _, err := SortKeys(nil, []byte(`{"a": 1`), nil); err == nil {
+		t.Error("SortKeys: expected error for invalid JSON")
+	}
+}
+
+func TestParseKeyOrder(t *testing.T) {
+	for _, name := range []string{"lexical", "natural", "numeric", "ci", "collate:de", "collate:sv-SE"} {
+		if order, err := ParseKeyOrder(name); err != nil || order == nil {
+			t.Errorf("ParseKeyOrder(%q) = %v, %v", name, order, err)
+		}
+	}
+	for _, name := range []string{"", "Lexical", "collate:", "collate:not a tag"} {
+		if _, err := ParseKeyOrder(name); err == nil {
+			t.Errorf("ParseKeyOrder(%q): expected an error", name)
+		}
+	}
+}
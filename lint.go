@@ -0,0 +1,431 @@
+package pjson
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A Finding is a Diagnostic reported by Lint.
+type Finding = Diagnostic
+
+// LintEvent is the type of a LintNode.
+type LintEvent uint8
+
+const (
+	// LintBegin is the start of an object or array, before its children.
+	LintBegin LintEvent = iota
+	// LintKey is an object key.
+	LintKey
+	// LintValue is a complete value. Objects and arrays are visited
+	// after their children.
+	LintValue
+)
+
+// A LintNode is passed to each Rule for every object key and value in a
+// document. The node and its fields are only valid for the duration of
+// the call to Rule.Check.
+type LintNode struct {
+	Event LintEvent
+	// Path is the path of the value. For keys it is the path of the value
+	// the key names.
+	Path Path
+	// Kind is the kind of the value, keys are KindString.
+	Kind Kind
+	// Raw is the JSON encoding of the key or value. For LintBegin it is
+	// the opening '{' or '['.
+	Raw []byte
+	// Offset is the byte offset of Raw in the document.
+	Offset int64
+	// Depth is the number of objects and arrays that contain the value.
+	Depth int
+}
+
+// A Rule is a lint check. Rules may be stateful, so the same Rule should
+// not be used by concurrent calls to Lint.
+type Rule interface {
+	// Name returns the name of the rule, such as "duplicate-keys".
+	Name() string
+	// Check is called for each node of a document in order and calls
+	// report for each problem found with the node.
+	Check(n *LintNode, report func(severity Severity, msg string))
+}
+
+// DefaultRules returns new instances of the rules used by Diagnose.
+func DefaultRules() []Rule {
+	return []Rule{
+		DuplicateKeysRule(),
+		LargeIntegersRule(),
+		NumericStringsRule(),
+		EmptyKeysRule(),
+		InvalidUTF8Rule(),
+		MixedTypesRule(),
+		MaxDepthRule(LintMaxDepth),
+	}
+}
+
+// Lint reads a JSON document from src and returns its syntax errors or,
+// if it is valid, the findings of rules. If no rules are provided the
+// DefaultRules are used.
+func Lint(src io.Reader, rules ...Rule) []Finding {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return []Finding{{Offset: int64(len(data)), Severity: SeverityError, Message: err.Error()}}
+	}
+	if len(rules) == 0 {
+		rules = DefaultRules()
+	}
+	findings := syntaxFindings(data, 0)
+	if len(findings) == 0 {
+		findings = lint(data, rules)
+	}
+	setLineCol(data, findings)
+	return findings
+}
+
+// syntaxFindings returns up to max syntax errors in data.
+func syntaxFindings(data []byte, max int) []Finding {
+	var findings []Finding
+	for _, err := range ValidateAll(data, max) {
+		se := err.(*SyntaxError)
+		off := se.Offset - 1 // Offset is the number of bytes read
+		if off < 0 {
+			off = 0
+		}
+		findings = append(findings, Finding{
+			Offset:   off,
+			Severity: SeverityError,
+			Message:  se.msg,
+			Rule:     "syntax",
+		})
+	}
+	return findings
+}
+
+// lint returns the findings of rules for the valid JSON document data.
+func lint(data []byte, rules []Rule) []Finding {
+	scan := newScanner()
+	defer freeScanner(scan)
+
+	var findings []Finding
+	var node LintNode
+	var rule Rule
+	report := func(severity Severity, msg string) {
+		findings = append(findings, Finding{
+			Offset:   node.Offset,
+			Severity: severity,
+			Message:  msg,
+			Path:     node.Path.String(),
+			Rule:     rule.Name(),
+		})
+	}
+	check := func(event LintEvent, path Path, kind Kind, raw []byte, off, depth int) {
+		node = LintNode{Event: event, Path: path, Kind: kind, Raw: raw, Offset: int64(off), Depth: depth}
+		for _, rule = range rules {
+			rule.Check(&node, report)
+		}
+	}
+
+	visit(data, false, func(ev visitEvent, path Path, start, end int) bool {
+		raw := data[start:end]
+		switch ev {
+		case visitBegin:
+			check(LintBegin, path, kindOf(raw[0]), raw, start, len(path))
+		case visitKey:
+			check(LintKey, path, KindString, raw, start, len(path))
+		default:
+			check(LintValue, path, kindOf(raw[0]), raw, start, len(path))
+		}
+		return true
+	})
+	return findings
+}
+
+type duplicateKeys struct {
+	keys []map[string]bool // keys of the enclosing objects, nil for arrays
+}
+
+// DuplicateKeysRule returns a Rule that warns about duplicate object keys.
+func DuplicateKeysRule() Rule { return new(duplicateKeys) }
+
+func (*duplicateKeys) Name() string { return "duplicate-keys" }
+
+func (r *duplicateKeys) Check(n *LintNode, report func(Severity, string)) {
+	switch {
+	case n.Event == LintBegin && n.Kind == KindObject:
+		r.keys = append(r.keys, make(map[string]bool))
+	case n.Event == LintBegin:
+		r.keys = append(r.keys, nil)
+	case n.Event == LintValue && (n.Kind == KindObject || n.Kind == KindArray):
+		r.keys = r.keys[:len(r.keys)-1]
+	case n.Event == LintKey:
+		key := n.Path[len(n.Path)-1].Key
+		m := r.keys[len(r.keys)-1]
+		if m[key] {
+			report(SeverityWarning, "duplicate object key "+strconv.Quote(key))
+		}
+		m[key] = true
+	}
+}
+
+type largeIntegers struct{}
+
+// LargeIntegersRule returns a Rule that warns about integers that cannot
+// be represented exactly by a float64.
+func LargeIntegersRule() Rule { return largeIntegers{} }
+
+func (largeIntegers) Name() string { return "large-integers" }
+
+func (largeIntegers) Check(n *LintNode, report func(Severity, string)) {
+	if n.Event != LintValue || n.Kind != KindNumber || bytes.IndexAny(n.Raw, ".eE") != -1 {
+		return
+	}
+	i, err := strconv.ParseInt(string(n.Raw), 10, 64)
+	if err != nil || i > maxSafeInteger || i < -maxSafeInteger {
+		report(SeverityWarning, "integer "+string(n.Raw)+" exceeds 2^53 and may lose precision")
+	}
+}
+
+type numericStrings struct{}
+
+// NumericStringsRule returns a Rule that warns about strings that look
+// like numbers or booleans, which often indicates type drift between
+// the producers of a document.
+func NumericStringsRule() Rule { return numericStrings{} }
+
+func (numericStrings) Name() string { return "numeric-strings" }
+
+func (numericStrings) Check(n *LintNode, report func(Severity, string)) {
+	if n.Event == LintValue && n.Kind == KindString {
+		if kind := stringLooksLike(n.Raw); kind != "" {
+			report(SeverityWarning, "string "+string(n.Raw)+" looks like a "+kind)
+		}
+	}
+}
+
+// stringLooksLike returns "number" or "boolean" if the JSON string lit
+// holds a number or boolean, ignoring surrounding space and the case of
+// booleans, otherwise it returns "". Numbers with leading zeros, such as
+// "007", are not reported since they are likely identifiers.
+func stringLooksLike(lit []byte) string {
+	s, ok := unquote(lit)
+	if !ok {
+		return ""
+	}
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		return ""
+	case isValidNumber(s):
+		return "number"
+	case strings.EqualFold(s, "true"), strings.EqualFold(s, "false"):
+		return "boolean"
+	}
+	return ""
+}
+
+// isBlankKey reports whether the JSON string lit is empty or contains
+// only white space, including escaped white space such as "\t".
+func isBlankKey(lit []byte) bool {
+	s := lit[1 : len(lit)-1]
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ':
+			i++
+		case c == '\\':
+			if i+1 < len(s) && (s[i+1] == 't' || s[i+1] == 'n' || s[i+1] == 'r') {
+				i += 2
+			} else if r := getu4(s[i:]); r != -1 && unicode.IsSpace(r) {
+				i += 6
+			} else {
+				return false
+			}
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRune(s[i:])
+			if !unicode.IsSpace(r) {
+				return false
+			}
+			i += size
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+type emptyKeys struct{}
+
+// EmptyKeysRule returns a Rule that warns about object keys that are empty
+// or only contain white space. Such keys are legal, but almost always
+// a bug.
+func EmptyKeysRule() Rule { return emptyKeys{} }
+
+func (emptyKeys) Name() string { return "empty-keys" }
+
+func (emptyKeys) Check(n *LintNode, report func(Severity, string)) {
+	if n.Event != LintKey || !isBlankKey(n.Raw) {
+		return
+	}
+	if len(n.Raw) == 2 {
+		report(SeverityWarning, "empty object key")
+	} else {
+		report(SeverityWarning, "object key "+string(n.Raw)+" is only white space")
+	}
+}
+
+type invalidUTF8 struct{}
+
+// InvalidUTF8Rule returns a Rule that warns about object keys and strings
+// that are not valid UTF-8, which decoders replace with U+FFFD or reject.
+func InvalidUTF8Rule() Rule { return invalidUTF8{} }
+
+func (invalidUTF8) Name() string { return "invalid-utf8" }
+
+func (invalidUTF8) Check(n *LintNode, report func(Severity, string)) {
+	if n.Event == LintBegin || n.Kind != KindString || utf8.Valid(n.Raw) {
+		return
+	}
+	i := 0
+	for i < len(n.Raw) {
+		r, size := utf8.DecodeRune(n.Raw[i:])
+		if r == utf8.RuneError && size == 1 {
+			break
+		}
+		i += size
+	}
+	what := "string"
+	if n.Event == LintKey {
+		what = "object key"
+	}
+	report(SeverityWarning, "invalid UTF-8 byte 0x"+strconv.FormatUint(uint64(n.Raw[i]), 16)+
+		" in "+what+" at offset "+strconv.FormatInt(n.Offset+int64(i), 10))
+}
+
+type mixedTypes struct {
+	elems [][]Kind // kinds of the elements of the enclosing arrays, nil for objects
+}
+
+// MixedTypesRule returns a Rule that warns about arrays with elements of
+// differing kinds, such as numbers mixed with strings. Null elements are
+// ignored. The elements that differ from the most common kind are listed
+// by index.
+func MixedTypesRule() Rule { return new(mixedTypes) }
+
+func (*mixedTypes) Name() string { return "mixed-types" }
+
+// maxMixedIndices is the maximum number of element indices listed in
+// a mixed-types finding.
+const maxMixedIndices = 10
+
+func (r *mixedTypes) Check(n *LintNode, report func(Severity, string)) {
+	switch n.Event {
+	case LintBegin:
+		if n.Kind == KindArray {
+			r.elems = append(r.elems, []Kind{})
+		} else {
+			r.elems = append(r.elems, nil)
+		}
+		return
+	case LintKey:
+		return
+	}
+	if n.Kind == KindObject || n.Kind == KindArray {
+		elems := r.elems[len(r.elems)-1]
+		r.elems = r.elems[:len(r.elems)-1]
+		if n.Kind == KindArray {
+			r.check(elems, report)
+		}
+	}
+	// Record the kind of the value if it is an array element.
+	if i := len(r.elems) - 1; i >= 0 && n.Depth == len(r.elems) && r.elems[i] != nil {
+		r.elems[i] = append(r.elems[i], n.Kind)
+	}
+}
+
+func (r *mixedTypes) check(elems []Kind, report func(Severity, string)) {
+	var counts [KindArray + 1]int
+	var order []Kind // kinds in the order first seen
+	for _, k := range elems {
+		if k == KindNull {
+			continue
+		}
+		if counts[k] == 0 {
+			order = append(order, k)
+		}
+		counts[k]++
+	}
+	if len(order) < 2 {
+		return
+	}
+	common := order[0]
+	for _, k := range order[1:] {
+		if counts[k] > counts[common] {
+			common = k
+		}
+	}
+	var kinds []string
+	for _, k := range order {
+		kinds = append(kinds, k.String())
+	}
+	var w strings.Builder
+	w.WriteString("array mixes ")
+	w.WriteString(strings.Join(kinds, ", "))
+	w.WriteString(" elements (indices ")
+	listed := 0
+	for i, k := range elems {
+		if k == KindNull || k == common {
+			continue
+		}
+		if listed == maxMixedIndices {
+			w.WriteString(", ...")
+			break
+		}
+		if listed > 0 {
+			w.WriteString(", ")
+		}
+		w.WriteString(strconv.Itoa(i))
+		listed++
+	}
+	w.WriteByte(')')
+	report(SeverityWarning, w.String())
+}
+
+type maxDepth int
+
+// MaxDepthRule returns a Rule that warns about objects and arrays nested
+// deeper than max.
+func MaxDepthRule(max int) Rule { return maxDepth(max) }
+
+func (maxDepth) Name() string { return "max-depth" }
+
+func (max maxDepth) Check(n *LintNode, report func(Severity, string)) {
+	if n.Event == LintBegin && n.Depth == int(max) {
+		report(SeverityWarning, "nesting depth exceeds "+strconv.Itoa(int(max)))
+	}
+}
+
+// ReportText writes findings to w, one per line, in the form returned by
+// Diagnostic.String.
+func ReportText(w io.Writer, findings []Finding) error {
+	out := bufio.NewWriter(w)
+	for i := range findings {
+		out.WriteString(findings[i].String())
+		out.WriteByte('\n')
+	}
+	return out.Flush()
+}
+
+// ReportJSON writes findings to w as an indented JSON array.
+func ReportJSON(w io.Writer, findings []Finding) error {
+	if findings == nil {
+		findings = []Finding{} // encode as [] not null
+	}
+	enc := NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(findings)
+}
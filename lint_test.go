@@ -0,0 +1,169 @@
+package pjson
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// eventRule records the nodes it is called with.
+type eventRule struct {
+	events []string
+}
+
+func (*eventRule) Name() string { return "events" }
+
+func (r *eventRule) Check(n *LintNode, report func(Severity, string)) {
+	events := [...]string{"begin", "key", "value"}
+	r.events = append(r.events, events[n.Event]+" "+n.Path.String()+" "+
+		n.Kind.String()+" "+string(n.Raw))
+	if n.Event == LintValue && n.Kind == KindNull {
+		report(SeverityError, "null")
+	}
+}
+
+func TestLint(t *testing.T) {
+	const in = `{"a": [1, null], "b": {}}`
+	var rule eventRule
+	got := Lint(strings.NewReader(in), &rule)
+	want := []Finding{
+		{Line: 1, Col: 11, Offset: 10, Severity: SeverityError, Message: "null", Path: ".a[1]", Rule: "events"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lint() = %+v; want: %+v", got, want)
+	}
+	wantEvents := []string{
+		"begin . object {",
+		`key .a string "a"`,
+		"begin .a array [",
+		"value .a[0] number 1",
+		"value .a[1] null null",
+		"value .a array [1, null]",
+		`key .b string "b"`,
+		"begin .b object {",
+		"value .b object {}",
+		`value . object ` + in,
+	}
+	if !reflect.DeepEqual(rule.events, wantEvents) {
+		t.Errorf("events:\ngot:  %q\nwant: %q", rule.events, wantEvents)
+	}
+
+	// Syntax errors are reported instead of running rules.
+	rule.events = nil
+	got = Lint(strings.NewReader(`[1,]`), &rule)
+	if len(got) != 1 || got[0].Rule != "syntax" || len(rule.events) != 0 {
+		t.Errorf("Lint() = %+v; want a single syntax error", got)
+	}
+
+	// Default rules
+	got = Lint(strings.NewReader(`{"a": "1", "a": 2}`))
+	if len(got) != 2 || got[0].Rule != "numeric-strings" || got[1].Rule != "duplicate-keys" {
+		t.Errorf("Lint() = %+v; want numeric-strings and duplicate-keys findings", got)
+	}
+
+	// Read error
+	rerr := errors.New("read error")
+	got = Lint(iotest.ErrReader(rerr))
+	if len(got) != 1 || got[0].Message != rerr.Error() {
+		t.Errorf("Lint() = %+v; want read error", got)
+	}
+}
+
+func TestReportFindings(t *testing.T) {
+	findings := []Finding{
+		{File: "a.json", Line: 1, Col: 2, Offset: 1, Severity: SeverityWarning, Message: "m1", Path: ".a", Rule: "r"},
+		{Line: 3, Col: 4, Offset: 9, Severity: SeverityError, Message: "m2"},
+	}
+	var buf bytes.Buffer
+	if err := ReportText(&buf, findings); err != nil {
+		t.Fatal(err)
+	}
+	const wantText = "a.json:1:2: warning: m1 (.a)\n3:4: error: m2\n"
+	if buf.String() != wantText {
+		t.Errorf("ReportText() = %q; want: %q", buf.String(), wantText)
+	}
+
+	buf.Reset()
+	if err := ReportJSON(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "[]\n" {
+		t.Errorf("ReportJSON(nil) = %q; want: %q", buf.String(), "[]\n")
+	}
+	buf.Reset()
+	if err := ReportJSON(&buf, findings); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"rule": "r"`)) {
+		t.Errorf("ReportJSON() missing rule: %s", buf.Bytes())
+	}
+}
+
+func TestEmptyKeysRule(t *testing.T) {
+	blank := []string{`""`, `" "`, `"\t\n\r"`, `"  "`, "\"　 \""}
+	for _, s := range blank {
+		if !isBlankKey([]byte(s)) {
+			t.Errorf("isBlankKey(%s) = false; want: true", s)
+		}
+	}
+	for _, s := range []string{`"a"`, `" a "`, `"\\"`, `"A"`, `"\""`, `"\u00"`} {
+		if isBlankKey([]byte(s)) {
+			t.Errorf("isBlankKey(%s) = true; want: false", s)
+		}
+	}
+
+	got := Lint(strings.NewReader(`{"": 1, "a": {" ": 2}}`), EmptyKeysRule())
+	want := []Finding{
+		{Line: 1, Col: 2, Offset: 1, Severity: SeverityWarning, Message: "empty object key", Path: `.[""]`, Rule: "empty-keys"},
+		{Line: 1, Col: 15, Offset: 14, Severity: SeverityWarning, Message: `object key " " is only white space`, Path: `.a[" "]`, Rule: "empty-keys"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lint() = %+v; want: %+v", got, want)
+	}
+}
+
+func TestInvalidUTF8Rule(t *testing.T) {
+	got := Lint(strings.NewReader("{\"a\xff\": \"\u00e9\", \"b\": [\"ok\", \"x\xc3\"]}"), InvalidUTF8Rule())
+	want := []Finding{
+		{Line: 1, Col: 2, Offset: 1, Severity: SeverityWarning, Message: "invalid UTF-8 byte 0xff in object key at offset 3", Path: ".[\"a\ufffd\"]", Rule: "invalid-utf8"},
+		{Line: 1, Col: 26, Offset: 25, Severity: SeverityWarning, Message: "invalid UTF-8 byte 0xc3 in string at offset 27", Path: ".b[1]", Rule: "invalid-utf8"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lint() = %+v; want: %+v", got, want)
+	}
+}
+
+func TestMixedTypesRule(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{`[1, 2, null, 3]`, nil},
+		{`[true, false]`, nil},
+		{`[[1], [2], {}]`, []string{".: array mixes array, object elements (indices 2)"}},
+		{`[1, "a", 2, null, "b", 3]`, []string{".: array mixes number, string elements (indices 1, 4)"}},
+		{`{"a": ["x", 1, 1]}`, []string{".a: array mixes string, number elements (indices 0)"}},
+		{`[[1, "a"], [{"b": [true, 0]}]]`, []string{
+			".[0]: array mixes number, string elements (indices 1)",
+			".[1][0].b: array mixes bool, number elements (indices 1)",
+		}},
+		{`["a", 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, "b", "c"]`, []string{
+			".: array mixes string, number elements (indices 0, 13, 14)",
+		}},
+		{`["a", "b", 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, "c", "d", "e", "f", "g", "h", "i", "j", "k"]`, []string{
+			".: array mixes string, number elements (indices 0, 1, 14, 15, 16, 17, 18, 19, 20, 21, ...)",
+		}},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, f := range Lint(strings.NewReader(test.in), MixedTypesRule()) {
+			got = append(got, f.Path+": "+f.Message)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Lint(%s):\ngot:  %q\nwant: %q", test.in, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package pjson
+
+import (
+	"bytes"
+
+	"github.com/charlievieth/pjson/termcolor"
+)
+
+// A MergeConflict is a value changed differently by both sides of a
+// three-way merge. Values are compact JSON and nil if the value was
+// removed or not present.
+type MergeConflict struct {
+	Path   Path
+	Base   []byte
+	Ours   []byte
+	Theirs []byte
+}
+
+// A MergeResult is the result of a three-way merge, see Merge3.
+type MergeResult struct {
+	// Conflicts are the conflicting changes in document order.
+	Conflicts []MergeConflict
+
+	root *mergeNode
+}
+
+// mergeNode is a merged value: either a value taken from one of the
+// documents, an object with merged members or a conflict.
+type mergeNode struct {
+	value    []byte // nil if absent
+	members  []mergeMember
+	object   bool
+	conflict *MergeConflict
+}
+
+type mergeMember struct {
+	raw  []byte // quoted key
+	node *mergeNode
+}
+
+// absent reports whether the node is omitted from its object.
+func (n *mergeNode) absent() bool {
+	return n.value == nil && !n.object && n.conflict == nil
+}
+
+// Merge3 merges the changes made to the JSON document base by ours and
+// theirs. Changes are merged per object member: members changed by only
+// one side, or changed identically by both, are merged and members
+// changed differently are reported as conflicts. Arrays and other values
+// are merged as a whole. Values are compared semantically, as by Diff.
+func Merge3(base, ours, theirs []byte) (*MergeResult, error) {
+	var cb, co, ct bytes.Buffer
+	for _, x := range []struct {
+		dst *bytes.Buffer
+		src []byte
+	}{{&cb, base}, {&co, ours}, {&ct, theirs}} {
+		if err := compact(x.dst, x.src, false); err != nil {
+			return nil, err
+		}
+	}
+	var r MergeResult
+	r.root = r.merge(nil, cb.Bytes(), co.Bytes(), ct.Bytes())
+	return &r, nil
+}
+
+// mergeEqual reports whether the compact JSON values a and b, which may
+// be nil, are equal.
+func mergeEqual(a, b []byte) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return bytes.Equal(a, b) || bytes.Equal(canonical(nil, a), canonical(nil, b))
+}
+
+func isObject(v []byte) bool { return v != nil && v[0] == '{' }
+
+func (r *MergeResult) merge(path Path, base, ours, theirs []byte) *mergeNode {
+	switch {
+	case mergeEqual(ours, theirs), mergeEqual(base, theirs):
+		return &mergeNode{value: ours}
+	case mergeEqual(base, ours):
+		return &mergeNode{value: theirs}
+	case isObject(ours) && isObject(theirs) && (base == nil || isObject(base)):
+		return r.mergeObjects(path, base, ours, theirs)
+	}
+	c := MergeConflict{
+		Path:   append(Path(nil), path...),
+		Base:   base,
+		Ours:   ours,
+		Theirs: theirs,
+	}
+	r.Conflicts = append(r.Conflicts, c)
+	return &mergeNode{conflict: &c}
+}
+
+func (r *MergeResult) mergeObjects(path Path, base, ours, theirs []byte) *mergeNode {
+	var bm []member
+	var bi map[string]int
+	if base != nil {
+		bm, bi = lastMembers(base)
+	}
+	om, oi := lastMembers(ours)
+	tm, ti := lastMembers(theirs)
+	lookup := func(members []member, index map[string]int, key string) []byte {
+		if i, ok := index[key]; ok {
+			return members[i].value
+		}
+		return nil
+	}
+
+	// Members are in the order of ours followed by those only in theirs.
+	keys := make([]member, 0, len(om)+len(tm))
+	keys = append(keys, om...)
+	for _, m := range tm {
+		if _, ok := oi[m.key]; !ok {
+			keys = append(keys, m)
+		}
+	}
+	n := &mergeNode{object: true}
+	path = append(path, PathElem{Index: -1})
+	for _, m := range keys {
+		path[len(path)-1].Key = m.key
+		child := r.merge(path, lookup(bm, bi, m.key), lookup(om, oi, m.key), lookup(tm, ti, m.key))
+		if !child.absent() {
+			n.members = append(n.members, mergeMember{raw: m.raw, node: child})
+		}
+	}
+	return n
+}
+
+// Conflict markers written by Format.
+const (
+	mergeMarkerOurs   = "<<<<<<< ours"
+	mergeMarkerSep    = "======="
+	mergeMarkerTheirs = ">>>>>>> theirs"
+)
+
+// Format writes the merged document to dst, indented as by
+// IndentConfig.Indent. Conflicts are written in the style of git, with
+// the version of ours and theirs between "<<<<<<< ours", "=======" and
+// ">>>>>>> theirs" lines, so the output is only valid JSON if there are
+// no conflicts. A trailing newline is only written if the document ends
+// with a conflict.
+func (r *MergeResult) Format(dst *bytes.Buffer, conf *IndentConfig, prefix, indent string) error {
+	f := mergeFormatter{dst: dst, conf: conf, emit: conf.emitter(), indent: indent}
+	if !conf.isPlain() {
+		f.marker = termcolor.Red
+	}
+	return f.node(r.root, prefix, nil, false)
+}
+
+type mergeFormatter struct {
+	dst    *bytes.Buffer
+	conf   *IndentConfig
+	emit   emitter
+	indent string
+	marker *termcolor.Color
+}
+
+func (f *mergeFormatter) writeMarker(s string) {
+	f.dst.WriteString(f.marker.Format())
+	f.dst.WriteString(s)
+	f.dst.WriteString(f.marker.Reset())
+	f.dst.WriteByte('\n')
+}
+
+// value writes the compact JSON value v, preceded by the object key raw
+// if not nil, and a trailing comma if comma is true.
+func (f *mergeFormatter) value(v []byte, prefix string, raw []byte, comma bool) error {
+	if raw != nil {
+		f.key(raw)
+	}
+	if err := f.conf.Indent(f.dst, v, prefix, f.indent); err != nil {
+		return err
+	}
+	if comma {
+		emitByte(f.emit, f.dst, classPunct, ',')
+	}
+	return nil
+}
+
+func (f *mergeFormatter) key(raw []byte) {
+	f.emit.begin(f.dst, classKey)
+	f.dst.Write(raw)
+	f.emit.end(f.dst, classKey)
+	emitByte(f.emit, f.dst, classPunct, ':')
+	f.dst.WriteByte(' ')
+}
+
+// node writes n, which is indented by prefix, preceded by the object key
+// raw if not nil, and a trailing comma if comma is true.
+func (f *mergeFormatter) node(n *mergeNode, prefix string, raw []byte, comma bool) error {
+	switch {
+	case n.conflict != nil:
+		// Conflicts are written on their own lines, so the markers
+		// begin a line, and end with a newline.
+		f.writeMarker(mergeMarkerOurs)
+		for i, v := range [][]byte{n.conflict.Ours, n.conflict.Theirs} {
+			if i == 1 {
+				f.writeMarker(mergeMarkerSep)
+			}
+			if v != nil {
+				f.dst.WriteString(prefix)
+				if err := f.value(v, prefix, raw, comma); err != nil {
+					return err
+				}
+				f.dst.WriteByte('\n')
+			}
+		}
+		f.writeMarker(mergeMarkerTheirs)
+		return nil
+	case !n.object:
+		return f.value(n.value, prefix, raw, comma)
+	}
+	if raw != nil {
+		f.key(raw)
+	}
+	emitByte(f.emit, f.dst, classPunct, '{')
+	inner := prefix + f.indent
+	for i, m := range n.members {
+		if i == 0 || n.members[i-1].node.conflict == nil {
+			f.dst.WriteByte('\n')
+		}
+		if m.node.conflict == nil {
+			f.dst.WriteString(inner)
+		}
+		if err := f.node(m.node, inner, m.raw, i < len(n.members)-1); err != nil {
+			return err
+		}
+	}
+	if len(n.members) > 0 {
+		if n.members[len(n.members)-1].node.conflict == nil {
+			f.dst.WriteByte('\n')
+		}
+		f.dst.WriteString(prefix)
+	}
+	emitByte(f.emit, f.dst, classPunct, '}')
+	if comma {
+		emitByte(f.emit, f.dst, classPunct, ',')
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package pjson
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMerge3(t *testing.T) {
+	tests := []struct {
+		base, ours, theirs string
+		want               string
+		conflicts          []string
+	}{
+		{`1`, `1`, `2`, `2`, nil},
+		{`1`, `2`, `1`, `2`, nil},
+		{`1`, `2`, `2.0`, `2`, nil},
+		{`1`, `2`, `3`, "<<<<<<< ours\n2\n=======\n3\n>>>>>>> theirs\n", []string{"."}},
+		{
+			`{"a": 1, "b": 2, "c": 3}`,
+			`{"a": 1, "b": 20, "c": 3, "d": 4}`,
+			`{"b": 2, "c": 3, "e": {"f": 5}}`,
+			"{\n  \"b\": 20,\n  \"c\": 3,\n  \"d\": 4,\n  \"e\": {\n    \"f\": 5\n  }\n}",
+			nil,
+		},
+		{
+			`{"a": {"x": 1, "y": [1]}, "b": 1}`,
+			`{"a": {"x": 2, "y": [1, 2]}, "b": 1}`,
+			`{"a": {"x": 3, "y": [1, 2]}}`,
+			"{\n  \"a\": {\n<<<<<<< ours\n    \"x\": 2,\n=======\n    \"x\": 3,\n>>>>>>> theirs\n" +
+				"    \"y\": [\n      1,\n      2\n    ]\n  }\n}",
+			[]string{".a.x"},
+		},
+		{
+			`{"a": 1}`,
+			`{"a": 2}`,
+			`{}`,
+			"{\n<<<<<<< ours\n  \"a\": 2\n=======\n>>>>>>> theirs\n}",
+			[]string{".a"},
+		},
+		{
+			`{}`,
+			`{"a": {"b": 1}}`,
+			`{"a": {"c": 2}}`,
+			"{\n  \"a\": {\n    \"b\": 1,\n    \"c\": 2\n  }\n}",
+			nil,
+		},
+	}
+	for _, test := range tests {
+		r, err := Merge3([]byte(test.base), []byte(test.ours), []byte(test.theirs))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := r.Format(&buf, &noColorIndentConfig, "", "  "); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("Merge3(%s, %s, %s):\ngot:\n%s\nwant:\n%s", test.base, test.ours, test.theirs, got, test.want)
+		}
+		var paths []string
+		for _, c := range r.Conflicts {
+			paths = append(paths, c.Path.String())
+		}
+		if len(paths) != len(test.conflicts) || (len(paths) > 0 && paths[0] != test.conflicts[0]) {
+			t.Errorf("Merge3(%s, %s, %s): conflicts = %q; want: %q", test.base, test.ours, test.theirs, paths, test.conflicts)
+		}
+		if len(test.conflicts) == 0 && !Valid(buf.Bytes()) {
+			t.Errorf("Merge3(%s, %s, %s): invalid JSON:\n%s", test.base, test.ours, test.theirs, buf.String())
+		}
+	}
+
+	if _, err := Merge3([]byte(`{}`), []byte(`{`), []byte(`{}`)); err == nil {
+		t.Error("Merge3: expected error for invalid JSON")
+	}
+}
@@ -0,0 +1,41 @@
+package pjson
+
+import (
+	"bytes"
+	"sync"
+)
+
+var messageBufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// FormatMessage returns an indented and colorized copy of the JSON
+// message msg using conf. It is intended for printing many small and
+// independent messages (for example Kafka or Pub/Sub payloads) and
+// avoids the setup cost of a Stream. If msg is not valid JSON it is
+// returned unmodified. A nil conf disables color.
+func FormatMessage(msg []byte, conf *IndentConfig) []byte {
+	return AppendMessage(nil, msg, conf)
+}
+
+// AppendMessage is like FormatMessage, but appends the formatted message
+// to dst and returns the extended buffer.
+func AppendMessage(dst, msg []byte, conf *IndentConfig) []byte {
+	if conf == nil {
+		conf = &noColorIndentConfig
+	}
+	buf := messageBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	if err := conf.Indent(buf, msg, "", "    "); err != nil {
+		dst = append(dst, msg...)
+	} else {
+		dst = append(dst, buf.Bytes()...)
+	}
+	// Avoid hanging on to large buffers.
+	if buf.Cap() <= 64*1024 {
+		messageBufferPool.Put(buf)
+	}
+	return dst
+}
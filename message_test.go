@@ -0,0 +1,54 @@
+package pjson
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFormatMessage(t *testing.T) {
+	msg := []byte(`{"a":[1,"b"],"c":null}`)
+	var want bytes.Buffer
+	if err := Indent(&want, msg, "", "    "); err != nil {
+		t.Fatal(err)
+	}
+	if got := FormatMessage(msg, nil); !bytes.Equal(got, want.Bytes()) {
+		t.Errorf("FormatMessage() = %q; want: %q", got, want.Bytes())
+	}
+
+	got := FormatMessage(msg, &DefaultIndentConfig)
+	if s := ansiRe.ReplaceAll(got, nil); !bytes.Equal(s, want.Bytes()) {
+		t.Errorf("FormatMessage() = %q; want: %q", s, want.Bytes())
+	}
+
+	invalid := []byte(`{"a":`)
+	if got := FormatMessage(invalid, &DefaultIndentConfig); !bytes.Equal(got, invalid) {
+		t.Errorf("FormatMessage(%q) = %q; want: %q", invalid, got, invalid)
+	}
+
+	dst := []byte("prefix: ")
+	if got := AppendMessage(dst, []byte(`[]`), nil); string(got) != "prefix: []" {
+		t.Errorf("AppendMessage() = %q; want: %q", got, "prefix: []")
+	}
+}
+
+func TestFormatMessageAllocs(t *testing.T) {
+	msg := []byte(`{"key1":1,"key2":"two","key3":[true,false,null]}`)
+	dst := make([]byte, 0, 1024)
+	allocs := testing.AllocsPerRun(100, func() {
+		dst = AppendMessage(dst[:0], msg, &DefaultIndentConfig)
+	})
+	if allocs > 0 {
+		t.Errorf("AppendMessage allocs = %.2f; want: 0", allocs)
+	}
+}
+
+func BenchmarkFormatMessage(b *testing.B) {
+	msg := []byte(`{"key1":1,"key2":"two","key3":[true,false,null]}`)
+	conf := DefaultIndentConfig
+	dst := make([]byte, 0, 1024)
+	b.SetBytes(int64(len(msg)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		dst = AppendMessage(dst[:0], msg, &conf)
+	}
+}
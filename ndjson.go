@@ -0,0 +1,85 @@
+package pjson
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+var errNotArray = errors.New("pjson: top-level value is not an array")
+
+// ArrayToLines reads a single JSON array from src and writes each of its
+// elements to dst in compact form followed by a newline (NDJSON). The
+// array is streamed, so its size is not limited by memory. An error is
+// returned if src does not contain exactly one array.
+func ArrayToLines(dst io.Writer, src io.Reader) error {
+	w, r := newBuffers(dst, src)
+	scan := newScanner()
+	defer freeBufioScanner(w, r, scan)
+
+	begun := false // read the opening '['
+	empty := true  // no element has been written
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			if err != io.EOF {
+				return err
+			}
+			break
+		}
+		v := scan.Step(c)
+		switch {
+		case v == ScanSkipSpace || v == ScanEnd:
+			continue
+		case v == ScanError:
+			w.Flush()
+			return scan.err
+		case !begun:
+			if v != ScanBeginArray {
+				return errNotArray
+			}
+			begun = true
+			continue
+		}
+		switch depth := len(scan.parseState); {
+		case depth == 1 && v == ScanArrayValue:
+			w.WriteByte('\n')
+		case depth == 0 && v == ScanEndArray:
+			if !empty {
+				w.WriteByte('\n')
+			}
+		default:
+			w.WriteByte(c)
+			empty = false
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if scan.EOF() == ScanError {
+		return scan.err
+	}
+	return nil
+}
+
+// LocateObject returns the start and end of the first JSON object in b,
+// such as the JSON of a log line following a timestamp, or -1, -1 if there
+// is none. The object is the first '{' that begins a valid object and its
+// balanced closing '}'; b[start:end] is the object.
+func LocateObject(b []byte) (start, end int) {
+	scan := newScanner()
+	defer freeScanner(scan)
+	for i := 0; i < len(b); i++ {
+		j := bytes.IndexByte(b[i:], '{')
+		if j == -1 {
+			break
+		}
+		i += j
+		scan.Reset()
+		scan.Step('{')
+		if end, _, ok := skipContainer(scan, b, i); ok {
+			return i, end + 1
+		}
+	}
+	return -1, -1
+}
@@ -0,0 +1,68 @@
+package pjson
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestArrayToLines(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`[]`, ``},
+		{" [ ] \n", ``},
+		{`[1]`, "1\n"},
+		{`[1, "a, b", true, null]`, "1\n\"a, b\"\ntrue\nnull\n"},
+		{"[\n  {\"a\": [1, 2]},\n  [[3], {}],\n  \"[x]\"\n]\n", "{\"a\":[1,2]}\n[[3],{}]\n\"[x]\"\n"},
+	}
+	readers := []func(io.Reader) io.Reader{
+		func(r io.Reader) io.Reader { return r },
+		iotest.OneByteReader,
+		iotest.DataErrReader,
+	}
+	for _, test := range tests {
+		for _, fn := range readers {
+			var dst bytes.Buffer
+			if err := ArrayToLines(&dst, fn(strings.NewReader(test.in))); err != nil {
+				t.Errorf("ArrayToLines(%q): %v", test.in, err)
+				continue
+			}
+			if got := dst.String(); got != test.want {
+				t.Errorf("ArrayToLines(%q) = %q; want: %q", test.in, got, test.want)
+			}
+		}
+	}
+
+	for _, in := range []string{``, `{}`, `1`, `"a"`, `[1,`, `[1] [2]`, `[1]]`} {
+		if err := ArrayToLines(io.Discard, strings.NewReader(in)); err == nil {
+			t.Errorf("ArrayToLines(%q): expected an error", in)
+		}
+	}
+}
+
+func TestLocateObject(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // "" if there is no object
+	}{
+		{`2024-01-02T03:04:05Z INFO {"msg": "started", "port": 80}`, `{"msg": "started", "port": 80}`},
+		{`{"a": {"b": "}"}} trailing text`, `{"a": {"b": "}"}}`},
+		{`level={bad} {"ok": true}`, `{"ok": true}`},
+		{`no json here`, ``},
+		{`unbalanced {"a": 1`, ``},
+		{`[1, 2] {}`, `{}`},
+	}
+	for _, test := range tests {
+		start, end := LocateObject([]byte(test.in))
+		got := ""
+		if start != -1 {
+			got = test.in[start:end]
+		}
+		if got != test.want {
+			t.Errorf("LocateObject(%q) = %q; want: %q", test.in, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package pjson
+
+import "github.com/charlievieth/pjson/termcolor"
+
+// colorCost returns the number of bytes written around a token colored
+// with c.
+func colorCost(c *termcolor.Color) int64 {
+	return int64(len(c.Format()) + len(c.Reset()))
+}
+
+// ColorOverhead returns the number of bytes of ANSI escape sequences that
+// conf adds when formatting the JSON values in src. The overhead does not
+// depend on indentation, so it is the same for indented and compact
+// output. An error is returned if src is not valid JSON.
+func (conf *IndentConfig) ColorOverhead(src []byte) (int64, error) {
+	punct := colorCost(conf.Punctuation)
+	var n int64
+	var members []int // keys read in each of the enclosing objects and arrays
+	err := visit(src, true, func(ev visitEvent, path Path, start, end int) bool {
+		switch ev {
+		case visitBegin, visitEnd:
+			n += punct
+			if ev == visitEnd {
+				members = members[:len(members)-1]
+				return true
+			}
+		case visitKey:
+			n += punct // ':'
+			class := conf.keyClass(classKey, src[start:end])
+			n += colorCost(conf.color(class))
+		case visitLiteral:
+			class := classNone // top-level literals are not colored
+			if len(path) != 0 {
+				class = literalClass(ParseObjectValue, src[start])
+			}
+			n += colorCost(conf.color(class))
+		}
+		// Members and elements after the first are preceded by a ','.
+		if i := len(members) - 1; ev == visitKey || i >= 0 && path[i].IsIndex() {
+			if members[i] > 0 {
+				n += punct
+			}
+			members[i]++
+		}
+		if ev == visitBegin {
+			members = append(members, 0)
+		}
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,48 @@
+package pjson
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestColorOverhead(t *testing.T) {
+	tests := []string{
+		`1`,
+		`"s"`,
+		`{}`,
+		`[]`,
+		`{"a": [1, "b", true, false, null, {}, []], "c": {"d": -1.5e3}}`,
+		`[[["a"]], {"b": {"c": null}}]`,
+		`1 2`,
+		`[1][2] {"a":"b"}"c"`,
+		`{"": 1, " ": {"\t": 2}}`,
+	}
+	for _, conf := range []*IndentConfig{&DefaultIndentConfig, &JQIndentConfig, &noColorIndentConfig} {
+		for _, in := range tests {
+			got, err := conf.ColorOverhead([]byte(in))
+			if err != nil {
+				t.Errorf("%q: %v", in, err)
+				continue
+			}
+			// Compare with the difference between colored and plain output.
+			var colored, plain bytes.Buffer
+			s := NewStream(bytes.NewReader([]byte(in)), conf)
+			if _, err := s.WriteTo(&colored); err != nil {
+				t.Fatal(err)
+			}
+			s = NewStream(bytes.NewReader([]byte(in)), &noColorIndentConfig)
+			if _, err := s.WriteTo(&plain); err != nil {
+				t.Fatal(err)
+			}
+			if want := int64(colored.Len() - plain.Len()); got != want {
+				t.Errorf("ColorOverhead(%q) = %d; want: %d", in, got, want)
+			}
+		}
+	}
+
+	for _, in := range []string{`{`, `[1,]`, `1 }`} {
+		if _, err := DefaultIndentConfig.ColorOverhead([]byte(in)); err == nil {
+			t.Errorf("ColorOverhead(%q): expected an error", in)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package pjson
+
+import (
+	"bytes"
+
+	"github.com/charlievieth/pjson/termcolor"
+)
+
+// An sgrSpan is an SGR escape sequence removed from colored input and the
+// offset of the byte it preceded in the input without escape sequences.
+type sgrSpan struct {
+	off int
+	seq []byte
+}
+
+// stripColor appends src to dst with the SGR escape sequences outside of
+// strings removed and returns them as spans. Escape sequences in strings
+// are left in place, since they are not valid JSON.
+func stripColor(dst, src []byte) ([]byte, []sgrSpan) {
+	var spans []sgrSpan
+	inString := false
+	escaped := false
+	start := 0 // start of src not yet appended to dst
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case !inString && c == '\x1b':
+			if n := termcolor.SGRLen(src[i:]); n > 0 {
+				dst = append(dst, src[start:i]...)
+				spans = append(spans, sgrSpan{off: len(dst), seq: src[i : i+n]})
+				i += n - 1
+				start = i + 1
+			}
+		}
+	}
+	return append(dst, src[start:]...), spans
+}
+
+// IndentPassthrough is like Indent but accepts input that is already
+// colored with SGR escape sequences ("\x1b[...m") between values, such as
+// the output of another JSON colorizer, and preserves them instead of
+// adding color. Each escape sequence is written immediately before the
+// token it preceded in src. Multiple top-level values are separated by a
+// newline.
+func IndentPassthrough(dst *bytes.Buffer, src []byte, prefix, indent string) error {
+	plain, spans := stripColor(nil, src)
+	src = plain
+
+	origLen := dst.Len()
+	scan := newScanner()
+	defer freeScanner(scan)
+
+	// flush writes the escape sequences that precede src[i]. They are
+	// written after any space added before src[i] so that they stay with
+	// the token that follows them.
+	flush := func(i int) {
+		for len(spans) > 0 && spans[0].off <= i {
+			dst.Write(spans[0].seq)
+			spans = spans[1:]
+		}
+	}
+
+	allSpaces := isAllSpaces(indent)
+	needIndent := false
+	needSep := false // a top-level value was written
+	depth := 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		v := scan.Step(c)
+		if v == ScanEnd {
+			// The top-level value ended before c.
+			scan.Reset()
+			needSep = true
+			v = scan.Step(c)
+		}
+		if v == ScanSkipSpace {
+			i += scan.skipSpace(src[i+1:])
+			continue
+		}
+		if v == ScanError {
+			break
+		}
+		if needSep {
+			needSep = false
+			dst.WriteByte('\n')
+			dst.WriteString(prefix)
+		}
+		if needIndent && v != ScanEndObject && v != ScanEndArray {
+			needIndent = false
+			depth++
+			newline(dst, prefix, indent, depth, allSpaces)
+		}
+		flush(i)
+		if v == ScanBeginLiteral {
+			j := i
+			for i++; i < len(src); i++ {
+				c = src[i]
+				v = scan.Step(c)
+				if v != ScanContinue {
+					break
+				}
+			}
+			dst.Write(src[j:i])
+			// Escape sequences that immediately follow a literal, such
+			// as a reset, stay with it.
+			flush(i)
+			switch {
+			case v == ScanEnd:
+				// c follows a top-level literal: handle it next.
+				i--
+				scan.Reset()
+				scan.bytes--
+				needSep = true
+				continue
+			case v == ScanSkipSpace:
+				i += scan.skipSpace(src[i+1:])
+				continue
+			case i == len(src):
+				continue
+			}
+		}
+
+		// Add spacing around real punctuation.
+		switch c {
+		case '{', '[':
+			// delay indent so that empty object and array are formatted as {} and [].
+			needIndent = true
+			dst.WriteByte(c)
+
+		case ',':
+			dst.WriteByte(c)
+			newline(dst, prefix, indent, depth, allSpaces)
+
+		case ':':
+			dst.WriteByte(c)
+			dst.WriteByte(' ')
+
+		case '}', ']':
+			if needIndent {
+				// suppress indent in empty object/array
+				needIndent = false
+			} else {
+				depth--
+				newline(dst, prefix, indent, depth, allSpaces)
+			}
+			dst.WriteByte(c)
+
+		default:
+			dst.WriteByte(c)
+		}
+	}
+	if scan.EOF() == ScanError {
+		dst.Truncate(origLen)
+		return scan.Err()
+	}
+	flush(len(src))
+	return nil
+}
@@ -0,0 +1,65 @@
+package pjson
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/charlievieth/pjson/termcolor"
+)
+
+func TestIndentPassthrough(t *testing.T) {
+	const (
+		blue  = "\x1b[34;1m"
+		green = "\x1b[0;32m"
+		reset = "\x1b[0m"
+	)
+	tests := []struct {
+		in, want string
+	}{
+		{
+			// Output of jq -C -c
+			in: "\x1b[1;39m{" + blue + `"a"` + reset + "\x1b[1;39m:" + green + `"x"` + reset +
+				"\x1b[1;39m," + blue + `"b"` + reset + "\x1b[1;39m:\x1b[0;39m1" + reset +
+				"\x1b[1;39m\x1b[1;39m}" + reset,
+			want: "\x1b[1;39m{\n  " + blue + `"a"` + reset + "\x1b[1;39m: " + green + `"x"` + reset +
+				"\x1b[1;39m,\n  " + blue + `"b"` + reset + "\x1b[1;39m: \x1b[0;39m1" + reset +
+				"\x1b[1;39m\x1b[1;39m\n}" + reset,
+		},
+		{
+			in:   green + "1" + reset + " " + green + "2" + reset + "\n[" + green + "3" + reset + "]",
+			want: green + "1" + reset + "\n" + green + "2" + reset + "\n[\n  " + green + "3" + reset + "\n]",
+		},
+		{
+			in:   `{"k": "a\u001b[0mb"}`,
+			want: "{\n  \"k\": \"a\\u001b[0mb\"\n}",
+		},
+		{
+			in:   `{"a":[],"b":{}} {}`,
+			want: "{\n  \"a\": [],\n  \"b\": {}\n}\n{}",
+		},
+	}
+	for _, test := range tests {
+		var dst bytes.Buffer
+		if err := IndentPassthrough(&dst, []byte(test.in), "", "  "); err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if got := dst.String(); got != test.want {
+			t.Errorf("%q:\ngot:  %q\nwant: %q", test.in, got, test.want)
+		}
+		// The output without color must match Indent.
+		var want bytes.Buffer
+		plain := termcolor.Strip(nil, []byte(test.in))
+		if err := Indent(&want, plain, "", "  "); err == nil {
+			if got := termcolor.Strip(nil, dst.Bytes()); string(got) != want.String() {
+				t.Errorf("%q: stripped output = %q; want: %q", test.in, got, want.String())
+			}
+		}
+	}
+
+	// A raw escape in a string is not valid JSON.
+	var dst bytes.Buffer
+	if err := IndentPassthrough(&dst, []byte("\"\x1b[0m\""), "", "  "); err == nil {
+		t.Error("expected an error for an escape sequence in a string")
+	}
+}
@@ -0,0 +1,353 @@
+package pjson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// newFlatNode returns the tree of the valid compact JSON value src.
+// Object members keep their order; the last of duplicate keys wins.
+func newFlatNode(src []byte) *flatNode {
+	switch kind := kindOf(src[0]); kind {
+	case KindObject:
+		n := &flatNode{kind: kind, fields: make(map[string]*flatNode)}
+		for _, m := range objectMembers(nil, src) {
+			if _, ok := n.fields[m.key]; !ok {
+				n.keys = append(n.keys, m.key)
+			}
+			n.fields[m.key] = newFlatNode(m.value)
+		}
+		return n
+	case KindArray:
+		n := &flatNode{kind: kind, elems: []*flatNode{}}
+		for _, e := range arrayElems(nil, src) {
+			n.elems = append(n.elems, newFlatNode(e))
+		}
+		return n
+	default:
+		return &flatNode{kind: kind, raw: src}
+	}
+}
+
+// appendNode appends the compact JSON encoding of n to dst.
+func appendNode(dst []byte, n *flatNode) []byte {
+	e := newEncodeState()
+	n.encode(e)
+	dst = append(dst, e.Bytes()...)
+	encodeStatePool.Put(e)
+	return dst
+}
+
+// A patchOp is an operation of a JSON Patch.
+type patchOp struct {
+	Op    string     `json:"op"`
+	Path  *string    `json:"path"`
+	From  *string    `json:"from"`
+	Value RawMessage `json:"value"`
+}
+
+// patchDoc is a document being patched.
+type patchDoc struct {
+	root *flatNode
+}
+
+// ApplyPatch applies the JSON Patch (RFC 6902) patch to the JSON document
+// doc and returns the result as compact JSON. The operations are applied
+// in order and if any fails, including a failed test, only an error is
+// returned. The order of object members is preserved and
+// added members follow the existing ones.
+func ApplyPatch(doc, patch []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := compact(&buf, doc, false); err != nil {
+		return nil, err
+	}
+	var ops []patchOp
+	if err := Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("pjson: invalid JSON Patch: %w", err)
+	}
+	d := patchDoc{root: newFlatNode(buf.Bytes())}
+	for i := range ops {
+		op := &ops[i]
+		if err := d.apply(op); err != nil {
+			path := ""
+			if op.Path != nil {
+				path = *op.Path
+			}
+			return nil, fmt.Errorf("pjson: patch operation %d (%s %q): %w", i, op.Op, path, err)
+		}
+	}
+	return appendNode(nil, d.root), nil
+}
+
+func (d *patchDoc) apply(op *patchOp) error {
+	if op.Path == nil {
+		return errors.New(`missing "path"`)
+	}
+	path, err := ParsePointer(*op.Path)
+	if err != nil {
+		return err
+	}
+	var from []string
+	switch op.Op {
+	case "move", "copy":
+		if op.From == nil {
+			return errors.New(`missing "from"`)
+		}
+		if from, err = ParsePointer(*op.From); err != nil {
+			return err
+		}
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return errors.New(`missing "value"`)
+		}
+	}
+	switch op.Op {
+	case "add":
+		v, err := patchValue(op.Value)
+		if err != nil {
+			return err
+		}
+		return d.add(path, v)
+	case "remove":
+		_, err := d.remove(path)
+		return err
+	case "replace":
+		v, err := patchValue(op.Value)
+		if err != nil {
+			return err
+		}
+		return d.replace(path, v)
+	case "move":
+		if *op.From == *op.Path {
+			_, err := d.get(from)
+			return err
+		}
+		if strings.HasPrefix(*op.Path, *op.From+"/") {
+			return errors.New("cannot move a value into itself")
+		}
+		v, err := d.remove(from)
+		if err != nil {
+			return err
+		}
+		return d.add(path, v)
+	case "copy":
+		v, err := d.get(from)
+		if err != nil {
+			return err
+		}
+		return d.add(path, newFlatNode(appendNode(nil, v)))
+	case "test":
+		v, err := d.get(path)
+		if err != nil {
+			return err
+		}
+		var want bytes.Buffer
+		if err := compact(&want, op.Value, false); err != nil {
+			return err
+		}
+		if !pathEqual(appendNode(nil, v), want.Bytes()) {
+			return errors.New("test failed")
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid op %q", op.Op)
+}
+
+// patchValue returns the tree of the value of an operation.
+func patchValue(raw []byte) (*flatNode, error) {
+	var buf bytes.Buffer
+	if err := compact(&buf, raw, false); err != nil {
+		return nil, err
+	}
+	return newFlatNode(buf.Bytes()), nil
+}
+
+// patchIndex returns the array index token tok of an array of length n.
+// The index n, the end of the array, is only valid if end is true.
+func patchIndex(tok string, n int, end bool) (int, error) {
+	if tok == "-" && end {
+		return n, nil
+	}
+	i, err := strconv.Atoi(tok)
+	if err != nil || i < 0 || tok != strconv.Itoa(i) {
+		return 0, fmt.Errorf("invalid array index %q", tok)
+	}
+	if i > n || i == n && !end {
+		return 0, fmt.Errorf("array index %d out of range", i)
+	}
+	return i, nil
+}
+
+// patchChild returns the child of n at the reference token tok.
+func patchChild(n *flatNode, tok string) (*flatNode, error) {
+	switch n.kind {
+	case KindObject:
+		if c, ok := n.fields[tok]; ok {
+			return c, nil
+		}
+		return nil, fmt.Errorf("no member %q", tok)
+	case KindArray:
+		i, err := patchIndex(tok, len(n.elems), false)
+		if err != nil {
+			return nil, err
+		}
+		return n.elems[i], nil
+	}
+	return nil, fmt.Errorf("cannot index %s with %q", n.kind, tok)
+}
+
+// get returns the value at path.
+func (d *patchDoc) get(path []string) (*flatNode, error) {
+	n := d.root
+	for _, tok := range path {
+		var err error
+		if n, err = patchChild(n, tok); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// parent returns the object or array containing the value at path, which
+// must not be the root.
+func (d *patchDoc) parent(path []string) (*flatNode, error) {
+	n, err := d.get(path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	if n.kind != KindObject && n.kind != KindArray {
+		return nil, fmt.Errorf("cannot index %s with %q", n.kind, path[len(path)-1])
+	}
+	return n, nil
+}
+
+func (d *patchDoc) add(path []string, v *flatNode) error {
+	if len(path) == 0 {
+		d.root = v
+		return nil
+	}
+	n, err := d.parent(path)
+	if err != nil {
+		return err
+	}
+	tok := path[len(path)-1]
+	if n.kind == KindObject {
+		if _, ok := n.fields[tok]; !ok {
+			n.keys = append(n.keys, tok)
+		}
+		n.fields[tok] = v
+		return nil
+	}
+	i, err := patchIndex(tok, len(n.elems), true)
+	if err != nil {
+		return err
+	}
+	n.elems = append(n.elems, nil)
+	copy(n.elems[i+1:], n.elems[i:])
+	n.elems[i] = v
+	return nil
+}
+
+func (d *patchDoc) remove(path []string) (*flatNode, error) {
+	if len(path) == 0 {
+		return nil, errors.New("cannot remove the root")
+	}
+	n, err := d.parent(path)
+	if err != nil {
+		return nil, err
+	}
+	tok := path[len(path)-1]
+	v, err := patchChild(n, tok)
+	if err != nil {
+		return nil, err
+	}
+	if n.kind == KindObject {
+		delete(n.fields, tok)
+		n.keys = removeKey(n.keys, tok)
+		return v, nil
+	}
+	i, _ := patchIndex(tok, len(n.elems), false)
+	n.elems = append(n.elems[:i], n.elems[i+1:]...)
+	return v, nil
+}
+
+func (d *patchDoc) replace(path []string, v *flatNode) error {
+	if len(path) == 0 {
+		d.root = v
+		return nil
+	}
+	n, err := d.parent(path)
+	if err != nil {
+		return err
+	}
+	tok := path[len(path)-1]
+	if _, err := patchChild(n, tok); err != nil {
+		return err
+	}
+	if n.kind == KindObject {
+		n.fields[tok] = v
+	} else {
+		i, _ := patchIndex(tok, len(n.elems), false)
+		n.elems[i] = v
+	}
+	return nil
+}
+
+// ApplyMergePatch applies the JSON Merge Patch (RFC 7386) patch to the JSON
+// document doc and returns the result as compact JSON. Members of patch
+// that are null remove the member from doc, other members replace or are
+// merged into the member of doc. A patch that is not an object replaces
+// doc. The order of object members is preserved and added members follow
+// the existing ones.
+func ApplyMergePatch(doc, patch []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := compact(&buf, doc, false); err != nil {
+		return nil, err
+	}
+	target := newFlatNode(buf.Bytes())
+	var pbuf bytes.Buffer
+	if err := compact(&pbuf, patch, false); err != nil {
+		return nil, fmt.Errorf("pjson: invalid JSON Merge Patch: %w", err)
+	}
+	return appendNode(nil, mergePatch(target, newFlatNode(pbuf.Bytes()))), nil
+}
+
+// mergePatch returns the result of applying patch to target, which is
+// modified, or nil if there is no target.
+func mergePatch(target, patch *flatNode) *flatNode {
+	if patch.kind != KindObject {
+		return patch
+	}
+	if target == nil || target.kind != KindObject {
+		target = &flatNode{kind: KindObject, fields: make(map[string]*flatNode)}
+	}
+	for _, k := range patch.keys {
+		v := patch.fields[k]
+		if v.kind == KindNull {
+			if _, ok := target.fields[k]; ok {
+				delete(target.fields, k)
+				target.keys = removeKey(target.keys, k)
+			}
+			continue
+		}
+		old, ok := target.fields[k]
+		if !ok {
+			target.keys = append(target.keys, k)
+		}
+		target.fields[k] = mergePatch(old, v)
+	}
+	return target
+}
+
+// removeKey removes the key k from keys.
+func removeKey(keys []string, k string) []string {
+	for i, key := range keys {
+		if key == k {
+			return append(keys[:i], keys[i+1:]...)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,103 @@
+package pjson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	tests := []struct {
+		doc, patch, want string
+	}{
+		// The examples of RFC 6902 appendix A.
+		{`{"foo": "bar"}`, `[{"op": "add", "path": "/baz", "value": "qux"}]`, `{"foo":"bar","baz":"qux"}`},
+		{`{"foo": ["bar", "baz"]}`, `[{"op": "add", "path": "/foo/1", "value": "qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"baz": "qux", "foo": "bar"}`, `[{"op": "remove", "path": "/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo": ["bar", "qux", "baz"]}`, `[{"op": "remove", "path": "/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz": "qux", "foo": "bar"}`, `[{"op": "replace", "path": "/baz", "value": "boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			`[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo": ["all", "grass", "cows", "eat"]}`, `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			`{"foo":["all","cows","eat","grass"]}`},
+		{`{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			`[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2.0}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo": "bar"}`, `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`,
+			`{"foo":"bar","child":{"grandchild":{}}}`},
+		{`{"foo": ["bar"]}`, `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+			`{"foo":["bar",["abc","def"]]}`},
+		{`{"/": 9, "~1": 10}`, `[{"op": "test", "path": "/~01", "value": 10}]`, `{"/":9,"~1":10}`},
+		{`{"a": [1]}`, `[{"op": "copy", "from": "/a", "path": "/b"}, {"op": "add", "path": "/b/0", "value": 0}]`,
+			`{"a":[1],"b":[0,1]}`},
+		{`{"a": 1}`, `[{"op": "replace", "path": "", "value": [1]}]`, `[1]`},
+		{`{"a": {"b": null}}`, `[{"op": "test", "path": "/a", "value": {"b": null}}]`, `{"a":{"b":null}}`},
+	}
+	for _, test := range tests {
+		got, err := ApplyPatch([]byte(test.doc), []byte(test.patch))
+		if err != nil {
+			t.Errorf("%s: %v", test.patch, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("%s:\ngot:  %s\nwant: %s", test.patch, got, test.want)
+		}
+	}
+}
+
+func TestApplyPatchError(t *testing.T) {
+	tests := []struct {
+		doc, patch, err string
+	}{
+		{`{"baz": "qux"}`, `[{"op": "test", "path": "/baz", "value": "bar"}]`, "test failed"},
+		{`{"foo": "bar"}`, `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`, `no member "baz"`},
+		{`{"foo": "bar"}`, `[{"op": "remove", "path": "/baz"}]`, `no member "baz"`},
+		{`{"foo": [1]}`, `[{"op": "add", "path": "/foo/2", "value": 0}]`, "out of range"},
+		{`{"foo": [1]}`, `[{"op": "replace", "path": "/foo/01", "value": 0}]`, "invalid array index"},
+		{`{"foo": "bar"}`, `[{"op": "add", "path": "/baz"}]`, `missing "value"`},
+		{`{"foo": "bar"}`, `[{"op": "move", "path": "/baz"}]`, `missing "from"`},
+		{`{"foo": {}}`, `[{"op": "move", "from": "/foo", "path": "/foo/a"}]`, "into itself"},
+		{`{"foo": "bar"}`, `[{"op": "frob", "path": "/foo"}]`, `invalid op "frob"`},
+		{`{"foo": "bar"}`, `{"op": "remove", "path": "/foo"}`, "invalid JSON Patch"},
+		{`{"foo": "bar"}`, `[{"op": "remove", "path": ""}]`, "cannot remove the root"},
+	}
+	for _, test := range tests {
+		_, err := ApplyPatch([]byte(test.doc), []byte(test.patch))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error = %v; want: %q", test.patch, err, test.err)
+		}
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	// The examples of RFC 7386 appendix A.
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, test := range tests {
+		got, err := ApplyMergePatch([]byte(test.doc), []byte(test.patch))
+		if err != nil {
+			t.Errorf("%s: %v", test.patch, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("%s + %s:\ngot:  %s\nwant: %s", test.doc, test.patch, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package pjson
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charlievieth/pjson/termcolor"
+)
+
+// Kind is the kind of a JSON value.
+type Kind int8
+
+const (
+	KindInvalid Kind = iota
+	KindNull
+	KindBool
+	KindNumber
+	KindString
+	KindObject
+	KindArray
+)
+
+var kindStrs = [...]string{
+	"invalid",
+	"null",
+	"bool",
+	"number",
+	"string",
+	"object",
+	"array",
+}
+
+func (k Kind) String() string {
+	if uint(k) < uint(len(kindStrs)) {
+		return kindStrs[k]
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// kindOf returns the Kind of the JSON value beginning with byte c.
+func kindOf(c byte) Kind {
+	switch c {
+	case '{':
+		return KindObject
+	case '[':
+		return KindArray
+	case '"':
+		return KindString
+	case 't', 'f':
+		return KindBool
+	case 'n':
+		return KindNull
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return KindNumber
+	}
+	return KindInvalid
+}
+
+// A PathElem is an element of a Path: either an object key or an array
+// index.
+type PathElem struct {
+	Key   string
+	Index int // array index or -1 if the element is an object key
+}
+
+// IsIndex reports whether e is an array index.
+func (e PathElem) IsIndex() bool { return e.Index >= 0 }
+
+// A Path is the location of a value in a JSON document.
+type Path []PathElem
+
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '_' && !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') &&
+			!(i > 0 && '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns p in the dotted form used by jq: `.a.b[0]["c d"]`.
+// The root path is "." and paths that begin with an index or a quoted key
+// begin with ".", such as `.["c d"]`.
+func (p Path) String() string {
+	if len(p) == 0 {
+		return "."
+	}
+	var w strings.Builder
+	if !p.dotted() {
+		w.WriteByte('.')
+	}
+	for _, e := range p {
+		switch {
+		case e.IsIndex():
+			w.WriteByte('[')
+			w.WriteString(strconv.Itoa(e.Index))
+			w.WriteByte(']')
+		case isIdentifier(e.Key):
+			w.WriteByte('.')
+			w.WriteString(e.Key)
+		default:
+			w.WriteByte('[')
+			w.Write(appendQuoted(nil, e.Key))
+			w.WriteByte(']')
+		}
+	}
+	return w.String()
+}
+
+// dotted reports whether the first element of the non-empty path p is
+// written with a leading '.', as a key that is an identifier.
+func (p Path) dotted() bool {
+	return !p[0].IsIndex() && isIdentifier(p[0].Key)
+}
+
+// pattern returns p in the dotted form of String with every array index
+// replaced by "[]", such as `.items[].name`.
+func (p Path) pattern() string {
+	if len(p) == 0 {
+		return "."
+	}
+	var w strings.Builder
+	if !p.dotted() {
+		w.WriteByte('.')
+	}
+	for _, e := range p {
+		switch {
+		case e.IsIndex():
+			w.WriteString("[]")
+		case isIdentifier(e.Key):
+			w.WriteByte('.')
+			w.WriteString(e.Key)
+		default:
+			w.WriteByte('[')
+			w.Write(appendQuoted(nil, e.Key))
+			w.WriteByte(']')
+		}
+	}
+	return w.String()
+}
+
+var pointerReplacer = strings.NewReplacer("~", "~0", "/", "~1")
+
+// Pointer returns p as a JSON Pointer (RFC 6901). The root path is "".
+func (p Path) Pointer() string {
+	var w strings.Builder
+	for _, e := range p {
+		w.WriteByte('/')
+		if e.IsIndex() {
+			w.WriteString(strconv.Itoa(e.Index))
+		} else {
+			pointerReplacer.WriteString(&w, e.Key)
+		}
+	}
+	return w.String()
+}
+
+// walk calls fn for every value in the JSON document data after the end
+// of the value is scanned. Children are visited before their parent, so
+// scalars are visited in document order. The path passed to fn is only
+// valid for the duration of the call. If fn returns false walking stops.
+// An error is returned if data is not valid JSON.
+func walk(data []byte, fn func(path Path, kind Kind, start, end int) bool) error {
+	return visit(data, false, func(ev visitEvent, path Path, start, end int) bool {
+		if ev == visitLiteral || ev == visitEnd {
+			return fn(path, kindOf(data[start]), start, end)
+		}
+		return true
+	})
+}
+
+var errOffsetRange = errors.New("pjson: offset is not within a JSON value")
+
+// ValueAt returns the extent src[start:end], path and Kind of the innermost
+// JSON value containing the byte at offset. Offsets within an object key
+// or between values resolve to the enclosing object or array. The path is
+// in the dotted form returned by Path.String.
+func ValueAt(src []byte, offset int) (start, end int, path string, kind Kind, err error) {
+	if offset < 0 || offset >= len(src) {
+		return 0, 0, "", KindInvalid, errOffsetRange
+	}
+	// Walk the entire document so that invalid JSON is reported.
+	found := false
+	err = walk(src, func(p Path, k Kind, s, e int) bool {
+		if !found && s <= offset && offset < e {
+			start, end, path, kind = s, e, p.String(), k
+			found = true
+		}
+		return true
+	})
+	if err != nil {
+		return 0, 0, "", KindInvalid, err
+	}
+	if !found {
+		return 0, 0, "", KindInvalid, errOffsetRange
+	}
+	return start, end, path, kind, nil
+}
+
+// Walk calls fn for every value in the JSON document data after the end
+// of the value is scanned. Children are visited before their parent, so
+// scalars are visited in document order. The path and value passed to fn
+// are only valid for the duration of the call. If fn returns false walking
+// stops. An error is returned if data is not valid JSON.
+func Walk(data []byte, fn func(path Path, kind Kind, value []byte) bool) error {
+	return walk(data, func(path Path, kind Kind, start, end int) bool {
+		return fn(path, kind, data[start:end])
+	})
+}
+
+// ValueColor returns the color conf uses for the JSON value beginning
+// with value[0].
+func (conf *IndentConfig) ValueColor(value []byte) *termcolor.Color {
+	if len(value) == 0 {
+		return nil
+	}
+	switch value[0] {
+	case '"':
+		return conf.String
+	case 'n':
+		return conf.Null
+	case 't':
+		return conf.True
+	case 'f':
+		return conf.False
+	case '{', '[':
+		return conf.Punctuation
+	}
+	return conf.Numeric
+}
+
+func appendColor(dst []byte, c *termcolor.Color, s string) []byte {
+	dst = c.Append(dst)
+	dst = append(dst, s...)
+	return append(dst, c.Reset()...)
+}
+
+// AppendPath appends the colorized form of p to dst. If pointer is true
+// p is formatted as a JSON Pointer, otherwise the dotted form returned by
+// Path.String is used.
+func (conf *IndentConfig) AppendPath(dst []byte, p Path, pointer bool) []byte {
+	if pointer {
+		for _, e := range p {
+			dst = appendColor(dst, conf.Punctuation, "/")
+			if e.IsIndex() {
+				dst = appendColor(dst, conf.Numeric, strconv.Itoa(e.Index))
+			} else {
+				dst = appendColor(dst, conf.Keyword, pointerReplacer.Replace(e.Key))
+			}
+		}
+		return dst
+	}
+	if len(p) == 0 || !p.dotted() {
+		dst = appendColor(dst, conf.Punctuation, ".")
+	}
+	for _, e := range p {
+		switch {
+		case e.IsIndex():
+			dst = appendColor(dst, conf.Punctuation, "[")
+			dst = appendColor(dst, conf.Numeric, strconv.Itoa(e.Index))
+			dst = appendColor(dst, conf.Punctuation, "]")
+		case isIdentifier(e.Key):
+			dst = appendColor(dst, conf.Punctuation, ".")
+			dst = appendColor(dst, conf.Keyword, e.Key)
+		default:
+			dst = appendColor(dst, conf.Punctuation, "[")
+			dst = appendColor(dst, conf.Keyword, string(appendQuoted(nil, e.Key)))
+			dst = appendColor(dst, conf.Punctuation, "]")
+		}
+	}
+	return dst
+}
+
+// ParsePath parses a path in the dotted form returned by Path.String,
+// such as `.a[0]["b c"]`. As with jq a '.' may precede a bracket
+// (`.["b c"]`). For compatibility with gron the path may begin with
+// "json" (`json.a[0]`) and, as written by older versions, with a bracket
+// (`["b c"]`).
+func ParsePath(s string) (Path, error) {
+	orig := s
+	if s == "" {
+		return nil, fmt.Errorf("pjson: invalid path %q", orig)
+	}
+	if strings.HasPrefix(s, "json") && (len(s) == 4 || s[4] == '.' || s[4] == '[') {
+		s = s[len("json"):]
+		if s == "" {
+			return nil, nil
+		}
+	}
+	if s == "." {
+		return nil, nil
+	}
+	var p Path
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			if len(s) > 1 && s[1] == '[' {
+				s = s[1:]
+				continue
+			}
+			i := 1
+			for i < len(s) && s[i] != '.' && s[i] != '[' {
+				i++
+			}
+			key := s[1:i]
+			if !isIdentifier(key) {
+				return nil, fmt.Errorf("pjson: invalid path %q: invalid key %q", orig, key)
+			}
+			p = append(p, PathElem{Key: key, Index: -1})
+			s = s[i:]
+		case '[':
+			if len(s) > 1 && s[1] == '"' {
+				prefix, err := strconv.QuotedPrefix(s[1:])
+				if err != nil || !strings.HasPrefix(s[1+len(prefix):], "]") {
+					return nil, fmt.Errorf("pjson: invalid path %q: invalid quoted key", orig)
+				}
+				key, _ := strconv.Unquote(prefix)
+				p = append(p, PathElem{Key: key, Index: -1})
+				s = s[len(prefix)+2:]
+				continue
+			}
+			i := strings.IndexByte(s, ']')
+			if i == -1 {
+				return nil, fmt.Errorf("pjson: invalid path %q: missing ']'", orig)
+			}
+			n, err := strconv.Atoi(s[1:i])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("pjson: invalid path %q: invalid index %q", orig, s[1:i])
+			}
+			p = append(p, PathElem{Index: n})
+			s = s[i+1:]
+		default:
+			return nil, fmt.Errorf("pjson: invalid path %q", orig)
+		}
+	}
+	return p, nil
+}
+
+// KeyPaths returns the distinct paths of the object members in the JSON
+// document data, in the order they first appear, in the dotted form of
+// Path.String with every array index replaced by "[]". For example, the
+// paths of {"items": [{"name": "a"}, {"name": "b"}]} are ".items" and
+// ".items[].name".
+func KeyPaths(data []byte) ([]string, error) {
+	first := make(map[string]int) // offset each path first appears at
+	err := walk(data, func(path Path, _ Kind, start, _ int) bool {
+		if len(path) == 0 || path[len(path)-1].IsIndex() {
+			return true
+		}
+		p := path.pattern()
+		if off, ok := first[p]; !ok || start < off {
+			first[p] = start
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(first))
+	for p := range first {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return first[paths[i]] < first[paths[j]]
+	})
+	return paths, nil
+}
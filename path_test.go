@@ -0,0 +1,216 @@
+package pjson
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/charlievieth/pjson/termcolor"
+)
+
+func TestPathString(t *testing.T) {
+	tests := []struct {
+		path    Path
+		str     string
+		pointer string
+	}{
+		{nil, ".", ""},
+		{Path{{Key: "a", Index: -1}}, ".a", "/a"},
+		{Path{{Key: "a", Index: -1}, {Index: 0}, {Key: "b_1", Index: -1}}, ".a[0].b_1", "/a/0/b_1"},
+		{Path{{Key: "a b", Index: -1}, {Key: "1", Index: -1}}, `.["a b"]["1"]`, "/a b/1"},
+		{Path{{Key: "", Index: -1}}, `.[""]`, "/"},
+		{Path{{Key: "a/b~c", Index: -1}}, `.["a/b~c"]`, "/a~1b~0c"},
+		{Path{{Key: "a b", Index: -1}, {Index: 0}}, `.["a b"][0]`, "/a b/0"},
+		{Path{{Index: 1}, {Key: "a", Index: -1}}, ".[1].a", "/1/a"},
+		// Keys are quoted as JSON strings, not Go strings.
+		{Path{{Key: "a\x00\a\vb", Index: -1}}, `.["a\u0000\u0007\u000bb"]`, "/a\x00\a\vb"},
+		{Path{{Key: "\U0001f600", Index: -1}}, ".[\"\U0001f600\"]", "/\U0001f600"},
+		{Path{{Key: "<\u2028>", Index: -1}}, `.["<\u2028>"]`, "/<\u2028>"},
+	}
+	for _, test := range tests {
+		if s := test.path.String(); s != test.str {
+			t.Errorf("String() = %q; want: %q", s, test.str)
+		}
+		if p, err := ParsePath(test.str); err != nil || !reflect.DeepEqual(p, test.path) {
+			t.Errorf("ParsePath(%q) = %v, %v; want: %v", test.str, p, err, test.path)
+		}
+		if s := test.path.Pointer(); s != test.pointer {
+			t.Errorf("Pointer() = %q; want: %q", s, test.pointer)
+		}
+	}
+}
+
+func TestWalk(t *testing.T) {
+	type value struct {
+		path string
+		kind Kind
+		raw  string
+	}
+	data := `{"a": [1, {"b": null}, "s"], "c d": {}, "e": [], "f": true}`
+	want := []value{
+		{".a[0]", KindNumber, "1"},
+		{".a[1].b", KindNull, "null"},
+		{".a[1]", KindObject, `{"b": null}`},
+		{".a[2]", KindString, `"s"`},
+		{".a", KindArray, `[1, {"b": null}, "s"]`},
+		{`.["c d"]`, KindObject, "{}"},
+		{".e", KindArray, "[]"},
+		{".f", KindBool, "true"},
+		{".", KindObject, data},
+	}
+	var got []value
+	err := walk([]byte(data), func(path Path, kind Kind, start, end int) bool {
+		got = append(got, value{path.String(), kind, data[start:end]})
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk:\ngot:  %+v\nwant: %+v", got, want)
+	}
+
+	for _, s := range []string{`[1,`, `{"a":1} x`, ``} {
+		if err := walk([]byte(s), func(Path, Kind, int, int) bool { return true }); err == nil {
+			t.Errorf("walk(%q): expected an error", s)
+		}
+	}
+}
+
+func TestKeyPaths(t *testing.T) {
+	data := `{"items": [{"name": "a", "tags": ["x"]}, {"id": 1, "name": "b"}], ` +
+		`"a b": {"c": [[{"d": null}]]}, "e": 1}`
+	want := []string{".items", ".items[].name", ".items[].tags", ".items[].id",
+		`.["a b"]`, `.["a b"].c`, `.["a b"].c[][].d`, ".e"}
+	got, err := KeyPaths([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("KeyPaths:\ngot:  %q\nwant: %q", got, want)
+	}
+	if got, err := KeyPaths([]byte(`[1, 2]`)); err != nil || len(got) != 0 {
+		t.Errorf("KeyPaths([1, 2]) = %q, %v; want: none", got, err)
+	}
+	if _, err := KeyPaths([]byte(`{"a":`)); err == nil {
+		t.Error("KeyPaths: expected an error for invalid JSON")
+	}
+}
+
+func TestValueAt(t *testing.T) {
+	src := []byte(`{"a": [1, {"b": null}, "str"], "c": 12.5}`)
+	tests := []struct {
+		offset     int
+		start, end int
+		path       string
+		kind       Kind
+	}{
+		{0, 0, len(src), ".", KindObject},
+		{2, 0, len(src), ".", KindObject}, // key
+		{7, 7, 8, ".a[0]", KindNumber},
+		{8, 6, 29, ".a", KindArray}, // comma
+		{17, 16, 20, ".a[1].b", KindNull},
+		{24, 23, 28, ".a[2]", KindString},
+		{37, 36, 40, ".c", KindNumber},
+	}
+	for _, test := range tests {
+		start, end, path, kind, err := ValueAt(src, test.offset)
+		if err != nil {
+			t.Errorf("ValueAt(%d): %v", test.offset, err)
+			continue
+		}
+		if start != test.start || end != test.end || path != test.path || kind != test.kind {
+			t.Errorf("ValueAt(%d) = %d, %d, %q, %s; want: %d, %d, %q, %s", test.offset,
+				start, end, path, kind, test.start, test.end, test.path, test.kind)
+		}
+	}
+
+	if _, _, _, _, err := ValueAt([]byte(" 1 "), 0); err == nil {
+		t.Error("expected an error for an offset outside of a value")
+	}
+	if _, _, _, _, err := ValueAt(src, len(src)); err == nil {
+		t.Error("expected an error for an out of range offset")
+	}
+	if _, _, _, _, err := ValueAt([]byte(`[1,`), 1); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
+
+func TestAppendPath(t *testing.T) {
+	path := Path{{Key: "a", Index: -1}, {Index: 1}, {Key: "b c", Index: -1}}
+	var noColor IndentConfig
+	if s := string(noColor.AppendPath(nil, path, false)); s != path.String() {
+		t.Errorf("AppendPath() = %q; want: %q", s, path.String())
+	}
+	if s := string(noColor.AppendPath(nil, path, true)); s != path.Pointer() {
+		t.Errorf("AppendPath(pointer) = %q; want: %q", s, path.Pointer())
+	}
+	if s := string(noColor.AppendPath(nil, nil, false)); s != "." {
+		t.Errorf("AppendPath(root) = %q; want: %q", s, ".")
+	}
+
+	conf := DefaultIndentConfig
+	got := string(conf.AppendPath(nil, path[:2], false))
+	want := conf.Punctuation.Sprintf(".") + conf.Keyword.Sprintf("a") +
+		conf.Punctuation.Sprintf("[") + conf.Numeric.Sprintf("1") + conf.Punctuation.Sprintf("]")
+	if got != want {
+		t.Errorf("AppendPath() = %q; want: %q", got, want)
+	}
+}
+
+func TestValueColor(t *testing.T) {
+	conf := JQIndentConfig
+	tests := []struct {
+		value string
+		want  *termcolor.Color
+	}{
+		{`"s"`, conf.String},
+		{`null`, conf.Null},
+		{`true`, conf.True},
+		{`false`, conf.False},
+		{`-1`, conf.Numeric},
+		{`{}`, conf.Punctuation},
+		{``, nil},
+	}
+	for _, test := range tests {
+		if got := conf.ValueColor([]byte(test.value)); got != test.want {
+			t.Errorf("ValueColor(%q) = %v; want: %v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Path
+	}{
+		{".", nil},
+		{"json", nil},
+		{".a", Path{{Key: "a", Index: -1}}},
+		{"json.a[0]", Path{{Key: "a", Index: -1}, {Index: 0}}},
+		{`.a[12]["b c"].d`, Path{{Key: "a", Index: -1}, {Index: 12}, {Key: "b c", Index: -1}, {Key: "d", Index: -1}}},
+		{`["a\"]"]`, Path{{Key: `a"]`, Index: -1}}},
+		{".json", Path{{Key: "json", Index: -1}}},
+		{`.["a b"][0]`, Path{{Key: "a b", Index: -1}, {Index: 0}}},
+		{".[1].a", Path{{Index: 1}, {Key: "a", Index: -1}}},
+		{"json[0]", Path{{Index: 0}}},
+	}
+	for _, test := range tests {
+		got, err := ParsePath(test.in)
+		if err != nil {
+			t.Errorf("ParsePath(%q): %v", test.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParsePath(%q) = %+v; want: %+v", test.in, got, test.want)
+		}
+		if s := got.String(); test.in[0] == '.' && s != test.in {
+			t.Errorf("ParsePath(%q).String() = %q", test.in, s)
+		}
+	}
+
+	for _, in := range []string{"", "a", ".a b", "..a", ".a[", ".a[-1]", ".a[x]", `.a["b"`, `["b"x]`} {
+		if _, err := ParsePath(in); err == nil {
+			t.Errorf("ParsePath(%q): expected error", in)
+		}
+	}
+}
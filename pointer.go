@@ -0,0 +1,146 @@
+package pjson
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrPointerNotFound is returned by ExtractPointer if there is no value at
+// the JSON Pointer.
+var ErrPointerNotFound = errors.New("pjson: no value at JSON Pointer")
+
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// ParsePointer parses the JSON Pointer (RFC 6901) s, such as "/a/0", and
+// returns its unescaped reference tokens. The pointer "" refers to the
+// whole document and has no tokens.
+func ParsePointer(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("pjson: invalid JSON Pointer %q: must begin with '/'", s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, tok := range tokens {
+		for j := 0; j < len(tok); j++ {
+			if tok[j] == '~' && (j+1 == len(tok) || tok[j+1] != '0' && tok[j+1] != '1') {
+				return nil, fmt.Errorf("pjson: invalid JSON Pointer %q: invalid escape in %q", s, tok)
+			}
+		}
+		tokens[i] = pointerUnescaper.Replace(tok)
+	}
+	return tokens, nil
+}
+
+// pointerFrame is an object or array enclosing the value being read by
+// ExtractPointer.
+type pointerFrame struct {
+	array bool
+	index int    // index of the current element
+	key   string // key of the current member
+}
+
+func (f *pointerFrame) matches(token string) bool {
+	if f.array {
+		return token == strconv.Itoa(f.index)
+	}
+	return f.key == token
+}
+
+// ExtractPointer returns the value at the JSON Pointer pointer in the
+// first JSON value read from r. Only the value is kept in memory and
+// reading stops once it ends, so the input that follows it is not
+// validated. If there is no such value the error is ErrPointerNotFound.
+func ExtractPointer(r io.Reader, pointer string) ([]byte, error) {
+	tokens, err := ParsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	scan := newScanner()
+	defer freeScanner(scan)
+
+	atTarget := func(frames []pointerFrame) bool {
+		if len(frames) != len(tokens) {
+			return false
+		}
+		for i := range frames {
+			if !frames[i].matches(tokens[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var frames []pointerFrame
+	var value []byte // the value being captured
+	capturing := false
+	literal := false // the captured value is a literal
+	var key []byte   // the key being read
+	inKey := false
+	buf := make([]byte, 32*1024)
+	for {
+		n, rerr := r.Read(buf)
+		for _, c := range buf[:n] {
+			v := scan.Step(c)
+			if v == ScanError {
+				return nil, scan.err
+			}
+			if capturing && literal && v != ScanContinue {
+				return value, nil
+			}
+			if v == ScanEnd {
+				return nil, fmt.Errorf("%w: %q", ErrPointerNotFound, pointer)
+			}
+			if inKey && v != ScanContinue {
+				inKey = false
+				frames[len(frames)-1].key, _ = unquote(key)
+			}
+			switch v {
+			case ScanBeginLiteral, ScanBeginObject, ScanBeginArray:
+				if v == ScanBeginLiteral && scan.CurrentParseState() == ParseObjectKey {
+					inKey = true
+					key = key[:0]
+					break
+				}
+				if n := len(frames) - 1; n >= 0 && frames[n].array {
+					frames[n].index++
+				}
+				if !capturing && atTarget(frames) {
+					capturing = true
+					literal = v == ScanBeginLiteral
+				}
+				if v != ScanBeginLiteral {
+					frames = append(frames, pointerFrame{array: v == ScanBeginArray, index: -1})
+				}
+			case ScanEndObject, ScanEndArray:
+				frames = frames[:len(frames)-1]
+			}
+			if inKey {
+				key = append(key, c)
+			}
+			if capturing {
+				value = append(value, c)
+				if !literal && (v == ScanEndObject || v == ScanEndArray) && len(frames) == len(tokens) {
+					return value, nil
+				}
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return nil, rerr
+		}
+	}
+	if scan.EOF() == ScanError {
+		return nil, scan.err
+	}
+	if capturing {
+		return value, nil // a top-level literal
+	}
+	return nil, fmt.Errorf("%w: %q", ErrPointerNotFound, pointer)
+}
@@ -0,0 +1,79 @@
+package pjson
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestParsePointer(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"/", []string{""}},
+		{"/a/0", []string{"a", "0"}},
+		{"/a~1b/c~0d/~01", []string{"a/b", "c~d", "~1"}},
+	}
+	for _, test := range tests {
+		got, err := ParsePointer(test.in)
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParsePointer(%q) = %q, %v; want: %q", test.in, got, err, test.want)
+		}
+	}
+	for _, s := range []string{"a", "/a~", "/a~2"} {
+		if _, err := ParsePointer(s); err == nil {
+			t.Errorf("ParsePointer(%q): expected an error", s)
+		}
+	}
+}
+
+func TestExtractPointer(t *testing.T) {
+	const doc = `{"a": {"b": [1, {"c": "x"}, [2, 3]]}, "a/b": true, "": 0, "d": "}"}` + "\n"
+	tests := []struct {
+		pointer string
+		want    string
+	}{
+		{"", strings.TrimSpace(doc)},
+		{"/a", `{"b": [1, {"c": "x"}, [2, 3]]}`},
+		{"/a/b/0", `1`},
+		{"/a/b/1", `{"c": "x"}`},
+		{"/a/b/1/c", `"x"`},
+		{"/a/b/2/1", `3`},
+		{"/a~1b", `true`},
+		{"/", `0`},
+		{"/d", `"}"`},
+	}
+	for _, test := range tests {
+		r := iotest.OneByteReader(strings.NewReader(doc))
+		got, err := ExtractPointer(r, test.pointer)
+		if err != nil {
+			t.Errorf("%q: %v", test.pointer, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("%q: got: %s; want: %s", test.pointer, got, test.want)
+		}
+	}
+
+	for _, pointer := range []string{"/x", "/a/b/3", "/a/b/01", "/a/b/-", "/a/b/0/x"} {
+		_, err := ExtractPointer(strings.NewReader(doc), pointer)
+		if !errors.Is(err, ErrPointerNotFound) {
+			t.Errorf("%q: error = %v; want: %v", pointer, err, ErrPointerNotFound)
+		}
+	}
+
+	// The value is returned before the invalid input that follows it.
+	if got, err := ExtractPointer(strings.NewReader(`[1, 2, x`), "/1"); err != nil || string(got) != "2" {
+		t.Errorf("ExtractPointer = %s, %v; want: 2", got, err)
+	}
+	if _, err := ExtractPointer(strings.NewReader(`[x, 2]`), "/1"); err == nil || errors.Is(err, ErrPointerNotFound) {
+		t.Errorf("ExtractPointer: error = %v; want: syntax error", err)
+	}
+	if got, err := ExtractPointer(strings.NewReader(`42`), ""); err != nil || string(got) != "42" {
+		t.Errorf("ExtractPointer = %s, %v; want: 42", got, err)
+	}
+}
@@ -0,0 +1,324 @@
+package pjson
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type queryOpKind int8
+
+const (
+	queryKey     queryOpKind = iota // .key or ["key"]
+	queryIndex                      // [n]
+	querySlice                      // [lo:hi]
+	queryIterate                    // []
+)
+
+// A queryOp is a step of a Query's path.
+type queryOp struct {
+	kind     queryOpKind
+	key      string
+	index    int // index, or the start of a slice
+	end      int // end of a slice
+	hasIndex bool
+	hasEnd   bool
+	optional bool // suppress errors, like jq's "?"
+}
+
+// A Selector selects values from a JSON value, such as a Query or a
+// JSONPath. Apply appends the selected values to dst.
+type Selector interface {
+	Apply(dst [][]byte, src []byte) ([][]byte, error)
+}
+
+// A Query is a filter in the subset of the jq language made of identity
+// (.), field access (.a, ."a b", .["a b"]), indexing (.[0], .[-1]),
+// slices (.[1:3]), iteration (.[]) and pipes (|). Any step may be followed
+// by "?" to ignore the errors it causes. A Query produces zero or more
+// values for each value it is applied to.
+type Query struct {
+	expr string
+	ops  []queryOp // pipes are flattened since each term is a path
+}
+
+// String returns the expression q was parsed from.
+func (q *Query) String() string { return q.expr }
+
+// ParseQuery parses the filter expression expr, such as `.items[] | .name`.
+func ParseQuery(expr string) (*Query, error) {
+	q := &Query{expr: expr}
+	for _, term := range splitPipes(expr) {
+		term = strings.TrimSpace(term)
+		if term == "" || term[0] != '.' {
+			return nil, fmt.Errorf("pjson: invalid filter %q: expected a path beginning with '.'", expr)
+		}
+		ops, err := parseQueryPath(term)
+		if err != nil {
+			return nil, fmt.Errorf("pjson: invalid filter %q: %w", expr, err)
+		}
+		q.ops = append(q.ops, ops...)
+	}
+	return q, nil
+}
+
+// splitPipes splits expr at each '|' that is not in a string.
+func splitPipes(expr string) []string {
+	var terms []string
+	inString := false
+	start := 0
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case c == '|' && !inString:
+			terms = append(terms, expr[start:i])
+			start = i + 1
+		}
+	}
+	return append(terms, expr[start:])
+}
+
+func isIdentByte(c byte, first bool) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' ||
+		!first && '0' <= c && c <= '9'
+}
+
+// parseQueryPath parses a term of a filter, such as `.a[0]."b c"[]`.
+func parseQueryPath(s string) ([]queryOp, error) {
+	if s == "." {
+		return nil, nil
+	}
+	var ops []queryOp
+	for len(s) > 0 {
+		var op queryOp
+		switch {
+		case s[0] == '.' && len(s) > 1 && s[1] == '"':
+			key, n, err := parseQueryString(s[1:])
+			if err != nil {
+				return nil, err
+			}
+			op = queryOp{kind: queryKey, key: key}
+			s = s[1+n:]
+		case s[0] == '.' && len(s) > 1 && isIdentByte(s[1], true):
+			i := 2
+			for i < len(s) && isIdentByte(s[i], false) {
+				i++
+			}
+			op = queryOp{kind: queryKey, key: s[1:i]}
+			s = s[i:]
+		case s[0] == '.' && len(s) > 1 && s[1] == '[':
+			s = s[1:]
+			continue
+		case s[0] == '[':
+			i := strings.IndexByte(s, ']')
+			if len(s) > 1 && s[1] == '"' {
+				key, n, err := parseQueryString(s[1:])
+				if err != nil {
+					return nil, err
+				}
+				if !strings.HasPrefix(s[1+n:], "]") {
+					return nil, fmt.Errorf("missing ']' after %s", s[1:1+n])
+				}
+				op = queryOp{kind: queryKey, key: key}
+				s = s[n+2:]
+				break
+			}
+			if i == -1 {
+				return nil, fmt.Errorf("missing ']' in %q", s)
+			}
+			var err error
+			if op, err = parseQueryIndex(strings.TrimSpace(s[1:i])); err != nil {
+				return nil, err
+			}
+			s = s[i+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q", s)
+		}
+		if strings.HasPrefix(s, "?") {
+			op.optional = true
+			s = s[1:]
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// parseQueryString parses the quoted string at the start of s and returns
+// it and its length in s.
+func parseQueryString(s string) (string, int, error) {
+	prefix, err := strconv.QuotedPrefix(s)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid string in %q", s)
+	}
+	key, err := strconv.Unquote(prefix)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid string %s", prefix)
+	}
+	return key, len(prefix), nil
+}
+
+// parseQueryIndex parses the contents of brackets that are not a key: an
+// empty iteration, an index or a slice.
+func parseQueryIndex(s string) (queryOp, error) {
+	if s == "" {
+		return queryOp{kind: queryIterate}, nil
+	}
+	lo, hi, isSlice := strings.Cut(s, ":")
+	op := queryOp{kind: queryIndex}
+	if isSlice {
+		op.kind = querySlice
+	}
+	var err error
+	if lo = strings.TrimSpace(lo); lo != "" {
+		op.hasIndex = true
+		if op.index, err = strconv.Atoi(lo); err != nil {
+			return op, fmt.Errorf("invalid index %q", lo)
+		}
+	} else if !isSlice {
+		return op, fmt.Errorf("invalid index %q", s)
+	}
+	if hi = strings.TrimSpace(hi); hi != "" {
+		op.hasEnd = true
+		if op.end, err = strconv.Atoi(hi); err != nil {
+			return op, fmt.Errorf("invalid index %q", hi)
+		}
+	}
+	return op, nil
+}
+
+var jsonNull = []byte("null")
+
+// Apply appends the results of applying q to the JSON value src to dst and
+// returns the extended slice. The result of the identity filter is src
+// itself, other results are compact JSON.
+func (q *Query) Apply(dst [][]byte, src []byte) ([][]byte, error) {
+	if len(q.ops) == 0 {
+		return append(dst, src), nil
+	}
+	var buf bytes.Buffer
+	if err := compact(&buf, src, false); err != nil {
+		return dst, err
+	}
+	return q.apply(dst, q.ops, buf.Bytes())
+}
+
+func (q *Query) apply(dst [][]byte, ops []queryOp, v []byte) ([][]byte, error) {
+	if len(ops) == 0 {
+		return append(dst, v), nil
+	}
+	op := ops[0]
+	var err error
+	switch kind := kindOf(v[0]); {
+	case kind == KindNull && op.kind != queryIterate:
+		// Indexing null yields null, as in jq.
+		return q.apply(dst, ops[1:], jsonNull)
+	case op.kind == queryKey:
+		if kind != KindObject {
+			err = fmt.Errorf("cannot index %s with %q", kind, op.key)
+			break
+		}
+		if value := memberValue(v, op.key); value != nil {
+			v = value
+		} else {
+			v = jsonNull
+		}
+		return q.apply(dst, ops[1:], v)
+	case op.kind == queryIndex:
+		if kind != KindArray {
+			err = fmt.Errorf("cannot index %s with number", kind)
+			break
+		}
+		elems := arrayElems(nil, v)
+		i := op.index
+		if i < 0 {
+			i += len(elems)
+		}
+		v = jsonNull
+		if 0 <= i && i < len(elems) {
+			v = elems[i]
+		}
+		return q.apply(dst, ops[1:], v)
+	case op.kind == querySlice:
+		switch kind {
+		case KindArray:
+			elems := arrayElems(nil, v)
+			lo, hi := op.bounds(len(elems))
+			b := []byte{'['}
+			for i, e := range elems[lo:hi] {
+				if i > 0 {
+					b = append(b, ',')
+				}
+				b = append(b, e...)
+			}
+			return q.apply(dst, ops[1:], append(b, ']'))
+		case KindString:
+			s, _ := unquote(v)
+			runes := []rune(s)
+			lo, hi := op.bounds(len(runes))
+			b := appendQuoted(nil, string(runes[lo:hi]))
+			return q.apply(dst, ops[1:], b)
+		}
+		err = fmt.Errorf("cannot slice %s", kind)
+	case op.kind == queryIterate:
+		switch kind {
+		case KindArray:
+			for _, e := range arrayElems(nil, v) {
+				if dst, err = q.apply(dst, ops[1:], e); err != nil {
+					return dst, err
+				}
+			}
+			return dst, nil
+		case KindObject:
+			for _, m := range objectMembers(nil, v) {
+				if dst, err = q.apply(dst, ops[1:], m.value); err != nil {
+					return dst, err
+				}
+			}
+			return dst, nil
+		}
+		err = fmt.Errorf("cannot iterate over %s", kind)
+	}
+	if op.optional {
+		return dst, nil
+	}
+	return dst, fmt.Errorf("pjson: %s: %w", q.expr, err)
+}
+
+// bounds returns the bounds of the slice op of a sequence of length n.
+// Negative bounds count from the end and bounds are clamped to [0, n].
+func (op *queryOp) bounds(n int) (lo, hi int) {
+	clamp := func(i int) int {
+		if i < 0 {
+			i += n
+		}
+		if i < 0 {
+			return 0
+		}
+		if i > n {
+			return n
+		}
+		return i
+	}
+	lo, hi = 0, n
+	if op.hasIndex {
+		lo = clamp(op.index)
+	}
+	if op.hasEnd {
+		hi = clamp(op.end)
+	}
+	if hi < lo {
+		hi = lo
+	}
+	return lo, hi
+}
+
+// appendQuoted appends s as a JSON string to dst.
+func appendQuoted(dst []byte, s string) []byte {
+	var e encodeState
+	e.string(s, false)
+	return append(dst, e.Bytes()...)
+}
@@ -0,0 +1,105 @@
+package pjson
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestQueryApply(t *testing.T) {
+	const doc = `{"items": [{"name": "a", "n": 1}, {"name": "b"}], "s": "héllo", "a b": {"c": null}, "e": {}}`
+	tests := []struct {
+		expr string
+		want string // results joined by spaces
+	}{
+		{".", doc},
+		{".items[0].name", `"a"`},
+		{".items[] | .name", `"a" "b"`},
+		{".items[].n", `1 null`},
+		{".items[-1]", `{"name":"b"}`},
+		{".items[5]", `null`},
+		{".items[1:]", `[{"name":"b"}]`},
+		{".items[:-2]", `[]`},
+		{".s[1:3]", `"él"`},
+		{`."a b".c`, `null`},
+		{`.["a b"] | .c.d`, `null`},
+		{".missing.x", `null`},
+		{".e[]", ``},
+		{".[] | .c?", `null null`},
+		{".s[]?", ``},
+		{". | .items | .[] | .name", `"a" "b"`},
+		{".items[]?.name?", `"a" "b"`},
+	}
+	for _, test := range tests {
+		q, err := ParseQuery(test.expr)
+		if err != nil {
+			t.Errorf("ParseQuery(%q): %v", test.expr, err)
+			continue
+		}
+		vals, err := q.Apply(nil, []byte(doc))
+		if err != nil {
+			t.Errorf("%q: Apply: %v", test.expr, err)
+			continue
+		}
+		if got := string(bytes.Join(vals, []byte(" "))); got != test.want {
+			t.Errorf("%q: got: %s; want: %s", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestQueryApplyError(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{".items.name", `cannot index array with "name"`},
+		{".s[0]", "cannot index string with number"},
+		{".s[]", "cannot iterate over string"},
+		{".e[1:2]", "cannot slice object"},
+	}
+	const doc = `{"items": [], "s": "x", "e": {}}`
+	for _, test := range tests {
+		q, err := ParseQuery(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = q.Apply(nil, []byte(doc))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%q: error = %v; want: %q", test.expr, err, test.err)
+		}
+	}
+}
+
+func TestParseQueryError(t *testing.T) {
+	for _, expr := range []string{"", "a", ".a |", ".[", ".[x]", `.["a"`, ".a.", "..", ".a | b"} {
+		if _, err := ParseQuery(expr); err == nil {
+			t.Errorf("ParseQuery(%q): expected an error", expr)
+		}
+	}
+}
+
+func TestStreamQuery(t *testing.T) {
+	in := `{"items": [{"name": "a"}, {"name": "b"}]} {"items": []} {"items": [{"name": "c"}]}`
+	q, err := ParseQuery(".items[].name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStream(strings.NewReader(in), &noColorIndentConfig)
+	s.SetQuery(q)
+	s.SetStreamArrays(true) // ignored
+	var dst bytes.Buffer
+	if _, err := s.WriteTo(&dst); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := dst.String(), "\"a\"\n\"b\"\n\"c\"\n"; got != want {
+		t.Errorf("got: %q; want: %q", got, want)
+	}
+
+	q, _ = ParseQuery(".a")
+	s = NewStream(strings.NewReader(`[1]`), &noColorIndentConfig)
+	s.SetQuery(q)
+	if _, err := s.WriteTo(io.Discard); err == nil {
+		t.Error("expected an error indexing an array with a key")
+	}
+}
@@ -0,0 +1,27 @@
+package pjson
+
+import "strings"
+
+// DefaultRedaction is the value Redact replaces redacted values with if
+// the replacement is empty.
+const DefaultRedaction = "«redacted»"
+
+// Redact appends src to dst with the value of each object member whose
+// key is one of keys, compared without regard to case, replaced by the
+// JSON string replacement, or DefaultRedaction if replacement is empty.
+// Objects and arrays are replaced whole. An error is returned if src is
+// not valid JSON.
+func Redact(dst, src []byte, keys []string, replacement string) ([]byte, error) {
+	var r rewriter
+	r.setRedact(keys, replacement)
+	return r.rewrite(dst, src)
+}
+
+func redactedKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package pjson
+
+import "testing"
+
+func TestRedact(t *testing.T) {
+	keys := []string{"password", "Token", "authorization"}
+	tests := []struct {
+		in, repl, want string
+	}{
+		{`{"user": "a", "password": "hunter2"}`, "", `{"user": "a", "password": "«redacted»"}`},
+		{`{"token":1,"TOKEN":null}`, "xxx", `{"token":"xxx","TOKEN":"xxx"}`},
+		{`[{"headers": {"Authorization": ["Bearer x", {"a": 1}], "Accept": "*/*"}}]`, "",
+			`[{"headers": {"Authorization": "«redacted»", "Accept": "*/*"}}]`},
+		{`{"password" : { "nested": [1, 2] } , "b": "password"}`, "-",
+			`{"password" : "-" , "b": "password"}`},
+		{`["password", "token"]`, "", `["password", "token"]`},
+		{`"password"`, "", `"password"`},
+		{`{"passwords": 1, "pass\u0077ord": 2}`, "x", `{"passwords": 1, "pass\u0077ord": "x"}`},
+	}
+	for _, test := range tests {
+		got, err := Redact(nil, []byte(test.in), keys, test.repl)
+		if err != nil {
+			t.Errorf("%s: %v", test.in, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("%s:\ngot:  %s\nwant: %s", test.in, got, test.want)
+		}
+	}
+	if _, err := Redact(nil, []byte(`{"password": [1,}`), keys, ""); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
@@ -0,0 +1,124 @@
+package pjson
+
+// A rewriter rewrites the keys and literals of JSON values in a single
+// pass. Each key or literal is passed, in order, to the hooks, replaced if
+// it is the value of a redacted key, truncated and then escaped. It holds
+// the rewrites enabled on a Stream and implements Redact.
+type rewriter struct {
+	hooks     Hooks
+	redact    []string // keys whose values are redacted
+	redaction []byte   // JSON string that replaces redacted values
+	maxString int      // truncate longer strings, 0 for none
+	unescape  bool     // decode \u escapes
+	html      bool     // escape HTML characters
+	ascii     bool     // escape non-ASCII characters
+	safe      bool     // escape terminal control characters
+	tmp       [2][]byte
+}
+
+func (r *rewriter) enabled() bool {
+	return r.hooks.enabled() || len(r.redact) > 0 || r.maxString > 0 ||
+		r.unescape || r.html || r.ascii || r.safe
+}
+
+// setRedact sets the keys whose values are replaced by the string
+// replacement, or DefaultRedaction if replacement is empty.
+func (r *rewriter) setRedact(keys []string, replacement string) {
+	if replacement == "" {
+		replacement = DefaultRedaction
+	}
+	r.redact = keys
+	r.redaction = appendQuoted(r.redaction[:0], replacement)
+}
+
+// rewrite appends src rewritten to dst. An error is returned if src is not
+// valid JSON.
+func (r *rewriter) rewrite(dst, src []byte) ([]byte, error) {
+	last := 0       // end of the src already copied to dst
+	redact := false // the next value is redacted
+	skip := -1      // depth of the redacted object or array
+	err := visit(src, false, func(ev visitEvent, path Path, start, end int) bool {
+		if skip != -1 {
+			if ev == visitEnd && len(path) == skip {
+				skip = -1
+				last = end
+			}
+			return true
+		}
+		switch ev {
+		case visitBegin:
+			if redact {
+				redact = false
+				dst = append(dst, src[last:start]...)
+				dst = r.token(dst, r.redaction, false)
+				skip = len(path)
+			}
+			return true
+		case visitEnd:
+			return true
+		}
+		dst = append(dst, src[last:start]...)
+		last = end
+		raw := r.hooks.token(ev, path, src[start:end])
+		if ev == visitKey {
+			if len(r.redact) > 0 {
+				key, _ := unquote(raw)
+				redact = redactedKey(r.redact, key)
+			}
+			dst = r.token(dst, raw, true)
+			return true
+		}
+		if redact {
+			redact = false
+			raw = r.redaction
+		}
+		dst = r.value(dst, raw)
+		return true
+	})
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, src[last:]...), nil
+}
+
+// value appends the value raw, returned by a hook, rewritten to dst.
+func (r *rewriter) value(dst, raw []byte) []byte {
+	if len(raw) == 0 || (raw[0] != '{' && raw[0] != '[') {
+		return r.token(dst, raw, false)
+	}
+	// Rewrite the strings of objects and arrays returned by hooks
+	// without passing them to the hooks again.
+	nested := *r
+	nested.hooks = Hooks{}
+	nested.tmp = [2][]byte{}
+	if out, err := nested.rewrite(dst, raw); err == nil {
+		return out
+	}
+	return append(dst, raw...)
+}
+
+// token appends the key or literal raw to dst escaped and, if it is a
+// string value, truncated.
+func (r *rewriter) token(dst, raw []byte, key bool) []byte {
+	if !key && r.maxString > 0 && len(raw) != 0 && raw[0] == '"' {
+		raw = truncateString(raw, r.maxString)
+	}
+	// Each step writes to the temporary buffer raw is not in.
+	i := 0
+	if r.unescape {
+		r.tmp[i] = UnescapeUnicode(r.tmp[i][:0], raw)
+		raw, i = r.tmp[i], i^1
+	}
+	if r.html {
+		r.tmp[i] = appendHTMLEscape(r.tmp[i][:0], raw)
+		raw, i = r.tmp[i], i^1
+	}
+	if r.ascii {
+		r.tmp[i] = EscapeASCII(r.tmp[i][:0], raw)
+		raw, i = r.tmp[i], i^1
+	}
+	if r.safe {
+		return Sanitize(dst, raw)
+	}
+	return append(dst, raw...)
+}
@@ -0,0 +1,44 @@
+package pjson
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRewriter(t *testing.T) {
+	upper := Hooks{
+		OnKey: func(_ Path, raw []byte) []byte { return bytes.ToUpper(raw) },
+		OnScalar: func(path Path, kind Kind, raw []byte) []byte {
+			if path.String() == ".obj" {
+				return []byte(`{"token": 1, "s": "<ééé>"}`)
+			}
+			return raw
+		},
+	}
+	tests := []struct {
+		r        rewriter
+		in, want string
+	}{
+		{rewriter{hooks: upper, redact: []string{"TOKEN"}, redaction: []byte(`"x"`)},
+			`{"token": [1], "a": 2}`, `{"TOKEN": "x", "A": 2}`},
+		{rewriter{hooks: upper, redact: []string{"token"}, redaction: []byte(`"x"`), maxString: 2, ascii: true},
+			`{"obj": null}`, `{"OBJ": {"token": "x", "s": "<\u00e9\u2026 (8 B)"}}`},
+		{rewriter{maxString: 3, html: true, ascii: true, unescape: true},
+			`{"<é>": "\u00e9<é>&", "n": 1.5}`, `{"\u003c\u00e9\u003e": "\u00e9\u003c\u00e9\u2026 (7 B)", "n": 1.5}`},
+		{rewriter{safe: true, redact: []string{"k"}, redaction: []byte("\"\x1b\"")},
+			`[{"k": {"a": {}}}, "\u001b"]`, `[{"k": "\u001b"}, "\u001b"]`},
+	}
+	for _, test := range tests {
+		got, err := test.r.rewrite(nil, []byte(test.in))
+		if err != nil {
+			t.Errorf("%s: %v", test.in, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("%s:\ngot:  %s\nwant: %s", test.in, got, test.want)
+		}
+	}
+	if _, err := new(rewriter).rewrite(nil, []byte(`{"a": [1,}`)); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
@@ -0,0 +1,85 @@
+package pjson
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// SampleArrays appends src to dst, compacted, with only the first n
+// elements of each array kept. The rest are replaced by a string element
+// counting them, such as "… 1,234,567 more elements", so the result is
+// valid JSON. An error is returned if src is not valid JSON.
+func SampleArrays(dst, src []byte, n int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := compact(&buf, src, false); err != nil {
+		return dst, err
+	}
+	return sampleValue(dst, buf.Bytes(), n), nil
+}
+
+// sampleValue appends the valid compact JSON value src to dst with the
+// arrays in it sampled.
+func sampleValue(dst, src []byte, n int) []byte {
+	switch src[0] {
+	case '[':
+		dst = append(dst, '[')
+		kept, more := 0, 0
+		for i := 1; src[i] != ']'; {
+			end := valueEnd(src[i:])
+			if kept < n {
+				if kept > 0 {
+					dst = append(dst, ',')
+				}
+				dst = sampleValue(dst, src[i:i+end], n)
+				kept++
+			} else {
+				more++
+			}
+			i += end
+			if src[i] == ',' {
+				i++
+			}
+		}
+		if more > 0 {
+			if kept > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendQuoted(dst, string(appendMoreElements(nil, int64(more))))
+		}
+		return append(dst, ']')
+	case '{':
+		dst = append(dst, '{')
+		for i := 1; src[i] != '}'; {
+			end := valueEnd(src[i:])
+			dst = append(dst, src[i:i+end+1]...) // key and ':'
+			i += end + 1
+			end = valueEnd(src[i:])
+			dst = sampleValue(dst, src[i:i+end], n)
+			i += end
+			if src[i] == ',' {
+				dst = append(dst, ',')
+				i++
+			}
+		}
+		return append(dst, '}')
+	}
+	return append(dst, src...)
+}
+
+// appendMoreElements appends the text of the element that replaces n
+// elements of a sampled array, such as "… 1,234,567 more elements", to
+// dst.
+func appendMoreElements(dst []byte, n int64) []byte {
+	dst = append(dst, truncationMark+" "...)
+	s := strconv.FormatInt(n, 10)
+	for i := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, s[i])
+	}
+	if n == 1 {
+		return append(dst, " more element"...)
+	}
+	return append(dst, " more elements"...)
+}
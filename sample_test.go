@@ -0,0 +1,35 @@
+package pjson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSampleArrays(t *testing.T) {
+	big := "[" + strings.Repeat("0,", 1234566) + "0]"
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{`[1, 2, 3]`, 2, `[1,2,"… 1 more element"]`},
+		{`[1, 2, 3]`, 3, `[1,2,3]`},
+		{`[1, 2, 3]`, 0, `["… 3 more elements"]`},
+		{`{"a": [[1, 2], [3], [4]], "b": {"c": []}}`, 1, `{"a":[[1,"… 1 more element"],"… 2 more elements"],"b":{"c":[]}}`},
+		{`{"[": "]", "x": "a,b"}`, 1, `{"[":"]","x":"a,b"}`},
+		{big, 1, `[0,"… 1,234,566 more elements"]`},
+	}
+	for _, test := range tests {
+		got, err := SampleArrays(nil, []byte(test.in), test.n)
+		if err != nil {
+			t.Errorf("%.40s: %v", test.in, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("%.40s: %d:\ngot:  %s\nwant: %s", test.in, test.n, got, test.want)
+		}
+	}
+	if _, err := SampleArrays(nil, []byte(`[1,`), 1); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
@@ -0,0 +1,192 @@
+package pjson
+
+import (
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// needsSanitize reports whether the string byte c might begin a terminal
+// control sequence.
+func needsSanitize(c byte) bool {
+	return c < ' ' || c >= utf8.RuneSelf-1 // DEL and non-ASCII
+}
+
+// Sanitize appends the JSON value src to dst with any characters in its
+// strings that a terminal could interpret as a control sequence replaced
+// by \u escapes. This includes C0 controls (including ESC), DEL and the
+// C1 controls U+0080 through U+009F. Invalid UTF-8 is replaced with
+// U+FFFD since a lone byte such as 0x9b is an 8-bit CSI on some
+// terminals. The decoded value of valid JSON is unchanged.
+//
+// If src does not need to be sanitized it is appended to dst unmodified.
+func Sanitize(dst, src []byte) []byte {
+	inString := false
+	escaped := false
+	start := 0 // start of src not yet appended to dst
+	for i := 0; i < len(src); {
+		c := src[i]
+		if !inString {
+			inString = c == '"'
+			i++
+			continue
+		}
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			inString = false
+		case needsSanitize(c):
+			r, size := rune(c), 1
+			if c >= utf8.RuneSelf {
+				r, size = utf8.DecodeRune(src[i:])
+			}
+			switch {
+			case r < ' ' || (0x7f <= r && r <= 0x9f):
+				dst = append(dst, src[start:i]...)
+				dst = append(dst, '\\', 'u', '0', '0', hex[r>>4], hex[r&0xF])
+				start = i + size
+			case r == utf8.RuneError && size == 1:
+				dst = append(dst, src[start:i]...)
+				dst = append(dst, `\ufffd`...)
+				start = i + size
+			}
+			i += size
+			continue
+		}
+		i++
+	}
+	return append(dst, src[start:]...)
+}
+
+// sanitizeRaw appends the unquoted contents of a string, as written by
+// SetRawStrings, to dst with the characters Sanitize escapes, other than
+// newline and tab, replaced by their \u escape and invalid UTF-8 replaced
+// with U+FFFD, so it cannot contain a terminal control sequence.
+func sanitizeRaw(dst, src []byte) []byte {
+	start := 0 // start of src not yet appended to dst
+	for i := 0; i < len(src); {
+		c := src[i]
+		if !needsSanitize(c) || c == '\n' || c == '\t' {
+			i++
+			continue
+		}
+		r, size := rune(c), 1
+		if c >= utf8.RuneSelf {
+			r, size = utf8.DecodeRune(src[i:])
+		}
+		switch {
+		case r < ' ' || (0x7f <= r && r <= 0x9f):
+			dst = append(dst, src[start:i]...)
+			dst = append(dst, '\\', 'u', '0', '0', hex[r>>4], hex[r&0xF])
+			start = i + size
+		case r == utf8.RuneError && size == 1:
+			dst = append(dst, src[start:i]...)
+			dst = utf8.AppendRune(dst, utf8.RuneError)
+			start = i + size
+		}
+		i += size
+	}
+	return append(dst, src[start:]...)
+}
+
+// EscapeASCII appends the JSON value src to dst with every non-ASCII
+// character in its strings replaced by a \u escape, like the ensure_ascii
+// option of Python's json module. Characters outside the Basic
+// Multilingual Plane are escaped as UTF-16 surrogate pairs and invalid
+// UTF-8 is replaced with \ufffd. The decoded value of valid JSON is
+// unchanged, apart from invalid UTF-8.
+//
+// If src is ASCII it is appended to dst unmodified.
+func EscapeASCII(dst, src []byte) []byte {
+	inString := false
+	escaped := false
+	start := 0 // start of src not yet appended to dst
+	for i := 0; i < len(src); {
+		c := src[i]
+		if !inString {
+			inString = c == '"'
+			i++
+			continue
+		}
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			inString = false
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRune(src[i:])
+			dst = append(dst, src[start:i]...)
+			if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+				dst = appendUnicodeEscape(dst, r1)
+				dst = appendUnicodeEscape(dst, r2)
+			} else {
+				dst = appendUnicodeEscape(dst, r)
+			}
+			i += size
+			start = i
+			continue
+		}
+		i++
+	}
+	return append(dst, src[start:]...)
+}
+
+// appendUnicodeEscape appends the \u escape of the BMP character r to dst.
+func appendUnicodeEscape(dst []byte, r rune) []byte {
+	return append(dst, '\\', 'u', hex[r>>12&0xF], hex[r>>8&0xF], hex[r>>4&0xF], hex[r&0xF])
+}
+
+// UnescapeUnicode appends the JSON value src to dst with the \u escapes in
+// its strings, including UTF-16 surrogate pairs, replaced by the UTF-8
+// encoding of the characters they represent. Escapes of characters that
+// must be escaped in JSON strings (quotation mark, reverse solidus and the
+// control characters U+0000 through U+001F) and of unpaired surrogates are
+// kept, so the result is valid JSON with the same decoded value as src.
+//
+// If src has no \u escapes it is appended to dst unmodified.
+func UnescapeUnicode(dst, src []byte) []byte {
+	inString := false
+	start := 0 // start of src not yet appended to dst
+	for i := 0; i < len(src); {
+		c := src[i]
+		if !inString {
+			inString = c == '"'
+			i++
+			continue
+		}
+		switch c {
+		case '"':
+			inString = false
+		case '\\':
+			r := getu4(src[i:])
+			if r < 0 {
+				i += 2 // skip the escaped character
+				continue
+			}
+			n := 6
+			if utf16.IsSurrogate(r) {
+				r = utf16.DecodeRune(r, getu4(src[i+6:]))
+				n = 12
+				if r == utf8.RuneError {
+					i += 6 // keep the unpaired surrogate
+					continue
+				}
+			}
+			if r < ' ' || r == '"' || r == '\\' {
+				i += 6 // keep the escape
+				continue
+			}
+			dst = append(dst, src[start:i]...)
+			dst = utf8.AppendRune(dst, r)
+			i += n
+			start = i
+			continue
+		}
+		i++
+	}
+	return append(dst, src[start:]...)
+}
@@ -0,0 +1,126 @@
+package pjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`{"a": [1, true, null]}`, `{"a": [1, true, null]}`},
+		{"{\n\t\"a\": \"b\"\r\n}", "{\n\t\"a\": \"b\"\r\n}"},
+		{`"日本語 ☺"`, `"日本語 ☺"`},
+		{`"\u001b[31m"`, `"\u001b[31m"`},
+		{"\"a\x1b[31mb\"", `"a\u001b[31mb"`},
+		{"\"\x07\x7f\"", `"\u0007\u007f"`},
+		{"\"\u009b2J\u0085 \"", "\"\\u009b2J\\u0085 \""},
+		{"\"\x9b2J\xff\"", `"\ufffd2J\ufffd"`},
+		{`"\"\x1b"`, `"\"\x1b"`},
+		{"{\"k\x1b\": \"\\\\\x1b\"}", `{"k\u001b": "\\\u001b"}`},
+	}
+	for _, test := range tests {
+		got := string(Sanitize(nil, []byte(test.in)))
+		if got != test.want {
+			t.Errorf("Sanitize(%q) = %q; want: %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestStreamSanitize(t *testing.T) {
+	const in = "[\"\x7f\u009b\", {\"a\": \"\xc2\x9b31m\"}]"
+	const want = `[
+    "\u007f\u009b",
+    {
+        "a": "\u009b31m"
+    }
+]
+`
+	s := NewStream(strings.NewReader(in), &DefaultIndentConfig)
+	s.SetIndent("", "    ")
+	s.SetSanitize(true)
+	var dst bytes.Buffer
+	if _, err := s.WriteTo(&dst); err != nil {
+		t.Fatal(err)
+	}
+	got := ansiRe.ReplaceAllString(dst.String(), "")
+	if got != want {
+		t.Errorf("got: %q want: %q", got, want)
+	}
+}
+
+func TestSanitizeRaw(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"a\tb\nc 日本語", "a\tb\nc 日本語"},
+		{"\x1b]0;pwned\x07x", `\u001b]0;pwned\u0007x`},
+		{"\r\x7f\u009b2J", `\u000d\u007f\u009b2J`},
+		{"a\x9bb\xff", "a\ufffdb\ufffd"},
+	}
+	for _, test := range tests {
+		got := string(sanitizeRaw(nil, []byte(test.in)))
+		if got != test.want {
+			t.Errorf("sanitizeRaw(%q) = %q; want: %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestStreamSanitizeRawStrings(t *testing.T) {
+	const in = `"\u001b]0;pwned\u0007x" "a\tb\nc" 1`
+	const want = "\\u001b]0;pwned\\u0007x\na\tb\nc\n1\n"
+	s := NewStream(strings.NewReader(in), &IndentConfig{})
+	s.SetRawStrings(true)
+	s.SetSanitize(true)
+	var dst bytes.Buffer
+	if _, err := s.WriteTo(&dst); err != nil {
+		t.Fatal(err)
+	}
+	if got := dst.String(); got != want {
+		t.Errorf("got: %q want: %q", got, want)
+	}
+}
+
+func TestEscapeASCII(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`{"a": [1, true, null]}`, `{"a": [1, true, null]}`},
+		{`"café"`, `"caf\u00e9"`},
+		{`{"日本": "☺"}`, `{"\u65e5\u672c": "\u263a"}`},
+		{`"😀"`, `"\ud83d\ude00"`},
+		{`"\"é\\é"`, `"\"\u00e9\\\u00e9"`},
+		{"\"\xff\"", `"\ufffd"`},
+		{"\"\x7f\"", "\"\x7f\""},
+	}
+	for _, test := range tests {
+		got := string(EscapeASCII(nil, []byte(test.in)))
+		if got != test.want {
+			t.Errorf("EscapeASCII(%q) = %q; want: %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestUnescapeUnicode(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`{"a": [1, true, null]}`, `{"a": [1, true, null]}`},
+		{`"caf\u00e9"`, `"café"`},
+		{`{"\u65E5\u672c": "\u263a"}`, `{"日本": "☺"}`},
+		{`"\ud83d\ude00!"`, `"😀!"`},
+		{`"\u0022\u005c\u001b\n\\u00e9"`, `"\u0022\u005c\u001b\n\\u00e9"`},
+		{`"\ud83d x \ude00"`, `"\ud83d x \ude00"`},
+		{`"\ufffd \udc00"`, `"� \udc00"`},
+		{`["\u0041", "\"\u0042"]`, `["A", "\"B"]`},
+	}
+	for _, test := range tests {
+		got := string(UnescapeUnicode(nil, []byte(test.in)))
+		if got != test.want {
+			t.Errorf("UnescapeUnicode(%q) = %q; want: %q", test.in, got, test.want)
+		}
+	}
+}
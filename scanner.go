@@ -14,8 +14,12 @@ package pjson
 // before diving into the scanner itself.
 
 import (
+	"bufio"
+	"io"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 // Valid reports whether data is a valid JSON encoding.
@@ -41,6 +45,147 @@ func checkValid(data []byte, scan *Scanner) error {
 	return nil
 }
 
+// ValidateStream reports whether r contains a stream of one or more valid
+// JSON values, which may be separated by white space. It returns the first
+// *SyntaxError in r, with its offset in the stream, or the error reading
+// r. Unlike Valid, the input is not read into memory.
+func ValidateStream(r io.Reader) error {
+	scan := newScanner()
+	defer freeScanner(scan)
+
+	buf := make([]byte, 64*1024)
+	started := false // a value began after the last one ended
+	ended := false   // a value ended
+	for {
+		n, err := r.Read(buf)
+		for _, c := range buf[:n] {
+			v := scan.Step(c)
+			if v == ScanEnd {
+				// The value ended before c: re-read c as the start
+				// of the next value.
+				scan.Reset()
+				scan.bytes--
+				v = scan.Step(c)
+				started = false
+				ended = true
+			}
+			switch v {
+			case ScanError:
+				return scan.err
+			case ScanSkipSpace:
+			default:
+				started = true
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if (started || !ended) && scan.EOF() == ScanError {
+		return scan.err
+	}
+	return nil
+}
+
+// ValidateAll is like Valid, but instead of stopping at the first error it
+// returns up to max syntax errors found in data. If max <= 0 all errors
+// are returned. After an error the scanner resynchronizes at the next
+// value separator or closing delimiter of the enclosing object or array.
+// All returned errors are of type *SyntaxError.
+func ValidateAll(data []byte, max int) []error {
+	scan := newScanner()
+	defer freeScanner(scan)
+
+	var errs []error
+	for i := 0; i < len(data); i++ {
+		scan.bytes = int64(i + 1)
+		if scan.step(scan, data[i]) != ScanError {
+			continue
+		}
+		errs = append(errs, scan.err)
+		if max > 0 && len(errs) >= max {
+			return errs
+		}
+		i = scan.resync(data, i, isStringError(scan.err))
+	}
+	scan.bytes = int64(len(data))
+	if scan.EOF() == ScanError {
+		errs = append(errs, scan.err)
+	}
+	return errs
+}
+
+// isStringError reports if err occurred inside of a string literal.
+func isStringError(err error) bool {
+	if se, ok := err.(*SyntaxError); ok {
+		return strings.HasSuffix(se.msg, "in string literal") ||
+			strings.HasSuffix(se.msg, "in string escape code") ||
+			strings.HasSuffix(se.msg, `in \u hexadecimal character escape`)
+	}
+	return false
+}
+
+// resync recovers from a syntax error at data[i] by skipping to the next
+// ',' or closing delimiter of the enclosing object or array and restoring
+// the matching scanner state. It returns the index of the last byte
+// consumed.
+func (s *Scanner) resync(data []byte, i int, inString bool) int {
+	s.err = nil
+	depth := 0 // nesting depth of skipped objects and arrays
+	escaped := false
+	for ; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		n := len(s.parseState)
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth > 0 {
+				depth--
+				continue
+			}
+			if n == 0 || (c == ']') != (s.parseState[n-1] == ParseArrayValue) {
+				continue
+			}
+			s.popParseState()
+			return i
+		case ',':
+			if depth > 0 || n == 0 {
+				continue
+			}
+			if s.parseState[n-1] == ParseArrayValue {
+				s.step = stateBeginValue
+			} else {
+				s.parseState[n-1] = ParseObjectKey
+				s.step = stateBeginString
+			}
+			return i
+		}
+	}
+	// Reached the end of data without resynchronizing.
+	if len(s.parseState) == 0 {
+		s.step = stateEndTop
+		s.endTop = true
+	}
+	return i
+}
+
 // A SyntaxError is a description of a JSON syntax error.
 type SyntaxError struct {
 	msg    string // description of error
@@ -61,6 +206,9 @@ func (e *SyntaxError) Error() string { return e.msg }
 // just got passed in.  (The indication must be delayed in order
 // to recognize the end of numbers: is 123 a whole value or
 // the beginning of 12345e+6?).
+//
+// Scanners are created with NewScanner and returned with Release, or
+// declared and prepared with Reset.
 type Scanner struct {
 	// The step is a func to be called to execute the next transition.
 	// Also tried using an integer constant and a single func
@@ -110,7 +258,34 @@ var scannerPool = sync.Pool{
 	},
 }
 
-func newScanner() *Scanner {
+// DefaultScannerPoolDepth is the default limit set by SetScannerPoolDepth.
+const DefaultScannerPoolDepth = 1024
+
+// scannerPoolDepth is the limit set by SetScannerPoolDepth, it is accessed
+// atomically.
+var scannerPoolDepth int64 = DefaultScannerPoolDepth
+
+// SetScannerPoolDepth limits the memory retained by the package's pool of
+// Scanners and returns the previous limit. A released Scanner is only
+// reused if the deepest value it scanned was nested at most depth levels,
+// otherwise its parse state stack is discarded. If depth is negative
+// Scanners are never pooled: NewScanner always allocates a new Scanner
+// and Release discards it.
+//
+// SetScannerPoolDepth is safe to call concurrently with other operations.
+func SetScannerPoolDepth(depth int) int {
+	return int(atomic.SwapInt64(&scannerPoolDepth, int64(depth)))
+}
+
+// NewScanner returns a Scanner ready to scan a new top-level value. The
+// Scanner may be taken from a pool shared by the package's functions, it
+// should be returned with Release when it is no longer needed.
+func NewScanner() *Scanner {
+	if atomic.LoadInt64(&scannerPoolDepth) < 0 {
+		scan := &Scanner{}
+		scan.Reset()
+		return scan
+	}
 	scan := scannerPool.Get().(*Scanner)
 	// scan.reset by design doesn't set bytes to zero
 	scan.bytes = 0
@@ -118,14 +293,26 @@ func newScanner() *Scanner {
 	return scan
 }
 
-func freeScanner(scan *Scanner) {
+// Release returns s to the pool used by NewScanner. The Scanner and the
+// slice returned by its ParseState method must not be used after calling
+// Release. A Scanner not returned by NewScanner may also be released.
+func (s *Scanner) Release() {
+	depth := atomic.LoadInt64(&scannerPoolDepth)
+	if depth < 0 {
+		return
+	}
 	// Avoid hanging on to too much memory in extreme cases.
-	if len(scan.parseState) > 1024 {
-		scan.parseState = nil
+	if int64(cap(s.parseState)) > depth {
+		s.parseState = nil
 	}
-	scannerPool.Put(scan)
+	s.err = nil
+	scannerPool.Put(s)
 }
 
+func newScanner() *Scanner { return NewScanner() }
+
+func freeScanner(scan *Scanner) { scan.Release() }
+
 // WARN: use or remove
 type ScanState int8
 
@@ -272,6 +459,28 @@ func isSpace(c byte) bool {
 	return c <= ' ' && (c == ' ' || c == '\t' || c == '\r' || c == '\n')
 }
 
+// skipSpace returns the number of space characters at the start of src,
+// which must immediately follow a byte for which s returned ScanSkipSpace,
+// and advances s past them. The space is skipped in bulk instead of being
+// stepped through since states that skip space are not changed by it.
+// This matters for inputs that are already indented, which are mostly
+// space.
+func (s *Scanner) skipSpace(src []byte) int {
+	i := 0
+	for i < len(src) && isSpace(src[i]) {
+		i++
+	}
+	s.bytes += int64(i)
+	return i
+}
+
+// skipSpaceReader is like skipSpace but discards the space buffered by r.
+// It does not read from the underlying reader.
+func (s *Scanner) skipSpaceReader(r *bufio.Reader) {
+	b, _ := r.Peek(r.Buffered())
+	r.Discard(s.skipSpace(b))
+}
+
 // stateBeginValueOrEmpty is the state after reading `[`.
 func stateBeginValueOrEmpty(s *Scanner, c byte) int {
 	if isSpace(c) {
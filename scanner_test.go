@@ -9,7 +9,9 @@ import (
 	"math"
 	"math/rand"
 	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 var validTests = []struct {
@@ -32,6 +34,49 @@ func TestValid(t *testing.T) {
 	}
 }
 
+func TestValidateAll(t *testing.T) {
+	tests := []struct {
+		data    string
+		max     int
+		offsets []int64
+	}{
+		{`{"a":1}`, 0, nil},
+		{`[1 2]`, 0, []int64{4}},
+		{`[1, , 2, 3 4, 5]`, 0, []int64{5, 12}},
+		{`[1, , 2, 3 4, 5]`, 1, []int64{5}},
+		{`{"a" 1, "b": tru, "c":1,}`, 0, []int64{6, 17, 25}},
+		{`[{"a": x {"b": [1,2]}}, 3, y]`, 0, []int64{8, 28}},
+		{`{"a": x"y,z", "b":1}`, 0, []int64{7}},
+		{"[\"a\nb\", 1, z]", 0, []int64{4, 12}},
+		{`[1, 2`, 0, []int64{5}},
+		{`1 2`, 0, []int64{3}},
+		{`[1] [2]`, 0, []int64{5}},
+	}
+	for _, test := range tests {
+		errs := ValidateAll([]byte(test.data), test.max)
+		var offsets []int64
+		for _, err := range errs {
+			se, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("ValidateAll(%#q): unexpected error type: %T", test.data, err)
+			}
+			offsets = append(offsets, se.Offset)
+		}
+		if !reflect.DeepEqual(offsets, test.offsets) {
+			t.Errorf("ValidateAll(%#q, %d) offsets = %v; want: %v\nerrors: %v",
+				test.data, test.max, offsets, test.offsets, errs)
+		}
+	}
+
+	// Must agree with Valid
+	for _, tt := range validTests {
+		errs := ValidateAll([]byte(tt.data), 0)
+		if ok := len(errs) == 0; ok != tt.ok {
+			t.Errorf("ValidateAll(%#q) = %v, want ok: %v", tt.data, errs, tt.ok)
+		}
+	}
+}
+
 // Tests of simple examples.
 
 type example struct {
@@ -299,3 +344,67 @@ func genMap(n int) map[string]interface{} {
 	}
 	return x
 }
+
+func TestScannerRelease(t *testing.T) {
+	defer SetScannerPoolDepth(DefaultScannerPoolDepth)
+
+	scan := NewScanner()
+	for _, c := range []byte(`[[[1]]]`) {
+		if v := scan.Step(c); v == ScanError {
+			t.Fatal(scan.Err())
+		}
+	}
+	if scan.EOF() != ScanEnd {
+		t.Fatal(scan.Err())
+	}
+	if prev := SetScannerPoolDepth(2); prev != DefaultScannerPoolDepth {
+		t.Errorf("SetScannerPoolDepth() = %d; want: %d", prev, DefaultScannerPoolDepth)
+	}
+	scan.Release()
+	if scan.parseState != nil {
+		t.Errorf("Release retained a parse state of depth %d", cap(scan.parseState))
+	}
+
+	// With pooling disabled every Scanner is new.
+	SetScannerPoolDepth(-1)
+	scan = NewScanner()
+	scan.Step('[')
+	scan.Release()
+	if scan2 := NewScanner(); scan2 == scan || scan2.Bytes() != 0 {
+		t.Error("NewScanner returned a released Scanner with pooling disabled")
+	}
+}
+
+func TestValidateStream(t *testing.T) {
+	tests := []struct {
+		in     string
+		offset int64 // offset of the error or -1 if valid
+	}{
+		{`{}`, -1},
+		{` 1 2 "a"[]{"b":null} `, -1},
+		{`{}{}[1]true`, -1},
+		{"", 0},
+		{"  \n", 3},
+		{`{"a":1} {"b" 2}`, 14},
+		{`[1,`, 3},
+		{`123 tru`, 7},
+		{`1 }`, 3},
+	}
+	for _, test := range tests {
+		err := ValidateStream(iotest.OneByteReader(strings.NewReader(test.in)))
+		if test.offset < 0 {
+			if err != nil {
+				t.Errorf("%q: %v", test.in, err)
+			}
+			continue
+		}
+		se, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("%q: error = %v; want a *SyntaxError", test.in, err)
+			continue
+		}
+		if se.Offset != test.offset {
+			t.Errorf("%q: offset = %d; want: %d", test.in, se.Offset, test.offset)
+		}
+	}
+}
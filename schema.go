@@ -0,0 +1,894 @@
+package pjson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// schemaTypes are the JSON Schema types in the order they are listed.
+var schemaTypes = [...]string{"null", "boolean", "integer", "number", "string", "array", "object"}
+
+const (
+	schemaNull = 1 << iota
+	schemaBoolean
+	schemaInteger
+	schemaNumber
+	schemaString
+	schemaArray
+	schemaObject
+)
+
+// A schemaNode is the shape of the values seen at a location.
+type schemaNode struct {
+	types   int // set of schema* types
+	objects int // number of objects seen
+	keys    []string
+	props   map[string]*schemaNode
+	present map[string]int // number of objects with each key
+	items   *schemaNode    // nil if no array elements were seen
+}
+
+func (n *schemaNode) add(src []byte) {
+	switch kindOf(src[0]) {
+	case KindNull:
+		n.types |= schemaNull
+	case KindBool:
+		n.types |= schemaBoolean
+	case KindString:
+		n.types |= schemaString
+	case KindNumber:
+		if bytes.ContainsAny(src, ".eE") {
+			n.types |= schemaNumber
+		} else {
+			n.types |= schemaInteger
+		}
+	case KindArray:
+		n.types |= schemaArray
+		for _, e := range arrayElems(nil, src) {
+			if n.items == nil {
+				n.items = new(schemaNode)
+			}
+			n.items.add(e)
+		}
+	case KindObject:
+		n.types |= schemaObject
+		n.objects++
+		if n.props == nil {
+			n.props = make(map[string]*schemaNode)
+			n.present = make(map[string]int)
+		}
+		members, _ := lastMembers(src)
+		for _, m := range members {
+			p := n.props[m.key]
+			if p == nil {
+				p = new(schemaNode)
+				n.props[m.key] = p
+				n.keys = append(n.keys, m.key)
+			}
+			p.add(m.value)
+			n.present[m.key]++
+		}
+	}
+}
+
+// appendSchema appends the compact JSON Schema of n to dst. If root is
+// true the schema declares its dialect.
+func (n *schemaNode) appendSchema(dst []byte, root bool) []byte {
+	dst = append(dst, '{')
+	if root {
+		dst = append(dst, `"$schema":"https://json-schema.org/draft/2020-12/schema",`...)
+	}
+	types := n.types
+	if types&schemaNumber != 0 {
+		types &^= schemaInteger // integers are numbers
+	}
+	var names []string
+	for i, name := range schemaTypes {
+		if types&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	dst = append(dst, `"type":`...)
+	if len(names) == 1 {
+		dst = appendQuoted(dst, names[0])
+	} else {
+		dst = append(dst, '[')
+		for i, name := range names {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendQuoted(dst, name)
+		}
+		dst = append(dst, ']')
+	}
+	if n.items != nil {
+		dst = append(dst, `,"items":`...)
+		dst = n.items.appendSchema(dst, false)
+	}
+	if n.types&schemaObject != 0 {
+		dst = append(dst, `,"properties":{`...)
+		var required []string
+		for i, k := range n.keys {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendQuoted(dst, k)
+			dst = append(dst, ':')
+			dst = n.props[k].appendSchema(dst, false)
+			if n.present[k] == n.objects {
+				required = append(required, k)
+			}
+		}
+		dst = append(dst, '}')
+		if len(required) > 0 {
+			dst = append(dst, `,"required":[`...)
+			for i, k := range required {
+				if i > 0 {
+					dst = append(dst, ',')
+				}
+				dst = appendQuoted(dst, k)
+			}
+			dst = append(dst, ']')
+		}
+	}
+	return append(dst, '}')
+}
+
+// InferSchema returns a draft JSON Schema (2020-12), as compact JSON, that
+// describes the shape of the JSON documents docs: the types of each value,
+// the properties of objects, which properties are required (those present
+// in every object seen at a location) and the types of array elements.
+// Properties are listed in the order they were first seen. The schema is
+// a starting point for documentation; it does not infer formats, enums or
+// bounds.
+func InferSchema(docs ...[]byte) ([]byte, error) {
+	if len(docs) == 0 {
+		return nil, errors.New("pjson: no documents to infer a schema from")
+	}
+	var root schemaNode
+	var buf bytes.Buffer
+	for _, doc := range docs {
+		buf.Reset()
+		if err := compact(&buf, doc, false); err != nil {
+			return nil, err
+		}
+		root.add(buf.Bytes())
+	}
+	return root.appendSchema(nil, true), nil
+}
+
+// A SchemaViolation is a way a JSON value does not conform to a Schema.
+type SchemaViolation struct {
+	Path    Path   // location of the value that does not conform
+	Keyword string // schema keyword, such as "required" or "type"
+	Message string
+}
+
+// Error returns the JSON Pointer of the value and the message.
+func (v *SchemaViolation) Error() string {
+	ptr := v.Path.Pointer()
+	if ptr == "" {
+		ptr = "(root)"
+	}
+	return ptr + ": " + v.Message
+}
+
+// A Schema is a compiled JSON Schema that values can be validated against.
+type Schema struct {
+	doc     []byte                 // compact schema document
+	root    *schemaRule            // schema at the root of doc
+	rules   map[string]*schemaRule // schemas by their JSON Pointer in doc
+	pending []*schemaRule          // schemas with an unresolved $ref
+}
+
+// A schemaPattern is a compiled pattern of a schema.
+type schemaPattern struct {
+	expr string
+	re   *regexp.Regexp
+	rule *schemaRule // for patternProperties
+}
+
+// A schemaRule is a compiled schema object or boolean schema.
+type schemaRule struct {
+	boolean bool // a true or false schema
+	allow   bool // value of a boolean schema
+
+	ref     string // JSON Pointer of $ref
+	refRule *schemaRule
+
+	types    int
+	enum     [][]byte
+	constVal []byte
+
+	propKeys    []string
+	properties  map[string]*schemaRule
+	patterns    []schemaPattern
+	additional  *schemaRule
+	names       *schemaRule
+	required    []string
+	depRequired map[string][]string
+	depKeys     []string
+	minProps    int
+	maxProps    int
+
+	prefixItems []*schemaRule
+	items       *schemaRule
+	contains    *schemaRule
+	minContains int
+	maxContains int
+	minItems    int
+	maxItems    int
+	unique      bool
+
+	minLength int
+	maxLength int
+	pattern   *schemaPattern
+
+	minimum, maximum    *big.Rat
+	exclMin, exclMax    *big.Rat
+	multipleOf          *big.Rat
+	minRaw, maxRaw      string
+	exclMinRaw          string
+	exclMaxRaw          string
+	multipleOfRaw       string
+	allOf, anyOf, oneOf []*schemaRule
+	not                 *schemaRule
+	ifRule              *schemaRule
+	thenRule, elseRule  *schemaRule
+}
+
+// maxSchemaDepth is the maximum number of nested schemas a value is
+// validated against, which stops schemas that reference themselves
+// without descending into the value.
+const maxSchemaDepth = 1000
+
+// CompileSchema compiles the JSON Schema schema. Most of the validation
+// keywords of draft 2020-12, and the array form of "items" of earlier
+// drafts, are supported. Annotations, such as "format" and "title", are
+// ignored. References ("$ref") must be JSON Pointers within the schema,
+// such as "#/$defs/item".
+func CompileSchema(schema []byte) (*Schema, error) {
+	var buf bytes.Buffer
+	if err := compact(&buf, schema, false); err != nil {
+		return nil, err
+	}
+	s := &Schema{doc: buf.Bytes(), rules: make(map[string]*schemaRule)}
+	var err error
+	if s.root, err = s.compile("", s.doc); err != nil {
+		return nil, err
+	}
+	for len(s.pending) > 0 {
+		r := s.pending[len(s.pending)-1]
+		s.pending = s.pending[:len(s.pending)-1]
+		target, err := s.resolve(r.ref)
+		if err != nil {
+			return nil, err
+		}
+		r.refRule = target
+	}
+	return s, nil
+}
+
+// resolve returns the schema at the JSON Pointer ptr in the schema
+// document.
+func (s *Schema) resolve(ptr string) (*schemaRule, error) {
+	if r, ok := s.rules[ptr]; ok {
+		return r, nil
+	}
+	tokens, err := ParsePointer(ptr)
+	if err != nil {
+		return nil, err
+	}
+	v := s.doc
+	for _, tok := range tokens {
+		var next []byte
+		switch kindOf(v[0]) {
+		case KindObject:
+			next = memberValue(v, tok)
+		case KindArray:
+			elems := arrayElems(nil, v)
+			if i, err := strconv.Atoi(tok); err == nil && 0 <= i && i < len(elems) {
+				next = elems[i]
+			}
+		}
+		if next == nil {
+			return nil, fmt.Errorf("pjson: invalid schema: $ref %q: no such schema", "#"+ptr)
+		}
+		v = next
+	}
+	return s.compile(ptr, v)
+}
+
+// schemaError returns an error for the invalid keyword at ptr.
+func schemaError(ptr, format string, args ...interface{}) error {
+	if ptr == "" {
+		ptr = "(root)"
+	}
+	return fmt.Errorf("pjson: invalid schema: %s: %s", ptr, fmt.Sprintf(format, args...))
+}
+
+// parseSchemaCount parses the non-negative integer value of the keyword at ptr.
+func parseSchemaCount(ptr string, v []byte) (int, error) {
+	n, err := strconv.Atoi(string(v))
+	if err != nil || n < 0 {
+		var r big.Rat
+		if _, ok := r.SetString(string(v)); !ok || !r.IsInt() || r.Sign() < 0 || !r.Num().IsInt64() {
+			return 0, schemaError(ptr, "must be a non-negative integer")
+		}
+		n = int(r.Num().Int64())
+	}
+	return n, nil
+}
+
+// parseSchemaNumber parses the number value of the keyword at ptr.
+func parseSchemaNumber(ptr string, v []byte) (*big.Rat, error) {
+	if kindOf(v[0]) != KindNumber {
+		return nil, schemaError(ptr, "must be a number")
+	}
+	r, ok := new(big.Rat).SetString(string(v))
+	if !ok {
+		return nil, schemaError(ptr, "invalid number %s", v)
+	}
+	return r, nil
+}
+
+// parseSchemaStrings parses the array of strings value of the keyword at ptr.
+func parseSchemaStrings(ptr string, v []byte) ([]string, error) {
+	if kindOf(v[0]) != KindArray {
+		return nil, schemaError(ptr, "must be an array of strings")
+	}
+	var strs []string
+	for _, e := range arrayElems(nil, v) {
+		if kindOf(e[0]) != KindString {
+			return nil, schemaError(ptr, "must be an array of strings")
+		}
+		s, _ := unquote(e)
+		strs = append(strs, s)
+	}
+	return strs, nil
+}
+
+// compileList compiles the array of schemas value of the keyword at ptr.
+func (s *Schema) compileList(ptr string, v []byte) ([]*schemaRule, error) {
+	if kindOf(v[0]) != KindArray {
+		return nil, schemaError(ptr, "must be an array of schemas")
+	}
+	var rules []*schemaRule
+	for i, e := range arrayElems(nil, v) {
+		r, err := s.compile(ptr+"/"+strconv.Itoa(i), e)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func compilePattern(ptr string, v []byte) (*schemaPattern, error) {
+	if kindOf(v[0]) != KindString {
+		return nil, schemaError(ptr, "must be a string")
+	}
+	expr, _ := unquote(v)
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, schemaError(ptr, "%v", err)
+	}
+	return &schemaPattern{expr: expr, re: re}, nil
+}
+
+// compile compiles the schema src at the JSON Pointer ptr in the schema
+// document.
+func (s *Schema) compile(ptr string, src []byte) (*schemaRule, error) {
+	if r, ok := s.rules[ptr]; ok {
+		return r, nil
+	}
+	r := &schemaRule{minContains: 1, maxProps: -1, maxItems: -1, maxLength: -1, maxContains: -1}
+	s.rules[ptr] = r // before the subschemas, which may reference r
+	switch kindOf(src[0]) {
+	case KindBool:
+		r.boolean = true
+		r.allow = src[0] == 't'
+		return r, nil
+	case KindObject:
+	default:
+		return nil, schemaError(ptr, "a schema must be an object or a boolean")
+	}
+	members, _ := lastMembers(src)
+	// The array form of "items" is only combined with "additionalItems".
+	itemsArray := false
+	for _, m := range members {
+		if m.key == "items" && kindOf(m.value[0]) == KindArray {
+			itemsArray = true
+		}
+	}
+	var err error
+	for _, m := range members {
+		kptr := ptr + "/" + pointerReplacer.Replace(m.key)
+		v := m.value
+		switch m.key {
+		case "$ref":
+			ref, _ := unquote(v)
+			if kindOf(v[0]) != KindString || !strings.HasPrefix(ref, "#") {
+				return nil, schemaError(kptr, "unsupported reference %s: only references within the schema are supported", v)
+			}
+			if ref, err = url.PathUnescape(ref[1:]); err != nil {
+				return nil, schemaError(kptr, "invalid reference %s", v)
+			}
+			if _, err := ParsePointer(ref); err != nil {
+				return nil, schemaError(kptr, "unsupported reference %s: only JSON Pointers are supported", v)
+			}
+			r.ref = ref
+			s.pending = append(s.pending, r)
+		case "type":
+			names := []string{""}
+			if kindOf(v[0]) == KindArray {
+				names, err = parseSchemaStrings(kptr, v)
+			} else if kindOf(v[0]) == KindString {
+				names[0], _ = unquote(v)
+			} else {
+				err = schemaError(kptr, "must be a string or an array of strings")
+			}
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range names {
+				i := 0
+				for i < len(schemaTypes) && schemaTypes[i] != name {
+					i++
+				}
+				if i == len(schemaTypes) {
+					return nil, schemaError(kptr, "invalid type %q", name)
+				}
+				r.types |= 1 << i
+			}
+		case "enum":
+			if kindOf(v[0]) != KindArray {
+				return nil, schemaError(kptr, "must be an array")
+			}
+			r.enum = arrayElems([][]byte{}, v)
+		case "const":
+			r.constVal = v
+		case "properties":
+			if kindOf(v[0]) != KindObject {
+				return nil, schemaError(kptr, "must be an object")
+			}
+			props, _ := lastMembers(v)
+			r.properties = make(map[string]*schemaRule, len(props))
+			for _, p := range props {
+				pr, err := s.compile(kptr+"/"+pointerReplacer.Replace(p.key), p.value)
+				if err != nil {
+					return nil, err
+				}
+				r.propKeys = append(r.propKeys, p.key)
+				r.properties[p.key] = pr
+			}
+		case "patternProperties":
+			if kindOf(v[0]) != KindObject {
+				return nil, schemaError(kptr, "must be an object")
+			}
+			props, _ := lastMembers(v)
+			for _, p := range props {
+				pp := kptr + "/" + pointerReplacer.Replace(p.key)
+				pat, err := compilePattern(pp, appendQuoted(nil, p.key))
+				if err != nil {
+					return nil, err
+				}
+				if pat.rule, err = s.compile(pp, p.value); err != nil {
+					return nil, err
+				}
+				r.patterns = append(r.patterns, *pat)
+			}
+		case "additionalProperties":
+			r.additional, err = s.compile(kptr, v)
+		case "propertyNames":
+			r.names, err = s.compile(kptr, v)
+		case "required":
+			r.required, err = parseSchemaStrings(kptr, v)
+		case "dependentRequired":
+			if kindOf(v[0]) != KindObject {
+				return nil, schemaError(kptr, "must be an object")
+			}
+			deps, _ := lastMembers(v)
+			r.depRequired = make(map[string][]string, len(deps))
+			for _, d := range deps {
+				if r.depRequired[d.key], err = parseSchemaStrings(kptr, d.value); err != nil {
+					return nil, err
+				}
+				r.depKeys = append(r.depKeys, d.key)
+			}
+		case "minProperties":
+			r.minProps, err = parseSchemaCount(kptr, v)
+		case "maxProperties":
+			r.maxProps, err = parseSchemaCount(kptr, v)
+		case "prefixItems":
+			r.prefixItems, err = s.compileList(kptr, v)
+		case "items":
+			if itemsArray {
+				r.prefixItems, err = s.compileList(kptr, v)
+			} else {
+				r.items, err = s.compile(kptr, v)
+			}
+		case "additionalItems":
+			if itemsArray {
+				r.items, err = s.compile(kptr, v)
+			}
+		case "contains":
+			r.contains, err = s.compile(kptr, v)
+		case "minContains":
+			r.minContains, err = parseSchemaCount(kptr, v)
+		case "maxContains":
+			r.maxContains, err = parseSchemaCount(kptr, v)
+		case "minItems":
+			r.minItems, err = parseSchemaCount(kptr, v)
+		case "maxItems":
+			r.maxItems, err = parseSchemaCount(kptr, v)
+		case "uniqueItems":
+			r.unique = string(v) == "true"
+		case "minLength":
+			r.minLength, err = parseSchemaCount(kptr, v)
+		case "maxLength":
+			r.maxLength, err = parseSchemaCount(kptr, v)
+		case "pattern":
+			r.pattern, err = compilePattern(kptr, v)
+		case "minimum":
+			r.minimum, err = parseSchemaNumber(kptr, v)
+			r.minRaw = string(v)
+		case "maximum":
+			r.maximum, err = parseSchemaNumber(kptr, v)
+			r.maxRaw = string(v)
+		case "exclusiveMinimum":
+			if kindOf(v[0]) != KindBool { // the boolean form of draft 4 is ignored
+				r.exclMin, err = parseSchemaNumber(kptr, v)
+				r.exclMinRaw = string(v)
+			}
+		case "exclusiveMaximum":
+			if kindOf(v[0]) != KindBool {
+				r.exclMax, err = parseSchemaNumber(kptr, v)
+				r.exclMaxRaw = string(v)
+			}
+		case "multipleOf":
+			if r.multipleOf, err = parseSchemaNumber(kptr, v); err == nil && r.multipleOf.Sign() <= 0 {
+				err = schemaError(kptr, "must be greater than 0")
+			}
+			r.multipleOfRaw = string(v)
+		case "allOf":
+			r.allOf, err = s.compileList(kptr, v)
+		case "anyOf":
+			r.anyOf, err = s.compileList(kptr, v)
+		case "oneOf":
+			r.oneOf, err = s.compileList(kptr, v)
+		case "not":
+			r.not, err = s.compile(kptr, v)
+		case "if":
+			r.ifRule, err = s.compile(kptr, v)
+		case "then":
+			r.thenRule, err = s.compile(kptr, v)
+		case "else":
+			r.elseRule, err = s.compile(kptr, v)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Validate validates the JSON value data against s and returns the ways
+// it does not conform, ordered by their location in data. The error is
+// only non-nil if data is not valid JSON.
+func (s *Schema) Validate(data []byte) ([]SchemaViolation, error) {
+	var buf bytes.Buffer
+	if err := compact(&buf, data, false); err != nil {
+		return nil, err
+	}
+	return s.root.validate(nil, nil, buf.Bytes(), 0), nil
+}
+
+// valid reports whether src conforms to r.
+func (r *schemaRule) valid(path Path, src []byte, depth int) bool {
+	return len(r.validate(nil, path, src, depth)) == 0
+}
+
+// validate appends the ways the compact JSON value src at path does not
+// conform to r to dst.
+func (r *schemaRule) validate(dst []SchemaViolation, path Path, src []byte, depth int) []SchemaViolation {
+	report := func(path Path, keyword, format string, args ...interface{}) {
+		dst = append(dst, SchemaViolation{
+			Path:    append(Path(nil), path...),
+			Keyword: keyword,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+	if r.boolean {
+		if !r.allow {
+			report(path, "false", "no value is allowed here")
+		}
+		return dst
+	}
+	if depth++; depth > maxSchemaDepth {
+		report(path, "$ref", "schemas are nested too deeply")
+		return dst
+	}
+	if r.refRule != nil {
+		dst = r.refRule.validate(dst, path, src, depth)
+	}
+
+	kind := kindOf(src[0])
+	var num *big.Rat
+	if kind == KindNumber {
+		num, _ = new(big.Rat).SetString(string(src))
+	}
+	if r.types != 0 {
+		t := 0
+		switch kind {
+		case KindNull:
+			t = schemaNull
+		case KindBool:
+			t = schemaBoolean
+		case KindString:
+			t = schemaString
+		case KindArray:
+			t = schemaArray
+		case KindObject:
+			t = schemaObject
+		case KindNumber:
+			t = schemaNumber
+			if num != nil && num.IsInt() {
+				t |= schemaInteger
+			}
+		}
+		if r.types&t == 0 {
+			var names []string
+			for i, name := range schemaTypes {
+				if r.types&(1<<i) != 0 {
+					names = append(names, name)
+				}
+			}
+			got := kind.String()
+			if kind == KindBool {
+				got = "boolean"
+			}
+			report(path, "type", "expected %s, got %s", strings.Join(names, " or "), got)
+		}
+	}
+	if r.enum != nil {
+		found := false
+		for _, e := range r.enum {
+			if pathEqual(src, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			report(path, "enum", "value must be one of the enum values")
+		}
+	}
+	if r.constVal != nil && !pathEqual(src, r.constVal) {
+		report(path, "const", "value must be %s", r.constVal)
+	}
+
+	switch kind {
+	case KindObject:
+		dst = r.validateObject(dst, path, src, depth)
+	case KindArray:
+		dst = r.validateArray(dst, path, src, depth)
+	case KindString:
+		s, _ := unquote(src)
+		if n := utf8.RuneCountInString(s); n < r.minLength {
+			report(path, "minLength", "string is shorter than %d characters", r.minLength)
+		} else if r.maxLength >= 0 && n > r.maxLength {
+			report(path, "maxLength", "string is longer than %d characters", r.maxLength)
+		}
+		if r.pattern != nil && !r.pattern.re.MatchString(s) {
+			report(path, "pattern", "string does not match the pattern %q", r.pattern.expr)
+		}
+	case KindNumber:
+		if num == nil {
+			break
+		}
+		if r.minimum != nil && num.Cmp(r.minimum) < 0 {
+			report(path, "minimum", "value must be at least %s", r.minRaw)
+		}
+		if r.maximum != nil && num.Cmp(r.maximum) > 0 {
+			report(path, "maximum", "value must be at most %s", r.maxRaw)
+		}
+		if r.exclMin != nil && num.Cmp(r.exclMin) <= 0 {
+			report(path, "exclusiveMinimum", "value must be greater than %s", r.exclMinRaw)
+		}
+		if r.exclMax != nil && num.Cmp(r.exclMax) >= 0 {
+			report(path, "exclusiveMaximum", "value must be less than %s", r.exclMaxRaw)
+		}
+		if r.multipleOf != nil && !new(big.Rat).Quo(num, r.multipleOf).IsInt() {
+			report(path, "multipleOf", "value must be a multiple of %s", r.multipleOfRaw)
+		}
+	}
+
+	for _, sub := range r.allOf {
+		dst = sub.validate(dst, path, src, depth)
+	}
+	if r.anyOf != nil {
+		matched := false
+		for _, sub := range r.anyOf {
+			if sub.valid(path, src, depth) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			report(path, "anyOf", "value does not match any of the schemas of anyOf")
+		}
+	}
+	if r.oneOf != nil {
+		var matches []int
+		for i, sub := range r.oneOf {
+			if sub.valid(path, src, depth) {
+				matches = append(matches, i)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			report(path, "oneOf", "value does not match any of the schemas of oneOf")
+		case 1:
+		default:
+			report(path, "oneOf", "value matches more than one of the schemas of oneOf (%d and %d)", matches[0], matches[1])
+		}
+	}
+	if r.not != nil && r.not.valid(path, src, depth) {
+		report(path, "not", "value must not match the schema of not")
+	}
+	if r.ifRule != nil {
+		if r.ifRule.valid(path, src, depth) {
+			if r.thenRule != nil {
+				dst = r.thenRule.validate(dst, path, src, depth)
+			}
+		} else if r.elseRule != nil {
+			dst = r.elseRule.validate(dst, path, src, depth)
+		}
+	}
+	return dst
+}
+
+func (r *schemaRule) validateObject(dst []SchemaViolation, path Path, src []byte, depth int) []SchemaViolation {
+	members, index := lastMembers(src)
+	for _, k := range r.required {
+		if _, ok := index[k]; !ok {
+			dst = append(dst, SchemaViolation{
+				Path:    append(Path(nil), path...),
+				Keyword: "required",
+				Message: fmt.Sprintf("missing required property %q", k),
+			})
+		}
+	}
+	for _, k := range r.depKeys {
+		if _, ok := index[k]; !ok {
+			continue
+		}
+		for _, dep := range r.depRequired[k] {
+			if _, ok := index[dep]; !ok {
+				dst = append(dst, SchemaViolation{
+					Path:    append(Path(nil), path...),
+					Keyword: "dependentRequired",
+					Message: fmt.Sprintf("property %q requires property %q", k, dep),
+				})
+			}
+		}
+	}
+	if len(members) < r.minProps {
+		dst = append(dst, SchemaViolation{
+			Path:    append(Path(nil), path...),
+			Keyword: "minProperties",
+			Message: fmt.Sprintf("object has fewer than %d properties", r.minProps),
+		})
+	} else if r.maxProps >= 0 && len(members) > r.maxProps {
+		dst = append(dst, SchemaViolation{
+			Path:    append(Path(nil), path...),
+			Keyword: "maxProperties",
+			Message: fmt.Sprintf("object has more than %d properties", r.maxProps),
+		})
+	}
+	for _, m := range members {
+		mpath := append(path, PathElem{Key: m.key, Index: -1})
+		if r.names != nil {
+			for _, v := range r.names.validate(nil, mpath, appendQuoted(nil, m.key), depth) {
+				v.Keyword = "propertyNames"
+				v.Message = fmt.Sprintf("invalid property name: %s", v.Message)
+				dst = append(dst, v)
+			}
+		}
+		matched := false
+		if p, ok := r.properties[m.key]; ok {
+			matched = true
+			dst = p.validate(dst, mpath, m.value, depth)
+		}
+		for _, pat := range r.patterns {
+			if pat.re.MatchString(m.key) {
+				matched = true
+				dst = pat.rule.validate(dst, mpath, m.value, depth)
+			}
+		}
+		if matched || r.additional == nil {
+			continue
+		}
+		if r.additional.boolean && !r.additional.allow {
+			dst = append(dst, SchemaViolation{
+				Path:    append(Path(nil), mpath...),
+				Keyword: "additionalProperties",
+				Message: fmt.Sprintf("property %q is not allowed", m.key),
+			})
+			continue
+		}
+		dst = r.additional.validate(dst, mpath, m.value, depth)
+	}
+	return dst
+}
+
+func (r *schemaRule) validateArray(dst []SchemaViolation, path Path, src []byte, depth int) []SchemaViolation {
+	elems := arrayElems(nil, src)
+	report := func(keyword, format string, args ...interface{}) {
+		dst = append(dst, SchemaViolation{
+			Path:    append(Path(nil), path...),
+			Keyword: keyword,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+	if len(elems) < r.minItems {
+		report("minItems", "array has fewer than %d items", r.minItems)
+	} else if r.maxItems >= 0 && len(elems) > r.maxItems {
+		report("maxItems", "array has more than %d items", r.maxItems)
+	}
+	if r.unique {
+	Unique:
+		for i := range elems {
+			for j := i + 1; j < len(elems); j++ {
+				if pathEqual(elems[i], elems[j]) {
+					report("uniqueItems", "items %d and %d are equal", i, j)
+					break Unique
+				}
+			}
+		}
+	}
+	if r.contains != nil {
+		n := 0
+		for i, e := range elems {
+			if r.contains.valid(append(path, PathElem{Index: i}), e, depth) {
+				n++
+			}
+		}
+		if n < r.minContains {
+			if r.minContains == 1 {
+				report("contains", "array does not contain a matching item")
+			} else {
+				report("minContains", "array contains fewer than %d matching items", r.minContains)
+			}
+		} else if r.maxContains >= 0 && n > r.maxContains {
+			report("maxContains", "array contains more than %d matching items", r.maxContains)
+		}
+	}
+	for i, e := range elems {
+		epath := append(path, PathElem{Index: i})
+		rule := r.items
+		if i < len(r.prefixItems) {
+			rule = r.prefixItems[i]
+		}
+		if rule == nil {
+			continue
+		}
+		if rule == r.items && rule.boolean && !rule.allow {
+			dst = append(dst, SchemaViolation{
+				Path:    append(Path(nil), path...),
+				Keyword: "items",
+				Message: fmt.Sprintf("array has more than %d items", len(r.prefixItems)),
+			})
+			break
+		}
+		dst = rule.validate(dst, epath, e, depth)
+	}
+	return dst
+}
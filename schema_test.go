@@ -0,0 +1,193 @@
+package pjson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInferSchema(t *testing.T) {
+	const prefix = `{"$schema":"https://json-schema.org/draft/2020-12/schema",`
+	tests := []struct {
+		docs []string
+		want string
+	}{
+		{[]string{`1`}, `"type":"integer"}`},
+		{[]string{`1`, `1.5`}, `"type":"number"}`},
+		{[]string{`"a"`, `null`, `true`}, `"type":["null","boolean","string"]}`},
+		{[]string{`[]`}, `"type":"array"}`},
+		{[]string{`[1, "a", 2]`}, `"type":"array","items":{"type":["integer","string"]}}`},
+		{
+			[]string{`{"id": 1, "name": "a", "tags": ["x"]}`, `{"name": null, "id": 2, "extra": {}}`},
+			`"type":"object","properties":{` +
+				`"id":{"type":"integer"},` +
+				`"name":{"type":["null","string"]},` +
+				`"tags":{"type":"array","items":{"type":"string"}},` +
+				`"extra":{"type":"object","properties":{}}},` +
+				`"required":["id","name"]}`,
+		},
+		{
+			[]string{`[{"a": 1}, {"a": 2, "b": 3}, 4]`},
+			`"type":"array","items":{"type":["integer","object"],"properties":{` +
+				`"a":{"type":"integer"},"b":{"type":"integer"}},"required":["a"]}}`,
+		},
+	}
+	for _, test := range tests {
+		var docs [][]byte
+		for _, d := range test.docs {
+			docs = append(docs, []byte(d))
+		}
+		got, err := InferSchema(docs...)
+		if err != nil {
+			t.Errorf("%q: %v", test.docs, err)
+			continue
+		}
+		if want := prefix + test.want; string(got) != want {
+			t.Errorf("%q:\ngot:  %s\nwant: %s", test.docs, got, want)
+		}
+	}
+	if _, err := InferSchema(); err == nil {
+		t.Error("expected an error with no documents")
+	}
+	if _, err := InferSchema([]byte(`{`)); err == nil {
+		t.Error("expected an error with invalid JSON")
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	const schema = `{
+		"type": "object",
+		"required": ["id", "name"],
+		"properties": {
+			"id": {"type": "integer", "minimum": 1},
+			"name": {"type": "string", "minLength": 1, "pattern": "^[a-z]+$"},
+			"tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true, "maxItems": 3},
+			"price": {"type": "number", "exclusiveMinimum": 0, "multipleOf": 0.01},
+			"kind": {"enum": ["a", "b"]},
+			"child": {"$ref": "#"},
+			"point": {"$ref": "#/$defs/point"}
+		},
+		"additionalProperties": false,
+		"$defs": {
+			"point": {
+				"type": "array",
+				"prefixItems": [{"type": "number"}, {"type": "number"}],
+				"items": false
+			}
+		}
+	}`
+	s, err := CompileSchema([]byte(schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		doc  string
+		want []string
+	}{
+		{`{"id": 1, "name": "a", "tags": ["x", "y"], "price": 9.99, "kind": "b", "point": [1, 2]}`, nil},
+		{`{"id": 2.0, "name": "abc", "child": {"id": 3, "name": "d"}}`, nil},
+		{`[]`, []string{"(root): expected object, got array"}},
+		{`{"name": "a"}`, []string{`(root): missing required property "id"`}},
+		{`{"id": 0, "name": ""}`, []string{
+			"/id: value must be at least 1",
+			"/name: string is shorter than 1 characters",
+			`/name: string does not match the pattern "^[a-z]+$"`,
+		}},
+		{`{"id": 1.5, "name": "a", "extra": 1}`, []string{
+			"/id: expected integer, got number",
+			`/extra: property "extra" is not allowed`,
+		}},
+		{`{"id": 1, "name": "a", "tags": ["x", 1, "x", "y"]}`, []string{
+			"/tags: array has more than 3 items",
+			"/tags: items 0 and 2 are equal",
+			"/tags/1: expected string, got number",
+		}},
+		{`{"id": 1, "name": "a", "price": 0.001, "kind": "c"}`, []string{
+			"/price: value must be a multiple of 0.01",
+			"/kind: value must be one of the enum values",
+		}},
+		{`{"id": 1, "name": "a", "child": {"id": -1, "name": "a"}}`, []string{"/child/id: value must be at least 1"}},
+		{`{"id": 1, "name": "a", "point": [1, "2", 3]}`, []string{
+			"/point/1: expected number, got string",
+			"/point: array has more than 2 items",
+		}},
+	}
+	for _, test := range tests {
+		violations, err := s.Validate([]byte(test.doc))
+		if err != nil {
+			t.Errorf("%s: %v", test.doc, err)
+			continue
+		}
+		var got []string
+		for i := range violations {
+			got = append(got, violations[i].Error())
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s:\ngot:  %q\nwant: %q", test.doc, got, test.want)
+		}
+	}
+}
+
+func TestSchemaCombinators(t *testing.T) {
+	tests := []struct {
+		schema, doc string
+		keyword     string // expected keyword, or "" if valid
+	}{
+		{`{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, `1`, ""},
+		{`{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, `true`, "anyOf"},
+		{`{"oneOf": [{"type": "number"}, {"type": "integer"}]}`, `1.5`, ""},
+		{`{"oneOf": [{"type": "number"}, {"type": "integer"}]}`, `1`, "oneOf"},
+		{`{"allOf": [{"minimum": 1}, {"maximum": 2}]}`, `3`, "maximum"},
+		{`{"not": {"type": "null"}}`, `null`, "not"},
+		{`{"if": {"minimum": 10}, "then": {"multipleOf": 10}, "else": {"const": 1}}`, `20`, ""},
+		{`{"if": {"minimum": 10}, "then": {"multipleOf": 10}, "else": {"const": 1}}`, `15`, "multipleOf"},
+		{`{"if": {"minimum": 10}, "then": {"multipleOf": 10}, "else": {"const": 1}}`, `2`, "const"},
+		{`{"contains": {"type": "string"}}`, `[1, 2]`, "contains"},
+		{`{"contains": {"type": "string"}, "maxContains": 1}`, `["a", "b"]`, "maxContains"},
+		{`{"dependentRequired": {"a": ["b"]}}`, `{"a": 1}`, "dependentRequired"},
+		{`{"propertyNames": {"maxLength": 2}}`, `{"abc": 1}`, "propertyNames"},
+		{`{"patternProperties": {"^x": {"type": "string"}}, "additionalProperties": false}`, `{"xa": "1"}`, ""},
+		{`{"patternProperties": {"^x": {"type": "string"}}, "additionalProperties": false}`, `{"y": "1"}`, "additionalProperties"},
+		{`{"items": [{"type": "string"}], "additionalItems": {"type": "integer"}}`, `["a", 1.5]`, "type"},
+		{`{"exclusiveMaximum": 1}`, `1`, "exclusiveMaximum"},
+		{`{"maxProperties": 1}`, `{"a": 1, "b": 2}`, "maxProperties"},
+		{`false`, `1`, "false"},
+		{`true`, `1`, ""},
+		{`{"$ref": "#"}`, `1`, "$ref"},
+	}
+	for _, test := range tests {
+		s, err := CompileSchema([]byte(test.schema))
+		if err != nil {
+			t.Errorf("%s: %v", test.schema, err)
+			continue
+		}
+		violations, err := s.Validate([]byte(test.doc))
+		if err != nil {
+			t.Errorf("%s: %v", test.doc, err)
+			continue
+		}
+		got := ""
+		if len(violations) > 0 {
+			got = violations[0].Keyword
+		}
+		if got != test.keyword {
+			t.Errorf("%s: %s: got: %q %v want: %q", test.schema, test.doc, got, violations, test.keyword)
+		}
+	}
+}
+
+func TestCompileSchemaError(t *testing.T) {
+	for _, schema := range []string{
+		`1`,
+		`{"type": "float"}`,
+		`{"minLength": -1}`,
+		`{"pattern": "("}`,
+		`{"$ref": "other.json"}`,
+		`{"$ref": "#/$defs/missing"}`,
+		`{"properties": {"a": 1}}`,
+		`{"multipleOf": 0}`,
+	} {
+		if _, err := CompileSchema([]byte(schema)); err == nil {
+			t.Errorf("%s: expected an error", schema)
+		}
+	}
+}
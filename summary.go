@@ -0,0 +1,182 @@
+package pjson
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A SummaryEntry describes the values found at a path of the JSON
+// documents passed to Summarize.
+type SummaryEntry struct {
+	// Path is the path of the values with array indices replaced by [],
+	// as returned by KeyPaths, such as .items[].name. The root is ".".
+	Path string
+
+	// Depth is the number of keys and indices in Path.
+	Depth int
+
+	Kinds []Kind // kinds of the values, in the order of the Kind constants
+	Count int    // number of values
+	Size  int64  // total size of the values, as they are in the input
+
+	// MinLen and MaxLen are the fewest and most elements of the arrays
+	// at Path, and ElemKinds the kinds of their elements.
+	MinLen, MaxLen int
+	ElemKinds      []Kind
+}
+
+// String returns a description of e, such as
+//
+//	.items: array[10432] of object (1.1 MB)
+//	.items[].tags: array[0-5] of string (×10432, 498.0 KB)
+func (e *SummaryEntry) String() string {
+	b := append([]byte(e.Path), ": "...)
+	for i, k := range e.Kinds {
+		if i > 0 {
+			b = append(b, " or "...)
+		}
+		b = append(b, k.String()...)
+		if k != KindArray {
+			continue
+		}
+		b = append(b, '[')
+		b = strconv.AppendInt(b, int64(e.MinLen), 10)
+		if e.MaxLen != e.MinLen {
+			b = append(b, '-')
+			b = strconv.AppendInt(b, int64(e.MaxLen), 10)
+		}
+		b = append(b, ']')
+		for j, ek := range e.ElemKinds {
+			if j == 0 {
+				b = append(b, " of "...)
+			} else {
+				b = append(b, '|')
+			}
+			b = append(b, ek.String()...)
+		}
+	}
+	b = append(b, " ("...)
+	if e.Count > 1 {
+		b = append(b, "×"...)
+		b = strconv.AppendInt(b, int64(e.Count), 10)
+		b = append(b, ", "...)
+	}
+	b = appendSize(b, int(e.Size))
+	return string(append(b, ')'))
+}
+
+// kindSet is a set of Kinds.
+type kindSet uint8
+
+func (s kindSet) kinds() []Kind {
+	var kinds []Kind
+	for k := KindNull; k <= KindArray; k++ {
+		if s&(1<<k) != 0 {
+			kinds = append(kinds, k)
+		}
+	}
+	return kinds
+}
+
+// Summarize returns a summary of the structure of the JSON documents docs:
+// an entry for each distinct path, with array indices replaced by [],
+// listing the kinds, number and total size of the values found at it.
+// Entries are in depth-first order, with the children of a path in the
+// order they first appear, so they can be printed as a tree. An error is
+// returned if any of docs is not valid JSON.
+func Summarize(docs ...[]byte) ([]SummaryEntry, error) {
+	type info struct {
+		entry    SummaryEntry
+		kinds    kindSet
+		elems    kindSet
+		doc      int // document the path first appears in
+		first    int // offset it first appears at
+		parent   string
+		children []*info
+	}
+	paths := make(map[string]*info)
+	var elems []int // elems[n] counts the elements of the array at depth n-1
+	for i, data := range docs {
+		err := walk(data, func(path Path, kind Kind, start, end int) bool {
+			p := path.pattern()
+			in := paths[p]
+			if in == nil {
+				in = &info{doc: i, first: start}
+				if len(path) > 0 {
+					in.parent = path[:len(path)-1].pattern()
+				}
+				in.entry.Path = p
+				in.entry.Depth = len(path)
+				in.entry.MinLen = -1
+				paths[p] = in
+			} else if in.doc == i && start < in.first {
+				in.first = start
+			}
+			in.kinds |= 1 << kind
+			in.entry.Count++
+			in.entry.Size += int64(end - start)
+			if n := len(path); n > 0 && path[n-1].IsIndex() {
+				for len(elems) <= n {
+					elems = append(elems, 0)
+				}
+				elems[n]++
+			}
+			if kind == KindArray {
+				n := 0
+				if len(path)+1 < len(elems) {
+					n = elems[len(path)+1]
+					elems[len(path)+1] = 0
+				}
+				if in.entry.MinLen == -1 || n < in.entry.MinLen {
+					in.entry.MinLen = n
+				}
+				if n > in.entry.MaxLen {
+					in.entry.MaxLen = n
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	root := paths["."]
+	if root == nil {
+		return nil, nil
+	}
+	for _, in := range paths {
+		if in == root {
+			continue
+		}
+		parent := paths[in.parent]
+		parent.children = append(parent.children, in)
+		if strings.HasSuffix(in.entry.Path, "[]") {
+			parent.elems |= in.kinds
+		}
+	}
+	entries := make([]SummaryEntry, 0, len(paths))
+	var add func(in *info)
+	add = func(in *info) {
+		e := in.entry
+		e.Kinds = in.kinds.kinds()
+		e.ElemKinds = in.elems.kinds()
+		if e.MinLen == -1 {
+			e.MinLen = 0
+		}
+		entries = append(entries, e)
+		sort.Slice(in.children, func(i, j int) bool {
+			a, b := in.children[i], in.children[j]
+			if a.doc != b.doc {
+				return a.doc < b.doc
+			}
+			return a.first < b.first
+		})
+		for _, c := range in.children {
+			add(c)
+		}
+	}
+	add(root)
+	return entries, nil
+}
@@ -0,0 +1,47 @@
+package pjson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	docs := [][]byte{
+		[]byte(`{"items": [{"id": 1, "tags": ["a", "b"]}, {"id": 2, "tags": [], "owner": {"name": "x"}}], "next": null}`),
+		[]byte(`{"items": [], "next": "abc"}`),
+	}
+	entries, err := Summarize(docs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, e := range entries {
+		lines = append(lines, strings.Repeat("  ", e.Depth)+e.String())
+	}
+	want := []string{
+		`.: object (×2, 131 B)`,
+		`  .items: array[0-2] of object (×2, 80 B)`,
+		`    .items[]: object (×2, 74 B)`,
+		`      .items[].id: number (×2, 2 B)`,
+		`      .items[].tags: array[0-2] of string (×2, 12 B)`,
+		`        .items[].tags[]: string (×2, 6 B)`,
+		`      .items[].owner: object (13 B)`,
+		`        .items[].owner.name: string (3 B)`,
+		`  .next: null or string (×2, 9 B)`,
+	}
+	if got := strings.Join(lines, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+
+	entries, err = Summarize([]byte(`[[1, "a"], 2]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := entries[0].String(), `.: array[2] of number|array (13 B)`; got != want {
+		t.Errorf("got: %s; want: %s", got, want)
+	}
+
+	if _, err := Summarize([]byte(`{"a": }`)); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
@@ -0,0 +1,175 @@
+package termcolor
+
+import (
+	"bytes"
+	"io"
+)
+
+// Legacy Windows console character attributes.
+const (
+	consoleFgBlue      = 0x0001
+	consoleFgGreen     = 0x0002
+	consoleFgRed       = 0x0004
+	consoleFgIntensity = 0x0008
+	consoleBgBlue      = 0x0010
+	consoleBgGreen     = 0x0020
+	consoleBgRed       = 0x0040
+	consoleBgIntensity = 0x0080
+	consoleFgMask      = 0x000F
+	consoleBgMask      = 0x00F0
+)
+
+// consoleColor converts an ANSI color index (0-7) to the legacy console
+// foreground color bits (the bit order of red and blue is swapped).
+func consoleColor(i Attribute) uint16 {
+	return uint16(i&1)<<2 | uint16(i&2) | uint16(i&4)>>2
+}
+
+// consoleAttr applies SGR attributes attrs to the legacy Windows console
+// attribute cur. The attribute def is restored by a reset.
+func consoleAttr(cur, def uint16, attrs []Attribute) uint16 {
+	if len(attrs) == 0 {
+		return def // "\x1b[m" is a reset
+	}
+	for i := 0; i < len(attrs); {
+		group, _ := nextAttrGroup(attrs[i:])
+		i += len(group)
+		switch a := group[0]; {
+		case a == None:
+			cur = def
+		case a == Bold:
+			cur |= consoleFgIntensity
+		case a == 22: // normal intensity
+			cur &^= consoleFgIntensity
+		case a == ReverseVideo:
+			cur = (cur&consoleFgMask)<<4 | (cur&consoleBgMask)>>4
+		case FgBlack <= a && a <= FgWhite:
+			// Preserve the intensity bit so that bold is not lost
+			cur = cur&^(consoleFgMask&^consoleFgIntensity) | consoleColor(a-FgBlack)
+		case FgBrightBlack <= a && a <= FgBrightWhite:
+			cur = cur&^consoleFgMask | consoleColor(a-FgBrightBlack) | consoleFgIntensity
+		case a == 39: // default foreground
+			cur = cur&^consoleFgMask | def&consoleFgMask
+		case BgBlack <= a && a <= BgWhite:
+			cur = cur&^consoleBgMask | consoleColor(a-BgBlack)<<4
+		case BgHiBlack <= a && a <= BgHiWhite:
+			cur = cur&^consoleBgMask | consoleColor(a-BgHiBlack)<<4 | consoleBgIntensity
+		case a == 49: // default background
+			cur = cur&^consoleBgMask | def&consoleBgMask
+		case (a == 38 || a == 48) && len(group) == 3:
+			// Downgrade 256 colors in the range of the basic 16 colors.
+			if n := group[2]; n < 16 {
+				c := consoleColor(n & 7)
+				if n >= 8 {
+					c |= consoleFgIntensity
+				}
+				if a == 38 {
+					cur = cur&^consoleFgMask | c
+				} else {
+					cur = cur&^consoleBgMask | c<<4
+				}
+			}
+		}
+	}
+	return cur
+}
+
+// parseSGR parses the SGR escape sequence at the start of p. It returns
+// the attributes of the sequence and its length. If the sequence is
+// incomplete n is zero and ok is true. If p does not start with a valid
+// SGR sequence ok is false.
+func parseSGR(p []byte) (attrs []Attribute, n int, ok bool) {
+	if len(p) < 2 {
+		return nil, 0, len(p) == 0 || p[0] == '\x1b'
+	}
+	if p[0] != '\x1b' || p[1] != '[' {
+		return nil, 0, false
+	}
+	v := 0
+	for i := 2; i < len(p); i++ {
+		switch c := p[i]; {
+		case '0' <= c && c <= '9':
+			v = v*10 + int(c-'0')
+			if v > 255 {
+				return nil, 0, false
+			}
+		case c == ';':
+			attrs = append(attrs, Attribute(v))
+			v = 0
+		case c == 'm':
+			if i > 2 {
+				attrs = append(attrs, Attribute(v))
+			}
+			return attrs, i + 1, true
+		default:
+			return nil, 0, false
+		}
+	}
+	return nil, 0, true
+}
+
+// maxPendingSGR is the length of an incomplete escape sequence after
+// which it is written as text instead of waiting for the rest of it.
+const maxPendingSGR = 64
+
+// sgrWriter translates SGR escape sequences written to it into calls
+// to setAttr and writes all other text to w unmodified.
+type sgrWriter struct {
+	w       io.Writer
+	setAttr func(uint16) error
+	cur     uint16
+	def     uint16
+	pending []byte // incomplete escape sequence
+}
+
+func (s *sgrWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	// The pending bytes were counted as written by a previous Write.
+	pending := len(s.pending)
+	if pending != 0 {
+		p = append(s.pending, p...)
+		s.pending = s.pending[:0]
+	}
+	off := 0 // bytes of p written
+	written := func() int {
+		if off < pending {
+			return 0
+		}
+		return off - pending
+	}
+	for off < len(p) {
+		q := p[off:]
+		i := bytes.IndexByte(q, '\x1b')
+		if i == -1 {
+			i = len(q)
+		}
+		if i > 0 {
+			m, err := s.w.Write(q[:i])
+			off += m
+			if err != nil {
+				return written(), err
+			}
+			continue
+		}
+		attrs, m, ok := parseSGR(q)
+		if m == 0 && ok && len(q) < maxPendingSGR {
+			s.pending = append(s.pending, q...)
+			break
+		}
+		if m == 0 {
+			// Not an SGR sequence, or one too long to be: pass the
+			// escape through
+			if _, err := s.w.Write(q[:1]); err != nil {
+				return written(), err
+			}
+			off++
+			continue
+		}
+		s.cur = consoleAttr(s.cur, s.def, attrs)
+		if err := s.setAttr(s.cur); err != nil {
+			return written(), err
+		}
+		off += m
+	}
+	return n, nil
+}
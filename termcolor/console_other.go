@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package termcolor
+
+import (
+	"io"
+	"os"
+)
+
+// EnableVirtualTerminal enables virtual terminal processing on Windows
+// consoles. It is a no-op on other platforms.
+func EnableVirtualTerminal(f *os.File) error { return nil }
+
+// NewConsoleWriter returns a writer for f that is able to display colors.
+// On Windows consoles that do not support virtual terminal processing SGR
+// escape sequences are translated to legacy console API calls. On all
+// other platforms f is returned.
+func NewConsoleWriter(f *os.File) io.Writer { return f }
@@ -0,0 +1,149 @@
+package termcolor
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConsoleAttr(t *testing.T) {
+	const def = consoleFgRed | consoleFgGreen | consoleFgBlue // white on black
+	tests := []struct {
+		attrs []Attribute
+		want  uint16
+	}{
+		{nil, def},
+		{[]Attribute{None}, def},
+		{[]Attribute{FgRed}, consoleFgRed},
+		{[]Attribute{FgBlue}, consoleFgBlue},
+		{[]Attribute{FgYellow}, consoleFgRed | consoleFgGreen},
+		{[]Attribute{Bold, FgCyan}, consoleFgGreen | consoleFgBlue | consoleFgIntensity},
+		{[]Attribute{Bold, FgCyan, 22}, consoleFgGreen | consoleFgBlue},
+		{[]Attribute{FgBrightMagenta}, consoleFgRed | consoleFgBlue | consoleFgIntensity},
+		{[]Attribute{BgBlue}, def | consoleBgBlue},
+		{[]Attribute{BgHiRed, FgBlack}, consoleBgRed | consoleBgIntensity},
+		{[]Attribute{38, 5, 9}, consoleFgRed | consoleFgIntensity},
+		{[]Attribute{38, 5, 200}, def},
+	}
+	for _, test := range tests {
+		got := consoleAttr(def, def, test.attrs)
+		if got != test.want {
+			t.Errorf("consoleAttr(%v) = %#04x; want: %#04x", test.attrs, got, test.want)
+		}
+	}
+}
+
+func TestParseSGR(t *testing.T) {
+	tests := []struct {
+		in    string
+		attrs []Attribute
+		n     int
+		ok    bool
+	}{
+		{"\x1b[m", nil, 3, true},
+		{"\x1b[31mX", []Attribute{31}, 5, true},
+		{"\x1b[1;38;5;200m", []Attribute{1, 38, 5, 200}, 13, true},
+		{"\x1b[1;3", nil, 0, true},
+		{"\x1b", nil, 0, true},
+		{"\x1b[2J", nil, 0, false},
+		{"\x1b]0;title", nil, 0, false},
+	}
+	for _, test := range tests {
+		attrs, n, ok := parseSGR([]byte(test.in))
+		if !reflect.DeepEqual(attrs, test.attrs) || n != test.n || ok != test.ok {
+			t.Errorf("parseSGR(%q) = %v, %d, %t; want: %v, %d, %t",
+				test.in, attrs, n, ok, test.attrs, test.n, test.ok)
+		}
+	}
+}
+
+func TestSGRWriter(t *testing.T) {
+	var buf bytes.Buffer
+	var calls []uint16
+	w := &sgrWriter{
+		w:   &buf,
+		def: 7,
+		cur: 7,
+		setAttr: func(attr uint16) error {
+			calls = append(calls, attr)
+			return nil
+		},
+	}
+	// Split an escape sequence across writes
+	for _, s := range []string{"a\x1b[3", "1mb", "\x1b[0mc\x1b[2J"} {
+		if n, err := w.Write([]byte(s)); n != len(s) || err != nil {
+			t.Fatalf("Write(%q) = %d, %v", s, n, err)
+		}
+	}
+	if got, want := buf.String(), "abc\x1b[2J"; got != want {
+		t.Errorf("got: %q want: %q", got, want)
+	}
+	if want := []uint16{consoleFgRed, 7}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls: %v want: %v", calls, want)
+	}
+}
+
+func TestSGRWriterLongSequence(t *testing.T) {
+	var buf bytes.Buffer
+	w := &sgrWriter{w: &buf, setAttr: func(uint16) error { return nil }}
+	// An unterminated sequence is written as text once it is too long.
+	seq := "\x1b[" + strings.Repeat("1;", maxPendingSGR)
+	for i := 0; i < len(seq); i++ {
+		if n, err := w.Write([]byte{seq[i]}); n != 1 || err != nil {
+			t.Fatalf("Write(%q) = %d, %v", seq[i], n, err)
+		}
+	}
+	if len(w.pending) >= maxPendingSGR {
+		t.Errorf("pending: %d bytes; want less than %d", len(w.pending), maxPendingSGR)
+	}
+	if _, err := w.Write([]byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), seq+"x"; got != want {
+		t.Errorf("got: %q want: %q", got, want)
+	}
+}
+
+// A limitWriter fails once n bytes have been written.
+type limitWriter struct {
+	buf bytes.Buffer
+	n   int
+}
+
+var errLimit = errors.New("limit reached")
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		w.buf.Write(p[:w.n])
+		n := w.n
+		w.n = 0
+		return n, errLimit
+	}
+	w.n -= len(p)
+	return w.buf.Write(p)
+}
+
+func TestSGRWriterError(t *testing.T) {
+	tests := []struct {
+		pending, in string
+		limit       int
+		want        int
+	}{
+		{"", "abc\x1b[31mdef", 5, 10},
+		{"", "abc\x1b[31mdef", 2, 2},
+		{"\x1b[3", "1mdef", 1, 3},
+		{"\x1b[3", "1\x1b[2Jx", 4, 1},
+	}
+	for _, test := range tests {
+		lw := &limitWriter{n: test.limit}
+		w := &sgrWriter{w: lw, setAttr: func(uint16) error { return nil }}
+		w.pending = []byte(test.pending)
+		n, err := w.Write([]byte(test.in))
+		if n != test.want || err != errLimit {
+			t.Errorf("Write(%q) after %q = %d, %v; want: %d, %v",
+				test.in, test.pending, n, err, test.want, errLimit)
+		}
+	}
+}
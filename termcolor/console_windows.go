@@ -0,0 +1,63 @@
+package termcolor
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+var procSetConsoleTextAttribute = windows.NewLazySystemDLL("kernel32.dll").
+	NewProc("SetConsoleTextAttribute")
+
+func setConsoleTextAttribute(h windows.Handle, attr uint16) error {
+	r, _, err := procSetConsoleTextAttribute.Call(uintptr(h), uintptr(attr))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
+
+// Virtual terminal processing was added in Windows 10 build 10586.
+const minVirtualTerminalBuild = 10586
+
+// EnableVirtualTerminal enables virtual terminal processing, which is
+// required for ANSI escape sequences, on the console referenced by f.
+func EnableVirtualTerminal(f *os.File) error {
+	if v := windows.RtlGetVersion(); v.MajorVersion < 10 ||
+		(v.MajorVersion == 10 && v.BuildNumber < minVirtualTerminalBuild) {
+		return windows.ERROR_NOT_SUPPORTED
+	}
+	h := windows.Handle(f.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(h, &mode); err != nil {
+		return err
+	}
+	if mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 {
+		return nil
+	}
+	return windows.SetConsoleMode(h, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING)
+}
+
+// NewConsoleWriter returns a writer for f that is able to display colors.
+// If f is a console that does not support virtual terminal processing
+// (older versions of Windows) SGR escape sequences are translated into
+// calls to SetConsoleTextAttribute, otherwise f is returned.
+func NewConsoleWriter(f *os.File) io.Writer {
+	if EnableVirtualTerminal(f) == nil {
+		return f
+	}
+	h := windows.Handle(f.Fd())
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(h, &info); err != nil {
+		return f // not a console
+	}
+	return &sgrWriter{
+		w:   f,
+		cur: info.Attributes,
+		def: info.Attributes,
+		setAttr: func(attr uint16) error {
+			return setConsoleTextAttribute(h, attr)
+		},
+	}
+}
@@ -0,0 +1,67 @@
+package termcolor
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Level is the color capability level of a terminal.
+type Level uint8
+
+const (
+	LevelNone      Level = iota // no color support
+	LevelBasic                  // 8 or 16 colors
+	Level256                    // 256 colors
+	LevelTrueColor              // 24-bit RGB colors
+)
+
+var levelStrs = [...]string{
+	"LevelNone",
+	"LevelBasic",
+	"Level256",
+	"LevelTrueColor",
+}
+
+func (l Level) String() string {
+	if int(l) < len(levelStrs) {
+		return levelStrs[l]
+	}
+	return "Level(" + strconv.Itoa(int(l)) + ")"
+}
+
+func levelForColors(n int) Level {
+	switch {
+	case n >= 1<<24:
+		return LevelTrueColor
+	case n >= 256:
+		return Level256
+	case n >= 8:
+		return LevelBasic
+	}
+	return LevelNone
+}
+
+// Detect returns the color capability level of the current terminal
+// based on the COLORTERM and TERM environment variables and the terminfo
+// entry for TERM. It does not check if any output is a terminal.
+func Detect() Level {
+	if TrueColorEnabled() {
+		return LevelTrueColor
+	}
+	term := os.Getenv("TERM")
+	if term == "" || term == "dumb" {
+		return LevelNone
+	}
+	if ti, err := LoadTerminfo(term); err == nil {
+		return levelForColors(ti.MaxColors)
+	}
+	// Unknown terminal fallback to heuristics.
+	switch {
+	case strings.HasSuffix(term, "-direct"):
+		return LevelTrueColor
+	case strings.Contains(term, "256color"):
+		return Level256
+	}
+	return LevelBasic
+}
@@ -0,0 +1,160 @@
+package termcolor
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func appendUint16(b []byte, v uint16) []byte {
+	var p [2]byte
+	binary.LittleEndian.PutUint16(p[:], v)
+	return append(b, p[:]...)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var p [4]byte
+	binary.LittleEndian.PutUint32(p[:], v)
+	return append(b, p[:]...)
+}
+
+// compileTerminfo returns a minimal compiled terminfo entry.
+func compileTerminfo(names string, magic uint16, maxColors int, strs ...int) []byte {
+	numSize := 2
+	if magic == terminfoMagic32 {
+		numSize = 4
+	}
+	const boolCount = 1
+	numCount := tiMaxColors + 1
+	strCount := tiEnterItalicsMode + 1
+	names += "\x00"
+
+	var b []byte
+	for _, v := range []int{int(magic), len(names), boolCount, numCount, strCount, 1} {
+		b = appendUint16(b, uint16(v))
+	}
+	b = append(b, names...)
+	b = append(b, make([]byte, boolCount)...)
+	if len(b)%2 != 0 {
+		b = append(b, 0)
+	}
+	for i := 0; i < numCount; i++ {
+		v := -1
+		if i == tiMaxColors {
+			v = maxColors
+		}
+		if numSize == 4 {
+			b = appendUint32(b, uint32(int32(v)))
+		} else {
+			b = appendUint16(b, uint16(int16(v)))
+		}
+	}
+	present := make(map[int]bool)
+	for _, i := range strs {
+		present[i] = true
+	}
+	for i := 0; i < strCount; i++ {
+		v := -1
+		if present[i] {
+			v = 0
+		}
+		b = appendUint16(b, uint16(int16(v)))
+	}
+	return append(b, 0) // string table
+}
+
+func writeTerminfo(t *testing.T, dir, name string, data []byte) {
+	t.Helper()
+	path := filepath.Join(dir, name[:1], name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseTerminfo(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want Terminfo
+	}{
+		{
+			compileTerminfo("test-16|Test 16", terminfoMagic16, 8, tiEnterBoldMode),
+			Terminfo{Names: []string{"test-16", "Test 16"}, MaxColors: 8, Bold: true},
+		},
+		{
+			compileTerminfo("test-32", terminfoMagic32, 1<<24, tiEnterUnderlineMode, tiEnterItalicsMode),
+			Terminfo{Names: []string{"test-32"}, MaxColors: 1 << 24, Underline: true, Italic: true},
+		},
+		{
+			compileTerminfo("mono", terminfoMagic16, -1),
+			Terminfo{Names: []string{"mono"}},
+		},
+	}
+	for _, test := range tests {
+		ti, err := parseTerminfo(test.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(*ti, test.want) {
+			t.Errorf("parseTerminfo() = %+v; want: %+v", *ti, test.want)
+		}
+	}
+
+	for _, data := range [][]byte{nil, make([]byte, 12), compileTerminfo("x", 1, 0)[:20]} {
+		if _, err := parseTerminfo(data); err == nil {
+			t.Errorf("parseTerminfo(%q): expected an error", data)
+		}
+	}
+}
+
+func TestLoadTerminfo(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TERMINFO", dir)
+	writeTerminfo(t, dir, "pjson-test", compileTerminfo("pjson-test", terminfoMagic16, 256))
+
+	ti, err := LoadTerminfo("pjson-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ti.MaxColors != 256 {
+		t.Errorf("MaxColors = %d; want: %d", ti.MaxColors, 256)
+	}
+	if _, err := LoadTerminfo("pjson-missing"); err == nil {
+		t.Error("expected an error for a missing terminfo entry")
+	}
+	if _, err := LoadTerminfo("../etc/passwd"); err == nil {
+		t.Error("expected an error for an invalid terminal name")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TERMINFO", dir)
+	writeTerminfo(t, dir, "pjson-direct", compileTerminfo("pjson-direct", terminfoMagic32, 1<<24))
+	writeTerminfo(t, dir, "pjson-mono", compileTerminfo("pjson-mono", terminfoMagic16, -1))
+
+	tests := []struct {
+		colorterm, term string
+		want            Level
+	}{
+		{"truecolor", "xterm", LevelTrueColor},
+		{"", "", LevelNone},
+		{"", "dumb", LevelNone},
+		{"", "pjson-direct", LevelTrueColor},
+		{"", "pjson-mono", LevelNone},
+		{"", "pjson-unknown-256color", Level256},
+		{"", "pjson-unknown", LevelBasic},
+	}
+	for _, test := range tests {
+		t.Setenv("COLORTERM", test.colorterm)
+		t.Setenv("TERM", test.term)
+		if got := Detect(); got != test.want {
+			t.Errorf("Detect(COLORTERM=%q TERM=%q) = %s; want: %s",
+				test.colorterm, test.term, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package termcolor
+
+import "math"
+
+func lerp(a, b uint8, t float64) uint8 {
+	return uint8(math.Round(float64(a) + (float64(b)-float64(a))*t))
+}
+
+// Lerp linearly interpolates between r and o. The value t is clamped
+// to the range [0, 1] where 0 returns r and 1 returns o.
+func (r RGB) Lerp(o RGB, t float64) RGB {
+	if t <= 0 || math.IsNaN(t) {
+		return r
+	}
+	if t >= 1 {
+		return o
+	}
+	return RGB{
+		R: lerp(r.R, o.R, t),
+		G: lerp(r.G, o.G, t),
+		B: lerp(r.B, o.B, t),
+	}
+}
+
+// GradientRGB returns steps colors evenly spaced between from and to
+// (inclusive).
+func GradientRGB(from, to RGB, steps int) []RGB {
+	if steps <= 0 {
+		return nil
+	}
+	if steps == 1 {
+		return []RGB{from}
+	}
+	colors := make([]RGB, steps)
+	for i := range colors {
+		colors[i] = from.Lerp(to, float64(i)/float64(steps-1))
+	}
+	return colors
+}
+
+// Gradient returns steps 24-bit foreground Colors evenly spaced between
+// from and to (inclusive). Use GradientRGB and RGB.Color to create a
+// gradient for terminals that do not support 24-bit color.
+func Gradient(from, to RGB, steps int) []*Color {
+	rgbs := GradientRGB(from, to, steps)
+	if rgbs == nil {
+		return nil
+	}
+	colors := make([]*Color, len(rgbs))
+	for i, c := range rgbs {
+		colors[i] = c.Color(LevelTrueColor, nil)
+	}
+	return colors
+}
@@ -0,0 +1,50 @@
+package termcolor
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestRGBLerp(t *testing.T) {
+	from := RGB{0, 100, 255}
+	to := RGB{255, 0, 55}
+	tests := []struct {
+		t    float64
+		want RGB
+	}{
+		{-1, from},
+		{0, from},
+		{math.NaN(), from},
+		{0.5, RGB{128, 50, 155}},
+		{1, to},
+		{2, to},
+	}
+	for _, test := range tests {
+		if got := from.Lerp(to, test.t); got != test.want {
+			t.Errorf("Lerp(%v, %v, %g) = %v; want: %v", from, to, test.t, got, test.want)
+		}
+	}
+}
+
+func TestGradient(t *testing.T) {
+	from := RGB{0, 0, 0}
+	to := RGB{200, 100, 0}
+	want := []RGB{{0, 0, 0}, {50, 25, 0}, {100, 50, 0}, {150, 75, 0}, {200, 100, 0}}
+	if got := GradientRGB(from, to, 5); !reflect.DeepEqual(got, want) {
+		t.Errorf("GradientRGB() = %v; want: %v", got, want)
+	}
+	if got := GradientRGB(from, to, 1); !reflect.DeepEqual(got, []RGB{from}) {
+		t.Errorf("GradientRGB(1) = %v; want: %v", got, []RGB{from})
+	}
+	if got := Gradient(from, to, 0); got != nil {
+		t.Errorf("Gradient(0) = %v; want: nil", got)
+	}
+	colors := Gradient(from, to, 5)
+	if len(colors) != 5 {
+		t.Fatalf("len(Gradient()) = %d; want: %d", len(colors), 5)
+	}
+	if got, want := colors[2].Format(), "\x1b[38;2;100;50;0m"; got != want {
+		t.Errorf("Gradient()[2] = %q; want: %q", got, want)
+	}
+}
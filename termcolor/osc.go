@@ -0,0 +1,50 @@
+package termcolor
+
+import "strconv"
+
+// A ProgressState is the state of the progress indicator set by an OSC 9;4
+// sequence, which is supported by Windows Terminal and ConEmu.
+type ProgressState int
+
+const (
+	ProgressNone          ProgressState = iota // remove the indicator
+	ProgressNormal                             // show percent complete
+	ProgressError                              // show percent complete as failed
+	ProgressIndeterminate                      // show activity without a percent
+	ProgressPaused                             // show percent complete as paused
+)
+
+// Escape sequences that save and restore the terminal title, supported by
+// xterm and most terminals derived from it.
+const (
+	PushTitle = "\x1b[22;0t"
+	PopTitle  = "\x1b[23;0t"
+)
+
+// AppendProgress appends the OSC 9;4 sequence that sets the terminal's
+// progress indicator to state and percent, which is clamped to [0, 100],
+// to dst.
+func AppendProgress(dst []byte, state ProgressState, percent int) []byte {
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+	dst = append(dst, "\x1b]9;4;"...)
+	dst = strconv.AppendInt(dst, int64(state), 10)
+	dst = append(dst, ';')
+	dst = strconv.AppendInt(dst, int64(percent), 10)
+	return append(dst, "\x1b\\"...)
+}
+
+// AppendTitle appends the OSC 2 sequence that sets the terminal title to
+// title, with any control characters removed, to dst.
+func AppendTitle(dst []byte, title string) []byte {
+	dst = append(dst, "\x1b]2;"...)
+	for i := 0; i < len(title); i++ {
+		if c := title[i]; c >= ' ' && c != 0x7f {
+			dst = append(dst, c)
+		}
+	}
+	return append(dst, "\x1b\\"...)
+}
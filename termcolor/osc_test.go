@@ -0,0 +1,29 @@
+package termcolor
+
+import "testing"
+
+func TestAppendProgress(t *testing.T) {
+	tests := []struct {
+		state   ProgressState
+		percent int
+		want    string
+	}{
+		{ProgressNormal, 42, "\x1b]9;4;1;42\x1b\\"},
+		{ProgressNormal, 101, "\x1b]9;4;1;100\x1b\\"},
+		{ProgressIndeterminate, -1, "\x1b]9;4;3;0\x1b\\"},
+		{ProgressNone, 0, "\x1b]9;4;0;0\x1b\\"},
+	}
+	for _, test := range tests {
+		if got := string(AppendProgress(nil, test.state, test.percent)); got != test.want {
+			t.Errorf("AppendProgress(%d, %d) = %q; want: %q", test.state, test.percent, got, test.want)
+		}
+	}
+}
+
+func TestAppendTitle(t *testing.T) {
+	got := string(AppendTitle(nil, "pjson: a\x1b]0;x\x07.json 5%"))
+	want := "\x1b]2;pjson: a]0;x.json 5%\x1b\\"
+	if got != want {
+		t.Errorf("AppendTitle() = %q; want: %q", got, want)
+	}
+}
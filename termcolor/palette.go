@@ -0,0 +1,80 @@
+package termcolor
+
+// A Palette is the RGB value of the 16 basic terminal colors, which vary
+// by terminal and theme. Index i corresponds to the colors FgBlack+i for
+// i < 8 and FgBrightBlack+i-8 for i >= 8.
+type Palette [16]RGB
+
+// DefaultPalette is the default palette used by xterm.
+var DefaultPalette = Palette{
+	{0, 0, 0},       // Black
+	{205, 0, 0},     // Red
+	{0, 205, 0},     // Green
+	{205, 205, 0},   // Yellow
+	{0, 0, 238},     // Blue
+	{205, 0, 205},   // Magenta
+	{0, 205, 205},   // Cyan
+	{229, 229, 229}, // White
+	{127, 127, 127}, // BrightBlack
+	{255, 0, 0},     // BrightRed
+	{0, 255, 0},     // BrightGreen
+	{255, 255, 0},   // BrightYellow
+	{92, 92, 255},   // BrightBlue
+	{255, 0, 255},   // BrightMagenta
+	{0, 255, 255},   // BrightCyan
+	{255, 255, 255}, // BrightWhite
+}
+
+// distance returns the perceptual distance between c1 and c2 using the
+// "redmean" weighted euclidean approximation. The square root is omitted
+// since it is only used for comparisons.
+func distance(c1, c2 RGB) int {
+	rmean := (int(c1.R) + int(c2.R)) / 2
+	r := int(c1.R) - int(c2.R)
+	g := int(c1.G) - int(c2.G)
+	b := int(c1.B) - int(c2.B)
+	return (((512 + rmean) * r * r) >> 8) + 4*g*g + (((767 - rmean) * b * b) >> 8)
+}
+
+// Nearest returns the foreground Attribute of the color in p that is
+// perceptually nearest to c.
+func (p *Palette) Nearest(c RGB) Attribute {
+	best := 0
+	min := -1
+	for i, x := range p {
+		if d := distance(c, x); min == -1 || d < min {
+			best = i
+			min = d
+		}
+	}
+	if best < 8 {
+		return FgBlack + Attribute(best)
+	}
+	return FgBrightBlack + Attribute(best-8)
+}
+
+// Basic returns the basic 16-color foreground Attribute nearest to r
+// using the DefaultPalette.
+func (r RGB) Basic() Attribute {
+	return DefaultPalette.Nearest(r)
+}
+
+// Color returns a foreground Color for r downgraded to the given color
+// capability level: 24-bit colors for LevelTrueColor, the nearest color
+// of the 256 color palette for Level256, the nearest of the 16 basic
+// colors in palette p for LevelBasic and NoColor for LevelNone. If p is
+// nil the DefaultPalette is used.
+func (r RGB) Color(level Level, p *Palette) *Color {
+	switch level {
+	case LevelTrueColor:
+		return NewColor(38, 2, Attribute(r.R), Attribute(r.G), Attribute(r.B))
+	case Level256:
+		return NewColor(38, 5, r.ANSI())
+	case LevelBasic:
+		if p == nil {
+			p = &DefaultPalette
+		}
+		return NewColor(p.Nearest(r))
+	}
+	return &NoColor
+}
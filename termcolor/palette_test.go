@@ -0,0 +1,49 @@
+package termcolor
+
+import "testing"
+
+func TestPaletteNearest(t *testing.T) {
+	tests := []struct {
+		rgb  RGB
+		want Attribute
+	}{
+		{RGB{0, 0, 0}, FgBlack},
+		{RGB{255, 255, 255}, FgBrightWhite},
+		{RGB{200, 10, 10}, FgRed},
+		{RGB{250, 40, 40}, FgBrightRed},
+		{RGB{20, 20, 230}, FgBlue},
+		{RGB{128, 128, 130}, FgBrightBlack},
+		{RGB{220, 220, 220}, FgWhite},
+		{RGB{0, 190, 200}, FgCyan},
+	}
+	for _, test := range tests {
+		if got := test.rgb.Basic(); got != test.want {
+			t.Errorf("%v.Basic() = %s; want: %s", test.rgb, got, test.want)
+		}
+	}
+
+	// A theme with a dark "white" should map light colors to BrightWhite
+	p := DefaultPalette
+	p[7] = RGB{120, 120, 120}
+	if got := p.Nearest(RGB{230, 230, 230}); got != FgBrightWhite {
+		t.Errorf("Nearest() = %s; want: %s", got, FgBrightWhite)
+	}
+}
+
+func TestRGBColor(t *testing.T) {
+	rgb := RGB{250, 40, 40}
+	tests := []struct {
+		level Level
+		want  string
+	}{
+		{LevelTrueColor, "\x1b[38;2;250;40;40m"},
+		{Level256, "\x1b[38;5;203m"},
+		{LevelBasic, "\x1b[91m"},
+		{LevelNone, ""},
+	}
+	for _, test := range tests {
+		if got := rgb.Color(test.level, nil).Format(); got != test.want {
+			t.Errorf("Color(%s) = %q; want: %q", test.level, got, test.want)
+		}
+	}
+}
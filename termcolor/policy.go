@@ -0,0 +1,119 @@
+package termcolor
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+)
+
+// A Check is one of the inputs considered when deciding whether to color
+// output, see Decision.
+type Check struct {
+	Name  string // name of the input, such as "NO_COLOR" or "tty"
+	Value string // value of the input or "(unset)"
+	Note  string // effect of the input on the decision, if any
+}
+
+// A Decision is the result of Policy.Decide: whether output is colored
+// and why.
+type Decision struct {
+	Enabled bool
+	Level   Level   // color capability of the terminal, see Detect
+	Reason  string  // the input that decided Enabled
+	Checks  []Check // every input considered in the order checked
+}
+
+// A Policy decides whether output written to a file is colored. The
+// inputs are checked in order and the first one that applies decides:
+//
+//  1. Disable (e.g. a --monochrome flag) disables color.
+//  2. Force (e.g. a --color flag) enables color.
+//  3. NO_COLOR set to any non-empty value disables color.
+//  4. CLICOLOR_FORCE set to a non-empty value other than "0" enables color.
+//  5. Color is disabled if the file is not a terminal or TERM is "dumb".
+//
+// Otherwise, color is enabled. The remaining inputs (COLORTERM and the
+// Windows virtual terminal status) do not change the decision, but are
+// reported since they affect how colors are displayed.
+type Policy struct {
+	Force   bool
+	Disable bool
+
+	// Getenv looks up environment variables, if nil os.Getenv is used.
+	Getenv func(key string) string
+}
+
+func envValue(s string) string {
+	if s == "" {
+		return "(unset)"
+	}
+	return strconv.Quote(s)
+}
+
+// Decide returns whether output written to f should be colored along
+// with the reasoning behind the decision.
+func (p *Policy) Decide(f *os.File) Decision {
+	getenv := p.Getenv
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	var d Decision
+	decided := false
+	check := func(name, value string, applies, enabled bool, note string) {
+		c := Check{Name: name, Value: value}
+		if applies && !decided {
+			decided = true
+			d.Enabled = enabled
+			d.Reason = note
+			c.Note = note
+		} else if applies {
+			c.Note = "ignored: " + d.Reason
+		}
+		d.Checks = append(d.Checks, c)
+	}
+
+	check("disable flag", strconv.FormatBool(p.Disable), p.Disable, false,
+		"disabled by flag")
+	check("force flag", strconv.FormatBool(p.Force), p.Force, true,
+		"forced by flag")
+
+	noColor := getenv("NO_COLOR")
+	check("NO_COLOR", envValue(noColor), noColor != "", false,
+		"NO_COLOR is set")
+	clicolor := getenv("CLICOLOR_FORCE")
+	check("CLICOLOR_FORCE", envValue(clicolor), clicolor != "" && clicolor != "0", true,
+		"CLICOLOR_FORCE is set")
+
+	isTerm := f != nil && IsTerminal(int(f.Fd()))
+	check("tty", strconv.FormatBool(isTerm), !isTerm, false,
+		"output is not a terminal")
+	term := getenv("TERM")
+	check("TERM", envValue(term), term == "dumb", false,
+		"TERM is \"dumb\"")
+	if !decided {
+		d.Enabled = true
+		d.Reason = "output is a terminal"
+	}
+
+	d.Checks = append(d.Checks, Check{Name: "COLORTERM", Value: envValue(getenv("COLORTERM"))})
+	if runtime.GOOS == "windows" && isTerm {
+		c := Check{Name: "windows VT", Value: "enabled"}
+		if err := EnableVirtualTerminal(f); err != nil {
+			c.Value = "unavailable: " + err.Error()
+			c.Note = "escape sequences are translated to console API calls"
+		}
+		d.Checks = append(d.Checks, c)
+	}
+	if d.Enabled {
+		d.Level = Detect()
+		d.Checks = append(d.Checks, Check{Name: "level", Value: d.Level.String()})
+	}
+	return d
+}
+
+// Decide is a shorthand for Policy.Decide with the environment of the
+// current process.
+func Decide(f *os.File, force, disable bool) Decision {
+	p := Policy{Force: force, Disable: disable}
+	return p.Decide(f)
+}
@@ -0,0 +1,54 @@
+package termcolor
+
+import (
+	"os"
+	"testing"
+)
+
+func TestPolicyDecide(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tests := []struct {
+		policy  Policy
+		env     map[string]string
+		isTerm  bool
+		enabled bool
+		reason  string
+	}{
+		{Policy{}, nil, true, true, "output is a terminal"},
+		{Policy{}, nil, false, false, "output is not a terminal"},
+		{Policy{Force: true}, nil, false, true, "forced by flag"},
+		{Policy{Force: true, Disable: true}, nil, true, false, "disabled by flag"},
+		{Policy{Force: true}, map[string]string{"NO_COLOR": "1"}, true, true, "forced by flag"},
+		{Policy{}, map[string]string{"NO_COLOR": "1"}, true, false, "NO_COLOR is set"},
+		{Policy{}, map[string]string{"CLICOLOR_FORCE": "1"}, false, true, "CLICOLOR_FORCE is set"},
+		{Policy{}, map[string]string{"CLICOLOR_FORCE": "0"}, false, false, "output is not a terminal"},
+		{Policy{}, map[string]string{"NO_COLOR": "1", "CLICOLOR_FORCE": "1"}, true, false, "NO_COLOR is set"},
+		{Policy{}, map[string]string{"TERM": "dumb"}, true, false, "TERM is \"dumb\""},
+	}
+	fd := int(f.Fd())
+	defer ClearForceTerminal(fd)
+	for i, test := range tests {
+		ForceTerminal(fd, test.isTerm)
+		p := test.policy
+		p.Getenv = func(key string) string { return test.env[key] }
+		d := p.Decide(f)
+		if d.Enabled != test.enabled || d.Reason != test.reason {
+			t.Errorf("%d: Decide() = %t, %q; want: %t, %q", i, d.Enabled, d.Reason,
+				test.enabled, test.reason)
+		}
+		var notes int
+		for _, c := range d.Checks {
+			if c.Note == test.reason {
+				notes++
+			}
+		}
+		if test.reason != "output is a terminal" && notes != 1 {
+			t.Errorf("%d: the deciding check is not reported exactly once: %+v", i, d.Checks)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package termcolor
+
+import "bytes"
+
+// Strip appends src to dst with any SGR escape sequences ("\x1b[...m")
+// removed. Other escape sequences are left unchanged.
+func Strip(dst, src []byte) []byte {
+	for {
+		i := bytes.IndexByte(src, '\x1b')
+		if i == -1 {
+			return append(dst, src...)
+		}
+		dst = append(dst, src[:i]...)
+		n := SGRLen(src[i:])
+		if n == 0 {
+			dst = append(dst, '\x1b')
+			n = 1
+		}
+		src = src[i+n:]
+	}
+}
+
+// SGRLen returns the length of the SGR sequence at the start of b or 0 if
+// b does not start with one.
+func SGRLen(b []byte) int {
+	if len(b) < 3 || b[0] != '\x1b' || b[1] != '[' {
+		return 0
+	}
+	for i := 2; i < len(b); i++ {
+		switch c := b[i]; {
+		case c == 'm':
+			return i + 1
+		case c != ';' && (c < '0' || c > '9'):
+			return 0
+		}
+	}
+	return 0
+}
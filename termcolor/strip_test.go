@@ -0,0 +1,24 @@
+package termcolor
+
+import "testing"
+
+func TestStrip(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"abc", "abc"},
+		{"\x1b[31mred\x1b[0m", "red"},
+		{"a\x1b[mb\x1b[38;5;208mc\x1b[0m", "abc"},
+		{"\x1b[1;38;2;1;2;3m{\x1b[0m\x1b[0m}", "{}"},
+		{"\x1b[2Jx", "\x1b[2Jx"},
+		{"x\x1b", "x\x1b"},
+		{"x\x1b[31", "x\x1b[31"},
+	}
+	for _, test := range tests {
+		got := string(Strip(nil, []byte(test.in)))
+		if got != test.want {
+			t.Errorf("Strip(%q) = %q; want: %q", test.in, got, test.want)
+		}
+	}
+}
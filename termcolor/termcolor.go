@@ -8,9 +8,6 @@ import (
 	"os"
 	"strconv"
 	"strings"
-	"sync"
-
-	"golang.org/x/term"
 )
 
 const Reset = "\x1b[0m"
@@ -20,22 +17,6 @@ var (
 	NoTrueColor = !TrueColorEnabled()
 )
 
-var (
-	isTermStdout bool
-	isTermStderr bool
-	initStdOnce  sync.Once
-)
-
-var (
-	fdStdout = int(os.Stdout.Fd())
-	fdStderr = int(os.Stderr.Fd())
-)
-
-func initStdTerms() {
-	isTermStdout = term.IsTerminal(fdStdout)
-	isTermStderr = term.IsTerminal(fdStderr)
-}
-
 //go:generate stringer -type=Attribute
 
 type Attribute uint8
@@ -178,6 +159,24 @@ func NewColor(attributes ...Attribute) *Color {
 	return &Color{escape: buildEscape(attrs), attrs: attrs}
 }
 
+// ParseColor returns the Color with the SGR parameters params, which are
+// the text of an escape sequence between "\x1b[" and "m", such as "1;34"
+// or "38;5;208". An empty string is NoColor.
+func ParseColor(params string) (*Color, error) {
+	if params == "" {
+		return &NoColor, nil
+	}
+	var attrs []Attribute
+	for _, s := range strings.Split(params, ";") {
+		n, err := strconv.ParseUint(s, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("termcolor: invalid SGR parameter %q in %q", s, params)
+		}
+		attrs = append(attrs, Attribute(n))
+	}
+	return NewColor(attrs...), nil
+}
+
 // 256-color mode — foreground: ESC[38;5;#m   background: ESC[48;5;#m
 
 func (c *Color) String() string {
@@ -204,6 +203,63 @@ func (c *Color) Has(attr Attribute) bool {
 	return false
 }
 
+// Attrs returns a copy of the attributes of c.
+func (c *Color) Attrs() []Attribute {
+	if c == nil || len(c.attrs) == 0 {
+		return nil
+	}
+	attrs := make([]Attribute, len(c.attrs))
+	copy(attrs, c.attrs)
+	return attrs
+}
+
+// Without returns a Color with attr removed from c. If attr begins an
+// extended color sequence (38 or 48) the entire sequence is removed.
+// The parameters of an extended color sequence, like the 1 of 38;5;1,
+// are not attributes and are never removed.
+func (c *Color) Without(attr Attribute) *Color {
+	if c == nil {
+		return &NoColor
+	}
+	var attrs []Attribute
+	removed := false
+	for i := 0; i < len(c.attrs); {
+		group, _ := nextAttrGroup(c.attrs[i:])
+		i += len(group)
+		if group[0] == attr {
+			removed = true
+		} else {
+			attrs = append(attrs, group...)
+		}
+	}
+	if !removed {
+		return c
+	}
+	return NewColor(attrs...)
+}
+
+func (c *Color) classAttrs(class attrClass) *Color {
+	var attrs []Attribute
+	if c != nil {
+		for i := 0; i < len(c.attrs); {
+			group, cls := nextAttrGroup(c.attrs[i:])
+			i += len(group)
+			if cls == class {
+				attrs = group
+			}
+		}
+	}
+	return NewColor(attrs...)
+}
+
+// Fg returns the foreground color of c or NoColor if c does not have
+// a foreground color.
+func (c *Color) Fg() *Color { return c.classAttrs(classFg) }
+
+// Bg returns the background color of c or NoColor if c does not have
+// a background color.
+func (c *Color) Bg() *Color { return c.classAttrs(classBg) }
+
 func (c *Color) Set(attr Attribute) *Color {
 	if c == nil {
 		return NewColor(attr)
@@ -217,10 +273,111 @@ func (c *Color) Set(attr Attribute) *Color {
 	return &Color{escape: buildEscape(attrs), attrs: attrs}
 }
 
+// attrClass is used to resolve conflicts when combining Colors.
+type attrClass uint8
+
+const (
+	classOther attrClass = iota
+	classFg
+	classBg
+)
+
+// nextAttrGroup returns the first attribute group in attrs and its class.
+// Extended color sequences (38;5;n and 38;2;r;g;b and their background
+// equivalents) are returned as a single group.
+func nextAttrGroup(attrs []Attribute) ([]Attribute, attrClass) {
+	a := attrs[0]
+	switch {
+	case a == 38 || a == 48:
+		n := 1
+		if len(attrs) > 1 {
+			switch attrs[1] {
+			case 5:
+				n = 3
+			case 2:
+				n = 5
+			}
+		}
+		if n > len(attrs) {
+			n = len(attrs)
+		}
+		if a == 38 {
+			return attrs[:n], classFg
+		}
+		return attrs[:n], classBg
+	case FgBlack <= a && a <= FgWhite, FgBrightBlack <= a && a <= FgBrightWhite:
+		return attrs[:1], classFg
+	case BgBlack <= a && a <= BgWhite, BgHiBlack <= a && a <= BgHiWhite:
+		return attrs[:1], classBg
+	}
+	return attrs[:1], classOther
+}
+
+func containsGroup(attrs, group []Attribute) bool {
+Outer:
+	for i := 0; i+len(group) <= len(attrs); {
+		g, _ := nextAttrGroup(attrs[i:])
+		i += len(g)
+		if len(g) != len(group) {
+			continue
+		}
+		for j := range g {
+			if g[j] != group[j] {
+				continue Outer
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Combine merges the attributes of colors into a single Color. Duplicate
+// attributes are removed and if more than one foreground or background
+// color is provided the last one wins. Nil colors are ignored.
+func Combine(colors ...*Color) *Color {
+	var attrs, fg, bg []Attribute
+	for _, c := range colors {
+		if c == nil {
+			continue
+		}
+		for i := 0; i < len(c.attrs); {
+			group, class := nextAttrGroup(c.attrs[i:])
+			i += len(group)
+			switch class {
+			case classFg:
+				fg = group
+			case classBg:
+				bg = group
+			default:
+				if !containsGroup(attrs, group) {
+					attrs = append(attrs, group...)
+				}
+			}
+		}
+	}
+	attrs = append(attrs, fg...)
+	attrs = append(attrs, bg...)
+	if len(attrs) == 0 {
+		return &NoColor
+	}
+	return &Color{escape: buildEscape(attrs), attrs: attrs}
+}
+
+// With returns a new Color that combines the attributes of c and o. The
+// foreground and background colors of o, if any, take precedence.
+func (c *Color) With(o *Color) *Color {
+	return Combine(c, o)
+}
+
 func (c *Color) IsZero() bool {
 	return c == nil || len(c.escape) == 0
 }
 
+// active returns if c is non-zero and color output is enabled.
+func (c *Color) active() bool {
+	return !c.IsZero() && Enabled()
+}
+
 func (c *Color) Equal(o *Color) bool {
 	if c == nil {
 		return o == nil
@@ -229,28 +386,28 @@ func (c *Color) Equal(o *Color) bool {
 }
 
 func (x *Color) Format() string {
-	if !x.IsZero() {
+	if x.active() {
 		return x.escape
 	}
 	return ""
 }
 
 func (x *Color) Append(b []byte) []byte {
-	if !x.IsZero() {
+	if x.active() {
 		b = append(b, x.escape...)
 	}
 	return b
 }
 
 func (x *Color) Reset() string {
-	if !x.IsZero() {
+	if x.active() {
 		return Reset
 	}
 	return ""
 }
 
 func (x *Color) Sprintf(format string, v ...any) string {
-	if !x.IsZero() {
+	if x.active() {
 		return fmt.Sprintf(x.escape+format+Reset, v...)
 	}
 	return fmt.Sprintf(format, v...)
@@ -297,21 +454,6 @@ func (b *Buffer) WriteString(c *Color, s string) (int, error) {
 	return len(s), nil
 }
 
-// IsTerminal returns whether the given file descriptor is a terminal.
-func IsTerminal(fd int) bool {
-	// WARN: this breaks if someone changes Stdout or Stderr
-	if fd == fdStdout || fd == fdStderr {
-		initStdOnce.Do(initStdTerms)
-		if fd == fdStdout {
-			return isTermStdout
-		}
-		return isTermStderr
-	}
-	// TODO: consider caching the result of this, but note that
-	// caching breaks if FDs are reused.
-	return term.IsTerminal(fd)
-}
-
 func TrueColorEnabled() bool {
 	switch os.Getenv("COLORTERM") {
 	case "truecolor", "24bit":
@@ -223,6 +223,87 @@ func TestColorString(t *testing.T) {
 	}
 }
 
+func TestCombine(t *testing.T) {
+	tests := []struct {
+		colors []*Color
+		want   string
+	}{
+		{nil, ""},
+		{[]*Color{nil, &NoColor}, ""},
+		{[]*Color{Red}, "\x1b[31m"},
+		{[]*Color{NewColor(Bold), Red}, "\x1b[1;31m"},
+		{[]*Color{NewColor(Bold, FgRed), NewColor(Bold, Underline)}, "\x1b[1;4;31m"},
+		{[]*Color{Red, Blue}, "\x1b[34m"},
+		{[]*Color{NewColor(FgRed, BgBlue), NewColor(BgHiWhite)}, "\x1b[31;107m"},
+		{[]*Color{NewColor(38, 5, 200), Red}, "\x1b[31m"},
+		{[]*Color{Red, NewColor(38, 2, 1, 2, 3), NewColor(Bold)}, "\x1b[1;38;2;1;2;3m"},
+		{[]*Color{NewColor(48, 5, 1), NewColor(Italic, 48, 5, 2)}, "\x1b[3;48;5;2m"},
+	}
+	for _, test := range tests {
+		got := Combine(test.colors...).Format()
+		if got != test.want {
+			t.Errorf("Combine(%v) = %q; want: %q", test.colors, got, test.want)
+		}
+	}
+}
+
+func TestColorWith(t *testing.T) {
+	c := NewColor(Bold, FgRed)
+	x := c.With(Green)
+	if want := "\x1b[1;32m"; x.Format() != want {
+		t.Errorf("With = %q; want: %q", x.Format(), want)
+	}
+	if want := "\x1b[1;31m"; c.Format() != want {
+		t.Errorf("With modified the receiver: %q; want: %q", c.Format(), want)
+	}
+	var nilColor *Color
+	if got := nilColor.With(Red); !got.Equal(Red) {
+		t.Errorf("nil.With(Red) = %q; want: %q", got.Format(), Red.Format())
+	}
+}
+
+func TestColorAttrs(t *testing.T) {
+	c := NewColor(Bold, FgRed, 48, 5, 200)
+	attrs := c.Attrs()
+	if want := []Attribute{Bold, FgRed, 48, 5, 200}; !reflect.DeepEqual(attrs, want) {
+		t.Errorf("Attrs() = %v; want: %v", attrs, want)
+	}
+	attrs[0] = Italic
+	if c.Attrs()[0] != Bold {
+		t.Error("Attrs() should return a copy")
+	}
+	var nilColor *Color
+	if attrs := nilColor.Attrs(); attrs != nil {
+		t.Errorf("nil.Attrs() = %v; want: nil", attrs)
+	}
+
+	tests := []struct {
+		name string
+		got  *Color
+		want string
+	}{
+		{"Fg", c.Fg(), "\x1b[31m"},
+		{"Bg", c.Bg(), "\x1b[48;5;200m"},
+		{"NoBg", Red.Bg(), ""},
+		{"NilFg", nilColor.Fg(), ""},
+		{"Without", c.Without(Bold), "\x1b[31;48;5;200m"},
+		{"WithoutExtended", c.Without(48), "\x1b[1;31m"},
+		{"WithoutMissing", c.Without(Italic), c.Format()},
+		{"WithoutAll", Red.Without(FgRed), ""},
+		{"WithoutParameter", NewColor(38, 5, 1).Without(Bold), "\x1b[38;5;1m"},
+		{"WithoutParameterBold", NewColor(38, 5, 1, 1).Without(Bold), "\x1b[38;5;1m"},
+		{"WithoutParameterRGB", NewColor(48, 2, 1, 3, 5).Without(5), "\x1b[48;2;1;3;5m"},
+	}
+	for _, test := range tests {
+		if got := test.got.Format(); got != test.want {
+			t.Errorf("%s: got: %q want: %q", test.name, got, test.want)
+		}
+	}
+	if ext := NewColor(38, 5, 1); ext.Without(Bold) != ext {
+		t.Error("Without of a missing attribute returned a new Color")
+	}
+}
+
 type buildEscapeTest struct {
 	attrs []Attribute
 	want  string
@@ -486,3 +567,29 @@ func TestXXX(t *testing.T) {
 // 		_ = rgb.ANSI()
 // 	}
 // }
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"", "", true},
+		{"0", "\x1b[0m", true},
+		{"1;34", "\x1b[1;34m", true},
+		{"38;5;208;48;2;1;2;3", "\x1b[38;5;208;48;2;1;2;3m", true},
+		{"1;", "", false},
+		{"256", "", false},
+		{"x", "", false},
+	}
+	for _, test := range tests {
+		c, err := ParseColor(test.in)
+		if (err == nil) != test.ok {
+			t.Errorf("ParseColor(%q) error = %v; want ok: %t", test.in, err, test.ok)
+			continue
+		}
+		if err == nil && c.Format() != test.want {
+			t.Errorf("ParseColor(%q) = %q; want: %q", test.in, c.Format(), test.want)
+		}
+	}
+}
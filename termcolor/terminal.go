@@ -0,0 +1,71 @@
+package termcolor
+
+import (
+	"os"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// termCache caches the result of term.IsTerminal for a standard file.
+type termCache struct {
+	file   *os.File
+	fd     int
+	isTerm bool
+}
+
+var (
+	termMu      sync.Mutex
+	stdoutCache termCache
+	stderrCache termCache
+	forcedTerms map[int]bool
+)
+
+// lookup returns if fd refers to a terminal. The cached result is
+// recomputed if f or its file descriptor changed since the last call.
+func (c *termCache) lookup(f *os.File, fd int) bool {
+	if c.file != f || c.fd != fd {
+		c.file = f
+		c.fd = fd
+		c.isTerm = term.IsTerminal(fd)
+	}
+	return c.isTerm
+}
+
+// IsTerminal returns whether the given file descriptor is a terminal.
+// The results for os.Stdout and os.Stderr are cached, but are recomputed
+// if either is changed to point to a different file.
+func IsTerminal(fd int) bool {
+	termMu.Lock()
+	defer termMu.Unlock()
+	if isTerm, ok := forcedTerms[fd]; ok {
+		return isTerm
+	}
+	if f := os.Stdout; f != nil && int(f.Fd()) == fd {
+		return stdoutCache.lookup(f, fd)
+	}
+	if f := os.Stderr; f != nil && int(f.Fd()) == fd {
+		return stderrCache.lookup(f, fd)
+	}
+	// The results for other file descriptors are not cached since
+	// caching breaks if FDs are reused.
+	return term.IsTerminal(fd)
+}
+
+// ForceTerminal overrides the result of IsTerminal for fd. This is useful
+// for tests and for programs running under a PTY wrapper.
+func ForceTerminal(fd int, isTerm bool) {
+	termMu.Lock()
+	if forcedTerms == nil {
+		forcedTerms = make(map[int]bool)
+	}
+	forcedTerms[fd] = isTerm
+	termMu.Unlock()
+}
+
+// ClearForceTerminal removes any override set by ForceTerminal for fd.
+func ClearForceTerminal(fd int) {
+	termMu.Lock()
+	delete(forcedTerms, fd)
+	termMu.Unlock()
+}
@@ -0,0 +1,46 @@
+package termcolor
+
+import (
+	"os"
+	"testing"
+)
+
+func TestIsTerminalStdoutChange(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "stdout-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	orig := os.Stdout
+	defer func() { os.Stdout = orig }()
+
+	ForceTerminal(int(f.Fd()), true)
+	os.Stdout = f
+	if !IsTerminal(int(f.Fd())) {
+		t.Error("IsTerminal() = false; want: true when forced")
+	}
+	ClearForceTerminal(int(f.Fd()))
+	if IsTerminal(int(f.Fd())) {
+		t.Error("IsTerminal() = true; want: false for a regular file")
+	}
+	if stdoutCache.file != f {
+		t.Error("stdout cache was not updated after os.Stdout changed")
+	}
+}
+
+func TestForceTerminal(t *testing.T) {
+	const fd = 12345 // not a valid file descriptor
+	if IsTerminal(fd) {
+		t.Fatalf("IsTerminal(%d) = true; want: false", fd)
+	}
+	ForceTerminal(fd, true)
+	defer ClearForceTerminal(fd)
+	if !IsTerminal(fd) {
+		t.Errorf("IsTerminal(%d) = false; want: true", fd)
+	}
+	ForceTerminal(fd, false)
+	if IsTerminal(fd) {
+		t.Errorf("IsTerminal(%d) = true; want: false", fd)
+	}
+}
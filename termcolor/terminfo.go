@@ -0,0 +1,164 @@
+package termcolor
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Terminfo describes the color capabilities of a terminal.
+type Terminfo struct {
+	Names     []string // terminal names and aliases
+	MaxColors int      // max_colors (colors) or 0 if not supported
+	Bold      bool     // enter_bold_mode (bold) is supported
+	Underline bool     // enter_underline_mode (smul) is supported
+	Italic    bool     // enter_italics_mode (sitm) is supported
+}
+
+// Terminfo magic numbers for the legacy (16-bit) and extended number
+// (32-bit) formats.
+const (
+	terminfoMagic16 = 0432
+	terminfoMagic32 = 01036
+)
+
+// Indexes of capabilities in the terminfo numbers and strings sections.
+const (
+	tiMaxColors          = 13
+	tiEnterBoldMode      = 27
+	tiEnterUnderlineMode = 36
+	tiEnterItalicsMode   = 311
+)
+
+var errInvalidTerminfo = errors.New("termcolor: invalid terminfo data")
+
+// parseTerminfo parses a compiled terminfo entry, see term(5).
+func parseTerminfo(data []byte) (*Terminfo, error) {
+	const headerSize = 12
+	if len(data) < headerSize {
+		return nil, errInvalidTerminfo
+	}
+	header := make([]int, 6)
+	for i := range header {
+		header[i] = int(binary.LittleEndian.Uint16(data[i*2:]))
+	}
+	numSize := 2
+	switch header[0] {
+	case terminfoMagic16:
+	case terminfoMagic32:
+		numSize = 4
+	default:
+		return nil, errInvalidTerminfo
+	}
+	namesSize, boolCount, numCount, strCount := header[1], header[2], header[3], header[4]
+
+	off := headerSize
+	if len(data) < off+namesSize+boolCount {
+		return nil, errInvalidTerminfo
+	}
+	names := strings.TrimRight(string(data[off:off+namesSize]), "\x00")
+	off += namesSize + boolCount
+	if off%2 != 0 {
+		off++ // numbers are aligned on an even byte
+	}
+
+	if len(data) < off+numCount*numSize+strCount*2 {
+		return nil, errInvalidTerminfo
+	}
+	num := func(i int) int {
+		if i >= numCount {
+			return -1
+		}
+		p := data[off+i*numSize:]
+		if numSize == 4 {
+			return int(int32(binary.LittleEndian.Uint32(p)))
+		}
+		return int(int16(binary.LittleEndian.Uint16(p)))
+	}
+	ti := &Terminfo{
+		Names:     strings.Split(names, "|"),
+		MaxColors: num(tiMaxColors),
+	}
+	if ti.MaxColors < 0 {
+		ti.MaxColors = 0
+	}
+	off += numCount * numSize
+
+	// A string capability is present if its offset is non-negative.
+	str := func(i int) bool {
+		return i < strCount && int16(binary.LittleEndian.Uint16(data[off+i*2:])) >= 0
+	}
+	ti.Bold = str(tiEnterBoldMode)
+	ti.Underline = str(tiEnterUnderlineMode)
+	ti.Italic = str(tiEnterItalicsMode)
+	return ti, nil
+}
+
+// terminfoDirs returns the directories searched for terminfo entries.
+func terminfoDirs() []string {
+	var dirs []string
+	if dir := os.Getenv("TERMINFO"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".terminfo"))
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("TERMINFO_DIRS")) {
+		if dir == "" {
+			dir = "/usr/share/terminfo" // empty means the system default
+		}
+		dirs = append(dirs, dir)
+	}
+	return append(dirs, "/etc/terminfo", "/lib/terminfo", "/usr/share/terminfo")
+}
+
+// builtinTerminfo is used when a terminal is not found in the terminfo
+// database.
+var builtinTerminfo = map[string]Terminfo{
+	"dumb":                  {},
+	"vt100":                 {Bold: true, Underline: true},
+	"linux":                 {MaxColors: 8, Bold: true, Underline: true},
+	"ansi":                  {MaxColors: 8, Bold: true, Underline: true},
+	"xterm":                 {MaxColors: 8, Bold: true, Underline: true, Italic: true},
+	"xterm-color":           {MaxColors: 8, Bold: true, Underline: true},
+	"xterm-16color":         {MaxColors: 16, Bold: true, Underline: true, Italic: true},
+	"xterm-256color":        {MaxColors: 256, Bold: true, Underline: true, Italic: true},
+	"xterm-direct":          {MaxColors: 1 << 24, Bold: true, Underline: true, Italic: true},
+	"xterm-kitty":           {MaxColors: 256, Bold: true, Underline: true, Italic: true},
+	"alacritty":             {MaxColors: 256, Bold: true, Underline: true, Italic: true},
+	"screen":                {MaxColors: 8, Bold: true, Underline: true},
+	"screen-256color":       {MaxColors: 256, Bold: true, Underline: true},
+	"tmux":                  {MaxColors: 8, Bold: true, Underline: true, Italic: true},
+	"tmux-256color":         {MaxColors: 256, Bold: true, Underline: true, Italic: true},
+	"rxvt-unicode":          {MaxColors: 88, Bold: true, Underline: true, Italic: true},
+	"rxvt-unicode-256color": {MaxColors: 256, Bold: true, Underline: true, Italic: true},
+}
+
+// LoadTerminfo returns the Terminfo for terminal name. The terminfo
+// database is searched first and if name is not found there a builtin
+// table of common terminals is consulted.
+func LoadTerminfo(name string) (*Terminfo, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, errors.New("termcolor: invalid terminal name: " + strconv.Quote(name))
+	}
+	for _, dir := range terminfoDirs() {
+		// Darwin uses the hex value of the first character.
+		for _, sub := range []string{name[:1], strconv.FormatUint(uint64(name[0]), 16)} {
+			data, err := os.ReadFile(filepath.Join(dir, sub, name))
+			if err != nil {
+				continue
+			}
+			if ti, err := parseTerminfo(data); err == nil {
+				return ti, nil
+			}
+		}
+	}
+	if ti, ok := builtinTerminfo[name]; ok {
+		ti.Names = []string{name}
+		return &ti, nil
+	}
+	return nil, errors.New("termcolor: terminfo entry not found: " + strconv.Quote(name))
+}
@@ -0,0 +1,89 @@
+package termcolor
+
+import (
+	"io"
+	"strings"
+)
+
+type textSegment struct {
+	color *Color
+	end   int // end offset of the segment in Text.buf
+}
+
+// A Text is used to build a line of text from multiple colored segments.
+// Adjacent segments with identical styles are coalesced so that only one
+// pair of escape sequences is emitted for them. The zero value is ready
+// to use.
+type Text struct {
+	buf  []byte
+	segs []textSegment
+}
+
+func sameStyle(c1, c2 *Color) bool {
+	if c1.IsZero() {
+		return c2.IsZero()
+	}
+	return !c2.IsZero() && c1.escape == c2.escape
+}
+
+// Append appends s colored with c to t and returns t.
+func (t *Text) Append(c *Color, s string) *Text {
+	if len(s) == 0 {
+		return t
+	}
+	t.buf = append(t.buf, s...)
+	if n := len(t.segs) - 1; n >= 0 && sameStyle(t.segs[n].color, c) {
+		t.segs[n].end = len(t.buf)
+	} else {
+		t.segs = append(t.segs, textSegment{color: c, end: len(t.buf)})
+	}
+	return t
+}
+
+// AppendPlain appends s to t without any color and returns t.
+func (t *Text) AppendPlain(s string) *Text {
+	return t.Append(nil, s)
+}
+
+// Len returns the length of the text in t excluding escape sequences.
+func (t *Text) Len() int { return len(t.buf) }
+
+// Reset resets t to be empty.
+func (t *Text) Reset() {
+	t.buf = t.buf[:0]
+	t.segs = t.segs[:0]
+}
+
+// Plain returns the text of t without any escape sequences.
+func (t *Text) Plain() string { return string(t.buf) }
+
+// AppendTo appends the colored text of t to b and returns the result.
+func (t *Text) AppendTo(b []byte) []byte {
+	start := 0
+	for _, seg := range t.segs {
+		b = seg.color.Append(b)
+		b = append(b, t.buf[start:seg.end]...)
+		b = append(b, seg.color.Reset()...)
+		start = seg.end
+	}
+	return b
+}
+
+// String returns the colored text of t.
+func (t *Text) String() string {
+	var w strings.Builder
+	start := 0
+	for _, seg := range t.segs {
+		w.WriteString(seg.color.Format())
+		w.Write(t.buf[start:seg.end])
+		w.WriteString(seg.color.Reset())
+		start = seg.end
+	}
+	return w.String()
+}
+
+// WriteTo writes the colored text of t to w.
+func (t *Text) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(t.AppendTo(nil))
+	return int64(n), err
+}
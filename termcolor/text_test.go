@@ -0,0 +1,37 @@
+package termcolor
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestText(t *testing.T) {
+	var text Text
+	text.Append(Red, "a").Append(Red, "b").AppendPlain(" ").
+		Append(nil, "c").Append(Green, "").Append(NewColor(FgRed), "d")
+
+	want := Red.Format() + "ab" + Reset + " c" + Red.Format() + "d" + Reset
+	if got := text.String(); got != want {
+		t.Errorf("String() = %q; want: %q", got, want)
+	}
+	if got := string(text.AppendTo(nil)); got != want {
+		t.Errorf("AppendTo() = %q; want: %q", got, want)
+	}
+	var buf bytes.Buffer
+	if _, err := text.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("WriteTo() = %q; want: %q", got, want)
+	}
+	if got := text.Plain(); got != "ab cd" {
+		t.Errorf("Plain() = %q; want: %q", got, "ab cd")
+	}
+	if text.Len() != len("ab cd") {
+		t.Errorf("Len() = %d; want: %d", text.Len(), len("ab cd"))
+	}
+	text.Reset()
+	if s := text.String(); s != "" {
+		t.Errorf("String() = %q after Reset; want: %q", s, "")
+	}
+}
@@ -0,0 +1,77 @@
+package termcolor
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// disabled is non-zero if color output is disabled process-wide.
+var disabled int32
+
+// Disable disables color output process-wide. When disabled the
+// Format, Reset, Append and Sprintf methods of all Colors produce
+// no escape sequences.
+func Disable() { atomic.StoreInt32(&disabled, 1) }
+
+// Enable re-enables color output process-wide. Color output is
+// enabled by default.
+func Enable() { atomic.StoreInt32(&disabled, 0) }
+
+// Enabled reports whether color output is enabled process-wide.
+func Enabled() bool { return atomic.LoadInt32(&disabled) == 0 }
+
+// A ColorWriter wraps an io.Writer and only emits color escape
+// sequences if color is enabled for both the writer and the process.
+type ColorWriter struct {
+	io.Writer
+	disabled bool
+}
+
+// NewColorWriter returns a new ColorWriter that writes to w. Color is
+// enabled if w has a file descriptor that refers to a terminal.
+func NewColorWriter(w io.Writer) *ColorWriter {
+	enabled := false
+	if f, ok := w.(interface{ Fd() uintptr }); ok {
+		enabled = IsTerminal(int(f.Fd()))
+	}
+	return &ColorWriter{Writer: w, disabled: !enabled}
+}
+
+// SetEnabled enables or disables color output for w.
+func (w *ColorWriter) SetEnabled(enabled bool) { w.disabled = !enabled }
+
+// Enabled reports whether w will emit color escape sequences.
+func (w *ColorWriter) Enabled() bool { return !w.disabled && Enabled() }
+
+// Format returns the escape sequence of c or an empty string if color
+// is disabled for w.
+func (w *ColorWriter) Format(c *Color) string {
+	if w.Enabled() {
+		return c.Format()
+	}
+	return ""
+}
+
+// Reset returns the reset escape sequence for c or an empty string
+// if color is disabled for w.
+func (w *ColorWriter) Reset(c *Color) string {
+	if w.Enabled() {
+		return c.Reset()
+	}
+	return ""
+}
+
+// WriteColor writes s to w wrapped in the escape sequences of c.
+func (w *ColorWriter) WriteColor(c *Color, s string) (int, error) {
+	if w.Enabled() && !c.IsZero() {
+		return io.WriteString(w.Writer, c.escape+s+Reset)
+	}
+	return io.WriteString(w.Writer, s)
+}
+
+// Printf formats according to a format specifier and writes the
+// result to w wrapped in the escape sequences of c.
+func (w *ColorWriter) Printf(c *Color, format string, v ...interface{}) (int, error) {
+	return fmt.Fprintf(w.Writer, w.Format(c)+format+w.Reset(c), v...)
+}
@@ -0,0 +1,47 @@
+package termcolor
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDisable(t *testing.T) {
+	Disable()
+	defer Enable()
+	if Enabled() {
+		t.Fatal("Enabled() = true after Disable()")
+	}
+	if s := Red.Format(); s != "" {
+		t.Errorf("Format() = %q; want: %q", s, "")
+	}
+	if s := Red.Reset(); s != "" {
+		t.Errorf("Reset() = %q; want: %q", s, "")
+	}
+	if s := Red.Sprintf("%s", "Hello"); s != "Hello" {
+		t.Errorf("Sprintf() = %q; want: %q", s, "Hello")
+	}
+	Enable()
+	if s := Red.Format(); s != "\x1b[31m" {
+		t.Errorf("Format() = %q; want: %q", s, "\x1b[31m")
+	}
+}
+
+func TestColorWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewColorWriter(&buf)
+	if w.Enabled() {
+		t.Error("ColorWriter should be disabled for non-terminals")
+	}
+	w.WriteColor(Red, "a")
+	w.SetEnabled(true)
+	w.WriteColor(Red, "b")
+	w.Printf(Green, "%d", 1)
+	Disable()
+	w.WriteColor(Red, "c")
+	Enable()
+
+	want := "a" + Red.Format() + "b" + Reset + Green.Format() + "1" + Reset + "c"
+	if got := buf.String(); got != want {
+		t.Errorf("got: %q want: %q", got, want)
+	}
+}
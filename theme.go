@@ -0,0 +1,101 @@
+package pjson
+
+import "github.com/charlievieth/pjson/termcolor"
+
+// rgbTheme is a color scheme defined by the 24-bit color of each token
+// class. It is downgraded to the colors supported by the terminal.
+type rgbTheme struct {
+	null, boolean, key, str, num, punct termcolor.RGB
+}
+
+func (t *rgbTheme) config(level termcolor.Level) *IndentConfig {
+	if level == termcolor.LevelNone {
+		// Color was forced without a known capability.
+		level = termcolor.LevelBasic
+	}
+	color := func(c termcolor.RGB) *termcolor.Color { return c.Color(level, nil) }
+	key := color(t.key)
+	conf := NewIndentConfig(nil,
+		WithNull(color(t.null)),
+		WithBool(color(t.boolean)),
+		WithKeyword(key),
+		WithString(color(t.str)),
+		WithNumeric(color(t.num)),
+		WithPunctuation(color(t.punct)),
+	)
+	conf.EmptyKey = termcolor.Combine(termcolor.NewColor(termcolor.ReverseVideo), key)
+	return conf
+}
+
+// rgb returns the color with the hex value v, such as 0xf8f8f2.
+func rgb(v uint32) termcolor.RGB {
+	return termcolor.RGB{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v)}
+}
+
+// presetTheme returns the config function of a theme with the colors of
+// conf at every level.
+func presetTheme(conf *IndentConfig) func(termcolor.Level) *IndentConfig {
+	return func(termcolor.Level) *IndentConfig { return NewIndentConfig(conf) }
+}
+
+// themes are the built-in color schemes in the order they are listed.
+var themes = []struct {
+	name   string
+	config func(level termcolor.Level) *IndentConfig
+}{
+	{"default", presetTheme(&DefaultIndentConfig)},
+	{"jq", presetTheme(&JQIndentConfig)},
+	{"monokai", (&rgbTheme{
+		null:    rgb(0x66d9ef),
+		boolean: rgb(0x66d9ef),
+		key:     rgb(0xf92672),
+		str:     rgb(0xe6db74),
+		num:     rgb(0xae81ff),
+		punct:   rgb(0xf8f8f2),
+	}).config},
+	{"solarized-dark", (&rgbTheme{
+		null:    rgb(0xcb4b16),
+		boolean: rgb(0xb58900),
+		key:     rgb(0x268bd2),
+		str:     rgb(0x2aa198),
+		num:     rgb(0xd33682),
+		punct:   rgb(0x839496),
+	}).config},
+	{"solarized-light", (&rgbTheme{
+		null:    rgb(0xcb4b16),
+		boolean: rgb(0xb58900),
+		key:     rgb(0x268bd2),
+		str:     rgb(0x2aa198),
+		num:     rgb(0xd33682),
+		punct:   rgb(0x657b83),
+	}).config},
+	{"dracula", (&rgbTheme{
+		null:    rgb(0xff79c6),
+		boolean: rgb(0xff79c6),
+		key:     rgb(0x8be9fd),
+		str:     rgb(0xf1fa8c),
+		num:     rgb(0xbd93f9),
+		punct:   rgb(0xf8f8f2),
+	}).config},
+}
+
+// ThemeNames returns the names of the built-in themes.
+func ThemeNames() []string {
+	names := make([]string, len(themes))
+	for i, t := range themes {
+		names[i] = t.name
+	}
+	return names
+}
+
+// Theme returns a new IndentConfig with the colors of the built-in theme
+// name, using colors the terminal with the capability level supports. It
+// returns false if there is no such theme.
+func Theme(name string, level termcolor.Level) (*IndentConfig, bool) {
+	for _, t := range themes {
+		if t.name == name {
+			return t.config(level), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,43 @@
+package pjson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charlievieth/pjson/termcolor"
+)
+
+func TestTheme(t *testing.T) {
+	names := ThemeNames()
+	if len(names) == 0 || names[0] != "default" {
+		t.Fatalf("ThemeNames() = %q; want default first", names)
+	}
+	for _, name := range names {
+		for _, level := range []termcolor.Level{termcolor.LevelNone, termcolor.LevelBasic,
+			termcolor.Level256, termcolor.LevelTrueColor} {
+			conf, ok := Theme(name, level)
+			if !ok {
+				t.Fatalf("Theme(%q) not found", name)
+			}
+			if conf.isPlain() {
+				t.Errorf("Theme(%q, %s) has no colors", name, level)
+			}
+			var dst strings.Builder
+			if err := conf.IndentStream(&dst, strings.NewReader(`{"a":[1,true,null,"s"]}`), "", "  "); err != nil {
+				t.Fatal(err)
+			}
+			if got := termcolor.Strip(nil, []byte(dst.String())); string(got) != "{\n  \"a\": [\n    1,\n    true,\n    null,\n    \"s\"\n  ]\n}" {
+				t.Errorf("Theme(%q, %s): unexpected output: %q", name, level, got)
+			}
+		}
+	}
+	if conf, _ := Theme("default", termcolor.LevelBasic); *conf != DefaultIndentConfig {
+		t.Errorf("default theme = %+v; want: %+v", *conf, DefaultIndentConfig)
+	}
+	if conf, _ := Theme("dracula", termcolor.LevelTrueColor); conf.String.Format() != "\x1b[38;2;241;250;140m" {
+		t.Errorf("dracula string color = %q", conf.String.Format())
+	}
+	if _, ok := Theme("list", termcolor.LevelBasic); ok {
+		t.Error("Theme(\"list\") should not exist")
+	}
+}
@@ -0,0 +1,37 @@
+package pjson
+
+import "unicode/utf8"
+
+// truncationMark separates a truncated string from its original size.
+const truncationMark = "…"
+
+// TruncateStrings appends src to dst with each string value, not object
+// key, longer than max characters cut to its first max characters and
+// followed by an ellipsis and the size of the original string, such as
+// "AAAA… (84 KB)". The result is valid JSON. An error is returned if src
+// is not valid JSON.
+func TruncateStrings(dst, src []byte, max int) ([]byte, error) {
+	r := rewriter{maxString: max}
+	return r.rewrite(dst, src)
+}
+
+// truncateString returns the JSON string raw truncated to max characters.
+func truncateString(raw []byte, max int) []byte {
+	if len(raw)-2 <= max {
+		return raw // fewer bytes than characters
+	}
+	s, _ := unquote(raw)
+	if utf8.RuneCountInString(s) <= max {
+		return raw
+	}
+	cut := 0
+	for i := 0; i < max; i++ {
+		_, n := utf8.DecodeRuneInString(s[cut:])
+		cut += n
+	}
+	b := []byte(s[:cut])
+	b = append(b, truncationMark+" ("...)
+	b = appendSize(b, len(s))
+	b = append(b, ')')
+	return appendQuoted(nil, string(b))
+}
@@ -0,0 +1,33 @@
+package pjson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruncateStrings(t *testing.T) {
+	long := strings.Repeat("A", 84*1024)
+	tests := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{`{"a": "abc", "b": [1, "abcdef"]}`, 3, `{"a": "abc", "b": [1, "abc… (6 B)"]}`},
+		{`"` + long + `"`, 4, `"AAAA… (84.0 KB)"`},
+		{`{"abcdef": "xy"}`, 2, `{"abcdef": "xy"}`},
+		{`["日本語日本語"]`, 2, `["日本… (18 B)"]`},
+		{`["\u00e9\u00e9\u00e9"]`, 2, `["éé… (6 B)"]`},
+		{`["a\"bc"]`, 2, `["a\"… (4 B)"]`},
+		{`[1, true, null]`, 0, `[1, true, null]`},
+	}
+	for _, test := range tests {
+		got, err := TruncateStrings(nil, []byte(test.in), test.max)
+		if err != nil {
+			t.Errorf("%.40s: %v", test.in, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("%.40s: %d:\ngot:  %s\nwant: %s", test.in, test.max, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package pjson
+
+// A visitEvent is the kind of token passed to a visitFunc.
+type visitEvent uint8
+
+const (
+	visitBegin   visitEvent = iota // the '{' or '[' beginning an object or array
+	visitKey                       // an object key
+	visitLiteral                   // a string, number, boolean or null value
+	visitEnd                       // an object or array, after its end
+)
+
+// A visitFunc is called by visit for each token of a JSON value with the
+// path of the token and its extent start:end in the input. The extent of
+// visitBegin is the opening bracket and that of visitEnd the whole object
+// or array. The path of a key is the path of the value it names. The path
+// is only valid for the duration of the call. Returning false stops the
+// visit.
+type visitFunc func(ev visitEvent, path Path, start, end int) bool
+
+// visit calls fn for each token of the JSON value data in document order.
+// If stream is true data may be a sequence of values separated by white
+// space, each of which is visited with an empty path. An error is returned
+// if data is not valid JSON.
+func visit(data []byte, stream bool, fn visitFunc) error {
+	scan := newScanner()
+	defer freeScanner(scan)
+
+	var starts []int // offsets of the enclosing objects and arrays
+	var path Path    // path[i] is the current member or element of starts[i]
+	litStart := -1
+	litKey := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		v := scan.Step(c)
+		if v == ScanEnd && stream && !isSpace(c) {
+			// Start of the next top-level value
+			scan.Reset()
+			v = scan.Step(c)
+		}
+		if v == ScanError {
+			return scan.err
+		}
+		if litStart != -1 && v != ScanContinue {
+			ev := visitLiteral
+			if litKey {
+				ev = visitKey
+				path[len(path)-1].Key, _ = unquote(data[litStart:i])
+			}
+			if !fn(ev, path, litStart, i) {
+				return nil
+			}
+			litStart = -1
+		}
+		switch v {
+		case ScanBeginLiteral, ScanBeginObject, ScanBeginArray:
+			litKey = v == ScanBeginLiteral && scan.CurrentParseState() == ParseObjectKey
+			if n := len(starts) - 1; !litKey && n >= 0 && data[starts[n]] == '[' {
+				path[n].Index++
+			}
+			if v == ScanBeginLiteral {
+				litStart = i
+				break
+			}
+			if !fn(visitBegin, path, i, i+1) {
+				return nil
+			}
+			// The index of arrays is incremented to 0 when the
+			// first element begins.
+			starts = append(starts, i)
+			path = append(path, PathElem{Index: -1})
+		case ScanEndObject, ScanEndArray:
+			start := starts[len(starts)-1]
+			starts = starts[:len(starts)-1]
+			path = path[:len(path)-1]
+			if !fn(visitEnd, path, start, i+1) {
+				return nil
+			}
+		}
+	}
+	if scan.EOF() == ScanError {
+		return scan.err
+	}
+	if litStart != -1 {
+		// Top-level literal at the end of data
+		fn(visitLiteral, path, litStart, len(data))
+	}
+	return nil
+}